@@ -30,6 +30,8 @@ interface IContractTransparencyConfig {
     struct EventLogConfig {
         bytes32 eventSignature;
         Field[] visibleTo;
+        uint8[] visibleToDataFields; // offsets of the 32 bytes words of the ABI encoded event data containing addresses. The addresses found in these words will be able to query for that event.
+                                     // Each value type and each dynamic type (encoded as an offset) before the address takes one word, static arrays and structs take one word per element.
     }
 
     /**
@@ -38,6 +40,9 @@ interface IContractTransparencyConfig {
     struct VisibilityConfig {
         ContractCfg contractCfg;
         EventLogConfig[] eventLogConfigs;  // mapping from event signature to visibility configs per event
+        bytes32[] publicStorageSlots; // storage slots of a PRIVATE contract readable by everyone via getStorageAt (e.g. the owner or the paused flag)
+        uint64 revealAfterBatches; // the events and transactions of a PRIVATE contract become public this number of batches after their batch. 0 - never
+        uint64 revealAfterTimestamp; // all the events and transactions of a PRIVATE contract become public once the batch timestamp reaches this value. 0 - never
     }
 
     /**
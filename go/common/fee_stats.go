@@ -0,0 +1,88 @@
+package common
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FeeStatsPercentileStep - the granularity of the reward percentiles published for each batch.
+// The rewards are published for the percentiles 0, 5, 10, ..., 100.
+const FeeStatsPercentileStep = 5
+
+// FeeStatsMinTxs - the minimum number of transactions paying fees a batch must have for its fee stats to be published.
+// The percentiles of fewer transactions are close to the fees paid by each of them.
+const FeeStatsMinTxs = 5
+
+// BatchFeeStats - the aggregated priority fees paid by the transactions of a batch.
+// It is calculated by the enclave and streamed to the host together with the batch.
+// Only the gas-weighted percentiles are published, so the fees paid by the individual transactions are not revealed.
+type BatchFeeStats struct {
+	// Rewards - the effective priority fee per gas at each FeeStatsPercentileStep percentile.
+	Rewards []*big.Int `json:"rewards"`
+}
+
+// NewBatchFeeStats - calculates the reward percentiles from the receipts of the batch, the same way geth calculates the fee history.
+// Receipts which didn't pay any fees (e.g. the synthetic transactions) are ignored.
+// Returns nil when fewer than FeeStatsMinTxs transactions paid fees, so nothing is published for the batch.
+func NewBatchFeeStats(baseFee *big.Int, receipts types.Receipts) *BatchFeeStats {
+	type txGasAndReward struct {
+		gasUsed uint64
+		reward  *big.Int
+	}
+
+	sorter := make([]txGasAndReward, 0, len(receipts))
+	totalGasUsed := uint64(0)
+	for _, receipt := range receipts {
+		if receipt == nil || receipt.EffectiveGasPrice == nil || receipt.EffectiveGasPrice.Sign() == 0 || receipt.GasUsed == 0 {
+			continue
+		}
+		reward := new(big.Int).Set(receipt.EffectiveGasPrice)
+		if baseFee != nil {
+			reward.Sub(reward, baseFee)
+		}
+		if reward.Sign() < 0 {
+			reward.SetInt64(0)
+		}
+		sorter = append(sorter, txGasAndReward{gasUsed: receipt.GasUsed, reward: reward})
+		totalGasUsed += receipt.GasUsed
+	}
+
+	if len(sorter) < FeeStatsMinTxs {
+		return nil
+	}
+
+	sort.SliceStable(sorter, func(i, j int) bool {
+		return sorter[i].reward.Cmp(sorter[j].reward) < 0
+	})
+
+	stats := &BatchFeeStats{}
+	var txIndex int
+	sumGasUsed := sorter[0].gasUsed
+	for p := 0; p <= 100; p += FeeStatsPercentileStep {
+		thresholdGasUsed := totalGasUsed * uint64(p) / 100
+		for sumGasUsed < thresholdGasUsed && txIndex < len(sorter)-1 {
+			txIndex++
+			sumGasUsed += sorter[txIndex].gasUsed
+		}
+		stats.Rewards = append(stats.Rewards, sorter[txIndex].reward)
+	}
+	return stats
+}
+
+// Reward - returns the reward published for the highest percentile which is not greater than the requested one.
+// Returns zero when no stats were published for the batch.
+func (s *BatchFeeStats) Reward(percentile float64) *big.Int {
+	if s == nil || len(s.Rewards) == 0 {
+		return big.NewInt(0)
+	}
+	idx := int(percentile) / FeeStatsPercentileStep
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(s.Rewards) {
+		idx = len(s.Rewards) - 1
+	}
+	return s.Rewards[idx]
+}
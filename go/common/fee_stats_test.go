@@ -0,0 +1,72 @@
+package common
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchFeeStats_WeightedByGasUsed(t *testing.T) {
+	baseFee := big.NewInt(100)
+	receipts := types.Receipts{
+		{GasUsed: 21_000, EffectiveGasPrice: big.NewInt(101)},
+		{GasUsed: 21_000, EffectiveGasPrice: big.NewInt(110)},
+		{GasUsed: 8_000, EffectiveGasPrice: big.NewInt(200)},
+		{GasUsed: 21_000, EffectiveGasPrice: big.NewInt(110)},
+		{GasUsed: 21_000, EffectiveGasPrice: big.NewInt(110)},
+		{GasUsed: 8_000, EffectiveGasPrice: big.NewInt(200)},
+		// synthetic transactions don't pay fees, so they are ignored
+		{GasUsed: 50_000, EffectiveGasPrice: big.NewInt(0)},
+	}
+
+	stats := NewBatchFeeStats(baseFee, receipts)
+	require.Len(t, stats.Rewards, 100/FeeStatsPercentileStep+1)
+
+	require.Equal(t, big.NewInt(1), stats.Reward(0))
+	require.Equal(t, big.NewInt(1), stats.Reward(20))
+	require.Equal(t, big.NewInt(10), stats.Reward(50))
+	require.Equal(t, big.NewInt(10), stats.Reward(84))
+	require.Equal(t, big.NewInt(100), stats.Reward(85))
+	require.Equal(t, big.NewInt(100), stats.Reward(100))
+}
+
+func TestBatchFeeStats_RewardRoundsDownToPublishedPercentile(t *testing.T) {
+	stats := NewBatchFeeStats(big.NewInt(0), types.Receipts{
+		{GasUsed: 50, EffectiveGasPrice: big.NewInt(1)},
+		{GasUsed: 50, EffectiveGasPrice: big.NewInt(1)},
+		{GasUsed: 50, EffectiveGasPrice: big.NewInt(1)},
+		{GasUsed: 50, EffectiveGasPrice: big.NewInt(2)},
+		{GasUsed: 50, EffectiveGasPrice: big.NewInt(2)},
+		{GasUsed: 50, EffectiveGasPrice: big.NewInt(2)},
+	})
+
+	require.Equal(t, big.NewInt(1), stats.Reward(50))
+	require.Equal(t, big.NewInt(1), stats.Reward(54.9))
+	require.Equal(t, big.NewInt(2), stats.Reward(55))
+}
+
+func TestBatchFeeStats_EmptyBatch(t *testing.T) {
+	stats := NewBatchFeeStats(big.NewInt(100), types.Receipts{})
+	require.Nil(t, stats)
+	require.Equal(t, big.NewInt(0), stats.Reward(50))
+
+	var missing *BatchFeeStats
+	require.Equal(t, big.NewInt(0), missing.Reward(50))
+}
+
+func TestBatchFeeStats_TooFewTransactions(t *testing.T) {
+	receipts := make(types.Receipts, 0, FeeStatsMinTxs)
+	for i := 0; i < FeeStatsMinTxs-1; i++ {
+		receipts = append(receipts, &types.Receipt{GasUsed: 21_000, EffectiveGasPrice: big.NewInt(int64(110 + i))})
+	}
+	// the transactions which didn't pay fees are not counted
+	receipts = append(receipts, &types.Receipt{GasUsed: 50_000, EffectiveGasPrice: big.NewInt(0)})
+
+	// the percentiles of a few transactions would reveal the fees paid by each of them
+	require.Nil(t, NewBatchFeeStats(big.NewInt(100), receipts))
+
+	receipts = append(receipts, &types.Receipt{GasUsed: 21_000, EffectiveGasPrice: big.NewInt(120)})
+	require.NotNil(t, NewBatchFeeStats(big.NewInt(100), receipts))
+}
@@ -2,7 +2,9 @@ package host
 
 import (
 	"context"
+	"time"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ten-protocol/go-ten/go/common"
 	hostconfig "github.com/ten-protocol/go-ten/go/host/config"
 	"github.com/ten-protocol/go-ten/go/host/storage"
@@ -38,6 +40,11 @@ type Host interface {
 
 	// ConfirmedHeadBatch retrieves the most recent batch processed by the enclave
 	ConfirmedHeadBatch() (*common.BatchHeader, error)
+
+	// Peers returns the peer table the host discovered from the L1 enclave registry
+	Peers() []*PeerInfo
+	// PeerScores returns the scores of the authenticated P2P peers the host received messages from
+	PeerScores() []PeerScore
 }
 
 type BatchMsg struct {
@@ -45,6 +52,28 @@ type BatchMsg struct {
 	IsLive  bool               // true if these batches are being sent as new, false if in response to a p2p request
 }
 
+// PeerInfo is an entry of the peer table, describing an enclave registered on L1 and the host it runs behind
+type PeerInfo struct {
+	EnclaveID   common.EnclaveID
+	HostAddress string
+	HostID      gethcommon.Address // the L1 account of the host, which authenticates its P2P connections
+	IsAttested  bool               // whether an attested enclave responded to the secret request of the enclave
+	IsSequencer bool               // whether the enclave is permissioned to produce batches
+	L1Block     uint64             // the L1 block of the last change to the entry
+}
+
+// PeerScore is the reputation of an authenticated P2P peer, built from the messages received from it
+type PeerScore struct {
+	Address           string
+	Score             float64
+	DecodeFailures    uint64        // messages from the peer which couldn't be decoded
+	InvalidSignatures uint64        // batches from the peer not signed by a sequencer enclave
+	RateLimited       uint64        // messages from the peer dropped for exceeding its rate limit
+	Requests          uint64        // batch requests received from the peer
+	Latency           time.Duration // the average time the peer took to respond to our batch requests
+	BannedUntil       time.Time     // zero if the peer is not banned
+}
+
 type P2PHostService interface {
 	Service
 	P2P
@@ -58,6 +58,17 @@ type P2P interface {
 	// SubscribeForBatchRequests will register a handler to receive new batch requests from peers, returns unsubscribe func
 	// todo (@matt) feels a bit weird to have this in this interface since it relates to serving data rather than receiving
 	SubscribeForBatchRequests(handler P2PBatchRequestHandler) func()
+
+	// Peers returns the peer table discovered from the L1 enclave registry, empty if the discovery is disabled
+	Peers() []*PeerInfo
+	// PeerScores returns the scores of the authenticated peers the host received messages from
+	PeerScores() []PeerScore
+
+	// SetStandby switches a sequencer host between the active sequencer and a standby which follows the batches like a
+	// validator, used by the sequencer failover
+	SetStandby(standby bool)
+	// IsStandby returns whether the host is a standby sequencer host
+	IsStandby() bool
 }
 
 // P2PBatchHandler is an interface for receiving new batches from the P2P network as they arrive
@@ -84,11 +95,15 @@ type L1DataService interface {
 	Subscribe(handler L1BlockHandler) func()
 
 	FetchBlockByHeight(height *big.Int) (*types.Header, error)
+	// FetchBlock returns the block with the given hash
+	FetchBlock(ctx context.Context, blockHash common.L1BlockHash) (*types.Header, error)
 	// FetchNextBlock returns the next canonical block after a given block hash
 	// It returns the new block, a bool which is true if the block is the current L1 head and a bool if the block is on a different fork to prevBlock
 	FetchNextBlock(prevBlock gethcommon.Hash) (*types.Header, bool, error)
 	// GetTenRelevantTransactions returns the events and transactions relevant to Ten
 	GetTenRelevantTransactions(block *types.Header) (*common.ProcessedL1Data, error)
+	// FetchEnclaveRegistryEvents returns the changes to the enclave registry in the given range of blocks, in order
+	FetchEnclaveRegistryEvents(fromHeight *big.Int, toHeight *big.Int) ([]*common.EnclaveRegistryEvent, error)
 }
 
 // L1BlockHandler is an interface for receiving new blocks from the repository as they arrive
@@ -121,6 +136,10 @@ type L1Publisher interface {
 	GetImportantContracts() *common.NetworkConfigAddresses
 	// ResyncImportantContracts will fetch the latest important contracts from the network contract amd update the cache
 	ResyncImportantContracts() error
+	// IsSequencerEnclave returns whether the enclave is permissioned to produce batches in the enclave registry
+	IsSequencerEnclave(enclaveID common.EnclaveID) (bool, error)
+	// IsEnclaveAttested returns whether the enclave is attested in the enclave registry
+	IsEnclaveAttested(enclaveID common.EnclaveID) (bool, error)
 }
 
 // L2BatchRepository provides an interface for the host to request L2 batch data (live-streaming and historical)
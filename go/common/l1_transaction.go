@@ -170,3 +170,13 @@ func (p *ProcessedL1Data) HasEvents(tenEventType L1TenEventType) bool {
 
 	return false
 }
+
+// EnclaveRegistryEvent is a change to the network enclave registry on L1, which the hosts use to discover their peers.
+// The Type is one of InitialiseSecretTx, SecretRequestTx, SecretResponseTx, SequencerAddedTx or SequencerRevokedTx.
+type EnclaveRegistryEvent struct {
+	Type        L1TenEventType
+	EnclaveID   EnclaveID
+	HostAddress string             // the P2P address from the attestation, only set for the events which carry one
+	HostID      gethcommon.Address // the L1 account of the host which submitted the attestation, set with the HostAddress
+	BlockNumber uint64
+}
@@ -55,6 +55,11 @@ type LogSubscription struct {
 
 	// A subscriber-defined filter to apply to the stream of logs.
 	Filter *FilterCriteriaJSON
+
+	// AccountState - the subscription is notified of the batches which changed the state of the account of the viewing
+	// key, instead of the logs matching the filter. Each notification is a log without topics, with the address of the
+	// account and the hash and number of the batch.
+	AccountState bool
 }
 
 func CreateAuthenticatedLogSubscriptionPayload(args []interface{}, vk *viewingkey.ViewingKey) (*LogSubscription, error) {
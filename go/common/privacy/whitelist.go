@@ -2,10 +2,15 @@ package privacy
 
 import (
 	"math/big"
+	"slices"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// Whitelist - the storage slots of private contracts which can be read by everyone.
+// The proxy slots are allowed for all contracts, and each contract can declare additional public slots in its visibility rules.
 type Whitelist struct {
 	AllowedStorageSlots map[string]bool
 }
@@ -23,6 +28,14 @@ func NewWhitelist() *Whitelist {
 	}
 }
 
+// IsStorageSlotAllowed - returns true if the slot is one of the proxy slots, or one of the public slots declared by the contract
+func (w *Whitelist) IsStorageSlotAllowed(slot common.Hash, contractPublicSlots []common.Hash) bool {
+	if w.AllowedStorageSlots[hexutil.EncodeBig(slot.Big())] {
+		return true
+	}
+	return slices.Contains(contractPublicSlots, slot)
+}
+
 func toEip1967HashHex(key string) string {
 	hash := crypto.Keccak256Hash([]byte(key))
 	hashAsBig := hash.Big()
@@ -0,0 +1,25 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageSlotWhitelist(t *testing.T) {
+	whitelist := NewWhitelist()
+	implementationSlot := common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	ownerSlot := common.HexToHash("0x0")
+	pausedSlot := common.HexToHash("0x5")
+
+	// the proxy slots are allowed for all contracts
+	require.True(t, whitelist.IsStorageSlotAllowed(implementationSlot, nil))
+	require.False(t, whitelist.IsStorageSlotAllowed(ownerSlot, nil))
+
+	// the public slots declared by a contract are allowed only for that contract
+	contractSlots := []common.Hash{ownerSlot, pausedSlot}
+	require.True(t, whitelist.IsStorageSlotAllowed(ownerSlot, contractSlots))
+	require.True(t, whitelist.IsStorageSlotAllowed(pausedSlot, contractSlots))
+	require.False(t, whitelist.IsStorageSlotAllowed(common.HexToHash("0x1"), contractSlots))
+}
@@ -45,6 +45,7 @@ type PublicTransaction struct {
 	BatchHeight     *big.Int
 	BatchTimestamp  uint64
 	Finality        FinalityType
+	Revealed        bool // true when the receipt became public because of the delayed disclosure rules of the contract
 }
 
 type PublicBatch struct {
@@ -21,7 +21,13 @@ const (
 	ERPCGetLogs                 = "ten_getLogs"
 	ERPCGetStorageAt            = "ten_getStorageAt"
 	ERPCDebugLogs               = "debug_eventLogRelevancy"
+	ERPCDebugTraceTransaction   = "debug_traceTransaction"
+	ERPCDebugTraceCall          = "debug_traceCall"
+	ERPCDebugTraceBlockByNumber = "debug_traceBlockByNumber"
 	ERPCGetPersonalTransactions = "scan_getPersonalTransactions"
+	ERPCGetPendingTransactions  = "ten_getPendingTransactions"
+	ERPCGetTxPoolContent        = "ten_txPoolContent"
+	ERPCGetProof                = "ten_getProof"
 )
 
 var encryptedMethods = []string{
@@ -37,7 +43,13 @@ var encryptedMethods = []string{
 	ERPCGetLogs,
 	ERPCGetStorageAt,
 	ERPCDebugLogs,
+	ERPCDebugTraceTransaction,
+	ERPCDebugTraceCall,
+	ERPCDebugTraceBlockByNumber,
 	ERPCGetPersonalTransactions,
+	ERPCGetPendingTransactions,
+	ERPCGetTxPoolContent,
+	ERPCGetProof,
 }
 
 // IsEncryptedMethod indicates whether the RPC method's requests and responses should be encrypted.
@@ -1,80 +1,103 @@
-// // Package native: This file was copied/adapted from geth - go-ethereum/eth/tracers
-// //
-// //
-// // Copyright 2021 The go-ethereum Authors
-// // This file is part of the go-ethereum library.
-// //
-// // The go-ethereum library is free software: you can redistribute it and/or modify
-// // it under the terms of the GNU Lesser General Public License as published by
-// // the Free Software Foundation, either version 3 of the License, or
-// // (at your option) any later version.
-// //
-// // The go-ethereum library is distributed in the hope that it will be useful,
-// // but WITHOUT ANY WARRANTY; without even the implied warranty of
-// // MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
-// // GNU Lesser General Public License for more details.
-// //
-// // You should have received a copy of the GNU Lesser General Public License
-// // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
-package native
-
-//
-//import (
-//	"encoding/json"
-//	"math/big"
-//
-//	"github.com/ethereum/go-ethereum/common"
-//	"github.com/ethereum/go-ethereum/core/vm"
-//	"github.com/ethereum/go-ethereum/eth/tracers"
-//)
-//
-//func init() {
-//	tracers.DefaultDirectory.Register("noopTracer", newNoopTracer, false)
-//}
-//
-//// noopTracer is a go implementation of the Tracer interface which
-//// performs no action. It's mostly useful for testing purposes.
-//type noopTracer struct{}
-//
-//// newNoopTracer returns a new noop tracer.
-//func newNoopTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
-//	return &noopTracer{}, nil
-//}
-//
-//// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
-//func (t *noopTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
-//}
-//
-//// CaptureEnd is called after the call finishes to finalize the tracing.
-//func (t *noopTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
-//}
+// Package native: This file was copied/adapted from geth - go-ethereum/eth/tracers
 //
-//// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
-//func (t *noopTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
-//}
 //
-//// CaptureFault implements the EVMLogger interface to trace an execution fault.
-//func (t *noopTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
-//}
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
 //
-//// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
-//func (t *noopTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
-//}
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
 //
-//// CaptureExit is called when EVM exits a scope, even if the scope didn't
-//// execute any code.
-//func (t *noopTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
-//}
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
 //
-//func (*noopTracer) CaptureTxStart(gasLimit uint64) {}
-//
-//func (*noopTracer) CaptureTxEnd(restGas uint64) {}
-//
-//// GetResult returns an empty json object.
-//func (t *noopTracer) GetResult() (json.RawMessage, error) {
-//	return json.RawMessage(`{}`), nil
-//}
-//
-//// Stop terminates execution of the tracer at the first opportune moment.
-//func (t *noopTracer) Stop(error) {
-//}
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/ten-protocol/go-ten/go/common/tracers"
+)
+
+func init() {
+	register("noopTracer", newNoopTracer)
+}
+
+// noopTracer is a go implementation of the Tracer interface which
+// performs no action. It's mostly useful for testing purposes.
+type noopTracer struct{}
+
+// newNoopTracer returns a new noop tracer.
+func newNoopTracer(ctx *tracers.Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*tracers.Tracer, error) {
+	t := &noopTracer{}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart:       t.OnTxStart,
+			OnTxEnd:         t.OnTxEnd,
+			OnEnter:         t.OnEnter,
+			OnExit:          t.OnExit,
+			OnOpcode:        t.OnOpcode,
+			OnFault:         t.OnFault,
+			OnGasChange:     t.OnGasChange,
+			OnBalanceChange: t.OnBalanceChange,
+			OnNonceChange:   t.OnNonceChange,
+			OnCodeChange:    t.OnCodeChange,
+			OnStorageChange: t.OnStorageChange,
+			OnLog:           t.OnLog,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+func (t *noopTracer) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+}
+
+func (t *noopTracer) OnFault(pc uint64, op byte, gas, cost uint64, _ tracing.OpContext, depth int, err error) {
+}
+
+func (t *noopTracer) OnGasChange(old, new uint64, reason tracing.GasChangeReason) {}
+
+func (t *noopTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+func (t *noopTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+}
+
+func (*noopTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+}
+
+func (*noopTracer) OnTxEnd(receipt *types.Receipt, err error) {}
+
+func (*noopTracer) OnBalanceChange(a common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+}
+
+func (*noopTracer) OnNonceChange(a common.Address, prev, new uint64) {}
+
+func (*noopTracer) OnCodeChange(a common.Address, prevCodeHash common.Hash, prev []byte, codeHash common.Hash, code []byte) {
+}
+
+func (*noopTracer) OnStorageChange(a common.Address, k, prev, new common.Hash) {}
+
+func (*noopTracer) OnLog(log *types.Log) {}
+
+// GetResult returns an empty json object.
+func (t *noopTracer) GetResult() (json.RawMessage, error) {
+	return json.RawMessage(`{}`), nil
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *noopTracer) Stop(err error) {
+}
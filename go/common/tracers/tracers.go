@@ -7,9 +7,13 @@ package tracers
 import (
 	"encoding/json"
 	"errors"
+	"math/big"
 
 	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	gethlogger "github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // TraceConfig holds extra parameters to trace functions.
@@ -26,21 +30,35 @@ type TraceConfig struct {
 // Context contains some contextual infos for a transaction execution that is not
 // available from within the EVM object.
 type Context struct {
-	BlockHash gethcommon.Hash // Hash of the block the tx is contained within (zero if dangling tx or call)
-	TxIndex   int             // Index of the transaction within a block (zero if dangling tx or call)
-	TxHash    gethcommon.Hash // Hash of the transaction being traced (zero if dangling call)
+	BlockHash   gethcommon.Hash // Hash of the block the tx is contained within (zero if dangling tx or call)
+	BlockNumber *big.Int        // Number of the block the tx is contained within (zero if dangling tx or call)
+	TxIndex     int             // Index of the transaction within a block (zero if dangling tx or call)
+	TxHash      gethcommon.Hash // Hash of the transaction being traced (zero if dangling call)
 }
 
-// Tracer interface extends vm.EVMLogger and additionally
-// allows collecting the tracing result.
-type Tracer interface {
-	// vm.EVMLogger
-	GetResult() (json.RawMessage, error)
+// TxTraceResult is the result of a single transaction trace.
+type TxTraceResult struct {
+	TxHash  gethcommon.Hash `json:"txHash"`           // transaction hash
+	TxIndex hexutil.Uint    `json:"txIndex"`          // index of the transaction within the batch
+	Result  interface{}     `json:"result,omitempty"` // Trace results produced by the tracer
+	Error   string          `json:"error,omitempty"`  // Trace failure produced by the tracer
+}
+
+// Tracer represents the set of methods that must be exposed by a tracer
+// for it to be available through the RPC interface.
+// This involves a method to retrieve results and one to
+// stop tracing.
+type Tracer struct {
+	*tracing.Hooks
+	GetResult func() (json.RawMessage, error)
 	// Stop terminates execution of the tracer at the first opportune moment.
-	Stop(err error)
+	Stop func(err error)
 }
 
-type lookupFunc func(string, *Context) (Tracer, error)
+// ErrTracerNotFound is returned by a lookup which doesn't know the requested tracer
+var ErrTracerNotFound = errors.New("tracer not found")
+
+type lookupFunc func(string, *Context, json.RawMessage, *params.ChainConfig) (*Tracer, error)
 
 var lookups []lookupFunc
 
@@ -57,12 +75,20 @@ func RegisterLookup(wildcard bool, lookup lookupFunc) {
 }
 
 // New returns a new instance of a tracer, by iterating through the
-// registered lookups.
-func New(code string, ctx *Context) (Tracer, error) {
+// registered lookups. Errors other than ErrTracerNotFound (e.g. an invalid
+// tracer config) are returned to the caller.
+func New(code string, ctx *Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*Tracer, error) {
+	if len(cfg) == 0 {
+		cfg = json.RawMessage("{}")
+	}
 	for _, lookup := range lookups {
-		if tracer, err := lookup(code, ctx); err == nil {
+		tracer, err := lookup(code, ctx, cfg, chainConfig)
+		if err == nil {
 			return tracer, nil
 		}
+		if !errors.Is(err, ErrTracerNotFound) {
+			return nil, err
+		}
 	}
-	return nil, errors.New("tracer not found")
+	return nil, ErrTracerNotFound
 }
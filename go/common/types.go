@@ -27,8 +27,10 @@ type (
 	// when streaming batches out of the enclave.
 	// The properties inside need to be encrypted according to the privacy rules.
 	StreamL2UpdatesResponse struct {
-		Batch *ExtBatch
-		Logs  EncryptedSubscriptionLogs
+		Batch       *ExtBatch
+		FeeStats    *BatchFeeStats // the aggregated fees of the batch. Only set when the batch was executed by the enclave
+		RevealedTxs []TxHash       // the transactions which became public with this batch, because of the delayed disclosure rules of the contracts
+		Logs        EncryptedSubscriptionLogs
 	}
 
 	// MainNet aliases
@@ -6,13 +6,14 @@ import "time"
 //
 //	yaml: `host`
 type HostConfig struct {
-	DB      *HostDB      `mapstructure:"db"`
-	Debug   *HostDebug   `mapstructure:"debug"`
-	Enclave *HostEnclave `mapstructure:"enclave"`
-	L1      *HostL1      `mapstructure:"l1"`
-	Log     *HostLog     `mapstructure:"log"`
-	P2P     *HostP2P     `mapstructure:"p2p"`
-	RPC     *HostRPC     `mapstructure:"rpc"`
+	DB       *HostDB       `mapstructure:"db"`
+	Debug    *HostDebug    `mapstructure:"debug"`
+	Enclave  *HostEnclave  `mapstructure:"enclave"`
+	Failover *HostFailover `mapstructure:"failover"`
+	L1       *HostL1       `mapstructure:"l1"`
+	Log      *HostLog      `mapstructure:"log"`
+	P2P      *HostP2P      `mapstructure:"p2p"`
+	RPC      *HostRPC      `mapstructure:"rpc"`
 }
 
 // HostDB contains the configuration for the host database.
@@ -57,6 +58,36 @@ type HostP2P struct {
 	// (note: this is not the publicly advertised host address, which is currently on node config).
 	BindAddress string        `mapstructure:"bindAddress"`
 	Timeout     time.Duration `mapstructure:"timeout"`
+	// Authenticated specifies whether the hosts keep long-lived connections to each other, authenticated with their L1 keys,
+	// rather than opening a new connection for every message. Only the hosts registered in the L1 enclave registry with their
+	// address and L1 key are accepted, so it requires Discovery.
+	Authenticated bool `mapstructure:"authenticated"`
+	// Gossip specifies whether the validators relay the new batches to each other and serve each other's batch requests,
+	// so the sequencer only sends the batches to a few of them.
+	Gossip bool `mapstructure:"gossip"`
+	// GossipFanout is the number of random peers each batch is sent to when gossip is enabled.
+	GossipFanout int `mapstructure:"gossipFanout"`
+	// Discovery specifies whether the hosts build their peer table from the enclaves registered in the L1 enclave
+	// registry, rather than only from the configured sequencer address and the broadcast registrations.
+	Discovery bool `mapstructure:"discovery"`
+}
+
+// HostFailover contains the configuration for the automatic failover between the sequencer hosts. The hosts don't
+// retarget on a failover: the sequencer hosts must all use `network.sequencer.p2pAddress` as their public P2P
+// address, and the routing in front of them (e.g. a load balancer with health checks) must send it to the active host.
+//
+//	yaml: `host.failover`
+type HostFailover struct {
+	// Enabled specifies whether the sequencer hosts elect the active sequencer with a lease held in a shared database,
+	// and a standby host takes over when the active one stops producing batches and fails its health probes.
+	Enabled bool `mapstructure:"enabled"`
+	// LeaseDBURL is the URL of the Postgres database shared by the sequencer hosts, which holds the sequencer lease.
+	LeaseDBURL    string        `mapstructure:"leaseDBURL"`
+	LeaseDuration time.Duration `mapstructure:"leaseDuration"`
+	// LivenessTimeout is how long the standby hosts wait for a batch before probing the health of the active host.
+	LivenessTimeout time.Duration `mapstructure:"livenessTimeout"`
+	// HealthAddress is the RPC address the other sequencer hosts probe the health of this host at.
+	HealthAddress string `mapstructure:"healthAddress"`
 }
 
 // HostL1 contains the configuration for the host's L1 client and interactions.
@@ -89,4 +120,5 @@ type HostDebug struct {
 	MetricsHTTPPort      uint `mapstructure:"metricsHTTPPort"`
 	EnableProfiler       bool `mapstructure:"enableProfiler"`
 	EnableDebugNamespace bool `mapstructure:"enableDebugNamespace"`
+	EnableAdminNamespace bool `mapstructure:"enableAdminNamespace"`
 }
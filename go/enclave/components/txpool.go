@@ -57,6 +57,10 @@ const (
 // this is how long the node waits to receive the second batch (longer now as we have to wait for all the contracts to be deployed)
 var startMempoolTimeout = 180 * time.Second
 
+// ErrNotAvailableOnValidators - the content of the mempool is only available on the sequencer. The validators only validate
+// the transactions before forwarding them to the sequencer, so their mempool is always empty.
+var ErrNotAvailableOnValidators = errors.New("the mempool content is not available on validators. Please query the sequencer")
+
 // TxPool is an obscuro wrapper around geths transaction pool
 type TxPool struct {
 	txPoolConfig     legacypool.Config
@@ -228,6 +232,33 @@ func (t *TxPool) PendingTransactions() map[gethcommon.Address][]*gethtxpool.Lazy
 	})
 }
 
+// PendingTransactionsFrom returns the pending transactions of the sender, ordered by nonce.
+// Returns ErrNotAvailableOnValidators on validators, because only the sequencer keeps transactions in the pool.
+func (t *TxPool) PendingTransactionsFrom(sender gethcommon.Address) ([]*types.Transaction, error) {
+	if t.validateOnly.Load() {
+		return nil, ErrNotAvailableOnValidators
+	}
+
+	lazyTxs := t.PendingTransactions()[sender]
+	txs := make([]*types.Transaction, 0, len(lazyTxs))
+	for _, lazyTx := range lazyTxs {
+		if tx := lazyTx.Resolve(); tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+// ContentFrom returns the pending and the queued transactions of the sender, ordered by nonce.
+// Returns ErrNotAvailableOnValidators on validators, because only the sequencer keeps transactions in the pool.
+func (t *TxPool) ContentFrom(sender gethcommon.Address) ([]*types.Transaction, []*types.Transaction, error) {
+	if t.validateOnly.Load() {
+		return nil, nil, ErrNotAvailableOnValidators
+	}
+	pending, queued := t.legacyPool.ContentFrom(sender)
+	return pending, queued, nil
+}
+
 func (t *TxPool) Close() error {
 	defer func() {
 		if err := recover(); err != nil {
@@ -17,17 +17,21 @@ import (
 // 3. DD configures and specify the contract as non-transparent, but doesn't configure the event - Contract: false/false , EventVisibilityConfig.AutoConfig=true
 // DD configures the contract as non-transparent, and also configures the topics for the event
 type EventVisibilityConfig struct {
-	AutoConfig                                  bool  // true for events that have no explicit configuration
-	Public                                      bool  // everyone can see and query for this event
-	Topic1CanView, Topic2CanView, Topic3CanView *bool // If the event is not public, and this is true, it means that the address from topicI is an EOA that can view this event
-	SenderCanView                               *bool // if true, the tx signer will see this event. Default false
+	AutoConfig                                  bool    // true for events that have no explicit configuration
+	Public                                      bool    // everyone can see and query for this event
+	Topic1CanView, Topic2CanView, Topic3CanView *bool   // If the event is not public, and this is true, it means that the address from topicI is an EOA that can view this event
+	SenderCanView                               *bool   // if true, the tx signer will see this event. Default false
+	DataFieldsCanView                           []uint8 // the offsets of the 32 bytes words of the log data containing addresses that can view this event
 }
 
 // ContractVisibilityConfig represents the configuration as defined by the dApp developer in the smart contract
 type ContractVisibilityConfig struct {
-	AutoConfig   bool                                       // true for contracts that have no explicit configuration
-	Transparent  *bool                                      // users can configure contracts to be fully transparent. All events will be public, and it will expose the internal storage.
-	EventConfigs map[gethcommon.Hash]*EventVisibilityConfig // map from the event log signature (topics[0]) to the settings
+	AutoConfig           bool                                       // true for contracts that have no explicit configuration
+	Transparent          *bool                                      // users can configure contracts to be fully transparent. All events will be public, and it will expose the internal storage.
+	EventConfigs         map[gethcommon.Hash]*EventVisibilityConfig // map from the event log signature (topics[0]) to the settings
+	PublicStorageSlots   []gethcommon.Hash                          // the storage slots of a private contract which can be read by everyone via getStorageAt
+	RevealAfterBatches   *uint64                                    // the events and transactions of a private contract become public this number of batches after their batch
+	RevealAfterTimestamp *uint64                                    // the events and transactions of a private contract become public once the head batch reaches this timestamp
 }
 
 type TxExecResult struct {
@@ -0,0 +1,277 @@
+package debugger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	"github.com/ten-protocol/go-ten/go/enclave/storage"
+)
+
+// the names of the tracers, as registered in the native package
+const (
+	structLogger   = ""
+	callTracer     = "callTracer"
+	prestateTracer = "prestateTracer"
+	fourByteTracer = "4byteTracer"
+	noopTracer     = "noopTracer"
+)
+
+// visibilityFilter - removes from the trace results the storage, the calls and the logs the requester is not entitled
+// to see. The storage of a contract is visible only if the contract is transparent.
+// Logs follow the same rules as the receipts.
+type visibilityFilter struct {
+	ctx       context.Context
+	storage   storage.Storage
+	requester *gethcommon.Address
+	// cache of the transparency of the contracts touched by the transaction
+	transparent map[gethcommon.Address]bool
+	// the error raised while checking the visibility of the execution reported to the tracer
+	hooksErr error
+}
+
+func newVisibilityFilter(ctx context.Context, storage storage.Storage, requester *gethcommon.Address) *visibilityFilter {
+	return &visibilityFilter{
+		ctx:         ctx,
+		storage:     storage,
+		requester:   requester,
+		transparent: make(map[gethcommon.Address]bool),
+	}
+}
+
+// hideExecution - wraps the hooks of the tracers whose output can't be redacted afterwards, so they are not told about
+// the calls made by the contracts which are not transparent, with all their sub-calls, like in the call tracer output.
+// With hideSteps the opcodes executed by those contracts are not reported either: their sequence, gas, stack, memory
+// and return data reveal what the contract computed, and the accounts it looked up.
+func (f *visibilityFilter) hideExecution(hooks *tracing.Hooks, hideSteps bool) *tracing.Hooks {
+	wrapped := *hooks
+	var hidden []bool // whether each frame of the call stack is hidden
+	inHiddenFrame := func() bool {
+		return len(hidden) > 0 && hidden[len(hidden)-1]
+	}
+	wrapped.OnEnter = func(depth int, typ byte, from gethcommon.Address, to gethcommon.Address, input []byte, gas uint64, value *big.Int) {
+		isHidden := inHiddenFrame()
+		if !isHidden && depth > 0 && (f.requester == nil || from != *f.requester) {
+			isHidden = !f.isVisibleToHooks(from)
+		}
+		hidden = append(hidden, isHidden)
+		if !isHidden && hooks.OnEnter != nil {
+			hooks.OnEnter(depth, typ, from, to, input, gas, value)
+		}
+	}
+	wrapped.OnExit = func(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+		isHidden := false
+		if len(hidden) > 0 {
+			isHidden = hidden[len(hidden)-1]
+			hidden = hidden[:len(hidden)-1]
+		}
+		if !isHidden && hooks.OnExit != nil {
+			hooks.OnExit(depth, output, gasUsed, err, reverted)
+		}
+	}
+	if hooks.OnOpcode != nil {
+		wrapped.OnOpcode = func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+			if inHiddenFrame() || (hideSteps && !f.isVisibleToHooks(scope.Address())) {
+				return
+			}
+			hooks.OnOpcode(pc, op, gas, cost, scope, rData, depth, err)
+		}
+	}
+	return &wrapped
+}
+
+// isVisibleToHooks - like isStorageVisible, the error is recorded to be returned instead of the trace
+func (f *visibilityFilter) isVisibleToHooks(address gethcommon.Address) bool {
+	visible, err := f.isStorageVisible(address)
+	if err != nil && f.hooksErr == nil {
+		f.hooksErr = err
+	}
+	return visible
+}
+
+func (f *visibilityFilter) redact(tracerName string, result json.RawMessage) (json.RawMessage, error) {
+	if f.hooksErr != nil {
+		return nil, f.hooksErr
+	}
+	switch tracerName {
+	case structLogger, fourByteTracer, noopTracer:
+		// the hidden execution was not reported to the tracer
+		return result, nil
+	case callTracer:
+		return f.redactCallFrames(result)
+	case prestateTracer:
+		return f.redactPrestate(result)
+	default:
+		return nil, fmt.Errorf("tracer %s is not supported", tracerName)
+	}
+}
+
+// callLog - the log format of the call tracer
+type callLog struct {
+	Address  gethcommon.Address `json:"address"`
+	Topics   []gethcommon.Hash  `json:"topics"`
+	Data     hexutil.Bytes      `json:"data"`
+	Position hexutil.Uint       `json:"position"`
+}
+
+// redactCallFrames - removes the logs which are not visible to the requester from all the frames,
+// and the nested frames of the calls the requester is not entitled to see
+func (f *visibilityFilter) redactCallFrames(result json.RawMessage) (json.RawMessage, error) {
+	var frame map[string]json.RawMessage
+	if err := json.Unmarshal(result, &frame); err != nil {
+		return nil, fmt.Errorf("could not decode call frame. Cause: %w", err)
+	}
+
+	if rawLogs, found := frame["logs"]; found {
+		var logs []callLog
+		if err := json.Unmarshal(rawLogs, &logs); err != nil {
+			return nil, fmt.Errorf("could not decode call frame logs. Cause: %w", err)
+		}
+		visibleLogs := make([]callLog, 0, len(logs))
+		for _, l := range logs {
+			visible, err := f.isLogVisible(&types.Log{Address: l.Address, Topics: l.Topics, Data: l.Data})
+			if err != nil {
+				return nil, err
+			}
+			if visible {
+				visibleLogs = append(visibleLogs, l)
+			}
+		}
+		if len(visibleLogs) == 0 {
+			delete(frame, "logs")
+		} else {
+			encoded, err := json.Marshal(visibleLogs)
+			if err != nil {
+				return nil, err
+			}
+			frame["logs"] = encoded
+		}
+	}
+
+	if rawCalls, found := frame["calls"]; found {
+		var calls []json.RawMessage
+		if err := json.Unmarshal(rawCalls, &calls); err != nil {
+			return nil, fmt.Errorf("could not decode call frames. Cause: %w", err)
+		}
+		visibleCalls := make([]json.RawMessage, 0, len(calls))
+		for _, call := range calls {
+			visible, err := f.isCallVisible(call)
+			if err != nil {
+				return nil, err
+			}
+			if !visible {
+				continue
+			}
+			redacted, err := f.redactCallFrames(call)
+			if err != nil {
+				return nil, err
+			}
+			visibleCalls = append(visibleCalls, redacted)
+		}
+		if len(visibleCalls) == 0 {
+			delete(frame, "calls")
+		} else {
+			encoded, err := json.Marshal(visibleCalls)
+			if err != nil {
+				return nil, err
+			}
+			frame["calls"] = encoded
+		}
+	}
+
+	return json.Marshal(frame)
+}
+
+// isCallVisible - a nested call can be seen if it was made by the requester or by a transparent contract.
+// The calls made by the other contracts can reveal their private state, so they are removed together with their sub-calls.
+func (f *visibilityFilter) isCallVisible(rawFrame json.RawMessage) (bool, error) {
+	var frame struct {
+		From gethcommon.Address `json:"from"`
+	}
+	if err := json.Unmarshal(rawFrame, &frame); err != nil {
+		return false, fmt.Errorf("could not decode call frame. Cause: %w", err)
+	}
+	if f.requester != nil && frame.From == *f.requester {
+		return true, nil
+	}
+	return f.isStorageVisible(frame.From)
+}
+
+// redactPrestate - removes the storage of the accounts which are not transparent. The accounts only touched by the
+// hidden execution were not reported to the tracer. Supports both the prestate and the diff mode.
+func (f *visibilityFilter) redactPrestate(result json.RawMessage) (json.RawMessage, error) {
+	var diff struct {
+		Pre  map[gethcommon.Address]map[string]json.RawMessage `json:"pre"`
+		Post map[gethcommon.Address]map[string]json.RawMessage `json:"post"`
+	}
+	if err := json.Unmarshal(result, &diff); err == nil && diff.Pre != nil && diff.Post != nil {
+		if err := f.redactAccounts(diff.Pre); err != nil {
+			return nil, err
+		}
+		if err := f.redactAccounts(diff.Post); err != nil {
+			return nil, err
+		}
+		return json.Marshal(diff)
+	}
+
+	var accounts map[gethcommon.Address]map[string]json.RawMessage
+	if err := json.Unmarshal(result, &accounts); err != nil {
+		return nil, fmt.Errorf("could not decode prestate. Cause: %w", err)
+	}
+	if err := f.redactAccounts(accounts); err != nil {
+		return nil, err
+	}
+	return json.Marshal(accounts)
+}
+
+func (f *visibilityFilter) redactAccounts(accounts map[gethcommon.Address]map[string]json.RawMessage) error {
+	for address, account := range accounts {
+		if _, found := account["storage"]; !found {
+			continue
+		}
+		visible, err := f.isStorageVisible(address)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			delete(account, "storage")
+		}
+	}
+	return nil
+}
+
+// isStorageVisible - only the storage of transparent contracts can be seen
+func (f *visibilityFilter) isStorageVisible(address gethcommon.Address) (bool, error) {
+	if transparent, found := f.transparent[address]; found {
+		return transparent, nil
+	}
+	contract, err := f.storage.ReadContract(f.ctx, address)
+	if err != nil && !errors.Is(err, errutil.ErrNotFound) {
+		return false, fmt.Errorf("could not read contract %s. Cause: %w", address, err)
+	}
+	transparent := err == nil && contract.IsTransparent()
+	f.transparent[address] = transparent
+	return transparent, nil
+}
+
+// isLogVisible - applies the same rules as the receipt
+func (f *visibilityFilter) isLogVisible(l *types.Log) (bool, error) {
+	if len(l.Topics) == 0 {
+		return false, nil
+	}
+	eventType, err := f.storage.ReadEventType(f.ctx, l.Address, l.Topics[0])
+	if err != nil {
+		if errors.Is(err, errutil.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not read event type. Cause: %w", err)
+	}
+	return eventType.IsVisibleToSender(l, f.requester), nil
+}
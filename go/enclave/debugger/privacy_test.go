@@ -0,0 +1,260 @@
+package debugger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	gethlogger "github.com/ethereum/go-ethereum/eth/tracers/logger"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	"github.com/ten-protocol/go-ten/go/common/tracers"
+	"github.com/ten-protocol/go-ten/go/enclave/evm/ethchainadapter"
+	"github.com/ten-protocol/go-ten/go/enclave/storage"
+	"github.com/ten-protocol/go-ten/go/enclave/storage/enclavedb"
+)
+
+// The traced transaction calls a transparent contract, which calls a private contract, which calls another transparent
+// contract. Each contract writes its storage and emits an event. The call made by the private contract is hidden with
+// everything it touched, and the storage, steps and events of the private contract are hidden.
+var (
+	requester           = gethcommon.HexToAddress("0x1000")
+	transparentAddr     = gethcommon.HexToAddress("0x2000")
+	privateAddr         = gethcommon.HexToAddress("0x3000")
+	hiddenCalleeAddr    = gethcommon.HexToAddress("0x4000")
+	hiddenLookupAddr    = gethcommon.HexToAddress("0x5000") // only looked up by the hidden callee
+	transparentTopic    = gethcommon.HexToHash("0xaa")
+	privateTopic        = gethcommon.HexToHash("0xbb")
+	hiddenCalleeTopic   = gethcommon.HexToHash("0xcc")
+	transparentSelector = []byte{0x11, 0x11, 0x11, 0x11}
+	privateSelector     = []byte{0x22, 0x22, 0x22, 0x22}
+	hiddenSelector      = []byte{0x33, 0x33, 0x33, 0x33}
+)
+
+func TestVisibilityFilter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config *tracers.TraceConfig
+		check  func(t *testing.T, result json.RawMessage)
+	}{
+		{
+			name:   "struct logger",
+			config: &tracers.TraceConfig{Config: &gethlogger.Config{EnableReturnData: true}},
+			check: func(t *testing.T, result json.RawMessage) {
+				var res struct {
+					StructLogs []struct {
+						Op         string            `json:"op"`
+						Depth      int               `json:"depth"`
+						Storage    map[string]string `json:"storage"`
+						ReturnData string            `json:"returnData"`
+					} `json:"structLogs"`
+				}
+				require.NoError(t, json.Unmarshal(result, &res))
+				require.NotEmpty(t, res.StructLogs)
+				ops := make([]string, 0, len(res.StructLogs))
+				for _, step := range res.StructLogs {
+					// only the steps of the transparent contract called by the requester are kept
+					require.Equal(t, 1, step.Depth)
+					require.Empty(t, step.ReturnData)
+					ops = append(ops, step.Op)
+				}
+				require.Contains(t, ops, "CALL")
+				require.NotContains(t, ops, "BALANCE")
+				// the storage of the transparent contract is visible
+				sstore := res.StructLogs[slices.Index(ops, "SSTORE")]
+				require.Equal(t, fmt.Sprintf("%x", gethcommon.BigToHash(big.NewInt(2))), sstore.Storage[fmt.Sprintf("%x", gethcommon.Hash{})])
+			},
+		},
+		{
+			name:   "call tracer",
+			config: &tracers.TraceConfig{Tracer: ptr(callTracer), TracerConfig: json.RawMessage(`{"withLog": true}`)},
+			check: func(t *testing.T, result json.RawMessage) {
+				var frame testCallFrame
+				require.NoError(t, json.Unmarshal(result, &frame))
+				require.Equal(t, transparentAddr, frame.To)
+				require.Len(t, frame.Logs, 1)
+				require.Equal(t, transparentTopic, frame.Logs[0].Topics[0])
+				// the call made by the transparent contract is visible, but not the events and calls of the private contract
+				require.Len(t, frame.Calls, 1)
+				require.Equal(t, privateAddr, frame.Calls[0].To)
+				require.Empty(t, frame.Calls[0].Logs)
+				require.Empty(t, frame.Calls[0].Calls)
+			},
+		},
+		{
+			name:   "prestate tracer",
+			config: &tracers.TraceConfig{Tracer: ptr(prestateTracer)},
+			check: func(t *testing.T, result json.RawMessage) {
+				var accounts map[gethcommon.Address]map[string]json.RawMessage
+				require.NoError(t, json.Unmarshal(result, &accounts))
+				require.Contains(t, accounts, requester)
+				require.Contains(t, accounts[transparentAddr], "storage")
+				require.Contains(t, accounts, privateAddr)
+				require.NotContains(t, accounts[privateAddr], "storage")
+				require.NotContains(t, accounts, hiddenCalleeAddr)
+				require.NotContains(t, accounts, hiddenLookupAddr)
+			},
+		},
+		{
+			name:   "prestate tracer diff mode",
+			config: &tracers.TraceConfig{Tracer: ptr(prestateTracer), TracerConfig: json.RawMessage(`{"diffMode": true}`)},
+			check: func(t *testing.T, result json.RawMessage) {
+				var diff struct {
+					Pre  map[gethcommon.Address]map[string]json.RawMessage `json:"pre"`
+					Post map[gethcommon.Address]map[string]json.RawMessage `json:"post"`
+				}
+				require.NoError(t, json.Unmarshal(result, &diff))
+				require.Contains(t, diff.Post[transparentAddr], "storage")
+				for _, accounts := range []map[gethcommon.Address]map[string]json.RawMessage{diff.Pre, diff.Post} {
+					require.NotContains(t, accounts[privateAddr], "storage")
+					require.NotContains(t, accounts, hiddenCalleeAddr)
+					require.NotContains(t, accounts, hiddenLookupAddr)
+				}
+			},
+		},
+		{
+			name:   "4byte tracer",
+			config: &tracers.TraceConfig{Tracer: ptr(fourByteTracer)},
+			check: func(t *testing.T, result json.RawMessage) {
+				var selectors map[string]int
+				require.NoError(t, json.Unmarshal(result, &selectors))
+				require.Equal(t, map[string]int{
+					hexutil.Encode(transparentSelector) + "-0": 1,
+					hexutil.Encode(privateSelector) + "-0":     1,
+				}, selectors)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &Debugger{chainConfig: ethchainadapter.ChainParams(big.NewInt(443)), logger: gethlog.New()}
+			ctx := context.Background()
+			filter := newVisibilityFilter(ctx, newTestVisibilityStorage(), ptr(requester))
+			result, err := d.trace(ctx, &tracers.Context{}, tc.config, filter, func(hooks *tracing.Hooks) error {
+				return executeTestTx(d, hooks)
+			})
+			require.NoError(t, err)
+			tc.check(t, result)
+		})
+	}
+}
+
+type testCallFrame struct {
+	To    gethcommon.Address `json:"to"`
+	Logs  []callLog          `json:"logs"`
+	Calls []testCallFrame    `json:"calls"`
+}
+
+// testVisibilityStorage - the contracts and event types of the traced transaction
+type testVisibilityStorage struct {
+	storage.Storage
+	contracts map[gethcommon.Address]*enclavedb.Contract
+}
+
+func newTestVisibilityStorage() *testVisibilityStorage {
+	return &testVisibilityStorage{contracts: map[gethcommon.Address]*enclavedb.Contract{
+		transparentAddr:  {Address: transparentAddr, Transparent: ptr(true)},
+		privateAddr:      {Address: privateAddr, Transparent: ptr(false)},
+		hiddenCalleeAddr: {Address: hiddenCalleeAddr, Transparent: ptr(true)},
+	}}
+}
+
+func (s *testVisibilityStorage) ReadContract(_ context.Context, address gethcommon.Address) (*enclavedb.Contract, error) {
+	contract, found := s.contracts[address]
+	if !found {
+		return nil, errutil.ErrNotFound
+	}
+	return contract, nil
+}
+
+func (s *testVisibilityStorage) ReadEventType(_ context.Context, address gethcommon.Address, eventSignature gethcommon.Hash) (*enclavedb.EventType, error) {
+	contract, found := s.contracts[address]
+	if !found {
+		return nil, errutil.ErrNotFound
+	}
+	return &enclavedb.EventType{Contract: contract, EventSignature: eventSignature}, nil
+}
+
+// executeTestTx - executes the call of the requester to the transparent contract, like the evm facade traces a call
+func executeTestTx(d *Debugger, hooks *tracing.Hooks) error {
+	stateDB, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	if err != nil {
+		return err
+	}
+	stateDB.SetBalance(requester, uint256.NewInt(1e18), tracing.BalanceChangeUnspecified)
+	stateDB.SetCode(transparentAddr, contractCode(0x02, transparentTopic, privateSelector, privateAddr, nil))
+	stateDB.SetCode(privateAddr, contractCode(0x01, privateTopic, hiddenSelector, hiddenCalleeAddr, nil))
+	stateDB.SetCode(hiddenCalleeAddr, contractCode(0x03, hiddenCalleeTopic, nil, gethcommon.Address{}, &hiddenLookupAddr))
+	stateDB.Finalise(true)
+
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(0), GasLimit: 10_000_000, BaseFee: big.NewInt(0)}
+	coinbase := gethcommon.Address{}
+	vmEnv := vm.NewEVM(gethcore.NewEVMBlockContext(header, nil, &coinbase), state.NewHookedState(stateDB, hooks), d.chainConfig, vm.Config{NoBaseFee: true, Tracer: hooks})
+	msg := &gethcore.Message{
+		From:      requester,
+		To:        &transparentAddr,
+		GasLimit:  1_000_000,
+		GasPrice:  big.NewInt(0),
+		GasFeeCap: big.NewInt(0),
+		GasTipCap: big.NewInt(0),
+		Value:     big.NewInt(0),
+		Data:      transparentSelector,
+	}
+	if hooks.OnTxStart != nil {
+		hooks.OnTxStart(vmEnv.GetVMContext(), types.NewTx(&types.LegacyTx{To: msg.To, Gas: msg.GasLimit, Data: msg.Data}), msg.From)
+	}
+	gp := gethcore.GasPool(header.GasLimit)
+	result, err := gethcore.ApplyMessage(vmEnv, msg, &gp)
+	if err != nil {
+		return err
+	}
+	if result.Err != nil {
+		return result.Err
+	}
+	if hooks.OnTxEnd != nil {
+		hooks.OnTxEnd(&types.Receipt{GasUsed: result.UsedGas}, nil)
+	}
+	return nil
+}
+
+// contractCode - assembles a contract which stores the value in slot 0, emits an event with the topic, calls the callee
+// with the selector or looks up the balance of an account, and returns the first word of its memory
+func contractCode(value byte, topic gethcommon.Hash, selector []byte, callee gethcommon.Address, lookup *gethcommon.Address) []byte {
+	code := []byte{byte(vm.PUSH1), value, byte(vm.PUSH1), 0, byte(vm.SSTORE)}
+	code = append(code, byte(vm.PUSH32))
+	code = append(code, topic.Bytes()...)
+	code = append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG1))
+	if lookup != nil {
+		code = append(code, byte(vm.PUSH20))
+		code = append(code, lookup.Bytes()...)
+		code = append(code, byte(vm.BALANCE), byte(vm.POP))
+	}
+	if selector != nil {
+		// the selector is stored in the first 4 bytes of the memory, and sent as the input of the call
+		code = append(code, byte(vm.PUSH4))
+		code = append(code, selector...)
+		code = append(code, byte(vm.PUSH1), 0xe0, byte(vm.SHL), byte(vm.PUSH1), 0, byte(vm.MSTORE))
+		code = append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 4, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0)
+		code = append(code, byte(vm.PUSH20))
+		code = append(code, callee.Bytes()...)
+		code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.POP))
+	}
+	return append(code, byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
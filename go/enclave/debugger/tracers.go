@@ -4,146 +4,341 @@ package debugger
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
-	"github.com/ten-protocol/go-ten/go/enclave/components"
-
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethlogger "github.com/ethereum/go-ethereum/eth/tracers/logger"
+	gethlog "github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/gethencoding"
 	"github.com/ten-protocol/go-ten/go/common/tracers"
+	"github.com/ten-protocol/go-ten/go/enclave/components"
+	"github.com/ten-protocol/go-ten/go/enclave/core"
+	"github.com/ten-protocol/go-ten/go/enclave/crypto"
+	"github.com/ten-protocol/go-ten/go/enclave/evm"
+	"github.com/ten-protocol/go-ten/go/enclave/gas"
 	"github.com/ten-protocol/go-ten/go/enclave/storage"
+	gethrpc "github.com/ten-protocol/go-ten/lib/gethfork/rpc"
 
-	gethcommon "github.com/ethereum/go-ethereum/common"
+	// registers the native tracers
+	_ "github.com/ten-protocol/go-ten/go/common/tracers/native"
 )
 
-const (
 // defaultTraceTimeout is the amount of time a single transaction can execute
 // by default before being forcefully aborted.
-// defaultTraceTimeout = 5 * time.Second
+const defaultTraceTimeout = 5 * time.Second
 
-// defaultTraceReexec is the number of blocks the tracer is willing to go back
-// and reexecute to produce missing historical state necessary to run a specific
-// trace.
-// defaultTraceReexec = uint64(128)
-)
+// ErrNotTraceable - returned for transactions that are not part of the user transactions of a batch
+// (e.g. the synthetic transactions or the system contracts deployment)
+var ErrNotTraceable = errors.New("transaction is not traceable")
 
 type Debugger struct {
-	chain       components.TENChain
-	storage     storage.Storage
-	chainConfig *params.ChainConfig
+	storage             storage.Storage
+	registry            components.BatchRegistry
+	evmFacade           evm.EVMFacade
+	gasOracle           gas.Oracle
+	gethEncodingService gethencoding.EncodingService
+	entropyService      *crypto.EvmEntropyService
+	chainConfig         *params.ChainConfig
+	logger              gethlog.Logger
 }
 
-func New(chain components.TENChain, storage storage.Storage, config *params.ChainConfig) *Debugger {
+func New(storage storage.Storage, registry components.BatchRegistry, evmFacade evm.EVMFacade, gasOracle gas.Oracle, gethEncodingService gethencoding.EncodingService, entropyService *crypto.EvmEntropyService, config *params.ChainConfig, logger gethlog.Logger) *Debugger {
 	return &Debugger{
-		chain:       chain,
-		chainConfig: config,
-		storage:     storage,
+		storage:             storage,
+		registry:            registry,
+		evmFacade:           evmFacade,
+		gasOracle:           gasOracle,
+		gethEncodingService: gethEncodingService,
+		entropyService:      entropyService,
+		chainConfig:         config,
+		logger:              logger,
 	}
 }
 
-func (d *Debugger) DebugTraceTransaction(context.Context, gethcommon.Hash, *tracers.TraceConfig) (json.RawMessage, error) {
-	return nil, nil
-	//_, blockHash, blockNumber, index, err := d.storage.GetTransaction(txHash)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//// It shouldn't happen in practice.
-	//if blockNumber == 0 {
-	//	return nil, errors.New("genesis is not traceable")
-	//}
-	//reexec := defaultTraceReexec
-	//if config != nil && config.Reexec != nil {
-	//	reexec = *config.Reexec
-	//}
-	//batch, err := d.storage.FetchBatch(blockHash)
-	//if err != nil {
-	//	return nil, err
-	//}
-
-	//msg, vmctx, statedb, err := d.chain.GetChainStateAtTransaction(batch, int(index), reexec)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//
-	//txctx := &gethtracers.Context{
-	//	BlockHash: blockHash,
-	//	TxIndex:   int(index),
-	//	TxHash:    txHash,
-	//}
-	//return d.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+// DebugTraceTransaction - re-executes the batch which includes the transaction on top of the state of the parent batch,
+// and traces the execution of the transaction.
+// The requester must be the sender of the transaction. The result is redacted to what the requester is allowed to see.
+func (d *Debugger) DebugTraceTransaction(ctx context.Context, txHash gethcommon.Hash, batchHash common.L2BatchHash, config *tracers.TraceConfig, requester *gethcommon.Address) (json.RawMessage, error) {
+	batch, err := d.storage.FetchBatch(ctx, batchHash)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch batch %s. Cause: %w", batchHash, err)
+	}
+
+	// the system contracts are deployed in a dedicated batch, which is not traceable
+	if batch.SeqNo().Uint64() <= common.L2SysContractGenesisSeqNo {
+		return nil, ErrNotTraceable
+	}
+
+	txIndex := -1
+	for i, tx := range batch.Transactions {
+		if tx.Hash() == txHash {
+			txIndex = i
+			break
+		}
+	}
+	// synthetic transactions are not part of the batch body
+	if txIndex < 0 {
+		return nil, ErrNotTraceable
+	}
+
+	replay, err := d.newBatchReplay(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if err = replay.executeUpTo(txIndex); err != nil {
+		return nil, err
+	}
+
+	txCtx := &tracers.Context{
+		BlockHash:   batch.Hash(),
+		BlockNumber: batch.Number(),
+		TxIndex:     txIndex,
+		TxHash:      txHash,
+	}
+	return d.trace(ctx, txCtx, config, newVisibilityFilter(ctx, d.storage, requester), func(hooks *tracing.Hooks) error {
+		return replay.trace(txIndex, hooks)
+	})
 }
 
-// traceTx configures a new tracer according to the provided configuration, and
-// executes the given message in the provided environment. The return value will
+// DebugTraceBatch - re-executes the batch and traces all the transactions sent by the requester.
+// The other transactions are executed, but not traced.
+func (d *Debugger) DebugTraceBatch(ctx context.Context, batch *core.Batch, config *tracers.TraceConfig, requester *gethcommon.Address) ([]*tracers.TxTraceResult, error) {
+	if batch.SeqNo().Uint64() <= common.L2SysContractGenesisSeqNo {
+		return nil, ErrNotTraceable
+	}
+
+	replay, err := d.newBatchReplay(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*tracers.TxTraceResult, 0)
+	for i, tx := range batch.Transactions {
+		sender, err := core.GetAuthenticatedSender(d.chainConfig.ChainID.Int64(), tx)
+		if err != nil {
+			return nil, fmt.Errorf("could not recover sender of tx %s. Cause: %w", tx.Hash(), err)
+		}
+		if *sender != *requester {
+			continue
+		}
+
+		if err = replay.executeUpTo(i); err != nil {
+			return nil, err
+		}
+		txCtx := &tracers.Context{
+			BlockHash:   batch.Hash(),
+			BlockNumber: batch.Number(),
+			TxIndex:     i,
+			TxHash:      tx.Hash(),
+		}
+		txIndex := i
+		res, err := d.trace(ctx, txCtx, config, newVisibilityFilter(ctx, d.storage, requester), func(hooks *tracing.Hooks) error {
+			return replay.trace(txIndex, hooks)
+		})
+		if err != nil {
+			results = append(results, &tracers.TxTraceResult{TxHash: tx.Hash(), TxIndex: hexutil.Uint(i), Error: err.Error()})
+			// the state is unusable if the transaction was not executed
+			if replay.nextTx <= i {
+				return results, nil
+			}
+			continue
+		}
+		results = append(results, &tracers.TxTraceResult{TxHash: tx.Hash(), TxIndex: hexutil.Uint(i), Result: res})
+	}
+	return results, nil
+}
+
+// DebugTraceCall - executes the call on top of the state of the batch, and traces the execution.
+// The result is redacted to what the requester (the sender of the call) is allowed to see.
+func (d *Debugger) DebugTraceCall(ctx context.Context, msg *gethcore.Message, batch *core.Batch, config *tracers.TraceConfig) (json.RawMessage, error) {
+	batchHash := batch.Hash()
+	stateDB, err := d.registry.GetBatchState(ctx, gethrpc.BlockNumberOrHash{BlockHash: &batchHash})
+	if err != nil {
+		return nil, fmt.Errorf("could not create stateDB. Cause: %w", err)
+	}
+
+	txCtx := &tracers.Context{
+		BlockHash:   batch.Hash(),
+		BlockNumber: batch.Number(),
+	}
+	return d.trace(ctx, txCtx, config, newVisibilityFilter(ctx, d.storage, &msg.From), func(hooks *tracing.Hooks) error {
+		_, userErr, sysErr := d.evmFacade.TraceCall(ctx, msg, stateDB, batch.Header, hooks)
+		if sysErr != nil {
+			return sysErr
+		}
+		// like in geth, the failed calls are traced as well
+		if userErr != nil {
+			d.logger.Debug("Traced call failed", "err", userErr)
+		}
+		return nil
+	})
+}
+
+// trace configures a new tracer according to the provided configuration, and
+// executes the given function with the tracer hooks. The return value will
 // be tracer dependent.
-//
-//func (d *Debugger) traceTx(ctx context.Context, message *core.Message, txctx *gethtracers.Context, vmctx vm.BlockContext, statedb *state.StateDB, config *tracers.TraceConfig) (json.RawMessage, error) {
-//	// Assemble the structured logger or the JavaScript tracer
-//	var (
-//		tracer    vm.EVMLogger
-//		err       error
-//		txContext = core.NewEVMTxContext(message)
-//	)
-//	switch {
-//	case config == nil:
-//		tracer = logger.NewStructLogger(nil)
-//	case config.Tracer != nil:
-//		// Define a meaningful timeout of a single transaction trace
-//		timeout := defaultTraceTimeout
-//		if config.Timeout != nil {
-//			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-//				return nil, err
-//			}
-//		}
-//		//if t, err := tracers.New(*config.Tracer, (*tracers.Context)(txctx)); err != nil {
-//		//	return nil, err
-//		//} else {
-//		//	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-//		//	go func() {
-//		//		<-deadlineCtx.Done()
-//		//		if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
-//		//			t.Stop(errors.New("execution timeout"))
-//		//		}
-//		//	}()
-//		//	defer cancel()
-//		//	tracer = t
-//		//}
-//	default:
-//		tracer = logger.NewStructLogger(config.Config)
-//	}
-//	// Run the transaction with tracing enabled.
-//	vmenv := vm.NewEVM(vmctx, txContext, statedb, d.chainConfig, vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
-//
-//	// Call Prepare to clear out the statedb access list
-//	statedb.Prepare(txctx.TxHash, txctx.TxIndex)
-//
-//	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
-//	if err != nil {
-//		return nil, fmt.Errorf("tracing failed: %w", err)
-//	}
-//
-//	// Depending on the tracer type, format and return the output.
-//	switch tracer := tracer.(type) {
-//	case *logger.StructLogger:
-//		// If the result contains a revert reason, return it.
-//		returnVal := fmt.Sprintf("%x", result.Return())
-//		if len(result.Revert()) > 0 {
-//			returnVal = fmt.Sprintf("%x", result.Revert())
-//		}
-//		exec := &gethapi.ExecutionResult{
-//			Gas:         result.UsedGas,
-//			Failed:      result.Failed(),
-//			ReturnValue: returnVal,
-//			StructLogs:  gethapi.FormatLogs(tracer.StructLogs()),
-//		}
-//		jsonRaw, err := json.Marshal(exec)
-//		if err != nil {
-//			return nil, err
-//		}
-//		return jsonRaw, nil
-//
-//	case tracers.Tracer:
-//		return tracer.GetResult()
-//
-//	default:
-//		panic(fmt.Sprintf("bad tracer type %T", tracer))
-//	}
-//}
+func (d *Debugger) trace(ctx context.Context, txCtx *tracers.Context, config *tracers.TraceConfig, filter *visibilityFilter, execute func(hooks *tracing.Hooks) error) (json.RawMessage, error) {
+	if config == nil {
+		config = &tracers.TraceConfig{}
+	}
+
+	// Define a meaningful timeout of a single transaction trace
+	timeout := defaultTraceTimeout
+	if config.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout. Cause: %w", err)
+		}
+	}
+
+	// Assemble the structured logger or the native tracer
+	var tracer *tracers.Tracer
+	tracerName := ""
+	if config.Tracer == nil {
+		loggerConfig := gethlogger.Config{}
+		if config.Config != nil {
+			loggerConfig = *config.Config
+		}
+		// the return data of a call is the output of the callee, which can be a contract that is not transparent
+		loggerConfig.EnableReturnData = false
+		logger := gethlogger.NewStructLogger(&loggerConfig)
+		tracer = &tracers.Tracer{
+			Hooks:     logger.Hooks(),
+			GetResult: logger.GetResult,
+			Stop:      logger.Stop,
+		}
+	} else {
+		var err error
+		tracerName = *config.Tracer
+		tracer, err = tracers.New(tracerName, txCtx, config.TracerConfig, d.chainConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		<-deadlineCtx.Done()
+		if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+			tracer.Stop(errors.New("execution timeout"))
+		}
+	}()
+	defer cancel()
+
+	// the output of the call tracer is redacted, the other tracers are not told about the hidden execution
+	hooks := tracer.Hooks
+	switch tracerName {
+	case structLogger, prestateTracer:
+		hooks = filter.hideExecution(tracer.Hooks, true)
+	case fourByteTracer:
+		hooks = filter.hideExecution(tracer.Hooks, false)
+	}
+
+	if err := execute(hooks); err != nil {
+		return nil, fmt.Errorf("tracing failed: %w", err)
+	}
+
+	result, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	return filter.redact(tracerName, result)
+}
+
+// batchReplay - re-executes the transactions of an existing batch, following the conventions of the batch executor
+type batchReplay struct {
+	debugger  *Debugger
+	batch     *core.Batch
+	l1Block   *types.Header
+	ethHeader *types.Header
+	stateDB   *state.StateDB
+	gasPool   *gethcore.GasPool
+	usedGas   *uint64
+	nextTx    int
+}
+
+func (d *Debugger) newBatchReplay(ctx context.Context, batch *core.Batch) (*batchReplay, error) {
+	stateDB, err := d.registry.GetBatchState(ctx, gethrpc.BlockNumberOrHash{BlockHash: &batch.Header.ParentHash})
+	if err != nil {
+		return nil, fmt.Errorf("could not create stateDB. Cause: %w", err)
+	}
+
+	ethHeader, err := d.gethEncodingService.CreateEthHeaderForBatch(ctx, batch.Header)
+	if err != nil {
+		return nil, fmt.Errorf("could not create eth header for batch. Cause: %w", err)
+	}
+
+	l1Block, err := d.storage.FetchBlock(ctx, batch.Header.L1Proof)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the l1 block of the batch. Cause: %w", err)
+	}
+
+	gp := gethcore.GasPool(batch.Header.GasLimit)
+	usedGas := uint64(0)
+	return &batchReplay{
+		debugger:  d,
+		batch:     batch,
+		l1Block:   l1Block,
+		ethHeader: ethHeader,
+		stateDB:   stateDB,
+		gasPool:   &gp,
+		usedGas:   &usedGas,
+	}, nil
+}
+
+// executeUpTo - executes all the transactions of the batch which precede the transaction at the index
+func (r *batchReplay) executeUpTo(txIndex int) error {
+	for r.nextTx < txIndex {
+		tx, header, err := r.prepare(r.nextTx)
+		if err != nil {
+			return err
+		}
+		result := r.debugger.evmFacade.ExecuteTx(tx, r.stateDB, header, r.gasPool, r.usedGas, r.nextTx, false)
+		if result.Err != nil {
+			return fmt.Errorf("could not replay transaction %s. Cause: %w", tx.Tx.Hash(), result.Err)
+		}
+		r.nextTx++
+	}
+	return nil
+}
+
+// trace - executes the transaction at the index, reporting it to the tracer hooks.
+// All the previous transactions must have been executed.
+func (r *batchReplay) trace(txIndex int, hooks *tracing.Hooks) error {
+	if txIndex != r.nextTx {
+		return fmt.Errorf("transaction %d can't be traced before all previous transactions are executed", txIndex)
+	}
+	tx, header, err := r.prepare(txIndex)
+	if err != nil {
+		return err
+	}
+	result := r.debugger.evmFacade.TraceTx(tx, r.stateDB, header, r.gasPool, r.usedGas, txIndex, hooks)
+	if result.Err != nil {
+		return result.Err
+	}
+	r.nextTx++
+	return nil
+}
+
+// prepare - calculates the l1 publishing cost and the per-tx header, the same way the batch executor does
+func (r *batchReplay) prepare(txIndex int) (*common.L2PricedTransaction, *types.Header, error) {
+	tx := r.batch.Transactions[txIndex]
+	cost, err := r.debugger.gasOracle.EstimateL1StorageGasCost(tx, r.l1Block, r.batch.Header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get gas cost for tx. Cause: %w", err)
+	}
+
+	header := *r.ethHeader
+	header.MixDigest = r.debugger.entropyService.TxEntropy(r.ethHeader.MixDigest.Bytes(), txIndex)
+
+	return &common.L2PricedTransaction{Tx: tx, PublishingCost: cost}, &header, nil
+}
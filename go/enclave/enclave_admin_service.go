@@ -384,7 +384,7 @@ func (e *enclaveAdminService) StreamL2Updates() (chan common.StreamL2UpdatesResp
 
 	e.registry.SubscribeForExecutedBatches(func(batch *core.Batch, receipts types.Receipts) {
 		defer core.LogMethodDuration(e.logger, measure.NewStopwatch(), "stream batch")
-		e.sendBatch(batch, l2UpdatesChannel)
+		e.sendBatch(batch, receipts, l2UpdatesChannel)
 		if receipts != nil {
 			e.streamEventsForNewHeadBatch(context.Background(), batch, receipts, l2UpdatesChannel)
 		}
@@ -469,6 +469,10 @@ func (e *enclaveAdminService) Stop() common.SystemError {
 	e.mainMutex.Lock()
 	defer e.mainMutex.Unlock()
 
+	if e.stopControl.IsStopping() {
+		// the host stops the enclave when it is demoted from active sequencer, and again when the host stops
+		return nil
+	}
 	e.logger.Warn("Shutting down enclave.")
 
 	// block all requests
@@ -505,7 +509,7 @@ func (e *enclaveAdminService) StopClient() common.SystemError {
 	return nil // The enclave is local so there is no client to stop
 }
 
-func (e *enclaveAdminService) sendBatch(batch *core.Batch, outChannel chan common.StreamL2UpdatesResponse) {
+func (e *enclaveAdminService) sendBatch(batch *core.Batch, receipts types.Receipts, outChannel chan common.StreamL2UpdatesResponse) {
 	if batch.SeqNo().Uint64()%10 == 0 {
 		e.logger.Info("Streaming batch to host", log.BatchHashKey, batch.Hash(), log.BatchSeqNoKey, batch.SeqNo())
 	} else {
@@ -519,6 +523,16 @@ func (e *enclaveAdminService) sendBatch(batch *core.Batch, outChannel chan commo
 	resp := common.StreamL2UpdatesResponse{
 		Batch: extBatch,
 	}
+	if receipts != nil {
+		// only the aggregated fees are published, to not reveal the fees paid by each transaction
+		resp.FeeStats = common.NewBatchFeeStats(batch.Header.BaseFee, receipts)
+	}
+	revealedTxs, err := e.storage.FetchRevealedTransactions(context.Background(), batch.Header)
+	if err != nil {
+		// the host will not list these transactions as revealed, but they are still visible through the enclave
+		e.logger.Error("Could not read the transactions revealed by the batch", log.BatchHashKey, batch.Hash(), log.ErrKey, err)
+	}
+	resp.RevealedTxs = revealedTxs
 	outChannel <- resp
 }
 
@@ -3,7 +3,6 @@ package enclave
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math/big"
 
@@ -19,7 +18,7 @@ import (
 
 	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ten-protocol/go-ten/go/common"
-	"github.com/ten-protocol/go-ten/go/common/syserr"
+	tenrpc "github.com/ten-protocol/go-ten/go/common/rpc"
 	"github.com/ten-protocol/go-ten/go/common/tracers"
 	"github.com/ten-protocol/go-ten/go/enclave/components"
 	enclaveconfig "github.com/ten-protocol/go-ten/go/enclave/config"
@@ -46,10 +45,10 @@ type enclaveRPCService struct {
 	logger               gethlog.Logger
 }
 
-func NewEnclaveRPCAPI(config *enclaveconfig.EnclaveConfig, storage storage.Storage, chain components.TENChain, logger gethlog.Logger, blockProcessor components.L1BlockProcessor, batchRegistry components.BatchRegistry, gethEncodingService gethencoding.EncodingService, cachingService *storage.CacheService, mempool *components.TxPool, chainConfig *params.ChainConfig, crossChainProcessors *crosschain.Processors, scb system.SystemContractCallbacks, subscriptionManager *events.SubscriptionManager, genesis *genesis.Genesis, gasOracle gas.Oracle, rpcKeyService *crypto.RPCKeyService, evmFacade evm.EVMFacade) common.EnclaveClientRPC {
-	debug := debugger.New(chain, storage, chainConfig)
+func NewEnclaveRPCAPI(config *enclaveconfig.EnclaveConfig, storage storage.Storage, chain components.TENChain, logger gethlog.Logger, blockProcessor components.L1BlockProcessor, batchRegistry components.BatchRegistry, gethEncodingService gethencoding.EncodingService, cachingService *storage.CacheService, mempool *components.TxPool, chainConfig *params.ChainConfig, crossChainProcessors *crosschain.Processors, scb system.SystemContractCallbacks, subscriptionManager *events.SubscriptionManager, genesis *genesis.Genesis, gasOracle gas.Oracle, rpcKeyService *crypto.RPCKeyService, evmFacade evm.EVMFacade, evmEntropyService *crypto.EvmEntropyService) common.EnclaveClientRPC {
+	debug := debugger.New(storage, batchRegistry, evmFacade, gasOracle, gethEncodingService, evmEntropyService, chainConfig, logger)
 
-	rpcEncryptionManager := rpc.NewEncryptionManager(storage, cachingService, batchRegistry, mempool, crossChainProcessors, config, gasOracle, storage, blockProcessor, chain, rpcKeyService, logger)
+	rpcEncryptionManager := rpc.NewEncryptionManager(storage, cachingService, batchRegistry, mempool, crossChainProcessors, config, gasOracle, storage, blockProcessor, chain, rpcKeyService, debug, logger)
 
 	return &enclaveRPCService{
 		rpcEncryptionManager: rpcEncryptionManager,
@@ -94,22 +93,9 @@ func (e *enclaveRPCService) Unsubscribe(id gethrpc.ID) common.SystemError {
 	return nil
 }
 
-func (e *enclaveRPCService) DebugTraceTransaction(ctx context.Context, txHash gethcommon.Hash, config *tracers.TraceConfig) (json.RawMessage, common.SystemError) {
-	// ensure the debug namespace is enabled
-	if !e.config.DebugNamespaceEnabled {
-		return nil, responses.ToInternalError(fmt.Errorf("debug namespace not enabled"))
-	}
-
-	jsonMsg, err := e.debugger.DebugTraceTransaction(ctx, txHash, config)
-	if err != nil {
-		if errors.Is(err, syserr.InternalError{}) {
-			return nil, responses.ToInternalError(err)
-		}
-		// TODO *Pedro* MOVE THIS TO Enclave Response
-		return json.RawMessage(err.Error()), nil
-	}
-
-	return jsonMsg, nil
+func (e *enclaveRPCService) DebugTraceTransaction(context.Context, gethcommon.Hash, *tracers.TraceConfig) (json.RawMessage, common.SystemError) {
+	// traces can only be returned to the sender of the transaction, so they must be requested through the encrypted RPC
+	return nil, responses.ToInternalError(fmt.Errorf("debug_traceTransaction must be requested via %s", tenrpc.EncRPC))
 }
 
 func (e *enclaveRPCService) GetTotalContractCount(ctx context.Context) (*big.Int, common.SystemError) {
@@ -15,9 +15,12 @@ import (
 
 	"github.com/ten-protocol/go-ten/go/enclave/storage"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	gethlog "github.com/ethereum/go-ethereum/log"
 	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/log"
 )
 
 type logSubscription struct {
@@ -105,7 +108,20 @@ func (s *SubscriptionManager) GetSubscribedLogsForBatch(ctx context.Context, bat
 		return nil, nil
 	}
 
+	// the accounts touched by the batch are only calculated if there are account state subscriptions
+	var touched *types.Bloom
 	for id, sub := range subs {
+		if sub.Subscription.AccountState {
+			if touched == nil {
+				touched = s.touchedAccounts(ctx, batch)
+			}
+			account := *sub.ViewingKeyEncryptor.AccountAddress
+			// the account trie is keyed by the hashes of the addresses
+			if touched.Test(crypto.Keccak256(account.Bytes())) {
+				relevantLogsPerSubscription[id] = []*types.Log{{Address: account, Topics: []gethcommon.Hash{}, Data: []byte{}, BlockHash: h, BlockNumber: batch.NumberU64()}}
+			}
+			continue
+		}
 		relevantLogsForSub, err := s.storage.FilterLogs(ctx, sub.ViewingKeyEncryptor.AccountAddress, nil, nil, &h, sub.Subscription.Filter.Addresses, sub.Subscription.Filter.Topics)
 		if err != nil {
 			return nil, err
@@ -119,6 +135,24 @@ func (s *SubscriptionManager) GetSubscribedLogsForBatch(ctx context.Context, bat
 	return s.encryptLogs(relevantLogsPerSubscription)
 }
 
+// touchedAccounts - a bloom filter of the accounts whose state was changed by the batch. When they can't be determined,
+// the filter of all the accounts is returned, so the account state subscriptions are notified of a possible change
+func (s *SubscriptionManager) touchedAccounts(ctx context.Context, batch *core.Batch) *types.Bloom {
+	parent, err := s.storage.FetchBatchHeader(ctx, batch.Header.ParentHash)
+	if err == nil {
+		var touched *types.Bloom
+		if touched, err = storage.TouchedAccounts(s.storage.StateDB(), parent.Root, batch.Header.Root); err == nil {
+			return touched
+		}
+	}
+	s.logger.Debug("Could not determine the accounts touched by the batch", log.BatchHashKey, batch.Hash(), log.ErrKey, err)
+	var all types.Bloom
+	for i := range all {
+		all[i] = 0xff
+	}
+	return &all
+}
+
 // Encrypts each log with the appropriate viewing key.
 func (s *SubscriptionManager) encryptLogs(logsByID map[gethrpc.ID][]*types.Log) (map[gethrpc.ID][]byte, error) {
 	encryptedLogsByID := map[gethrpc.ID][]byte{}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/viewingkey"
+	"github.com/ten-protocol/go-ten/go/enclave/core"
+	"github.com/ten-protocol/go-ten/go/enclave/storage"
+	"github.com/ten-protocol/go-ten/go/wallet"
+	gethrpc "github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+)
+
+const testChainID = 443
+
+// testStateStorage - the state of the batches whose touched accounts are notified
+type testStateStorage struct {
+	storage.Storage
+	stateDB     state.Database
+	parentRoots map[gethcommon.Hash]gethcommon.Hash
+}
+
+func (s *testStateStorage) StateDB() state.Database {
+	return s.stateDB
+}
+
+func (s *testStateStorage) FetchBatchHeader(_ context.Context, hash gethcommon.Hash) (*common.BatchHeader, error) {
+	return &common.BatchHeader{Root: s.parentRoots[hash]}, nil
+}
+
+func TestAccountStateSubscriptions(t *testing.T) {
+	touchedVK, touched := newViewingKey(t)
+	untouchedVK, untouched := newViewingKey(t)
+
+	db := state.NewDatabaseForTesting()
+	parentState, err := state.New(types.EmptyRootHash, db)
+	require.NoError(t, err)
+	parentState.SetBalance(touched, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	parentState.SetBalance(untouched, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	parentRoot, err := parentState.Commit(1, true, false)
+	require.NoError(t, err)
+	batchState, err := state.New(parentRoot, db)
+	require.NoError(t, err)
+	batchState.SetBalance(touched, uint256.NewInt(2), tracing.BalanceChangeUnspecified)
+	root, err := batchState.Commit(2, true, false)
+	require.NoError(t, err)
+
+	parentHash := gethcommon.HexToHash("0x01")
+	manager := NewSubscriptionManager(&testStateStorage{stateDB: db, parentRoots: map[gethcommon.Hash]gethcommon.Hash{parentHash: parentRoot}}, nil, testChainID, gethlog.New())
+	for id, vk := range map[gethrpc.ID]*viewingkey.ViewingKey{"touched": touchedVK, "untouched": untouchedVK} {
+		subscription, err := common.CreateAuthenticatedLogSubscriptionPayload([]any{"logs"}, vk)
+		require.NoError(t, err)
+		subscription.AccountState = true
+		encoded, err := json.Marshal(subscription)
+		require.NoError(t, err)
+		require.NoError(t, manager.AddSubscription(id, encoded))
+	}
+
+	batch := &core.Batch{Header: &common.BatchHeader{ParentHash: parentHash, Root: root, Number: big.NewInt(2)}}
+	notifications, err := manager.GetSubscribedLogsForBatch(context.Background(), batch, types.Receipts{{}})
+	require.NoError(t, err)
+
+	// only the owner of the touched account is notified, with a message only its viewing key can decrypt
+	require.Len(t, notifications, 1)
+	decrypted, err := touchedVK.PrivateKey.Decrypt(notifications["touched"], nil, nil)
+	require.NoError(t, err)
+	var logs []*types.Log
+	require.NoError(t, json.Unmarshal(decrypted, &logs))
+	require.Len(t, logs, 1)
+	require.Equal(t, touched, logs[0].Address)
+	require.Equal(t, batch.Hash(), logs[0].BlockHash)
+	require.Empty(t, logs[0].Topics)
+}
+
+func newViewingKey(t *testing.T) (*viewingkey.ViewingKey, gethcommon.Address) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	vk, err := viewingkey.GenerateViewingKeyForWallet(wallet.NewInMemoryWalletFromPK(big.NewInt(testChainID), key, gethlog.New()))
+	require.NoError(t, err)
+	return vk, crypto.PubkeyToAddress(key.PublicKey)
+}
@@ -1,6 +1,7 @@
 package evm
 
 import (
+	"bytes"
 	"errors"
 	"math/big"
 	"strings"
@@ -43,21 +44,35 @@ const (
 
 // ContractTranspMetaData contains all meta data concerning the TransparencyConfig contract.
 var ContractTranspMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[],\"name\":\"visibilityRules\",\"outputs\":[{\"components\":[{\"internalType\":\"enumContractTransparencyConfig.ContractCfg\",\"name\":\"contractCfg\",\"type\":\"uint8\"},{\"components\":[{\"internalType\":\"bytes32\",\"name\":\"eventSignature\",\"type\":\"bytes32\"},{\"internalType\":\"enumContractTransparencyConfig.Field[]\",\"name\":\"visibleTo\",\"type\":\"uint8[]\"},{\"internalType\":\"uint8[]\",\"name\":\"visibleToDataFields\",\"type\":\"uint8[]\"}],\"internalType\":\"structContractTransparencyConfig.EventLogConfig[]\",\"name\":\"eventLogConfigs\",\"type\":\"tuple[]\"},{\"internalType\":\"bytes32[]\",\"name\":\"publicStorageSlots\",\"type\":\"bytes32[]\"},{\"internalType\":\"uint64\",\"name\":\"revealAfterBatches\",\"type\":\"uint64\"},{\"internalType\":\"uint64\",\"name\":\"revealAfterTimestamp\",\"type\":\"uint64\"}],\"internalType\":\"structContractTransparencyConfig.VisibilityConfig\",\"name\":\"\",\"type\":\"tuple\"}],\"stateMutability\":\"pure\",\"type\":\"function\"}]",
+}
+
+// ContractTranspLegacyMetaData - the interface before the public storage slots, the data fields and the delayed disclosure were added.
+// Used to read the visibility rules of the contracts compiled against it.
+var ContractTranspLegacyMetaData = &bind.MetaData{
 	ABI: "[{\"inputs\":[],\"name\":\"visibilityRules\",\"outputs\":[{\"components\":[{\"internalType\":\"enumContractTransparencyConfig.ContractCfg\",\"name\":\"contractCfg\",\"type\":\"uint8\"},{\"components\":[{\"internalType\":\"bytes32\",\"name\":\"eventSignature\",\"type\":\"bytes32\"},{\"internalType\":\"enumContractTransparencyConfig.Field[]\",\"name\":\"visibleTo\",\"type\":\"uint8[]\"}],\"internalType\":\"structContractTransparencyConfig.EventLogConfig[]\",\"name\":\"eventLogConfigs\",\"type\":\"tuple[]\"}],\"internalType\":\"structContractTransparencyConfig.VisibilityConfig\",\"name\":\"\",\"type\":\"tuple\"}],\"stateMutability\":\"pure\",\"type\":\"function\"}]",
 }
 
 // ContractTransparencyConfigEventLogConfig is an auto generated low-level Go binding around an user-defined struct.
 type ContractTransparencyConfigEventLogConfig struct {
-	EventSignature common.Hash
-	VisibleTo      []uint8
+	EventSignature      common.Hash
+	VisibleTo           []uint8
+	VisibleToDataFields []uint8
 }
 
 // ContractTransparencyConfigVisibilityConfig is an auto generated low-level Go binding around an user-defined struct.
 type ContractTransparencyConfigVisibilityConfig struct {
-	ContractCfg     uint8
-	EventLogConfigs []ContractTransparencyConfigEventLogConfig
+	ContractCfg          uint8
+	EventLogConfigs      []ContractTransparencyConfigEventLogConfig
+	PublicStorageSlots   [][32]byte
+	RevealAfterBatches   uint64
+	RevealAfterTimestamp uint64
 }
 
+// visibilityRulesMetaData - the current and the legacy versions of the interface.
+// The fields added by the current version are appended to the structs, so the legacy result is a prefix of the current struct.
+var visibilityRulesMetaData = []*bind.MetaData{ContractTranspMetaData, ContractTranspLegacyMetaData}
+
 // TransparencyConfig is an auto generated Go binding around an Ethereum contract.
 type TransparencyConfig struct {
 	TransparencyConfigCaller // Read-only binding to the contract
@@ -87,16 +102,51 @@ func bindContractTransp(address common.Address, caller bind.ContractCaller, tran
 }
 
 // VisibilityRules is a free data retrieval call binding the contract method 0x30173dd1.
+// The contracts compiled against the older versions of the interface don't return the fields added later.
+// The ABI decoding is lenient, so the result is decoded with the first version of which it is the exact encoding.
 //
-// Solidity: function visibilityRules() pure returns((bool,(bytes,bool,bool,bool,bool,bool)[]))
+// Solidity: function visibilityRules() pure returns((uint8,(bytes32,uint8[],uint8[])[],bytes32[],uint64,uint64))
 func (_ContractTransp *TransparencyConfigCaller) VisibilityRules(opts *bind.CallOpts) (ContractTransparencyConfigVisibilityConfig, error) {
-	var out []interface{}
-	err := _ContractTransp.contract.Call(opts, &out, "visibilityRules")
+	parsed, err := ContractTranspMetaData.GetAbi()
+	if err != nil {
+		return *new(ContractTransparencyConfigVisibilityConfig), err
+	}
+	input, err := parsed.Pack("visibilityRules")
+	if err != nil {
+		return *new(ContractTransparencyConfigVisibilityConfig), err
+	}
+	output, err := _ContractTransp.contract.CallRaw(opts, input)
 	if err != nil {
 		return *new(ContractTransparencyConfigVisibilityConfig), err
 	}
 
-	out0 := *abi.ConvertType(out[0], new(ContractTransparencyConfigVisibilityConfig)).(*ContractTransparencyConfigVisibilityConfig)
+	for _, metaData := range visibilityRulesMetaData {
+		versionParsed, err := metaData.GetAbi()
+		if err != nil {
+			return *new(ContractTransparencyConfigVisibilityConfig), err
+		}
+		out, err := unpackCanonical(versionParsed, output)
+		if err != nil {
+			continue
+		}
+		// the missing fields of the older versions are left empty
+		return *abi.ConvertType(out[0], new(ContractTransparencyConfigVisibilityConfig)).(*ContractTransparencyConfigVisibilityConfig), nil
+	}
+	return *new(ContractTransparencyConfigVisibilityConfig), errors.New("visibility rules were not encoded with any known ABI")
+}
 
-	return out0, err
+// unpackCanonical - decodes the result of "visibilityRules" and checks that encoding it again returns the same bytes
+func unpackCanonical(parsed *abi.ABI, output []byte) ([]interface{}, error) {
+	out, err := parsed.Unpack("visibilityRules", output)
+	if err != nil {
+		return nil, err
+	}
+	repacked, err := parsed.Methods["visibilityRules"].Outputs.Pack(out...)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(repacked, output) {
+		return nil, errors.New("visibility rules were not encoded with this ABI")
+	}
+	return out, nil
 }
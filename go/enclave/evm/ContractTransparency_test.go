@@ -0,0 +1,101 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testEventSig = gethcommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	testSlot     = gethcommon.HexToHash("0x01")
+)
+
+func TestVisibilityRulesWithDelayedDisclosure(t *testing.T) {
+	rules := ContractTransparencyConfigVisibilityConfig{
+		ContractCfg: private,
+		EventLogConfigs: []ContractTransparencyConfigEventLogConfig{
+			{EventSignature: testEventSig, VisibleTo: []uint8{topic1}, VisibleToDataFields: []uint8{1}},
+		},
+		PublicStorageSlots:   [][32]byte{testSlot},
+		RevealAfterBatches:   100,
+		RevealAfterTimestamp: 1_700_000_000,
+	}
+	output := packVisibilityRules(t, ContractTranspMetaData, rules)
+
+	decoded := readVisibilityRules(t, output)
+	require.Equal(t, rules, decoded)
+}
+
+func TestVisibilityRulesWithDataFields(t *testing.T) {
+	rules := ContractTransparencyConfigVisibilityConfig{
+		ContractCfg: private,
+		EventLogConfigs: []ContractTransparencyConfigEventLogConfig{
+			{EventSignature: testEventSig, VisibleTo: []uint8{topic1, sender}, VisibleToDataFields: []uint8{0, 2}},
+			{EventSignature: testSlot, VisibleTo: []uint8{sender}, VisibleToDataFields: []uint8{}},
+		},
+		PublicStorageSlots: [][32]byte{testSlot},
+	}
+	output := packVisibilityRules(t, ContractTranspMetaData, rules)
+
+	// the event log configs without data fields and the zero reveal values are decoded as such
+	decoded := readVisibilityRules(t, output)
+	require.Equal(t, rules, decoded)
+}
+
+func TestVisibilityRulesOfLegacyContracts(t *testing.T) {
+	for _, eventLogConfigs := range [][]ContractTransparencyConfigEventLogConfig{
+		{},
+		{{EventSignature: testEventSig, VisibleTo: []uint8{topic1, sender}}},
+	} {
+		rules := ContractTransparencyConfigVisibilityConfig{
+			ContractCfg:     private,
+			EventLogConfigs: eventLogConfigs,
+		}
+		output := packVisibilityRules(t, ContractTranspLegacyMetaData, rules)
+
+		// the legacy encoding must not be interpreted as public storage slots or data fields
+		decoded := readVisibilityRules(t, output)
+		require.Equal(t, private, decoded.ContractCfg)
+		require.Len(t, decoded.EventLogConfigs, len(eventLogConfigs))
+		for i, eventLogConfig := range decoded.EventLogConfigs {
+			require.Equal(t, eventLogConfigs[i].VisibleTo, eventLogConfig.VisibleTo)
+			require.Empty(t, eventLogConfig.VisibleToDataFields)
+		}
+		require.Empty(t, decoded.PublicStorageSlots)
+	}
+}
+
+func packVisibilityRules(t *testing.T, metadata *bind.MetaData, rules any) []byte {
+	parsed, err := metadata.GetAbi()
+	require.NoError(t, err)
+	output, err := parsed.Methods["visibilityRules"].Outputs.Pack(rules)
+	require.NoError(t, err)
+	return output
+}
+
+func readVisibilityRules(t *testing.T, output []byte) ContractTransparencyConfigVisibilityConfig {
+	cc, err := NewTransparencyConfigCaller(gethcommon.Address{}, &fixedOutputCaller{output: output})
+	require.NoError(t, err)
+	decoded, err := cc.VisibilityRules(nil)
+	require.NoError(t, err)
+	return decoded
+}
+
+// fixedOutputCaller - returns the same output for all calls
+type fixedOutputCaller struct {
+	output []byte
+}
+
+func (c *fixedOutputCaller) CodeAt(context.Context, gethcommon.Address, *big.Int) ([]byte, error) {
+	return []byte{0}, nil
+}
+
+func (c *fixedOutputCaller) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return c.output, nil
+}
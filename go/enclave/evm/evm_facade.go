@@ -54,6 +54,15 @@ func NewEVMExecutor(chain *TenChainContext, cc *params.ChainConfig, config *encl
 }
 
 func (exec *evmExecutor) ExecuteTx(tx *common.L2PricedTransaction, s *state.StateDB, header *types.Header, gp *gethcore.GasPool, usedGas *uint64, tCount int, noBaseFee bool) *core.TxExecResult {
+	return exec.executeTx(tx, s, header, gp, usedGas, tCount, noBaseFee, nil)
+}
+
+// TraceTx - executes the transaction exactly like ExecuteTx, but also reports every step of the execution to the tracer
+func (exec *evmExecutor) TraceTx(tx *common.L2PricedTransaction, s *state.StateDB, header *types.Header, gp *gethcore.GasPool, usedGas *uint64, tCount int, tracer *tracing.Hooks) *core.TxExecResult {
+	return exec.executeTx(tx, s, header, gp, usedGas, tCount, false, tracer)
+}
+
+func (exec *evmExecutor) executeTx(tx *common.L2PricedTransaction, s *state.StateDB, header *types.Header, gp *gethcore.GasPool, usedGas *uint64, tCount int, noBaseFee bool, tracer *tracing.Hooks) *core.TxExecResult {
 	from, err := core.GetTxSigner(tx)
 	if err != nil {
 		return &core.TxExecResult{
@@ -63,7 +72,7 @@ func (exec *evmExecutor) ExecuteTx(tx *common.L2PricedTransaction, s *state.Stat
 	}
 
 	snap := s.Snapshot()
-	res, err := exec.execute(tx, from, s, header, gp, usedGas, tCount, noBaseFee)
+	res, err := exec.execute(tx, from, s, header, gp, usedGas, tCount, noBaseFee, tracer)
 	if err != nil {
 		s.RevertToSnapshot(snap)
 		return &core.TxExecResult{
@@ -74,24 +83,22 @@ func (exec *evmExecutor) ExecuteTx(tx *common.L2PricedTransaction, s *state.Stat
 	return res
 }
 
-func (exec *evmExecutor) execute(tx *common.L2PricedTransaction, from gethcommon.Address, s *state.StateDB, header *types.Header, gp *gethcore.GasPool, usedGas *uint64, tCount int, noBaseFee bool) (*core.TxExecResult, error) {
+func (exec *evmExecutor) execute(tx *common.L2PricedTransaction, from gethcommon.Address, s *state.StateDB, header *types.Header, gp *gethcore.GasPool, usedGas *uint64, tCount int, noBaseFee bool, tracer *tracing.Hooks) (*core.TxExecResult, error) {
 	// a transaction can create multiple contracts.
 	// we use a tracer hook to collect the addresses
 	var createdContracts []*gethcommon.Address
 	cfg := vm.Config{
 		NoBaseFee: noBaseFee,
-		Tracer: &tracing.Hooks{
-			// called when the code of a contract changes.
-			OnCodeChange: func(addr gethcommon.Address, prevCodeHash gethcommon.Hash, prevCode []byte, codeHash gethcommon.Hash, code []byte) {
-				// only proceed for new deployments.
-				if len(prevCode) > 0 {
-					exec.logger.Debug("OnCodeChange: Skipping contract deployment", "address", addr.Hex())
-					return
-				}
-				createdContracts = append(createdContracts, &addr)
-				exec.logger.Debug("OnCodeChange: Contract deployed", "address", addr.Hex())
-			},
-		},
+		// called when the code of a contract changes.
+		Tracer: withCodeChangeHook(tracer, func(addr gethcommon.Address, prevCodeHash gethcommon.Hash, prevCode []byte, codeHash gethcommon.Hash, code []byte) {
+			// only proceed for new deployments.
+			if len(prevCode) > 0 {
+				exec.logger.Debug("OnCodeChange: Skipping contract deployment", "address", addr.Hex())
+				return
+			}
+			createdContracts = append(createdContracts, &addr)
+			exec.logger.Debug("OnCodeChange: Contract deployed", "address", addr.Hex())
+		}),
 	}
 
 	hookedStateDb := state.NewHookedState(s, cfg.Tracer)
@@ -113,10 +120,16 @@ func (exec *evmExecutor) execute(tx *common.L2PricedTransaction, from gethcommon
 	}
 	receipt.Logs = s.GetLogs(tx.Tx.Hash(), header.Number.Uint64(), header.Hash())
 
+	// the visibility config is read by calling the new contracts, which is not part of the transaction being traced
+	visibilityEVM := evmEnv
+	if tracer != nil && len(createdContracts) > 0 {
+		visibilityEVM = vm.NewEVM(blockContext, s, exec.cc, vm.Config{NoBaseFee: noBaseFee})
+		visibilityEVM.SetTxContext(evmEnv.TxContext)
+	}
 	contractsWithVisibility := make(map[gethcommon.Address]*core.ContractVisibilityConfig)
 	for _, contractAddress := range createdContracts {
 		var err1 error
-		contractsWithVisibility[*contractAddress], err1 = exec.visibilityReader.ReadVisibilityConfig(context.Background(), evmEnv, *contractAddress)
+		contractsWithVisibility[*contractAddress], err1 = exec.visibilityReader.ReadVisibilityConfig(context.Background(), visibilityEVM, *contractAddress)
 		if err1 != nil {
 			exec.logger.Crit("could not read visibility config. Should not happen", log.ErrKey, err1)
 			return nil, err1
@@ -133,9 +146,18 @@ func (exec *evmExecutor) execute(tx *common.L2PricedTransaction, from gethcommon
 // ExecuteCall - executes the eth_call call
 func (exec *evmExecutor) ExecuteCall(ctx context.Context, msg *gethcore.Message, s *state.StateDB, header *common.BatchHeader) (*gethcore.ExecutionResult, error, common.SystemError) {
 	defer core.LogMethodDuration(exec.logger, measure.NewStopwatch(), "evm_facade.go:Call()")
+	return exec.executeCall(ctx, msg, s, header, nil)
+}
+
+// TraceCall - executes the call exactly like ExecuteCall, but also reports every step of the execution to the tracer
+func (exec *evmExecutor) TraceCall(ctx context.Context, msg *gethcore.Message, s *state.StateDB, header *common.BatchHeader, tracer *tracing.Hooks) (*gethcore.ExecutionResult, error, common.SystemError) {
+	return exec.executeCall(ctx, msg, s, header, tracer)
+}
 
+func (exec *evmExecutor) executeCall(ctx context.Context, msg *gethcore.Message, s *state.StateDB, header *common.BatchHeader, tracer *tracing.Hooks) (*gethcore.ExecutionResult, error, common.SystemError) {
 	vmCfg := vm.Config{
 		NoBaseFee: true,
+		Tracer:    tracer,
 	}
 
 	ethHeader, err := exec.gethEncodingService.CreateEthHeaderForBatch(ctx, header)
@@ -150,10 +172,21 @@ func (exec *evmExecutor) ExecuteCall(ctx context.Context, msg *gethcore.Message,
 	snapshot := cleanState.Snapshot()
 	defer cleanState.RevertToSnapshot(snapshot) // Always revert after simulation
 
+	var vmState vm.StateDB = cleanState
+	if tracer != nil {
+		vmState = state.NewHookedState(cleanState, tracer)
+	}
+
 	blockContext := gethcore.NewEVMBlockContext(ethHeader, exec.chain, nil)
 	// sets TxKey.origin
-	vmenv := vm.NewEVM(blockContext, cleanState, exec.cc, vmCfg)
+	vmenv := vm.NewEVM(blockContext, vmState, exec.cc, vmCfg)
+	if tracer != nil && tracer.OnTxStart != nil {
+		tracer.OnTxStart(vmenv.GetVMContext(), messageToTx(msg), msg.From)
+	}
 	result, err := gethcore.ApplyMessage(vmenv, msg, &gp)
+	if tracer != nil && tracer.OnTxEnd != nil && result != nil {
+		tracer.OnTxEnd(&types.Receipt{GasUsed: result.UsedGas}, err)
+	}
 	// Follow the same error check structure as in geth
 	// 1 - vmError / stateDB err check
 	// 2 - evm.Cancelled()  todo (#1576) - support the ability to cancel function call if it takes too long
@@ -173,6 +206,34 @@ func (exec *evmExecutor) ExecuteCall(ctx context.Context, msg *gethcore.Message,
 	return result, nil, nil
 }
 
+// withCodeChangeHook - returns a copy of the tracer hooks (which can be nil) where the OnCodeChange hook also calls the supplied function
+func withCodeChangeHook(tracer *tracing.Hooks, onCodeChange tracing.CodeChangeHook) *tracing.Hooks {
+	if tracer == nil {
+		return &tracing.Hooks{OnCodeChange: onCodeChange}
+	}
+	hooks := *tracer
+	tracerOnCodeChange := tracer.OnCodeChange
+	hooks.OnCodeChange = func(addr gethcommon.Address, prevCodeHash gethcommon.Hash, prevCode []byte, codeHash gethcommon.Hash, code []byte) {
+		onCodeChange(addr, prevCodeHash, prevCode, codeHash, code)
+		if tracerOnCodeChange != nil {
+			tracerOnCodeChange(addr, prevCodeHash, prevCode, codeHash, code)
+		}
+	}
+	return &hooks
+}
+
+// messageToTx - the tracers expect a transaction, so one is created from the call message
+func messageToTx(msg *gethcore.Message) *types.Transaction {
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    msg.Nonce,
+		GasPrice: msg.GasPrice,
+		Gas:      msg.GasLimit,
+		To:       msg.To,
+		Value:    msg.Value,
+		Data:     msg.Data,
+	})
+}
+
 func createCleanState(s *state.StateDB, msg *gethcore.Message, ethHeader *types.Header, chainConfig *params.ChainConfig) *state.StateDB {
 	cleanState := s.Copy()
 	cleanState.Prepare(chainConfig.Rules(ethHeader.Number, true, 0), msg.From, ethHeader.Coinbase, msg.To, nil, msg.AccessList)
@@ -0,0 +1,76 @@
+package evm
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/tracers"
+	enclaveconfig "github.com/ten-protocol/go-ten/go/enclave/config"
+	"github.com/ten-protocol/go-ten/go/enclave/evm/ethchainadapter"
+
+	// registers the native tracers
+	_ "github.com/ten-protocol/go-ten/go/common/tracers/native"
+)
+
+// deploys a contract whose code is the single STOP opcode
+var deployStopContract = gethcommon.FromHex("0x6001600c60003960016000f300")
+
+func TestTraceDeployTx(t *testing.T) {
+	for _, tracerName := range []string{"callTracer", "prestateTracer", "4byteTracer"} {
+		t.Run(tracerName, func(t *testing.T) {
+			cc := ethchainadapter.ChainParams(big.NewInt(443))
+			logger := gethlog.New()
+			cfg := &enclaveconfig.EnclaveConfig{RPCTimeout: time.Second}
+			exec := NewEVMExecutor(NewTenChainContext(nil, nil, cfg, cc, logger), cc, cfg, 0, nil, nil, NewContractVisibilityReader(logger), logger)
+
+			key, err := crypto.GenerateKey()
+			require.NoError(t, err)
+			sender := crypto.PubkeyToAddress(key.PublicKey)
+			stateDB, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+			require.NoError(t, err)
+			stateDB.SetBalance(sender, uint256.NewInt(1e18), tracing.BalanceChangeUnspecified)
+
+			tx, err := types.SignNewTx(key, types.LatestSigner(cc), &types.LegacyTx{Gas: 1_000_000, GasPrice: big.NewInt(1), Data: deployStopContract})
+			require.NoError(t, err)
+			header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(0), GasLimit: 10_000_000, BaseFee: big.NewInt(1)}
+
+			tracer, err := tracers.New(tracerName, &tracers.Context{}, nil, cc)
+			require.NoError(t, err)
+			gp := gethcore.GasPool(header.GasLimit)
+			usedGas := uint64(0)
+			result := exec.TraceTx(&common.L2PricedTransaction{Tx: tx, PublishingCost: big.NewInt(0)}, stateDB, header, &gp, &usedGas, 0, tracer.Hooks)
+			require.NoError(t, result.Err)
+			require.Len(t, result.CreatedContracts, 1)
+
+			// the visibility config of the new contract is read with a call the tracer must not see
+			res, err := tracer.GetResult()
+			require.NoError(t, err)
+			if tracerName == "callTracer" {
+				var frame struct {
+					Type  string             `json:"type"`
+					To    gethcommon.Address `json:"to"`
+					Calls []json.RawMessage  `json:"calls"`
+				}
+				require.NoError(t, json.Unmarshal(res, &frame))
+				require.Equal(t, "CREATE", frame.Type)
+				require.Equal(t, crypto.CreateAddress(sender, 0), frame.To)
+				require.Empty(t, frame.Calls)
+			}
+			if tracerName == "4byteTracer" {
+				require.JSONEq(t, "{}", string(res))
+			}
+		})
+	}
+}
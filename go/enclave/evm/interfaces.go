@@ -6,6 +6,7 @@ import (
 	gethcommon "github.com/ethereum/go-ethereum/common"
 	gethcore "github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ten-protocol/go-ten/go/common"
@@ -14,7 +15,11 @@ import (
 
 type EVMFacade interface {
 	ExecuteTx(tx *common.L2PricedTransaction, s *state.StateDB, header *types.Header, gp *gethcore.GasPool, usedGas *uint64, tCount int, noBaseFee bool) *core.TxExecResult
+	// TraceTx - same as ExecuteTx, but the execution is reported to the tracer. Used by the debug endpoints.
+	TraceTx(tx *common.L2PricedTransaction, s *state.StateDB, header *types.Header, gp *gethcore.GasPool, usedGas *uint64, tCount int, tracer *tracing.Hooks) *core.TxExecResult
 	ExecuteCall(ctx context.Context, msg *gethcore.Message, s *state.StateDB, header *common.BatchHeader) (*gethcore.ExecutionResult, error, common.SystemError)
+	// TraceCall - same as ExecuteCall, but the execution is reported to the tracer. Used by the debug endpoints.
+	TraceCall(ctx context.Context, msg *gethcore.Message, s *state.StateDB, header *common.BatchHeader, tracer *tracing.Hooks) (*gethcore.ExecutionResult, error, common.SystemError)
 }
 
 type ContractVisibilityReader interface {
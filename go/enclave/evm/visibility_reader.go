@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"slices"
 
 	"github.com/ethereum/go-ethereum"
 	gethcommon "github.com/ethereum/go-ethereum/common"
@@ -67,6 +68,18 @@ func (v *contractVisibilityReader) readVisibilityConfig(evm *vm.EVM, contractAdd
 		cfg.EventConfigs[logConfig.EventSignature] = eventCfg(logConfig)
 	}
 
+	for _, slot := range visibilityRules.PublicStorageSlots {
+		cfg.PublicStorageSlots = append(cfg.PublicStorageSlots, slot)
+	}
+
+	// zero means that the data is never revealed
+	if visibilityRules.RevealAfterBatches > 0 {
+		cfg.RevealAfterBatches = &visibilityRules.RevealAfterBatches
+	}
+	if visibilityRules.RevealAfterTimestamp > 0 {
+		cfg.RevealAfterTimestamp = &visibilityRules.RevealAfterTimestamp
+	}
+
 	return cfg, nil
 }
 
@@ -86,13 +99,24 @@ func eventCfg(logConfig ContractTransparencyConfigEventLogConfig) *core.EventVis
 	t3 := relevantToMap[topic3]
 	s := relevantToMap[sender]
 	return &core.EventVisibilityConfig{
-		AutoConfig:    false,
-		Public:        false,
-		Topic1CanView: &t1,
-		Topic2CanView: &t2,
-		Topic3CanView: &t3,
-		SenderCanView: &s,
+		AutoConfig:        false,
+		Public:            false,
+		Topic1CanView:     &t1,
+		Topic2CanView:     &t2,
+		Topic3CanView:     &t3,
+		SenderCanView:     &s,
+		DataFieldsCanView: dataFields(logConfig.VisibleToDataFields),
+	}
+}
+
+// dataFields - returns the sorted positions without duplicates
+func dataFields(positions []uint8) []uint8 {
+	if len(positions) == 0 {
+		return nil
 	}
+	result := slices.Clone(positions)
+	slices.Sort(result)
+	return slices.Compact(result)
 }
 
 // used as a wrapper around the vm.EVM to allow for easier calling of smart contract view functions
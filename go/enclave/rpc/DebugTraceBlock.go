@@ -0,0 +1,75 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	"github.com/ten-protocol/go-ten/go/common/gethencoding"
+	"github.com/ten-protocol/go-ten/go/common/tracers"
+	"github.com/ten-protocol/go-ten/go/enclave/debugger"
+	gethrpc "github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+)
+
+type traceBlockParams struct {
+	block  *gethrpc.BlockNumberOrHash
+	config *tracers.TraceConfig
+}
+
+func DebugTraceBlockValidate(reqParams []any, builder *CallBuilder[traceBlockParams, []*tracers.TxTraceResult], rpc *EncryptionManager) error {
+	if !storeTxEnabled(rpc, builder) {
+		return nil
+	}
+	// Parameters are [BlockNumber, TraceConfig]. The config is optional
+	if len(reqParams) < 1 || len(reqParams) > 2 {
+		builder.Err = fmt.Errorf("unexpected number of parameters")
+		return nil
+	}
+
+	blkNumber, err := gethencoding.ExtractBlockNumber(reqParams[0])
+	if err != nil {
+		builder.Err = fmt.Errorf("unable to extract requested block number - %w", err)
+		return nil
+	}
+
+	var config *tracers.TraceConfig
+	if len(reqParams) == 2 {
+		config, err = extractTraceConfig(reqParams[1])
+		if err != nil {
+			builder.Err = err
+			return nil
+		}
+	}
+
+	builder.Param = &traceBlockParams{block: blkNumber, config: config}
+	return nil
+}
+
+// DebugTraceBlockExecute - traces the transactions of the batch which were sent by the requester
+func DebugTraceBlockExecute(builder *CallBuilder[traceBlockParams, []*tracers.TxTraceResult], rpc *EncryptionManager) error {
+	if !rpc.config.DebugNamespaceEnabled {
+		builder.Err = fmt.Errorf("debug namespace not enabled")
+		return nil
+	}
+
+	batch, err := fetchBatch(builder, rpc, builder.Param.block)
+	if err != nil {
+		if errors.Is(err, errutil.ErrNotFound) {
+			builder.Status = NotFound
+			return nil
+		}
+		return err
+	}
+
+	traces, err := rpc.debugger.DebugTraceBatch(builder.ctx, batch, builder.Param.config, builder.VK.AccountAddress)
+	if err != nil {
+		if errors.Is(err, debugger.ErrNotTraceable) {
+			builder.Err = err
+			return nil
+		}
+		return fmt.Errorf("could not trace batch %s. Cause: %w", batch.Hash(), err)
+	}
+
+	builder.ReturnValue = &traces
+	return nil
+}
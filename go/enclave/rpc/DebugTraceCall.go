@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	"github.com/ten-protocol/go-ten/go/common/gethapi"
+	"github.com/ten-protocol/go-ten/go/common/gethencoding"
+	"github.com/ten-protocol/go-ten/go/common/tracers"
+	"github.com/ten-protocol/go-ten/go/enclave/core"
+	gethrpc "github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+)
+
+type traceCallParams struct {
+	callParams *gethapi.TransactionArgs
+	block      *gethrpc.BlockNumberOrHash
+	config     *tracers.TraceConfig
+}
+
+func DebugTraceCallValidate(reqParams []any, builder *CallBuilder[traceCallParams, json.RawMessage], _ *EncryptionManager) error {
+	// Parameters are [TransactionArgs, BlockNumberOrHash, TraceConfig]. The config is optional
+	if len(reqParams) < 2 || len(reqParams) > 3 {
+		builder.Err = fmt.Errorf("unexpected number of parameters")
+		return nil
+	}
+	apiArgs, err := gethencoding.ExtractEthCall(reqParams[0])
+	if err != nil {
+		builder.Err = fmt.Errorf("unable to decode EthCall Params - %w", err)
+		return nil
+	}
+
+	if apiArgs.From == nil {
+		builder.Err = fmt.Errorf("no from address provided")
+		return nil
+	}
+
+	blkNumber, err := gethencoding.ExtractBlockNumber(reqParams[1])
+	if err != nil {
+		builder.Err = fmt.Errorf("unable to extract requested block number - %w", err)
+		return nil
+	}
+
+	var config *tracers.TraceConfig
+	if len(reqParams) == 3 {
+		config, err = extractTraceConfig(reqParams[2])
+		if err != nil {
+			builder.Err = err
+			return nil
+		}
+	}
+
+	builder.From = apiArgs.From
+	builder.Param = &traceCallParams{callParams: apiArgs, block: blkNumber, config: config}
+	return nil
+}
+
+// DebugTraceCallExecute - traces the call on top of the requested batch. Only the sender of the call can trace it.
+func DebugTraceCallExecute(builder *CallBuilder[traceCallParams, json.RawMessage], rpc *EncryptionManager) error {
+	if !rpc.config.DebugNamespaceEnabled {
+		builder.Err = fmt.Errorf("debug namespace not enabled")
+		return nil
+	}
+
+	err := authenticateFrom(builder.VK, builder.From)
+	if err != nil {
+		builder.Err = err
+		return nil //nolint:nilerr
+	}
+
+	batch, err := fetchBatch(builder, rpc, builder.Param.block)
+	if err != nil {
+		if errors.Is(err, errutil.ErrNotFound) {
+			builder.Status = NotFound
+			return nil
+		}
+		return err
+	}
+
+	callMsg, err := builder.Param.callParams.ToMessage(batch.Header.GasLimit-1, batch.Header.BaseFee)
+	if err != nil {
+		builder.Err = fmt.Errorf("unable to convert TransactionArgs to Message - %w", err)
+		return nil
+	}
+
+	trace, err := rpc.debugger.DebugTraceCall(builder.ctx, callMsg, batch, builder.Param.config)
+	if err != nil {
+		return fmt.Errorf("could not trace call. Cause: %w", err)
+	}
+
+	builder.ReturnValue = &trace
+	return nil
+}
+
+func fetchBatch[P any, R any](builder *CallBuilder[P, R], rpc *EncryptionManager, block *gethrpc.BlockNumberOrHash) (*core.Batch, error) {
+	if block.BlockHash != nil {
+		return rpc.storage.FetchBatch(builder.ctx, *block.BlockHash)
+	}
+	return rpc.registry.GetBatchAtHeight(builder.ctx, *block.BlockNumber)
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	"github.com/ten-protocol/go-ten/go/common/tracers"
+	"github.com/ten-protocol/go-ten/go/enclave/debugger"
+)
+
+type traceTxParams struct {
+	txHash gethcommon.Hash
+	config *tracers.TraceConfig
+}
+
+func DebugTraceTransactionValidate(reqParams []any, builder *CallBuilder[traceTxParams, json.RawMessage], rpc *EncryptionManager) error {
+	if !storeTxEnabled(rpc, builder) {
+		return nil
+	}
+	// Parameters are [Hash, TraceConfig]. The config is optional
+	if len(reqParams) < 1 || len(reqParams) > 2 {
+		builder.Err = fmt.Errorf("unexpected number of parameters")
+		return nil
+	}
+	txHashStr, ok := reqParams[0].(string)
+	if !ok {
+		builder.Err = fmt.Errorf("unexpected tx hash parameter")
+		return nil
+	}
+
+	params := traceTxParams{txHash: gethcommon.HexToHash(txHashStr)}
+	if len(reqParams) == 2 {
+		config, err := extractTraceConfig(reqParams[1])
+		if err != nil {
+			builder.Err = err
+			return nil
+		}
+		params.config = config
+	}
+
+	builder.Param = &params
+	return nil
+}
+
+func DebugTraceTransactionExecute(builder *CallBuilder[traceTxParams, json.RawMessage], rpc *EncryptionManager) error {
+	if !rpc.config.DebugNamespaceEnabled {
+		builder.Err = fmt.Errorf("debug namespace not enabled")
+		return nil
+	}
+
+	txHash := builder.Param.txHash
+	_, batchHash, _, _, sender, err := rpc.storage.GetTransaction(builder.ctx, txHash)
+	if err != nil {
+		if errors.Is(err, errutil.ErrNotFound) {
+			builder.Status = NotFound
+			return nil
+		}
+		return err
+	}
+
+	// authorise - only the signer can trace the transaction
+	if sender != *builder.VK.AccountAddress {
+		builder.Status = NotAuthorised
+		return nil
+	}
+
+	trace, err := rpc.debugger.DebugTraceTransaction(builder.ctx, txHash, batchHash, builder.Param.config, builder.VK.AccountAddress)
+	if err != nil {
+		if errors.Is(err, debugger.ErrNotTraceable) {
+			builder.Err = err
+			return nil
+		}
+		return fmt.Errorf("could not trace transaction %s. Cause: %w", txHash, err)
+	}
+
+	builder.ReturnValue = &trace
+	return nil
+}
+
+// extractTraceConfig - the optional trace config, in the geth format
+func extractTraceConfig(param any) (*tracers.TraceConfig, error) {
+	if param == nil {
+		return nil, nil
+	}
+	serialised, err := json.Marshal(param)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace config: %w", err)
+	}
+	var config tracers.TraceConfig
+	if err = json.Unmarshal(serialised, &config); err != nil {
+		return nil, fmt.Errorf("invalid trace config: %w", err)
+	}
+	return &config, nil
+}
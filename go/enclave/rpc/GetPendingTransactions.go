@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/ten-protocol/go-ten/go/common/gethutil"
+)
+
+func GetPendingTransactionsValidate(reqParams []any, builder *CallBuilder[any, []*RpcTransaction], _ *EncryptionManager) error {
+	// there are no parameters. The transactions of the viewing key account are returned
+	if len(reqParams) != 0 {
+		builder.Err = fmt.Errorf("unexpected number of parameters")
+		return nil
+	}
+	builder.Param = new(any)
+	return nil
+}
+
+// GetPendingTransactionsExecute - returns the transactions from the mempool sent by the account of the viewing key.
+// Only available on the sequencer.
+func GetPendingTransactionsExecute(builder *CallBuilder[any, []*RpcTransaction], rpc *EncryptionManager) error {
+	requester := *builder.VK.AccountAddress
+
+	txs, err := rpc.mempool.PendingTransactionsFrom(requester)
+	if err != nil {
+		builder.Err = err
+		return nil
+	}
+	result := make([]*RpcTransaction, 0, len(txs))
+	for _, tx := range txs {
+		result = append(result, newRPCTransaction(tx, gethutil.EmptyHash, 0, 0, rpc.config.BaseFee, requester))
+	}
+
+	builder.ReturnValue = &result
+	return nil
+}
@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	"github.com/ten-protocol/go-ten/go/common/gethencoding"
+	gethrpc "github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+)
+
+// AccountResult - the result of "eth_getProof". Same format as geth
+type AccountResult struct {
+	Address      gethcommon.Address `json:"address"`
+	AccountProof []string           `json:"accountProof"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     gethcommon.Hash    `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  gethcommon.Hash    `json:"storageHash"`
+	StorageProof []StorageResult    `json:"storageProof"`
+}
+
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+type proofRequest struct {
+	address     gethcommon.Address
+	storageKeys []gethcommon.Hash
+	keyLengths  []int // the length of each key as provided by the user. Used to encode the key in the result
+	block       *gethrpc.BlockNumberOrHash
+	isPrivate   bool // the contract is not transparent, so the commitments to its storage are withheld
+}
+
+// GetProofValidate - the proofs are restricted with the same rules as "eth_getStorageAt":
+// the storage of transparent contracts, the whitelisted storage slots of any contract,
+// and the account of the requester.
+// For the contracts which are not transparent only the values of the whitelisted slots are returned: the storage root,
+// which changes with every write to the private state, is withheld together with the proofs which contain it.
+func GetProofValidate(reqParams []any, builder *CallBuilder[proofRequest, AccountResult], rpc *EncryptionManager) error {
+	// Parameters are [Address, StorageKeys, BlockNumber]
+	if len(reqParams) != 3 {
+		builder.Err = fmt.Errorf("unexpected number of parameters")
+		return nil
+	}
+
+	address, err := gethencoding.ExtractAddress(reqParams[0])
+	if err != nil {
+		builder.Err = fmt.Errorf("error extracting address - %w", err)
+		return nil
+	}
+
+	rawKeys, ok := reqParams[1].([]any)
+	if reqParams[1] != nil && !ok {
+		builder.Err = fmt.Errorf("storage keys not provided in parameters")
+		return nil
+	}
+	keys := make([]gethcommon.Hash, len(rawKeys))
+	keyLengths := make([]int, len(rawKeys))
+	for i, rawKey := range rawKeys {
+		hexKey, ok := rawKey.(string)
+		if !ok {
+			builder.Err = fmt.Errorf("invalid storage key %v", rawKey)
+			return nil
+		}
+		keys[i], keyLengths[i], err = decodeHash(hexKey)
+		if err != nil {
+			builder.Err = fmt.Errorf("invalid storage key %s - %w", hexKey, err)
+			return nil
+		}
+	}
+
+	blkNumber, err := gethencoding.ExtractBlockNumber(reqParams[2])
+	if err != nil {
+		builder.Err = fmt.Errorf("unable to extract requested block number - %w", err)
+		return nil
+	}
+
+	contract, err := rpc.storage.ReadContract(builder.ctx, *address)
+	if err != nil && !errors.Is(err, errutil.ErrNotFound) {
+		return fmt.Errorf("unable to read contract %s - %w", address, err)
+	}
+	switch {
+	case contract == nil:
+		// the balance and nonce of an externally owned account can only be proven to its owner
+		if *address != *builder.VK.AccountAddress {
+			builder.Err = fmt.Errorf("eth_getProof is not supported for this account")
+			return nil
+		}
+	case !contract.IsTransparent():
+		// block the call for un-transparent contracts and non-whitelisted slots
+		for _, key := range keys {
+			if !rpc.storageSlotWhitelist.IsStorageSlotAllowed(key, contract.PublicStorageSlots) {
+				builder.Err = fmt.Errorf("eth_getProof is not supported for this contract")
+				return nil
+			}
+		}
+	}
+
+	builder.Param = &proofRequest{
+		address:     *address,
+		storageKeys: keys,
+		keyLengths:  keyLengths,
+		block:       blkNumber,
+		isPrivate:   contract != nil && !contract.IsTransparent(),
+	}
+	return nil
+}
+
+// GetProofExecute - returns the Merkle proofs of the account and of the storage keys from the state of the batch
+func GetProofExecute(builder *CallBuilder[proofRequest, AccountResult], rpc *EncryptionManager) error {
+	stateDb, err := rpc.registry.GetBatchState(builder.ctx, *builder.Param.block)
+	if err != nil {
+		builder.Err = fmt.Errorf("unable to read block number - %w", err)
+		return nil
+	}
+
+	address := builder.Param.address
+	accountTrie := stateDb.GetTrie()
+	storageRoot := stateDb.GetStorageRoot(address)
+
+	storageProof := make([]StorageResult, len(builder.Param.storageKeys))
+	if len(builder.Param.storageKeys) > 0 {
+		var storageTrie state.Trie
+		if storageRoot != types.EmptyRootHash && storageRoot != (gethcommon.Hash{}) {
+			storageTrie, err = stateDb.Database().OpenStorageTrie(accountTrie.Hash(), address, storageRoot, accountTrie)
+			if err != nil {
+				builder.Err = fmt.Errorf("unable to open the storage trie - %w", err)
+				return nil
+			}
+		}
+		for i, key := range builder.Param.storageKeys {
+			// same as geth, the 32 bytes keys are returned as such, and the others as quantities
+			outputKey := hexutil.EncodeBig(key.Big())
+			if builder.Param.keyLengths[i] == 32 {
+				outputKey = hexutil.Encode(key[:])
+			}
+			if storageTrie == nil {
+				storageProof[i] = StorageResult{outputKey, &hexutil.Big{}, []string{}}
+				continue
+			}
+			value := (*hexutil.Big)(stateDb.GetState(address, key).Big())
+			if builder.Param.isPrivate {
+				storageProof[i] = StorageResult{outputKey, value, []string{}}
+				continue
+			}
+			var proof proofList
+			if err := storageTrie.Prove(crypto.Keccak256(key.Bytes()), &proof); err != nil {
+				return fmt.Errorf("unable to prove storage key %s - %w", outputKey, err)
+			}
+			storageProof[i] = StorageResult{outputKey, value, proof}
+		}
+	}
+
+	accountProof := proofList{}
+	if builder.Param.isPrivate {
+		// the account leaf contains the storage root
+		storageRoot = gethcommon.Hash{}
+	} else if err := accountTrie.Prove(crypto.Keccak256(address.Bytes()), &accountProof); err != nil {
+		return fmt.Errorf("unable to prove account %s - %w", address, err)
+	}
+
+	builder.ReturnValue = &AccountResult{
+		Address:      address,
+		AccountProof: accountProof,
+		Balance:      (*hexutil.Big)(stateDb.GetBalance(address).ToBig()),
+		CodeHash:     stateDb.GetCodeHash(address),
+		Nonce:        hexutil.Uint64(stateDb.GetNonce(address)),
+		StorageHash:  storageRoot,
+		StorageProof: storageProof,
+	}
+	return stateDb.Error()
+}
+
+// proofList implements ethdb.KeyValueWriter and collects the proofs as hex-strings
+type proofList []string
+
+func (n *proofList) Put(_ []byte, value []byte) error {
+	*n = append(*n, hexutil.Encode(value))
+	return nil
+}
+
+func (n *proofList) Delete([]byte) error {
+	return errors.New("not supported")
+}
+
+// decodeHash parses a hex-encoded hash of up to 32 bytes, optionally prefixed by 0x
+func decodeHash(s string) (gethcommon.Hash, int, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if (len(s) & 1) > 0 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return gethcommon.Hash{}, 0, errors.New("hex string invalid")
+	}
+	if len(b) > 32 {
+		return gethcommon.Hash{}, len(b), errors.New("hex string too long, want at most 32 bytes")
+	}
+	return gethcommon.BytesToHash(b), len(b), nil
+}
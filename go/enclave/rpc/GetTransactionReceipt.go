@@ -103,13 +103,17 @@ func fetchFromCache(ctx context.Context, storage storage.Storage, cacheService *
 	logs := rec.Receipt.Logs
 	// filter out the logs that the sender can't read
 	// doesn't apply to contract creation (when to=nil)
-	if len(logs) > 0 && (rec.To != nil && *rec.To != (gethcommon.Address{})) {
+	if rec.To != nil && *rec.To != (gethcommon.Address{}) {
 		ctr, err := storage.ReadContract(ctx, *rec.To)
 		if err != nil && !errors.Is(err, errutil.ErrNotFound) {
 			return nil, fmt.Errorf("could not read contract in eth_getTransactionReceipt request. Cause: %w", err)
 		}
+		// the visibility of the data of contracts with delayed disclosure depends on the head batch, so it is checked by the database
+		if ctr != nil && ctr.HasDelayedDisclosure() {
+			return nil, nil
+		}
 		// only filter when the transaction calls a contract. Value transfers emit no events.
-		if ctr != nil {
+		if ctr != nil && len(logs) > 0 {
 			logs, err = filterLogs(ctx, storage, rec.Receipt.Logs, ctr, requester)
 			if err != nil && !errors.Is(err, errutil.ErrNotFound) {
 				return nil, fmt.Errorf("could not filter cached logs in eth_getTransactionReceipt request. Cause: %w", err)
@@ -153,23 +157,7 @@ func senderCanViewLog(ctx context.Context, storage storage.Storage, ctr *enclave
 	if errors.Is(err, errutil.ErrNotFound) {
 		return false, err
 	}
-	// event visibility logic
-	canView := eventType.IsPublic() ||
-		(eventType.AutoPublic != nil && *eventType.AutoPublic) ||
-		(eventType.SenderCanView != nil && *eventType.SenderCanView) ||
-		(eventType.Topic1CanView != nil && *eventType.Topic1CanView && isAddress(l.Topics, 1, sender)) ||
-		(eventType.Topic2CanView != nil && *eventType.Topic2CanView && isAddress(l.Topics, 2, sender)) ||
-		(eventType.Topic3CanView != nil && *eventType.Topic3CanView && isAddress(l.Topics, 3, sender)) ||
-		(eventType.AutoVisibility && (isAddress(l.Topics, 1, sender) || isAddress(l.Topics, 2, sender) || isAddress(l.Topics, 3, sender)))
-	return canView, nil
-}
-
-func isAddress(topics []gethcommon.Hash, nr int, requester *gethcommon.Address) bool {
-	if len(topics) < nr+1 {
-		return false
-	}
-	addressFromTopic := gethcommon.BytesToAddress(topics[nr].Bytes())
-	return addressFromTopic == *requester
+	return eventType.IsVisibleToSender(l, sender), nil
 }
 
 // marshalReceipt marshals a transaction receipt into a JSON object.
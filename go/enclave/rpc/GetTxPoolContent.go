@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ten-protocol/go-ten/go/common/gethutil"
+)
+
+// TxPoolContent - the transactions of an account from the mempool, keyed by nonce. The format of "txpool_contentFrom"
+type TxPoolContent struct {
+	Pending map[string]*RpcTransaction `json:"pending"`
+	Queued  map[string]*RpcTransaction `json:"queued"`
+}
+
+func GetTxPoolContentValidate(reqParams []any, builder *CallBuilder[any, TxPoolContent], _ *EncryptionManager) error {
+	// there are no parameters. The transactions of the viewing key account are returned
+	if len(reqParams) != 0 {
+		builder.Err = fmt.Errorf("unexpected number of parameters")
+		return nil
+	}
+	builder.Param = new(any)
+	return nil
+}
+
+// GetTxPoolContentExecute - returns the pending and queued transactions from the mempool sent by the account of the viewing key.
+// Only available on the sequencer.
+func GetTxPoolContentExecute(builder *CallBuilder[any, TxPoolContent], rpc *EncryptionManager) error {
+	requester := *builder.VK.AccountAddress
+
+	pending, queued, err := rpc.mempool.ContentFrom(requester)
+	if err != nil {
+		builder.Err = err
+		return nil
+	}
+	toMap := func(txs []*types.Transaction) map[string]*RpcTransaction {
+		dump := make(map[string]*RpcTransaction, len(txs))
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCTransaction(tx, gethutil.EmptyHash, 0, 0, rpc.config.BaseFee, requester)
+		}
+		return dump
+	}
+
+	builder.ReturnValue = &TxPoolContent{
+		Pending: toMap(pending),
+		Queued:  toMap(queued),
+	}
+	return nil
+}
@@ -37,6 +37,12 @@ func TenStorageReadValidate(reqParams []any, builder *CallBuilder[storageReadWit
 		return nil
 	}
 
+	sl, ok := new(big.Int).SetString(slot, 0)
+	if !ok {
+		builder.Err = fmt.Errorf("unable to parse storage slot (%s)", slot)
+		return nil
+	}
+
 	contract, err := rpc.storage.ReadContract(builder.ctx, *address)
 	if err != nil {
 		builder.Err = fmt.Errorf("eth_getStorageAt is not supported for this contract")
@@ -44,7 +50,7 @@ func TenStorageReadValidate(reqParams []any, builder *CallBuilder[storageReadWit
 	}
 
 	// block the call for un-transparent contracts and non-whitelisted slots
-	if !rpc.storageSlotWhitelist.AllowedStorageSlots[slot] && !contract.IsTransparent() {
+	if !contract.IsTransparent() && !rpc.storageSlotWhitelist.IsStorageSlotAllowed(common.BigToHash(sl), contract.PublicStorageSlots) {
 		builder.Err = fmt.Errorf("eth_getStorageAt is not supported for this contract")
 		return nil
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/ten-protocol/go-ten/go/enclave/crypto"
+	"github.com/ten-protocol/go-ten/go/enclave/debugger"
 
 	"github.com/ten-protocol/go-ten/go/common/privacy"
 	enclaveconfig "github.com/ten-protocol/go-ten/go/enclave/config"
@@ -30,9 +31,10 @@ type EncryptionManager struct {
 	config               *enclaveconfig.EnclaveConfig
 	logger               gethlog.Logger
 	storageSlotWhitelist *privacy.Whitelist
+	debugger             *debugger.Debugger
 }
 
-func NewEncryptionManager(storage storage.Storage, cacheService *storage.CacheService, registry components.BatchRegistry, mempool *components.TxPool, processors *crosschain.Processors, config *enclaveconfig.EnclaveConfig, oracle gas.Oracle, blockResolver storage.BlockResolver, l1BlockProcessor components.L1BlockProcessor, chain components.TENChain, rpcKeyService *crypto.RPCKeyService, logger gethlog.Logger) *EncryptionManager {
+func NewEncryptionManager(storage storage.Storage, cacheService *storage.CacheService, registry components.BatchRegistry, mempool *components.TxPool, processors *crosschain.Processors, config *enclaveconfig.EnclaveConfig, oracle gas.Oracle, blockResolver storage.BlockResolver, l1BlockProcessor components.L1BlockProcessor, chain components.TENChain, rpcKeyService *crypto.RPCKeyService, debugger *debugger.Debugger, logger gethlog.Logger) *EncryptionManager {
 	return &EncryptionManager{
 		storage:              storage,
 		cacheService:         cacheService,
@@ -47,6 +49,7 @@ func NewEncryptionManager(storage storage.Storage, cacheService *storage.CacheSe
 		rpcKeyService:        rpcKeyService,
 		storageSlotWhitelist: privacy.NewWhitelist(),
 		mempool:              mempool,
+		debugger:             debugger,
 	}
 }
 
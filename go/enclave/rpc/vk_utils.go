@@ -101,8 +101,20 @@ func HandleEncryptedRPC(ctx context.Context,
 		return withVKEncryption(ctx, encManager, decodedRequest, vk, TenStorageReadValidate, TenStorageReadExecute)
 	case rpc.ERPCDebugLogs:
 		return withVKEncryption(ctx, encManager, decodedRequest, vk, DebugLogsValidate, DebugLogsExecute)
+	case rpc.ERPCDebugTraceTransaction:
+		return withVKEncryption(ctx, encManager, decodedRequest, vk, DebugTraceTransactionValidate, DebugTraceTransactionExecute)
+	case rpc.ERPCDebugTraceCall:
+		return withVKEncryption(ctx, encManager, decodedRequest, vk, DebugTraceCallValidate, DebugTraceCallExecute)
+	case rpc.ERPCDebugTraceBlockByNumber:
+		return withVKEncryption(ctx, encManager, decodedRequest, vk, DebugTraceBlockValidate, DebugTraceBlockExecute)
 	case rpc.ERPCGetPersonalTransactions:
 		return withVKEncryption(ctx, encManager, decodedRequest, vk, GetPersonalTransactionsValidate, GetPersonalTransactionsExecute)
+	case rpc.ERPCGetPendingTransactions:
+		return withVKEncryption(ctx, encManager, decodedRequest, vk, GetPendingTransactionsValidate, GetPendingTransactionsExecute)
+	case rpc.ERPCGetTxPoolContent:
+		return withVKEncryption(ctx, encManager, decodedRequest, vk, GetTxPoolContentValidate, GetTxPoolContentExecute)
+	case rpc.ERPCGetProof:
+		return withVKEncryption(ctx, encManager, decodedRequest, vk, GetProofValidate, GetProofExecute)
 	default:
 		return nil, fmt.Errorf("unsupported method %s", decodedRequest.Method)
 	}
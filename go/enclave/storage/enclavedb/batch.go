@@ -143,6 +143,12 @@ func WriteReceipt(ctx context.Context, dbtx *sqlx.Tx, batchSeqNo uint64, txId *u
 	return uint64(id), nil
 }
 
+// WriteRevealSchedule - records when the receipt becomes public, so the transactions revealed by a batch are found without scanning the chain.
+func WriteRevealSchedule(ctx context.Context, dbtx *sqlx.Tx, receiptId uint64, revealHeight *uint64, revealTime *uint64) error {
+	_, err := dbtx.ExecContext(ctx, "insert into reveal_schedule (receipt, reveal_height, reveal_time) values (?,?,?)", receiptId, revealHeight, revealTime)
+	return err
+}
+
 func ReadTransactionIdAndSender(ctx context.Context, dbtx *sqlx.Tx, txHash gethcommon.Hash) (*uint64, *uint64, error) {
 	var txId uint64
 	var senderId uint64
@@ -258,8 +264,8 @@ func fetchBatches(ctx context.Context, db *sqlx.DB, whereQuery string, args ...a
 	return result, nil
 }
 
-func ReadReceipt(ctx context.Context, stmtCache *PreparedStatementCache, txHash common.L2TxHash, requester *gethcommon.Address) (*core.InternalReceipt, error) {
-	rec, _, err := loadReceiptsAndEventLogs(ctx, stmtCache, requester, " AND curr_tx.hash=?", []any{txHash.Bytes()}, true)
+func ReadReceipt(ctx context.Context, stmtCache *PreparedStatementCache, txHash common.L2TxHash, requester *gethcommon.Address, reveal *RevealPoint) (*core.InternalReceipt, error) {
+	rec, _, err := loadReceiptsAndEventLogs(ctx, stmtCache, requester, reveal, " AND curr_tx.hash=?", []any{txHash.Bytes()}, true)
 	if err != nil {
 		return nil, err
 	}
@@ -267,6 +273,32 @@ func ReadReceipt(ctx context.Context, stmtCache *PreparedStatementCache, txHash
 	return rec[0], nil
 }
 
+// ReadRevealedTransactions - returns the transactions which become public with the batch at the reveal point.
+// These are the canonical transactions scheduled to be revealed at this height, or at a time after the timestamp of
+// the parent batch which is reached by this batch.
+func ReadRevealedTransactions(ctx context.Context, db *sqlx.DB, reveal RevealPoint, parentTimestamp uint64) ([]common.L2TxHash, error) {
+	query := "select distinct curr_tx.hash from reveal_schedule r " +
+		" join receipt rec on r.receipt=rec.id " +
+		" join batch b on rec.batch=b.sequence " +
+		" join tx curr_tx on rec.tx=curr_tx.id " +
+		" where b.is_canonical=true AND b.height <= ? AND (r.reveal_height = ? OR (r.reveal_time > ? AND r.reveal_time <= ?))"
+	rows, err := db.QueryContext(ctx, query, reveal.Height, reveal.Height, parentTimestamp, reveal.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txHashes []common.L2TxHash
+	for rows.Next() {
+		var txHash gethcommon.Hash
+		if err := rows.Scan(&txHash); err != nil {
+			return nil, err
+		}
+		txHashes = append(txHashes, txHash)
+	}
+	return txHashes, rows.Err()
+}
+
 func ExistsReceipt(ctx context.Context, db *sqlx.DB, txHash common.L2TxHash) (bool, error) {
 	query := "select count(1) from receipt rec join tx curr_tx on rec.tx=curr_tx.id where curr_tx.hash=?"
 	row := db.QueryRowContext(ctx, query, txHash.Bytes())
@@ -14,6 +14,7 @@ import (
 
 const (
 	cfgInsert = "insert into config values (?,?)"
+	cfgUpdate = "update config set val=? where ky=?"
 	cfgSelect = "select val from config where ky=?"
 )
 
@@ -28,6 +29,23 @@ func WriteConfigToTx(ctx context.Context, dbtx *sqlx.Tx, key string, value any)
 	return dbtx.ExecContext(ctx, cfgInsert, key, value)
 }
 
+// UpsertConfigToTx - updates the value of the key, or inserts it if it doesn't exist yet
+func UpsertConfigToTx(ctx context.Context, dbtx *sqlx.Tx, key string, value any) error {
+	res, err := dbtx.ExecContext(ctx, cfgUpdate, value, key)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated > 0 {
+		return nil
+	}
+	_, err = WriteConfigToTx(ctx, dbtx, key, value)
+	return err
+}
+
 func WriteConfig(ctx context.Context, db *sqlx.Tx, key string, value []byte) (sql.Result, error) {
 	return db.ExecContext(ctx, cfgInsert, key, value)
 }
@@ -40,8 +40,12 @@ const (
 )
 
 func WriteEventType(ctx context.Context, dbTX *sqlx.Tx, et *EventType) (uint64, error) {
-	res, err := dbTX.ExecContext(ctx, "insert into event_type (contract, event_sig, auto_visibility,auto_public, config_public, topic1_can_view, topic2_can_view, topic3_can_view, sender_can_view) values (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		et.Contract.Id, et.EventSignature.Bytes(), et.AutoVisibility, et.AutoPublic, et.ConfigPublic, et.Topic1CanView, et.Topic2CanView, et.Topic3CanView, et.SenderCanView)
+	var dataFields []byte
+	if len(et.DataFieldsCanView) > 0 {
+		dataFields = et.DataFieldsCanView
+	}
+	res, err := dbTX.ExecContext(ctx, "insert into event_type (contract, event_sig, auto_visibility,auto_public, config_public, topic1_can_view, topic2_can_view, topic3_can_view, sender_can_view, data_fields_can_view) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		et.Contract.Id, et.EventSignature.Bytes(), et.AutoVisibility, et.AutoPublic, et.ConfigPublic, et.Topic1CanView, et.Topic2CanView, et.Topic3CanView, et.SenderCanView, dataFields)
 	if err != nil {
 		return 0, err
 	}
@@ -55,9 +59,9 @@ func WriteEventType(ctx context.Context, dbTX *sqlx.Tx, et *EventType) (uint64,
 func ReadEventType(ctx context.Context, dbTX *sqlx.Tx, contract *Contract, eventSignature gethcommon.Hash) (*EventType, error) {
 	et := EventType{Contract: contract}
 	err := dbTX.QueryRowContext(ctx,
-		"select id, event_sig, auto_visibility, auto_public, config_public, topic1_can_view, topic2_can_view, topic3_can_view, sender_can_view from event_type where contract=? and event_sig=?",
+		"select id, event_sig, auto_visibility, auto_public, config_public, topic1_can_view, topic2_can_view, topic3_can_view, sender_can_view, data_fields_can_view from event_type where contract=? and event_sig=?",
 		contract.Id, eventSignature.Bytes(),
-	).Scan(&et.Id, &et.EventSignature, &et.AutoVisibility, &et.AutoPublic, &et.ConfigPublic, &et.Topic1CanView, &et.Topic2CanView, &et.Topic3CanView, &et.SenderCanView)
+	).Scan(&et.Id, &et.EventSignature, &et.AutoVisibility, &et.AutoPublic, &et.ConfigPublic, &et.Topic1CanView, &et.Topic2CanView, &et.Topic3CanView, &et.SenderCanView, &et.DataFieldsCanView)
 	if errors.Is(err, sql.ErrNoRows) {
 		// make sure the error is converted to obscuro-wide not found error
 		return nil, errutil.ErrNotFound
@@ -105,13 +109,32 @@ func ReadRelevantAddressFromEventTopic(ctx context.Context, dbTX *sqlx.Tx, id ui
 	return address, err
 }
 
-func WriteEventLog(ctx context.Context, dbTX *sqlx.Tx, eventTypeId uint64, userTopics []*uint64, data []byte, logIdx uint, execTx uint64) error {
-	_, err := dbTX.ExecContext(ctx, "insert into event_log (event_type, topic1, topic2, topic3, datablob, log_idx, receipt) values (?,?,?,?,?,?,?)",
+func WriteEventLog(ctx context.Context, dbTX *sqlx.Tx, eventTypeId uint64, userTopics []*uint64, data []byte, logIdx uint, execTx uint64) (uint64, error) {
+	res, err := dbTX.ExecContext(ctx, "insert into event_log (event_type, topic1, topic2, topic3, datablob, log_idx, receipt) values (?,?,?,?,?,?,?)",
 		eventTypeId, userTopics[0], userTopics[1], userTopics[2], data, logIdx, execTx)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), nil
+}
+
+// WriteEventLogDataAddress - links the event log to an account found in one of the data fields configured to be relevant
+func WriteEventLogDataAddress(ctx context.Context, dbTX *sqlx.Tx, eventLogId uint64, addressId uint64) error {
+	_, err := dbTX.ExecContext(ctx, "insert into event_log_data_address (event_log, rel_address) values (?,?)", eventLogId, addressId)
 	return err
 }
 
-func FilterLogs(ctx context.Context, stmtCache *PreparedStatementCache, requestingAccount *gethcommon.Address, fromBlock, toBlock *big.Int, batchHash *common.L2BatchHash, addresses []gethcommon.Address, topics [][]gethcommon.Hash) ([]*types.Log, error) {
+// RevealPoint - the head of the chain. Used to decide which events and transactions of the contracts with delayed disclosure are public.
+type RevealPoint struct {
+	Height    uint64
+	Timestamp uint64
+}
+
+func FilterLogs(ctx context.Context, stmtCache *PreparedStatementCache, requestingAccount *gethcommon.Address, reveal *RevealPoint, fromBlock, toBlock *big.Int, batchHash *common.L2BatchHash, addresses []gethcommon.Address, topics [][]gethcommon.Hash) ([]*types.Log, error) {
 	queryParams := []any{}
 	query := ""
 
@@ -154,7 +177,7 @@ func FilterLogs(ctx context.Context, stmtCache *PreparedStatementCache, requesti
 		}
 	}
 
-	_, logs, err := loadReceiptsAndEventLogs(ctx, stmtCache, requestingAccount, query, queryParams, false)
+	_, logs, err := loadReceiptsAndEventLogs(ctx, stmtCache, requestingAccount, reveal, query, queryParams, false)
 	return logs, err
 }
 
@@ -307,7 +330,7 @@ func onRowWithReceipt(rows *sql.Rows) (*core.InternalReceipt, error) {
 // returns either receipts with logs, or only logs
 // this complexity is necessary to avoid executing multiple queries.
 // todo always pass in the actual batch hashes because of reorgs, or make sure to clean up log entries from discarded batches
-func loadReceiptsAndEventLogs(ctx context.Context, stmtCache *PreparedStatementCache, requestingAccount *gethcommon.Address, whereCondition string, whereParams []any, withReceipts bool) ([]*core.InternalReceipt, []*types.Log, error) {
+func loadReceiptsAndEventLogs(ctx context.Context, stmtCache *PreparedStatementCache, requestingAccount *gethcommon.Address, reveal *RevealPoint, whereCondition string, whereParams []any, withReceipts bool) ([]*core.InternalReceipt, []*types.Log, error) {
 	logsQuery := " et.event_sig, t1.topic, t2.topic, t3.topic, datablob, log_idx, b.hash, b.height, curr_tx.hash, curr_tx.idx, c.address "
 	receiptQuery := " rec.post_state, rec.status, rec.gas_used, rec.effective_gas_price, rec.created_contract_address, tx_sender.address, tx_contr.address, curr_tx.type "
 
@@ -322,13 +345,13 @@ func loadReceiptsAndEventLogs(ctx context.Context, stmtCache *PreparedStatementC
 
 	if requestingAccount != nil {
 		// Add log visibility rules
-		logsVisibQuery, logsVisibParams := logsVisibilityQuery(requestingAccount, withReceipts)
+		logsVisibQuery, logsVisibParams := logsVisibilityQuery(requestingAccount, reveal, withReceipts)
 		query += logsVisibQuery
 		queryParams = append(queryParams, logsVisibParams...)
 
 		// add receipt visibility rules
 		if withReceipts {
-			receiptsVisibQuery, receiptsVisibParams := receiptsVisibilityQuery(requestingAccount)
+			receiptsVisibQuery, receiptsVisibParams := receiptsVisibilityQuery(requestingAccount, reveal)
 			query += receiptsVisibQuery
 			queryParams = append(queryParams, receiptsVisibParams...)
 		}
@@ -343,8 +366,9 @@ func loadReceiptsAndEventLogs(ctx context.Context, stmtCache *PreparedStatementC
 		query += " select null, null, null, null, null, null, b.hash, b.height, curr_tx.hash, curr_tx.idx, null, " + receiptQuery
 		query += baseReceiptJoin
 		query += " where b.is_canonical=true "
-		query += " AND tx_sender.address = ? "
+		query += " AND (tx_sender.address = ? " + revealedQuery("tx_contr", reveal) + ")"
 		queryParams = append(queryParams, requestingAccount.Bytes())
+		queryParams = append(queryParams, revealedParams(reveal)...)
 		query += whereCondition
 		queryParams = append(queryParams, whereParams...)
 	}
@@ -458,18 +482,38 @@ func onRowWithEventLogAndReceipt(rows *sql.Rows, withReceipts bool) (*core.Inter
 	return nil, &l, nil
 }
 
-func receiptsVisibilityQuery(requestingAccount *gethcommon.Address) (string, []any) {
+func receiptsVisibilityQuery(requestingAccount *gethcommon.Address, reveal *RevealPoint) (string, []any) {
 	// the visibility rules for the receipt:
 	// - the sender can query
 	// - anyone can query if the contract is transparent
+	// - anyone can query if the data of the contract was revealed
 	// - anyone who can view an event log should also be able to view the receipt
-	query := " AND ( (e.id IS NOT NULL) OR (tx_sender.address = ?) OR (tx_contr.transparent=true) )"
+	query := " AND ( (e.id IS NOT NULL) OR (tx_sender.address = ?) OR (tx_contr.transparent=true) " + revealedQuery("tx_contr", reveal) + ")"
 	queryParams := []any{requestingAccount.Bytes()}
+	queryParams = append(queryParams, revealedParams(reveal)...)
 	return query, queryParams
 }
 
+// revealedQuery - the condition for the data of the contract with the given alias to be revealed at the reveal point.
+// The contracts with delayed disclosure reveal the data of a batch after the configured number of batches,
+// and all their data once the head batch reaches the configured timestamp.
+func revealedQuery(contractAlias string, reveal *RevealPoint) string {
+	if reveal == nil {
+		return ""
+	}
+	return " OR (" + contractAlias + ".reveal_after_batches IS NOT NULL AND b.height + " + contractAlias + ".reveal_after_batches <= ?) " +
+		" OR (" + contractAlias + ".reveal_after_time IS NOT NULL AND " + contractAlias + ".reveal_after_time <= ?) "
+}
+
+func revealedParams(reveal *RevealPoint) []any {
+	if reveal == nil {
+		return nil
+	}
+	return []any{reveal.Height, reveal.Timestamp}
+}
+
 // this function encodes the event log visibility rules
-func logsVisibilityQuery(requestingAccount *gethcommon.Address, withReceipts bool) (string, []any) {
+func logsVisibilityQuery(requestingAccount *gethcommon.Address, reveal *RevealPoint, withReceipts bool) (string, []any) {
 	acc := requestingAccount.Bytes()
 
 	visibParams := make([]any, 0)
@@ -492,7 +536,7 @@ func logsVisibilityQuery(requestingAccount *gethcommon.Address, withReceipts boo
 	visibParams = append(visibParams, acc)
 	visibParams = append(visibParams, acc)
 
-	// Configured events that are not public specify explicitly which event topics are addresses empowered to view that event
+	// Configured events that are not public specify explicitly which event topics and data fields are addresses empowered to view that event
 	visibQuery += " OR (" +
 		"et.auto_visibility=false AND et.config_public=false AND " +
 		"  (" +
@@ -500,12 +544,21 @@ func logsVisibilityQuery(requestingAccount *gethcommon.Address, withReceipts boo
 		"    OR (et.topic2_can_view AND eoa2.address=?) " +
 		"    OR (et.topic3_can_view AND eoa3.address=?)" +
 		"    OR (et.sender_can_view AND tx_sender.address=?)" +
+		"    OR (et.data_fields_can_view IS NOT NULL AND EXISTS (" +
+		"         select 1 from event_log_data_address ed join externally_owned_account eoad on ed.rel_address=eoad.id " +
+		"         where ed.event_log=e.id AND eoad.address=?" +
+		"    ))" +
 		"  )" +
 		")"
 	visibParams = append(visibParams, acc)
 	visibParams = append(visibParams, acc)
 	visibParams = append(visibParams, acc)
 	visibParams = append(visibParams, acc)
+	visibParams = append(visibParams, acc)
+
+	// the events of contracts with delayed disclosure become public
+	visibQuery += revealedQuery("c", reveal)
+	visibParams = append(visibParams, revealedParams(reveal)...)
 
 	visibQuery += ") "
 	return visibQuery, visibParams
@@ -541,8 +594,8 @@ func ReadEoa(ctx context.Context, dbTx *sqlx.Tx, addr gethcommon.Address) (uint6
 }
 
 func WriteContractConfig(ctx context.Context, dbTX *sqlx.Tx, contractAddress gethcommon.Address, eoaId uint64, cfg *core.ContractVisibilityConfig, txId uint64) (*uint64, error) {
-	insert := "insert into contract (address, creator, auto_visibility, transparent, tx) values (?,?,?,?,?)"
-	res, err := dbTX.ExecContext(ctx, insert, contractAddress.Bytes(), eoaId, cfg.AutoConfig, cfg.Transparent, txId)
+	insert := "insert into contract (address, creator, auto_visibility, transparent, public_slots, reveal_after_batches, reveal_after_time, tx) values (?,?,?,?,?,?,?,?)"
+	res, err := dbTX.ExecContext(ctx, insert, contractAddress.Bytes(), eoaId, cfg.AutoConfig, cfg.Transparent, hashesToBytes(cfg.PublicStorageSlots), cfg.RevealAfterBatches, cfg.RevealAfterTimestamp, txId)
 	if err != nil {
 		return nil, err
 	}
@@ -555,10 +608,11 @@ func WriteContractConfig(ctx context.Context, dbTX *sqlx.Tx, contractAddress get
 }
 
 func ReadContractByAddress(ctx context.Context, dbTx *sqlx.Tx, addr gethcommon.Address) (*Contract, error) {
-	row := dbTx.QueryRowContext(ctx, "select c.id, c.address, c.auto_visibility, c.transparent, eoa.address from contract c join externally_owned_account eoa on c.creator=eoa.id where c.address = ?", addr.Bytes())
+	row := dbTx.QueryRowContext(ctx, "select c.id, c.address, c.auto_visibility, c.transparent, c.public_slots, c.reveal_after_batches, c.reveal_after_time, eoa.address from contract c join externally_owned_account eoa on c.creator=eoa.id where c.address = ?", addr.Bytes())
 
 	var c Contract
-	err := row.Scan(&c.Id, &c.Address, &c.AutoVisibility, &c.Transparent, &c.Creator)
+	var publicSlots []byte
+	err := row.Scan(&c.Id, &c.Address, &c.AutoVisibility, &c.Transparent, &publicSlots, &c.RevealAfterBatches, &c.RevealAfterTimestamp, &c.Creator)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// make sure the error is converted to obscuro-wide not found error
@@ -566,10 +620,31 @@ func ReadContractByAddress(ctx context.Context, dbTx *sqlx.Tx, addr gethcommon.A
 		}
 		return nil, err
 	}
+	c.PublicStorageSlots = bytesToHashes(publicSlots)
 
 	return &c, nil
 }
 
+// hashesToBytes - concatenates the hashes. Returns nil when there are no hashes
+func hashesToBytes(hashes []gethcommon.Hash) []byte {
+	if len(hashes) == 0 {
+		return nil
+	}
+	result := make([]byte, 0, len(hashes)*gethcommon.HashLength)
+	for _, h := range hashes {
+		result = append(result, h.Bytes()...)
+	}
+	return result
+}
+
+func bytesToHashes(b []byte) []gethcommon.Hash {
+	hashes := make([]gethcommon.Hash, 0, len(b)/gethcommon.HashLength)
+	for i := 0; i+gethcommon.HashLength <= len(b); i += gethcommon.HashLength {
+		hashes = append(hashes, gethcommon.BytesToHash(b[i:i+gethcommon.HashLength]))
+	}
+	return hashes
+}
+
 func byteArrayToHash(b []byte) gethcommon.Hash {
 	result := gethcommon.Hash{}
 	result.SetBytes(b)
@@ -0,0 +1,225 @@
+package enclavedb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	enclaveconfig "github.com/ten-protocol/go-ten/go/enclave/config"
+	"github.com/ten-protocol/go-ten/go/enclave/core"
+	"github.com/ten-protocol/go-ten/go/enclave/storage/enclavedb"
+	"github.com/ten-protocol/go-ten/go/enclave/storage/init/sqlite"
+)
+
+var (
+	txSender     = gethcommon.HexToAddress("0x1000000000000000000000000000000000000001")
+	counterparty = gethcommon.HexToAddress("0x2000000000000000000000000000000000000002")
+	otherAccount = gethcommon.HexToAddress("0x3000000000000000000000000000000000000003")
+	contractAddr = gethcommon.HexToAddress("0x4000000000000000000000000000000000000004")
+	eventSig     = gethcommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	testTxHash   = gethcommon.HexToHash("0x02")
+)
+
+// the data of an event with the non-indexed parameters (uint256 amount, address counterparty)
+func eventData(amount int64, addr gethcommon.Address) []byte {
+	data := gethcommon.BigToHash(gethcommon.Big1.SetInt64(amount)).Bytes()
+	return append(data, gethcommon.BytesToHash(addr.Bytes()).Bytes()...)
+}
+
+func privateEventType(dataFields ...uint8) enclavedb.EventType {
+	f := false
+	transparent := false
+	return enclavedb.EventType{
+		Contract:          &enclavedb.Contract{Transparent: &transparent},
+		EventSignature:    eventSig,
+		Topic1CanView:     &f,
+		Topic2CanView:     &f,
+		Topic3CanView:     &f,
+		SenderCanView:     &f,
+		DataFieldsCanView: dataFields,
+	}
+}
+
+func TestDataFieldAddresses(t *testing.T) {
+	data := eventData(100, counterparty)
+
+	et := privateEventType(1)
+	require.Equal(t, []gethcommon.Address{counterparty}, et.DataFieldAddresses(data))
+
+	// the amount is not an address, and the position 2 is out of bounds
+	et = privateEventType(0, 2)
+	require.Empty(t, et.DataFieldAddresses(data))
+
+	// the positions are word offsets: the offset of a dynamic parameter takes one word and is not an address
+	// (string memo, address counterparty)
+	data = gethcommon.BigToHash(gethcommon.Big1.SetInt64(64)).Bytes()
+	data = append(data, gethcommon.BytesToHash(counterparty.Bytes()).Bytes()...)
+	data = append(data, gethcommon.BigToHash(gethcommon.Big1.SetInt64(4)).Bytes()...)
+	data = append(data, gethcommon.RightPadBytes([]byte("memo"), gethcommon.HashLength)...)
+	et = privateEventType(0, 1, 2, 3)
+	require.Equal(t, []gethcommon.Address{counterparty}, et.DataFieldAddresses(data))
+
+	// the data fields are ignored for event types without an explicit private configuration
+	et = privateEventType(1)
+	et.AutoVisibility = true
+	require.Empty(t, et.DataFieldAddresses(data))
+}
+
+func TestIsVisibleToSenderWithDataFields(t *testing.T) {
+	l := &types.Log{Topics: []gethcommon.Hash{eventSig}, Data: eventData(100, counterparty)}
+
+	et := privateEventType(1)
+	require.True(t, et.IsVisibleToSender(l, &counterparty))
+	require.False(t, et.IsVisibleToSender(l, &otherAccount))
+
+	et = privateEventType()
+	require.False(t, et.IsVisibleToSender(l, &counterparty))
+}
+
+func TestFilterLogsWithDataFieldVisibility(t *testing.T) {
+	transparent := false
+	db, stmtCache := createEventLogDB(t, &core.ContractVisibilityConfig{Transparent: &transparent})
+
+	for requester, expectedLogs := range map[gethcommon.Address]int{
+		counterparty: 1,
+		otherAccount: 0,
+		// the sender was not configured to view the event
+		txSender: 0,
+	} {
+		logs, err := enclavedb.FilterLogs(context.Background(), stmtCache, &requester, nil, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, logs, expectedLogs, "requester %s", requester)
+	}
+
+	et, err := readEventType(db)
+	require.NoError(t, err)
+	require.Equal(t, []uint8{1}, et.DataFieldsCanView)
+}
+
+func TestDelayedDisclosureAfterBatches(t *testing.T) {
+	ctx := context.Background()
+	transparent := false
+	revealAfter := uint64(10)
+	db, stmtCache := createEventLogDB(t, &core.ContractVisibilityConfig{Transparent: &transparent, RevealAfterBatches: &revealAfter})
+
+	// the log and the receipt of the batch 1 are revealed by the batch 11
+	requireRevealed(t, stmtCache, &enclavedb.RevealPoint{Height: 10}, false)
+	requireRevealed(t, stmtCache, &enclavedb.RevealPoint{Height: 11}, true)
+
+	revealed, err := enclavedb.ReadRevealedTransactions(ctx, db.GetSQLDB(), enclavedb.RevealPoint{Height: 11}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []gethcommon.Hash{testTxHash}, revealed)
+	revealed, err = enclavedb.ReadRevealedTransactions(ctx, db.GetSQLDB(), enclavedb.RevealPoint{Height: 12}, 0)
+	require.NoError(t, err)
+	require.Empty(t, revealed)
+}
+
+func TestDelayedDisclosureAfterTimestamp(t *testing.T) {
+	ctx := context.Background()
+	transparent := false
+	revealTime := uint64(1000)
+	db, stmtCache := createEventLogDB(t, &core.ContractVisibilityConfig{Transparent: &transparent, RevealAfterTimestamp: &revealTime})
+
+	requireRevealed(t, stmtCache, &enclavedb.RevealPoint{Height: 5, Timestamp: 999}, false)
+	requireRevealed(t, stmtCache, &enclavedb.RevealPoint{Height: 5, Timestamp: 1000}, true)
+
+	// the transactions are revealed by the first batch which reaches the timestamp
+	revealed, err := enclavedb.ReadRevealedTransactions(ctx, db.GetSQLDB(), enclavedb.RevealPoint{Height: 5, Timestamp: 1000}, 990)
+	require.NoError(t, err)
+	require.Equal(t, []gethcommon.Hash{testTxHash}, revealed)
+	revealed, err = enclavedb.ReadRevealedTransactions(ctx, db.GetSQLDB(), enclavedb.RevealPoint{Height: 6, Timestamp: 1010}, 1000)
+	require.NoError(t, err)
+	require.Empty(t, revealed)
+}
+
+// requireRevealed - checks whether the log and the receipt are visible to an account which is not configured to see them
+func requireRevealed(t *testing.T, stmtCache *enclavedb.PreparedStatementCache, reveal *enclavedb.RevealPoint, expected bool) {
+	logs, err := enclavedb.FilterLogs(context.Background(), stmtCache, &otherAccount, reveal, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	receipt, err := enclavedb.ReadReceipt(context.Background(), stmtCache, testTxHash, &otherAccount, reveal)
+	if expected {
+		require.Len(t, logs, 1)
+		require.NoError(t, err)
+		require.Len(t, receipt.Logs, 1)
+	} else {
+		require.Empty(t, logs)
+		require.ErrorIs(t, err, errutil.ErrNotFound)
+	}
+}
+
+// createEventLogDB - stores a transaction in the batch 1, which emitted an event with the counterparty in the data field 1
+func createEventLogDB(t *testing.T, contractCfg *core.ContractVisibilityConfig) (enclavedb.EnclaveDB, *enclavedb.PreparedStatementCache) {
+	ctx := context.Background()
+	logger := gethlog.New()
+	db, err := sqlite.CreateTemporarySQLiteDB("", "", &enclaveconfig.EnclaveConfig{RPCTimeout: time.Second}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	dbTX, err := db.NewDBTransaction(ctx)
+	require.NoError(t, err)
+	defer dbTX.Rollback()
+	senderId, err := enclavedb.WriteEoa(ctx, dbTX, txSender)
+	require.NoError(t, err)
+	counterpartyId, err := enclavedb.WriteEoa(ctx, dbTX, counterparty)
+	require.NoError(t, err)
+	_, err = enclavedb.WriteEoa(ctx, dbTX, otherAccount)
+	require.NoError(t, err)
+
+	_, err = dbTX.ExecContext(ctx, "insert into batch (sequence, converted_hash, hash, height, is_canonical, header, l1_proof_hash, is_executed) values (1, ?, ?, 1, true, ?, ?, true)",
+		gethcommon.Hash{}.Bytes(), gethcommon.HexToHash("0x01").Bytes(), []byte{}, gethcommon.Hash{}.Bytes())
+	require.NoError(t, err)
+	res, err := dbTX.ExecContext(ctx, "insert into tx (hash, content, type, sender_address, idx, batch_height, is_synthetic) values (?, ?, 0, ?, 0, 1, false)",
+		testTxHash.Bytes(), []byte{}, senderId)
+	require.NoError(t, err)
+	txId, err := res.LastInsertId()
+	require.NoError(t, err)
+	res, err = dbTX.ExecContext(ctx, "insert into receipt (status, gas_used, tx, batch) values (1, 0, ?, 1)", txId)
+	require.NoError(t, err)
+	receiptId, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	_, err = enclavedb.WriteContractConfig(ctx, dbTX, contractAddr, senderId, contractCfg, uint64(txId))
+	require.NoError(t, err)
+	contract, err := enclavedb.ReadContractByAddress(ctx, dbTX, contractAddr)
+	require.NoError(t, err)
+
+	et := privateEventType(1)
+	et.Contract = contract
+	et.Id, err = enclavedb.WriteEventType(ctx, dbTX, &et)
+	require.NoError(t, err)
+
+	eventLogId, err := enclavedb.WriteEventLog(ctx, dbTX, et.Id, make([]*uint64, 3), eventData(100, counterparty), 0, uint64(receiptId))
+	require.NoError(t, err)
+	require.NoError(t, enclavedb.WriteEventLogDataAddress(ctx, dbTX, eventLogId, counterpartyId))
+
+	// the reveal schedule of the transaction in the batch 1, which has the timestamp 0
+	if contract.HasDelayedDisclosure() {
+		var revealHeight *uint64
+		if contract.RevealAfterBatches != nil {
+			h := 1 + *contract.RevealAfterBatches
+			revealHeight = &h
+		}
+		require.NoError(t, enclavedb.WriteRevealSchedule(ctx, dbTX, uint64(receiptId), revealHeight, contract.RevealAfterTimestamp))
+	}
+	require.NoError(t, dbTX.Commit())
+
+	return db, enclavedb.NewStatementCache(db.GetSQLDB(), logger)
+}
+
+func readEventType(db enclavedb.EnclaveDB) (*enclavedb.EventType, error) {
+	dbTX, err := db.NewDBTransaction(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer dbTX.Rollback()
+	contract, err := enclavedb.ReadContractByAddress(context.Background(), dbTX, contractAddr)
+	if err != nil {
+		return nil, err
+	}
+	return enclavedb.ReadEventType(context.Background(), dbTX, contract, eventSig)
+}
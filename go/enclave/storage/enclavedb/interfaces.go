@@ -3,10 +3,13 @@ package enclavedb
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/jmoiron/sqlx"
 
 	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ten-protocol/go-ten/go/common"
 
 	"github.com/ethereum/go-ethereum/ethdb"
 )
@@ -23,17 +26,25 @@ type EnclaveDB interface {
 
 // Contract - maps to the “contract“ table
 type Contract struct {
-	Id             uint64
-	Address        gethcommon.Address
-	Creator        gethcommon.Address
-	AutoVisibility bool
-	Transparent    *bool
+	Id                   uint64
+	Address              gethcommon.Address
+	Creator              gethcommon.Address
+	AutoVisibility       bool
+	Transparent          *bool
+	PublicStorageSlots   []gethcommon.Hash
+	RevealAfterBatches   *uint64
+	RevealAfterTimestamp *uint64
 }
 
 func (contract Contract) IsTransparent() bool {
 	return contract.Transparent != nil && *contract.Transparent
 }
 
+// HasDelayedDisclosure - returns true if the events and transactions of the contract become public at some point
+func (contract Contract) HasDelayedDisclosure() bool {
+	return contract.RevealAfterBatches != nil || contract.RevealAfterTimestamp != nil
+}
+
 // EventType - maps to the “event_type“ table
 type EventType struct {
 	Id                                          uint64
@@ -44,6 +55,7 @@ type EventType struct {
 	ConfigPublic                                bool
 	Topic1CanView, Topic2CanView, Topic3CanView *bool
 	SenderCanView                               *bool
+	DataFieldsCanView                           []uint8 // offsets of the 32 bytes words of the log data containing relevant addresses
 }
 
 func (et EventType) IsPublic() bool {
@@ -63,6 +75,49 @@ func (et EventType) IsTopicRelevant(topicNo int) bool {
 	panic(fmt.Sprintf("unknown topic no: %d", topicNo))
 }
 
+// IsVisibleToSender - returns true if the log can be viewed by the sender of the transaction which emitted it
+func (et EventType) IsVisibleToSender(l *types.Log, sender *gethcommon.Address) bool {
+	return et.IsPublic() ||
+		(et.AutoPublic != nil && *et.AutoPublic) ||
+		(et.SenderCanView != nil && *et.SenderCanView) ||
+		(et.IsTopicRelevant(1) && isTopicAddress(l.Topics, 1, sender)) ||
+		(et.IsTopicRelevant(2) && isTopicAddress(l.Topics, 2, sender)) ||
+		(et.IsTopicRelevant(3) && isTopicAddress(l.Topics, 3, sender)) ||
+		(et.AutoVisibility && (isTopicAddress(l.Topics, 1, sender) || isTopicAddress(l.Topics, 2, sender) || isTopicAddress(l.Topics, 3, sender))) ||
+		slices.Contains(et.DataFieldAddresses(l.Data), *sender)
+}
+
+// DataFieldAddresses - returns the addresses found in the data fields configured to be relevant.
+// The event ABI is not known by the enclave, so the positions are the offsets of 32 bytes words in the ABI encoded data,
+// not the positions of the parameters. In the head of the encoding, each value type (like an address) and each dynamic
+// type (which is encoded as the offset of its content) takes one word, while static arrays and structs take one word per element.
+// A word is only returned if it is the canonical encoding of an address, so the offsets, lengths and other values are skipped.
+func (et EventType) DataFieldAddresses(data []byte) []gethcommon.Address {
+	if et.IsPublic() || et.AutoVisibility {
+		return nil
+	}
+	var addresses []gethcommon.Address
+	for _, pos := range et.DataFieldsCanView {
+		start := int(pos) * gethcommon.HashLength
+		if start+gethcommon.HashLength > len(data) {
+			continue
+		}
+		addr := common.ExtractPotentialAddress(gethcommon.BytesToHash(data[start : start+gethcommon.HashLength]))
+		if addr != nil {
+			addresses = append(addresses, *addr)
+		}
+	}
+	return addresses
+}
+
+func isTopicAddress(topics []gethcommon.Hash, nr int, requester *gethcommon.Address) bool {
+	if len(topics) < nr+1 {
+		return false
+	}
+	addressFromTopic := gethcommon.BytesToAddress(topics[nr].Bytes())
+	return addressFromTopic == *requester
+}
+
 // EventTopic - maps to the "event_topic" table
 type EventTopic struct {
 	Id                uint64
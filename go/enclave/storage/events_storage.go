@@ -54,9 +54,59 @@ func (es *eventsStorage) storeReceiptAndEventLogs(ctx context.Context, dbTX *sql
 		}
 	}
 
+	err = es.storeRevealSchedule(ctx, dbTX, batch, txExecResult, receiptId)
+	if err != nil {
+		return fmt.Errorf("could not store the reveal schedule. Cause: %w", err)
+	}
+
 	return nil
 }
 
+// storeRevealSchedule - records when the transaction becomes public, if it calls or emits events from contracts with delayed disclosure.
+// The transaction is revealed by the first contract which reveals it. When the reveal time was already reached, it is revealed with this batch.
+func (es *eventsStorage) storeRevealSchedule(ctx context.Context, dbTX *sqlx.Tx, batch *common.BatchHeader, txExecResult *core.TxExecResult, receiptId uint64) error {
+	addresses := make([]gethcommon.Address, 0, len(txExecResult.Receipt.Logs)+1)
+	if txExecResult.TxWithSender != nil && txExecResult.TxWithSender.Tx.To() != nil {
+		addresses = append(addresses, *txExecResult.TxWithSender.Tx.To())
+	}
+	for _, l := range txExecResult.Receipt.Logs {
+		addresses = append(addresses, l.Address)
+	}
+
+	var revealHeight, revealTime *uint64
+	for _, addr := range addresses {
+		contract, err := es.readContract(ctx, dbTX, addr)
+		if errors.Is(err, errutil.ErrNotFound) {
+			// the transaction was sent to an account
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if contract.RevealAfterBatches != nil {
+			revealHeight = minReveal(revealHeight, batch.Number.Uint64()+*contract.RevealAfterBatches)
+		}
+		if contract.RevealAfterTimestamp != nil {
+			if *contract.RevealAfterTimestamp <= batch.Time {
+				revealHeight = minReveal(revealHeight, batch.Number.Uint64())
+			} else {
+				revealTime = minReveal(revealTime, *contract.RevealAfterTimestamp)
+			}
+		}
+	}
+	if revealHeight == nil && revealTime == nil {
+		return nil
+	}
+	return enclavedb.WriteRevealSchedule(ctx, dbTX, receiptId, revealHeight, revealTime)
+}
+
+func minReveal(current *uint64, value uint64) *uint64 {
+	if current != nil && *current <= value {
+		return current
+	}
+	return &value
+}
+
 func (es *eventsStorage) storeNewContractWithEventTypeConfigs(ctx context.Context, dbTX *sqlx.Tx, contractAddr gethcommon.Address, senderId *uint64, cfg *core.ContractVisibilityConfig, txId uint64) error {
 	_, err := enclavedb.WriteContractConfig(ctx, dbTX, contractAddr, *senderId, cfg, txId)
 	if err != nil {
@@ -71,14 +121,15 @@ func (es *eventsStorage) storeNewContractWithEventTypeConfigs(ctx context.Contex
 	// create the event types for the events that were configured
 	for eventSig, eventCfg := range cfg.EventConfigs {
 		_, err = enclavedb.WriteEventType(ctx, dbTX, &enclavedb.EventType{
-			Contract:       c,
-			EventSignature: eventSig,
-			AutoVisibility: eventCfg.AutoConfig,
-			ConfigPublic:   eventCfg.Public,
-			Topic1CanView:  eventCfg.Topic1CanView,
-			Topic2CanView:  eventCfg.Topic2CanView,
-			Topic3CanView:  eventCfg.Topic3CanView,
-			SenderCanView:  eventCfg.SenderCanView,
+			Contract:          c,
+			EventSignature:    eventSig,
+			AutoVisibility:    eventCfg.AutoConfig,
+			ConfigPublic:      eventCfg.Public,
+			Topic1CanView:     eventCfg.Topic1CanView,
+			Topic2CanView:     eventCfg.Topic2CanView,
+			Topic3CanView:     eventCfg.Topic3CanView,
+			SenderCanView:     eventCfg.SenderCanView,
+			DataFieldsCanView: eventCfg.DataFieldsCanView,
 		})
 		if err != nil {
 			return fmt.Errorf("could not write event type. cause %w", err)
@@ -125,11 +176,16 @@ func (es *eventsStorage) storeEventLog(ctx context.Context, dbTX *sqlx.Tx, recei
 	if len(data) == 0 {
 		data = nil
 	}
-	err = enclavedb.WriteEventLog(ctx, dbTX, eventType.Id, topicIds, data, l.Index, receiptId)
+	eventLogId, err := enclavedb.WriteEventLog(ctx, dbTX, eventType.Id, topicIds, data, l.Index, receiptId)
 	if err != nil {
 		return fmt.Errorf("could not write event log. Cause: %w", err)
 	}
 
+	err = es.storeDataFieldAddresses(ctx, dbTX, eventType, eventLogId, data)
+	if err != nil {
+		return fmt.Errorf("could not store the data field addresses. Cause: %w", err)
+	}
+
 	// event types that were not configured explicitly can be "Public events" as well.
 	// based on the topics, this logic determines whether the event type has any relevant addresses
 	// this is called only the first time an event is emitted
@@ -141,6 +197,37 @@ func (es *eventsStorage) storeEventLog(ctx context.Context, dbTX *sqlx.Tx, recei
 	return nil
 }
 
+// this function contains visibility logic
+// links the event log to the accounts found in the data fields which were configured to be relevant
+func (es *eventsStorage) storeDataFieldAddresses(ctx context.Context, dbTX *sqlx.Tx, eventType *enclavedb.EventType, eventLogId uint64, data []byte) error {
+	for _, addr := range eventType.DataFieldAddresses(data) {
+		eoaId, err := es.readEOA(ctx, dbTX, addr)
+		if err != nil && !errors.Is(err, errutil.ErrNotFound) {
+			return err
+		}
+		if eoaId == nil {
+			// the address is not an account yet, so it could be a contract
+			_, err = es.readContract(ctx, dbTX, addr)
+			if err != nil && !errors.Is(err, errutil.ErrNotFound) {
+				return err
+			}
+			if err == nil {
+				continue
+			}
+			id, err := enclavedb.WriteEoa(ctx, dbTX, addr)
+			if err != nil {
+				return err
+			}
+			eoaId = &id
+		}
+		err = enclavedb.WriteEventLogDataAddress(ctx, dbTX, eventLogId, *eoaId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (es *eventsStorage) setAutoVisibilityWhenEventFirstEmitted(ctx context.Context, dbTX *sqlx.Tx, eventType *enclavedb.EventType, topicIds []*uint64) error {
 	if !eventType.ConfigPublic && eventType.AutoVisibility && eventType.AutoPublic == nil {
 		isPublic := true
@@ -0,0 +1 @@
+ALTER TABLE tendb.contract ADD COLUMN public_slots blob;
@@ -0,0 +1 @@
+ALTER TABLE tendb.event_type ADD COLUMN data_fields_can_view blob;
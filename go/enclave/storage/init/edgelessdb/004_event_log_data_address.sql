@@ -0,0 +1,6 @@
+create table if not exists tendb.event_log_data_address
+(
+    event_log   INTEGER NOT NULL,
+    rel_address INTEGER NOT NULL,
+    INDEX (event_log, rel_address)
+);
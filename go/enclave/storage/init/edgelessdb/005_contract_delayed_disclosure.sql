@@ -0,0 +1 @@
+ALTER TABLE tendb.contract ADD COLUMN reveal_after_batches bigint, ADD COLUMN reveal_after_time bigint;
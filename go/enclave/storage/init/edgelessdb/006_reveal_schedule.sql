@@ -0,0 +1,8 @@
+create table if not exists tendb.reveal_schedule
+(
+    receipt       INTEGER NOT NULL,
+    reveal_height BIGINT,
+    reveal_time   BIGINT,
+    INDEX (reveal_height),
+    INDEX (reveal_time)
+);
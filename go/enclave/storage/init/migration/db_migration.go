@@ -48,7 +48,8 @@ func DBMigration(db *sqlx.DB, sqlFiles embed.FS, logger gethlog.Logger) error {
 		if err != nil {
 			return err
 		}
-		err = executeMigration(db, string(content), i)
+		// the version is the number of the last executed file
+		err = executeMigration(db, string(content), i+1)
 		if err != nil {
 			return fmt.Errorf("unable to execute migration for %s - %w", migrationFiles[i].Name(), err)
 		}
@@ -69,7 +70,7 @@ func executeMigration(db *sqlx.DB, content string, migrationOrder int64) error {
 		return err
 	}
 
-	_, err = enclavedb.WriteConfigToTx(context.Background(), tx, currentMigrationVersionKey, big.NewInt(migrationOrder).Bytes())
+	err = enclavedb.UpsertConfigToTx(context.Background(), tx, currentMigrationVersionKey, big.NewInt(migrationOrder).Bytes())
 	if err != nil {
 		return err
 	}
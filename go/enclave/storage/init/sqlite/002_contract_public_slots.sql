@@ -0,0 +1 @@
+alter table contract add column public_slots blob;
@@ -0,0 +1 @@
+alter table event_type add column data_fields_can_view blob;
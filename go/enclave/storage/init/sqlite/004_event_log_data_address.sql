@@ -0,0 +1,6 @@
+create table if not exists event_log_data_address
+(
+    event_log   INTEGER NOT NULL references event_log,
+    rel_address INTEGER NOT NULL references externally_owned_account
+);
+create index IDX_EV_DATA_ADDRESS on event_log_data_address (event_log, rel_address);
@@ -0,0 +1,2 @@
+alter table contract add column reveal_after_batches int;
+alter table contract add column reveal_after_time int;
@@ -0,0 +1,8 @@
+create table if not exists reveal_schedule
+(
+    receipt       INTEGER NOT NULL references receipt,
+    reveal_height int,
+    reveal_time   int
+);
+create index IDX_REVEAL_HEIGHT on reveal_schedule (reveal_height);
+create index IDX_REVEAL_TIME on reveal_schedule (reveal_time);
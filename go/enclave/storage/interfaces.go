@@ -101,6 +101,8 @@ type TransactionStorage interface {
 	// GetFilteredInternalReceipt - returns the receipt of a tx with event logs visible to the requester
 	GetFilteredInternalReceipt(ctx context.Context, txHash common.L2TxHash, requester *gethcommon.Address, syntheticTx bool) (*core.InternalReceipt, error)
 	ExistsTransactionReceipt(ctx context.Context, txHash common.L2TxHash) (bool, error)
+	// FetchRevealedTransactions - returns the transactions which became public with this batch, because of the delayed disclosure rules of the contracts
+	FetchRevealedTransactions(ctx context.Context, batch *common.BatchHeader) ([]common.L2TxHash, error)
 }
 
 type AttestationStorage interface {
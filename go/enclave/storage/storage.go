@@ -501,7 +501,39 @@ func (s *storageImpl) GetFilteredInternalReceipt(ctx context.Context, txHash com
 	if !syntheticTx && requester == nil {
 		return nil, errors.New("requester address is required for non-synthetic transactions")
 	}
-	return enclavedb.ReadReceipt(ctx, s.preparedStatementCache, txHash, requester)
+	reveal, err := s.revealPoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return enclavedb.ReadReceipt(ctx, s.preparedStatementCache, txHash, requester, reveal)
+}
+
+func (s *storageImpl) FetchRevealedTransactions(ctx context.Context, batch *common.BatchHeader) ([]common.L2TxHash, error) {
+	defer s.logDuration("FetchRevealedTransactions", measure.NewStopwatch())
+	// the transactions revealed by timestamp are the ones whose reveal time passed since the parent batch
+	var parentTimestamp uint64
+	parent, err := s.FetchBatchHeader(ctx, batch.ParentHash)
+	if err != nil && !errors.Is(err, errutil.ErrNotFound) {
+		return nil, err
+	}
+	if parent != nil {
+		parentTimestamp = parent.Time
+	}
+	reveal := enclavedb.RevealPoint{Height: batch.Number.Uint64(), Timestamp: batch.Time}
+	return enclavedb.ReadRevealedTransactions(ctx, s.db.GetSQLDB(), reveal, parentTimestamp)
+}
+
+// revealPoint - returns the head batch, which determines the data revealed by the contracts with delayed disclosure.
+// Returns nil when there is no batch yet.
+func (s *storageImpl) revealPoint(ctx context.Context) (*enclavedb.RevealPoint, error) {
+	head, err := s.FetchHeadBatchHeader(ctx)
+	if errors.Is(err, errutil.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the head batch. Cause: %w", err)
+	}
+	return &enclavedb.RevealPoint{Height: head.Number.Uint64(), Timestamp: head.Time}, nil
 }
 
 func (s *storageImpl) ExistsTransactionReceipt(ctx context.Context, txHash common.L2TxHash) (bool, error) {
@@ -564,6 +596,16 @@ func (s *storageImpl) StoreNodeType(ctx context.Context, enclaveId common.Enclav
 
 func (s *storageImpl) StoreNewEnclave(ctx context.Context, enclaveId common.EnclaveID, key *ecdsa.PublicKey) error {
 	defer s.logDuration("StoreNewEnclave", measure.NewStopwatch())
+	storedKey, _, err := enclavedb.FetchAttestation(ctx, s.db.GetSQLDB(), enclaveId)
+	switch {
+	case err == nil && bytes.Equal(storedKey, gethcrypto.CompressPubkey(key)):
+		// the secret request was already processed, e.g. in another fork of the L1 chain
+		return nil
+	case err == nil:
+		return fmt.Errorf("enclave %s is already attested with a different key", enclaveId)
+	case !errors.Is(err, errutil.ErrNotFound):
+		return err
+	}
 	dbTx, err := s.db.NewDBTransaction(ctx)
 	if err != nil {
 		return fmt.Errorf("could not create DB transaction - %w", err)
@@ -878,7 +920,11 @@ func (s *storageImpl) FilterLogs(
 	topics [][]gethcommon.Hash,
 ) ([]*types.Log, error) {
 	defer s.logDuration("FilterLogs", measure.NewStopwatch())
-	logs, err := enclavedb.FilterLogs(ctx, s.preparedStatementCache, requestingAccount, fromBlock, toBlock, blockHash, addresses, topics)
+	reveal, err := s.revealPoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := enclavedb.FilterLogs(ctx, s.preparedStatementCache, requestingAccount, reveal, fromBlock, toBlock, blockHash, addresses, topics)
 	if err != nil {
 		return nil, err
 	}
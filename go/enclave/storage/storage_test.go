@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	enclaveconfig "github.com/ten-protocol/go-ten/go/enclave/config"
+	"github.com/ten-protocol/go-ten/go/enclave/storage/init/sqlite"
+)
+
+func TestStoreNewEnclave(t *testing.T) {
+	backingDB, err := sqlite.CreateTemporarySQLiteDB("", "", &enclaveconfig.EnclaveConfig{RPCTimeout: time.Second}, gethlog.New())
+	require.NoError(t, err)
+	storageDB := NewStorage(backingDB, NewCacheService(gethlog.New(), true), nil, nil, gethlog.New())
+	ctx := context.Background()
+
+	key, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	enclaveID := gethcrypto.PubkeyToAddress(key.PublicKey)
+	require.NoError(t, storageDB.StoreNewEnclave(ctx, enclaveID, &key.PublicKey))
+	require.NoError(t, storageDB.StoreNodeType(ctx, enclaveID, common.Sequencer))
+
+	// the secret request is processed again when the L1 block is replayed on another fork, it must not add a second
+	// attestation of the enclave, or reset its node type
+	require.NoError(t, storageDB.StoreNewEnclave(ctx, enclaveID, &key.PublicKey))
+	attested, err := storageDB.GetEnclavePubKey(ctx, enclaveID)
+	require.NoError(t, err)
+	require.Equal(t, common.Sequencer, attested.Type)
+	require.NoError(t, storageDB.StoreNodeType(ctx, enclaveID, common.Validator))
+
+	// a request registering the enclave with another key is rejected
+	otherKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	require.ErrorContains(t, storageDB.StoreNewEnclave(ctx, enclaveID, &otherKey.PublicKey), "already attested with a different key")
+	attested, err = storageDB.GetEnclavePubKey(ctx, enclaveID)
+	require.NoError(t, err)
+	require.Equal(t, &key.PublicKey, attested.PubKey)
+}
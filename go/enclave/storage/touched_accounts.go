@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// TouchedAccounts - a bloom filter of the accounts whose state is different between the two state roots.
+// The account trie is keyed by the hash of the address, so the filter contains the hashed addresses
+// (test with `bloom.Test(crypto.Keccak256(address.Bytes()))`).
+// Only the changed subtries are visited, so the cost is proportional to the number of touched accounts.
+func TouchedAccounts(db state.Database, parentRoot gethcommon.Hash, root gethcommon.Hash) (*types.Bloom, error) {
+	parentTrie, err := db.OpenTrie(parentRoot)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the parent state trie. Cause: %w", err)
+	}
+	currentTrie, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the state trie. Cause: %w", err)
+	}
+
+	bloom := new(types.Bloom)
+	// the accounts which were created or changed, followed by the accounts which were deleted
+	for _, tries := range [][2]state.Trie{{parentTrie, currentTrie}, {currentTrie, parentTrie}} {
+		from, err := tries[0].NodeIterator(nil)
+		if err != nil {
+			return nil, err
+		}
+		to, err := tries[1].NodeIterator(nil)
+		if err != nil {
+			return nil, err
+		}
+		it, _ := trie.NewDifferenceIterator(from, to)
+		for it.Next(true) {
+			if it.Leaf() {
+				bloom.Add(it.LeafKey())
+			}
+		}
+		if it.Error() != nil {
+			return nil, fmt.Errorf("could not iterate the state trie. Cause: %w", it.Error())
+		}
+	}
+	return bloom, nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTouchedAccounts(t *testing.T) {
+	db := state.NewDatabaseForTesting()
+	unchanged := gethcommon.HexToAddress("0x01")
+	changed := gethcommon.HexToAddress("0x02")
+	created := gethcommon.HexToAddress("0x03")
+
+	parent, err := state.New(types.EmptyRootHash, db)
+	require.NoError(t, err)
+	parent.SetBalance(unchanged, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	parent.SetBalance(changed, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	parentRoot, err := parent.Commit(1, true, false)
+	require.NoError(t, err)
+
+	current, err := state.New(parentRoot, db)
+	require.NoError(t, err)
+	current.SetNonce(changed, 1, tracing.NonceChangeUnspecified)
+	current.SetBalance(created, uint256.NewInt(5), tracing.BalanceChangeUnspecified)
+	root, err := current.Commit(2, true, false)
+	require.NoError(t, err)
+
+	touched, err := TouchedAccounts(db, parentRoot, root)
+	require.NoError(t, err)
+	require.True(t, touched.Test(crypto.Keccak256(changed.Bytes())))
+	require.True(t, touched.Test(crypto.Keccak256(created.Bytes())))
+	require.False(t, touched.Test(crypto.Keccak256(unchanged.Bytes())))
+
+	// nothing is touched between identical states
+	touched, err = TouchedAccounts(db, root, root)
+	require.NoError(t, err)
+	require.Equal(t, types.Bloom{}, *touched)
+}
@@ -104,8 +104,29 @@ type HostConfig struct {
 	MetricsHTTPPort uint
 	// DebugNamespaceEnabled enables the debug namespace handler in the host rpc server
 	DebugNamespaceEnabled bool
+	// AdminNamespaceEnabled enables the admin namespace handler in the host rpc server
+	AdminNamespaceEnabled bool
 	// Whether p2p is enabled or not
 	IsInboundP2PDisabled bool
+	// Whether the P2P connections are long-lived and authenticated with the L1 keys of the hosts
+	IsP2PAuthenticated bool
+	// Whether the validators relay the new batches to each other, rather than all receiving them from the sequencer
+	IsP2PGossipEnabled bool
+	// The number of random peers each batch is relayed to when gossip is enabled
+	P2PGossipFanout int
+	// Whether the peer table is built from the enclaves registered in the L1 enclave registry
+	IsP2PDiscoveryEnabled bool
+
+	// Whether the sequencer hosts elect the active sequencer with a shared lease, and fail over when it is lost
+	IsSequencerFailoverEnabled bool
+	// The URL of the Postgres database shared by the sequencer hosts, which holds the sequencer lease
+	SequencerLeaseDBURL string
+	// How long the sequencer lease is granted for, the active sequencer host renews it several times per duration
+	SequencerLeaseDuration time.Duration
+	// How long the standby sequencer hosts wait for a batch before probing the health of the active sequencer host
+	SequencerLivenessTimeout time.Duration
+	// The RPC address the other sequencer hosts probe the health of this host at
+	SequencerHealthAddress string
 }
 
 func HostConfigFromTenConfig(tenCfg *config.TenConfig) *HostConfig {
@@ -145,10 +166,20 @@ func HostConfigFromTenConfig(tenCfg *config.TenConfig) *HostConfig {
 		EnclaveRPCAddresses: tenCfg.Host.Enclave.RPCAddresses,
 		EnclaveRPCTimeout:   tenCfg.Host.Enclave.RPCTimeout,
 
-		IsInboundP2PDisabled: tenCfg.Host.P2P.IsDisabled,
-		P2PBindAddress:       tenCfg.Host.P2P.BindAddress,
-		P2PConnectionTimeout: tenCfg.Host.P2P.Timeout,
-		P2PPublicAddress:     tenCfg.Node.HostAddress,
+		IsInboundP2PDisabled:  tenCfg.Host.P2P.IsDisabled,
+		P2PBindAddress:        tenCfg.Host.P2P.BindAddress,
+		P2PConnectionTimeout:  tenCfg.Host.P2P.Timeout,
+		IsP2PAuthenticated:    tenCfg.Host.P2P.Authenticated,
+		IsP2PGossipEnabled:    tenCfg.Host.P2P.Gossip,
+		P2PGossipFanout:       tenCfg.Host.P2P.GossipFanout,
+		IsP2PDiscoveryEnabled: tenCfg.Host.P2P.Discovery,
+		P2PPublicAddress:      tenCfg.Node.HostAddress,
+
+		IsSequencerFailoverEnabled: tenCfg.Host.Failover.Enabled,
+		SequencerLeaseDBURL:        tenCfg.Host.Failover.LeaseDBURL,
+		SequencerLeaseDuration:     tenCfg.Host.Failover.LeaseDuration,
+		SequencerLivenessTimeout:   tenCfg.Host.Failover.LivenessTimeout,
+		SequencerHealthAddress:     tenCfg.Host.Failover.HealthAddress,
 
 		L1WebsocketURL:   tenCfg.Host.L1.WebsocketURL,
 		L1BeaconUrl:      tenCfg.Host.L1.L1BeaconUrl,
@@ -159,5 +190,6 @@ func HostConfigFromTenConfig(tenCfg *config.TenConfig) *HostConfig {
 		MetricsEnabled:        tenCfg.Host.Debug.EnableMetrics,
 		MetricsHTTPPort:       tenCfg.Host.Debug.MetricsHTTPPort,
 		DebugNamespaceEnabled: tenCfg.Host.Debug.EnableDebugNamespace,
+		AdminNamespaceEnabled: tenCfg.Host.Debug.EnableAdminNamespace,
 	}
 }
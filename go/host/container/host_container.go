@@ -16,6 +16,7 @@ import (
 	"github.com/ten-protocol/go-ten/go/common/metrics"
 	"github.com/ten-protocol/go-ten/go/ethadapter"
 	"github.com/ten-protocol/go-ten/go/host"
+	"github.com/ten-protocol/go-ten/go/host/failover"
 	"github.com/ten-protocol/go-ten/go/host/p2p"
 	"github.com/ten-protocol/go-ten/go/host/rpc/clientapi"
 	"github.com/ten-protocol/go-ten/go/host/rpc/enclaverpc"
@@ -33,6 +34,7 @@ const (
 	APINamespaceScan  = "scan"
 	APINamespaceTest  = "test"
 	APINamespaceDebug = "debug"
+	APINamespaceAdmin = "admin"
 )
 
 type HostContainer struct {
@@ -150,13 +152,21 @@ func NewHostContainerFromConfig(cfg *hostconfig.HostConfig, logger gethlog.Logge
 	beaconFallback := ethadapter.NewBeaconHTTPClient(new(http.Client), cfg.L1BlobArchiveUrl)
 	blobResolver := l1.NewBlobResolver(ethadapter.NewL1BeaconClient(beaconClient, beaconFallback), logger)
 	l1Data := l1.NewL1DataService(l1Client, logger, contractRegistry, blobResolver, cfg.L1StartHash)
-	return NewHostContainer(cfg, services, aggP2P, l1Client, l1Data, enclaveClients, ethWallet, rpcServer, logger, metricsService, blobResolver, contractRegistry)
+
+	var sequencerLease failover.Lease
+	if cfg.IsSequencerFailoverEnabled && cfg.NodeType == common.Sequencer {
+		sequencerLease, err = failover.NewPostgresLease(cfg.SequencerLeaseDBURL)
+		if err != nil {
+			logger.Crit("unable to connect to the sequencer lease database", log.ErrKey, err)
+		}
+	}
+	return NewHostContainer(cfg, services, aggP2P, l1Client, l1Data, enclaveClients, ethWallet, rpcServer, logger, metricsService, blobResolver, contractRegistry, sequencerLease, failover.RPCHealthProbe)
 }
 
 // NewHostContainer builds a host container with dependency injection rather than from config.
 // Useful for testing etc. (want to be able to pass in logger, and also have option to mock out dependencies)
-func NewHostContainer(cfg *hostconfig.HostConfig, services *host.ServicesRegistry, p2p hostcommon.P2PHostService, l1Client ethadapter.EthClient, l1Repo hostcommon.L1RepoService, enclaveClients []common.Enclave, hostWallet wallet.Wallet, rpcServer node.Server, logger gethlog.Logger, metricsService *metrics.Service, blobResolver l1.BlobResolver, contractRegistry contractlib.ContractRegistryLib) *HostContainer {
-	h := host.NewHost(cfg, services, p2p, l1Client, l1Repo, enclaveClients, hostWallet, contractRegistry, logger, metricsService.Registry(), blobResolver)
+func NewHostContainer(cfg *hostconfig.HostConfig, services *host.ServicesRegistry, p2p hostcommon.P2PHostService, l1Client ethadapter.EthClient, l1Repo hostcommon.L1RepoService, enclaveClients []common.Enclave, hostWallet wallet.Wallet, rpcServer node.Server, logger gethlog.Logger, metricsService *metrics.Service, blobResolver l1.BlobResolver, contractRegistry contractlib.ContractRegistryLib, sequencerLease failover.Lease, healthProbe failover.HealthProbe) *HostContainer {
+	h := host.NewHost(cfg, services, p2p, l1Client, l1Repo, enclaveClients, hostWallet, contractRegistry, logger, metricsService.Registry(), blobResolver, sequencerLease, healthProbe)
 
 	hostContainer := &HostContainer{
 		host:           h,
@@ -200,6 +210,14 @@ func NewHostContainer(cfg *hostconfig.HostConfig, services *host.ServicesRegistr
 				},
 			})
 		}
+		if cfg.AdminNamespaceEnabled {
+			rpcServer.RegisterAPIs([]rpc.API{
+				{
+					Namespace: APINamespaceAdmin,
+					Service:   clientapi.NewAdminAPI(h),
+				},
+			})
+		}
 		services.RegisterService(hostcommon.FilterAPIServiceName, filterAPI.NewHeadsService)
 	}
 	return hostContainer
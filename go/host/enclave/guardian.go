@@ -593,7 +593,7 @@ func (g *Guardian) publishSharedSecretResponses(scrtResponses []*common.Produced
 	for _, scrtResponse := range scrtResponses {
 		// todo (#1624) - implement proper protocol so only one host responds to this secret requests initially
 		// 	for now we just have the genesis host respond until protocol implemented
-		if !g.hostData.IsSequencer {
+		if !g.hostData.IsSequencer || g.sl.P2P().IsStandby() {
 			g.logger.Trace("Not genesis node, not publishing response to secret request.",
 				"requester", scrtResponse.RequesterID)
 			return nil
@@ -651,8 +651,20 @@ func (g *Guardian) streamEnclaveData() {
 					// todo (@matt) this is a catastrophic scenario, the host may never get that batch - handle this
 					g.logger.Crit("failed to add batch to L2 repo", log.BatchHashKey, resp.Batch.Hash(), log.ErrKey, err)
 				}
+				if resp.FeeStats != nil {
+					err = g.storage.AddBatchFeeStats(resp.Batch.Hash(), resp.FeeStats)
+					if err != nil {
+						g.logger.Error("failed to store batch fee stats", log.BatchHashKey, resp.Batch.Hash(), log.ErrKey, err)
+					}
+				}
+				if len(resp.RevealedTxs) > 0 {
+					err = g.storage.AddRevealedTransactions(resp.RevealedTxs)
+					if err != nil {
+						g.logger.Error("failed to store revealed transactions", log.BatchHashKey, resp.Batch.Hash(), log.ErrKey, err)
+					}
+				}
 
-				if g.hostData.IsSequencer { // if we are the sequencer we need to broadcast this new batch to the network
+				if g.hostData.IsSequencer && !g.sl.P2P().IsStandby() { // if we are the sequencer we need to broadcast this new batch to the network
 					g.logger.Info("Batch produced. Sending to peers..", log.BatchHeightKey, resp.Batch.Header.Number, log.BatchHashKey, resp.Batch.Hash())
 
 					err = g.sl.P2P().BroadcastBatches([]*common.ExtBatch{resp.Batch})
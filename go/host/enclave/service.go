@@ -16,6 +16,7 @@ import (
 	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
 	"github.com/ten-protocol/go-ten/go/ethadapter"
 	hostconfig "github.com/ten-protocol/go-ten/go/host/config"
+	"github.com/ten-protocol/go-ten/go/host/failover"
 	"github.com/ten-protocol/go-ten/go/responses"
 	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
 )
@@ -23,6 +24,7 @@ import (
 var (
 	_noActiveSequencer    = &common.EnclaveID{} // used rather than nil to indicate no active sequencer
 	_defaultBatchInterval = 1 * time.Second     // used if batchInterval is not set in the config
+	_leaseCheckTimeout    = 2 * time.Second     // how long the lease storage has to confirm the lease before a batch or rollup
 )
 
 // This private interface enforces the services that the enclaves service depends on
@@ -41,6 +43,7 @@ type Service struct {
 	// The service goes via the Guardians to talk to the enclave (because guardian knows if the enclave is healthy etc.)
 	enclaveGuardians  []*Guardian
 	activeSequencerID atomic.Pointer[common.EnclaveID] // atomic pointer for thread safety
+	failover          *failover.Controller             // elects the active sequencer among the sequencer hosts, nil if disabled
 
 	// batch and rollup production config
 	batchInterval  time.Duration
@@ -78,6 +81,11 @@ func (e *Service) Start() error {
 
 	if e.hostData.IsSequencer {
 		e.activeSequencerID.Store(_noActiveSequencer)
+		if e.failover != nil {
+			// the batches received from the active sequencer host show it's alive
+			e.sl.L2Repo().SubscribeNewBatches(e.failover)
+			e.failover.Start()
+		}
 		go e.managePeriodicBatches()
 		go e.managePeriodicRollups()
 	}
@@ -85,6 +93,9 @@ func (e *Service) Start() error {
 }
 
 func (e *Service) Stop() error {
+	if e.failover != nil {
+		e.failover.Stop()
+	}
 	e.running.Store(false)
 	var errors []error
 	for i, guardian := range e.enclaveGuardians {
@@ -163,7 +174,7 @@ func (e *Service) SubmitAndBroadcastTx(ctx context.Context, encryptedParams comm
 		return enclaveResponse, nil //nolint: nilerr
 	}
 
-	if !e.hostData.IsSequencer {
+	if !e.hostData.IsSequencer || e.sl.P2P().IsStandby() {
 		err := e.sl.P2P().SendTxToSequencer(encryptedTx)
 		if err != nil {
 			return nil, fmt.Errorf("could not broadcast transaction to sequencer. Cause: %w", err)
@@ -195,6 +206,10 @@ func (e *Service) managePeriodicBatches() {
 	for e.running.Load() {
 		select {
 		case <-batchProductionTicker.C:
+			if !e.canProduce() {
+				// a standby sequencer host, or one which can't be sure it still holds the sequencer lease
+				continue
+			}
 			activeSeq, err := e.getActiveSequencerGuardian()
 			if err != nil {
 				e.logger.Info("No active sequencer found, trying to promote a new one", log.ErrKey, err)
@@ -203,6 +218,10 @@ func (e *Service) managePeriodicBatches() {
 			}
 
 			if activeSeq.InSyncWithL1() {
+				if err := e.checkLease(); err != nil {
+					e.logger.Warn("Not producing batch, the sequencer lease could not be confirmed", log.ErrKey, err)
+					continue
+				}
 				err = activeSeq.ProduceBatch()
 				if err != nil {
 					// todo: do we want to have a few failed attempts before looking to promote a new one?
@@ -283,6 +302,48 @@ func (e *Service) tryPromoteNewSequencer() {
 	e.activeSequencerID.Store(_noActiveSequencer)
 }
 
+// EnableFailover makes the host a candidate in the election of the active sequencer among the sequencer hosts, rather
+// than always producing batches. It must be called before the service is started.
+func (e *Service) EnableFailover(cfg failover.Config, lease failover.Lease, probe failover.HealthProbe) {
+	e.failover = failover.NewController(cfg, lease, e, probe, e.logger)
+}
+
+// Promote is called by the failover controller once the host holds the sequencer lease, it makes one of the enclaves the
+// active sequencer
+func (e *Service) Promote() error {
+	e.tryPromoteNewSequencer()
+	if e.activeSequencerID.Load() == _noActiveSequencer {
+		return errors.New("no enclave could be promoted to active sequencer")
+	}
+	e.sl.P2P().SetStandby(false)
+	return nil
+}
+
+// Demote is called by the failover controller when the host may not hold the sequencer lease anymore, the active enclave
+// is stopped so it can't produce any more batches
+func (e *Service) Demote() {
+	e.sl.P2P().SetStandby(true)
+	if guardian, err := e.getActiveSequencerGuardian(); err == nil {
+		guardian.DemoteFromActiveSequencer()
+	}
+	e.activeSequencerID.Store(_noActiveSequencer)
+}
+
+func (e *Service) canProduce() bool {
+	return e.failover == nil || e.failover.CanProduce()
+}
+
+// checkLease confirms with the lease storage that the host is still the active sequencer, right before a batch is
+// produced or a rollup is published
+func (e *Service) checkLease() error {
+	if e.failover == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), _leaseCheckTimeout)
+	defer cancel()
+	return e.failover.CheckLease(ctx)
+}
+
 // getFailoverCandidates returns a list of guardians that are not the active sequencer that seem healthy
 // it is used to check if failing over to a new active sequencer is possible
 
@@ -337,6 +398,10 @@ func (e *Service) managePeriodicRollups() {
 			}
 		}
 
+		if err := e.checkLease(); err != nil {
+			e.logger.Warn("Not publishing rollup, the sequencer lease could not be confirmed", log.ErrKey, err)
+			continue
+		}
 		// this method waits until the receipt is received
 		err = e.sl.L1Publisher().PublishBlob(*rollupToPublish)
 		if err != nil {
@@ -351,6 +416,10 @@ func (e *Service) managePeriodicRollups() {
 
 // returns true if a rollup is required, and the batch number to start from
 func (e *Service) isRollupRequired(lastSuccessfulRollup time.Time) (bool, uint64) {
+	if !e.canProduce() {
+		e.logger.Debug("Not the active sequencer host, skipping periodic rollup.")
+		return false, 0
+	}
 	if e.activeSequencerID.Load() == _noActiveSequencer {
 		e.logger.Debug("No active sequencer, skipping periodic rollup.")
 		return false, 0
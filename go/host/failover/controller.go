@@ -0,0 +1,293 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/host"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+	"github.com/ten-protocol/go-ten/go/rpc"
+)
+
+// Every sequencer host runs a controller. The host holding the lease is the active sequencer, the others are backups
+// which follow the batches like validators. A backup takes over when the active sequencer looks dead: no batch arrived
+// for the liveness timeout, and the health probe of the lease holder fails. The backups race to acquire the lease, so
+// exactly one of them is promoted, and it only makes one of its enclaves active once it holds the lease.
+//
+// The active sequencer renews the lease several times per lease duration. It only produces batches until a share of the
+// lease duration after it sent its last successful renewal, so it has stopped before the lease can expire in the shared
+// storage and be acquired by a backup. It demotes itself when it finds the lease held by another host, or granted again
+// with a new fencing token, since another host may have produced batches meanwhile. When the lease storage is
+// unreachable the active sequencer stops producing, the network halts rather than risking two active sequencers.
+//
+// The fencing token is enforced where the batches are produced and published: the active sequencer reads the lease from
+// the storage before producing each batch and publishing each rollup, and stops unless it still holds it with its token.
+// The token isn't sent with the batches, the peers couldn't tell a token relayed unaltered from one made up.
+//
+// The hosts don't retarget the transactions and the batch requests on a failover: the sequencer hosts all advertise the
+// same sequencer P2P address, and the routing in front of them must point it at the healthy host holding the lease.
+
+var (
+	_renewalsPerLease  = int64(4)
+	_leaseSafetyFactor = 0.8 // the share of the lease duration the holder produces batches for after a renewal
+	_probeTimeout      = 5 * time.Second
+)
+
+// Config is the configuration of the failover between the sequencer hosts
+type Config struct {
+	HostID        string // identifies the host in the lease
+	HealthAddress string // the RPC address the other sequencer hosts probe the health of this host at
+	// LeaseDuration is how long the lease is granted for, the active sequencer renews it several times per duration
+	LeaseDuration time.Duration
+	// LivenessTimeout is how long the backups wait for a batch before they probe the health of the active sequencer
+	LivenessTimeout time.Duration
+}
+
+// Sequencer is the batch production of the host, which the controller switches on and off
+type Sequencer interface {
+	// Promote makes the host the active sequencer, one of its enclaves is made active
+	Promote() error
+	// Demote stops the host producing batches, its active enclave is stopped
+	Demote()
+}
+
+// HealthProbe returns an error if the sequencer host at the address is unhealthy or unreachable
+type HealthProbe func(ctx context.Context, address string) error
+
+// Controller decides when the host becomes the active sequencer, and fences it when it may not be anymore
+type Controller struct {
+	cfg       Config
+	lease     Lease
+	sequencer Sequencer
+	probe     HealthProbe
+
+	mutex      sync.Mutex    // serialises the checks
+	token      atomic.Uint64 // the fencing token of our lease
+	isActive   atomic.Bool
+	validUntil atomic.Int64 // unix nanos, the time until which our lease is certainly held
+	lastBatch  atomic.Int64 // unix nanos, when the last batch was received
+
+	stopControl *stopcontrol.StopControl
+	logger      gethlog.Logger
+}
+
+func NewController(cfg Config, lease Lease, sequencer Sequencer, probe HealthProbe, logger gethlog.Logger) *Controller {
+	return &Controller{
+		cfg:         cfg,
+		lease:       lease,
+		sequencer:   sequencer,
+		probe:       probe,
+		stopControl: stopcontrol.New(),
+		logger:      logger,
+	}
+}
+
+func (c *Controller) Start() {
+	// the active sequencer is given the liveness timeout to show up
+	c.lastBatch.Store(time.Now().UnixNano())
+	go c.run()
+}
+
+// Stop releases the lease if the host holds it, so a backup can take over as soon as it notices the host is gone
+func (c *Controller) Stop() {
+	c.stopControl.Stop()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.isActive.Load() {
+		return
+	}
+	c.isActive.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), _probeTimeout)
+	defer cancel()
+	if err := c.lease.Release(ctx, c.cfg.HostID, c.token.Load()); err != nil {
+		c.logger.Warn("Could not release the sequencer lease", log.ErrKey, err)
+	}
+}
+
+// HandleBatch records the liveness of the active sequencer
+func (c *Controller) HandleBatch(*common.ExtBatch) {
+	c.lastBatch.Store(time.Now().UnixNano())
+}
+
+// CanProduce returns whether the host may produce batches and rollups, it is checked before producing each of them
+func (c *Controller) CanProduce() bool {
+	return c.isActive.Load() && time.Now().UnixNano() < c.validUntil.Load()
+}
+
+// CheckLease returns an error unless the lease storage confirms the host still holds the lease with its fencing token.
+// It is called before producing each batch and publishing each rollup, so a host whose lease was taken over stops at
+// once rather than at its next renewal.
+func (c *Controller) CheckLease(ctx context.Context) error {
+	if !c.CanProduce() {
+		return errors.New("the host is not the active sequencer")
+	}
+	record, err := c.lease.Current(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read the sequencer lease: %w", err)
+	}
+	if record == nil || record.Holder != c.cfg.HostID || record.Token != c.token.Load() {
+		return fmt.Errorf("the sequencer lease is not held with token %d anymore", c.token.Load())
+	}
+	return nil
+}
+
+// IsActive returns whether the host holds the lease and was promoted to active sequencer
+func (c *Controller) IsActive() bool {
+	return c.isActive.Load()
+}
+
+func (c *Controller) run() {
+	ticker := time.NewTicker(c.checkInterval())
+	defer ticker.Stop()
+	for {
+		c.check()
+		select {
+		case <-c.stopControl.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Controller) checkInterval() time.Duration {
+	return c.cfg.LeaseDuration / time.Duration(_renewalsPerLease)
+}
+
+// check renews the lease of the active sequencer, or lets a backup take over if the active sequencer is lost
+func (c *Controller) check() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stopControl.IsStopping() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.checkInterval())
+	defer cancel()
+
+	if c.isActive.Load() {
+		c.renew(ctx)
+		return
+	}
+	if c.activeSequencerLost(ctx) {
+		c.takeOver(ctx)
+	}
+}
+
+// activeSequencerLost returns true if no batch was received for the liveness timeout and the lease holder fails its
+// health probe, or if no host ever held the lease
+func (c *Controller) activeSequencerLost(ctx context.Context) bool {
+	record, err := c.lease.Current(ctx)
+	if err != nil {
+		// the lease couldn't be acquired anyway
+		c.logger.Warn("Could not read the sequencer lease", log.ErrKey, err)
+		return false
+	}
+	if record == nil {
+		// the network is starting
+		return true
+	}
+	if time.Since(time.Unix(0, c.lastBatch.Load())) < c.cfg.LivenessTimeout {
+		return false
+	}
+	if record.Holder == c.cfg.HostID {
+		// we held the lease before the host was restarted
+		return true
+	}
+	if time.Now().After(record.Expiry) {
+		// the holder was fenced, or is gone, but it may still pass the health probe. The clocks don't need to be in sync
+		// here, the lease storage only grants an expired lease.
+		c.logger.Warn("Active sequencer lease expired, attempting to take over", "holder", record.Holder)
+		return true
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, _probeTimeout)
+	defer cancel()
+	if err := c.probe(probeCtx, record.Address); err == nil {
+		// the batches may not reach us while the active sequencer is fine, e.g. a network partition
+		c.logger.Warn("No batch received from the active sequencer, but it is healthy", "holder", record.Holder)
+		return false
+	}
+	c.logger.Warn("Active sequencer lost, attempting to take over", "holder", record.Holder, log.ErrKey, err)
+	return true
+}
+
+func (c *Controller) takeOver(ctx context.Context) {
+	requested := time.Now()
+	record, err := c.lease.Acquire(ctx, c.cfg.HostID, c.cfg.HealthAddress, c.cfg.LeaseDuration)
+	if errors.Is(err, ErrLeaseHeld) {
+		c.logger.Info("Another sequencer host holds the lease", "holder", record.Holder)
+		return
+	}
+	if err != nil {
+		c.logger.Warn("Could not acquire the sequencer lease", log.ErrKey, err)
+		return
+	}
+	c.token.Store(record.Token)
+	c.validUntil.Store(c.leaseValidUntil(requested))
+
+	c.logger.Info("Acquired the sequencer lease, promoting host to active sequencer", "token", record.Token)
+	if err := c.sequencer.Promote(); err != nil {
+		c.logger.Error("Could not promote host to active sequencer, releasing the lease", log.ErrKey, err)
+		if err := c.lease.Release(ctx, c.cfg.HostID, record.Token); err != nil {
+			c.logger.Warn("Could not release the sequencer lease", log.ErrKey, err)
+		}
+		return
+	}
+	c.isActive.Store(true)
+}
+
+func (c *Controller) renew(ctx context.Context) {
+	requested := time.Now()
+	record, err := c.lease.Acquire(ctx, c.cfg.HostID, c.cfg.HealthAddress, c.cfg.LeaseDuration)
+	switch {
+	case err == nil && record.Token == c.token.Load():
+		c.validUntil.Store(c.leaseValidUntil(requested))
+	case err == nil:
+		// our lease expired before this renewal, so we can't know whether another host produced batches meanwhile
+		c.demote(fmt.Sprintf("the lease was granted again with token %d, ours was %d", record.Token, c.token.Load()))
+		if err := c.lease.Release(ctx, c.cfg.HostID, record.Token); err != nil {
+			c.logger.Warn("Could not release the sequencer lease", log.ErrKey, err)
+		}
+	case errors.Is(err, ErrLeaseHeld):
+		c.demote(fmt.Sprintf("the lease is held by %s", record.Holder))
+	case time.Now().UnixNano() >= c.validUntil.Load():
+		c.demote(fmt.Sprintf("the lease could not be renewed before it expired: %s", err))
+	default:
+		c.logger.Warn("Could not renew the sequencer lease, will retry", log.ErrKey, err)
+	}
+}
+
+// demote fences the host, it must not produce batches anymore
+func (c *Controller) demote(reason string) {
+	c.logger.Error("Demoting host from active sequencer", "reason", reason)
+	c.isActive.Store(false)
+	c.sequencer.Demote()
+}
+
+// leaseValidUntil returns the time until which a lease requested at the given time is certainly held, the safety margin
+// covers the clocks of the hosts and the storage running at slightly different rates
+func (c *Controller) leaseValidUntil(requested time.Time) int64 {
+	return requested.Add(time.Duration(_leaseSafetyFactor * float64(c.cfg.LeaseDuration))).UnixNano()
+}
+
+// RPCHealthProbe calls the health RPC of the host at the address
+func RPCHealthProbe(ctx context.Context, address string) error {
+	client, err := rpc.NewNetworkClient(address)
+	if err != nil {
+		return err
+	}
+	defer client.Stop()
+	var health host.HealthCheck
+	if err := client.CallContext(ctx, &health, rpc.Health); err != nil {
+		return err
+	}
+	if !health.OverallHealth {
+		return fmt.Errorf("host is not healthy: %v", health.Errors)
+	}
+	return nil
+}
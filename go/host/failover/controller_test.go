@@ -0,0 +1,48 @@
+package failover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+type testSequencer struct {
+	active bool
+}
+
+func (s *testSequencer) Promote() error {
+	s.active = true
+	return nil
+}
+
+func (s *testSequencer) Demote() {
+	s.active = false
+}
+
+func TestControllerCheckLease(t *testing.T) {
+	ctx := context.Background()
+	lease := NewInMemoryLease()
+	sequencer := &testSequencer{}
+	cfg := Config{HostID: "host1", HealthAddress: "address1", LeaseDuration: 100 * time.Millisecond, LivenessTimeout: time.Second}
+	controller := NewController(cfg, lease, sequencer, func(context.Context, string) error { return nil }, gethlog.New())
+
+	require.Error(t, controller.CheckLease(ctx))
+
+	// the first host to check takes the lease, as no host held it
+	controller.check()
+	require.True(t, sequencer.active)
+	require.NoError(t, controller.CheckLease(ctx))
+
+	// the lease is taken by another host once it expired, the host can't produce anymore before its next check
+	time.Sleep(100 * time.Millisecond)
+	_, err := lease.Acquire(ctx, "host2", "address2", time.Minute)
+	require.NoError(t, err)
+	require.Error(t, controller.CheckLease(ctx))
+
+	controller.check()
+	require.False(t, sequencer.active)
+	require.Error(t, controller.CheckLease(ctx))
+}
@@ -0,0 +1,84 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLeaseHeld is returned when acquiring the lease while another host holds it
+var ErrLeaseHeld = errors.New("the sequencer lease is held by another host")
+
+// LeaseRecord is the state of the sequencer lease
+type LeaseRecord struct {
+	Holder  string // the ID of the host holding the lease
+	Address string // the RPC address the health of the holder is probed at
+	// Token is the fencing token, it increases every time the lease changes hands or is taken again after expiring, so
+	// a holder which sees a different token than the one it was granted knows another host may have produced batches
+	Token  uint64
+	Expiry time.Time
+}
+
+// Lease elects the active sequencer among the sequencer hosts. The lease storage is shared by the hosts, and is the
+// only arbiter of which host may produce batches.
+type Lease interface {
+	// Acquire takes the lease for the holder, or renews it if the holder already has it. It returns ErrLeaseHeld, with
+	// the current record, if another host holds an unexpired lease.
+	Acquire(ctx context.Context, holder string, address string, duration time.Duration) (*LeaseRecord, error)
+	// Release gives up the lease, if the holder still holds it with the token
+	Release(ctx context.Context, holder string, token uint64) error
+	// Current returns the lease record, nil if the lease was never taken
+	Current(ctx context.Context) (*LeaseRecord, error)
+}
+
+// InMemoryLease is a lease shared by hosts running in the same process, used for testing
+type InMemoryLease struct {
+	mutex  sync.Mutex
+	record *LeaseRecord
+}
+
+func NewInMemoryLease() *InMemoryLease {
+	return &InMemoryLease{}
+}
+
+func (l *InMemoryLease) Acquire(_ context.Context, holder string, address string, duration time.Duration) (*LeaseRecord, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	if l.record == nil {
+		l.record = &LeaseRecord{}
+	}
+	isExpired := !now.Before(l.record.Expiry)
+	if l.record.Holder != holder && !isExpired {
+		current := *l.record
+		return &current, ErrLeaseHeld
+	}
+	if l.record.Holder != holder || isExpired {
+		l.record.Token++
+	}
+	l.record.Holder = holder
+	l.record.Address = address
+	l.record.Expiry = now.Add(duration)
+	granted := *l.record
+	return &granted, nil
+}
+
+func (l *InMemoryLease) Release(_ context.Context, holder string, token uint64) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.record != nil && l.record.Holder == holder && l.record.Token == token {
+		l.record.Expiry = time.Now()
+	}
+	return nil
+}
+
+func (l *InMemoryLease) Current(context.Context) (*LeaseRecord, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.record == nil {
+		return nil, nil //nolint:nilnil
+	}
+	current := *l.record
+	return &current, nil
+}
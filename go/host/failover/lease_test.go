@@ -0,0 +1,48 @@
+package failover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryLease(t *testing.T) {
+	ctx := context.Background()
+	lease := NewInMemoryLease()
+	current, err := lease.Current(ctx)
+	require.NoError(t, err)
+	require.Nil(t, current)
+
+	record, err := lease.Acquire(ctx, "host1", "address1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), record.Token)
+
+	// the holder keeps its token when it renews
+	record, err = lease.Acquire(ctx, "host1", "address1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), record.Token)
+
+	record, err = lease.Acquire(ctx, "host2", "address2", 100*time.Millisecond)
+	require.ErrorIs(t, err, ErrLeaseHeld)
+	require.Equal(t, "host1", record.Holder)
+	require.Equal(t, "address1", record.Address)
+
+	// the expired lease is granted with a new token, even to its previous holder
+	time.Sleep(150 * time.Millisecond)
+	record, err = lease.Acquire(ctx, "host1", "address1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), record.Token)
+
+	// releasing with a stale token has no effect
+	require.NoError(t, lease.Release(ctx, "host1", 1))
+	_, err = lease.Acquire(ctx, "host2", "address2", 100*time.Millisecond)
+	require.ErrorIs(t, err, ErrLeaseHeld)
+
+	require.NoError(t, lease.Release(ctx, "host1", 2))
+	record, err = lease.Acquire(ctx, "host2", "address2", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, "host2", record.Holder)
+	require.Equal(t, uint64(3), record.Token)
+}
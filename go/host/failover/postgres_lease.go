@@ -0,0 +1,108 @@
+package failover
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// The lease is a single row, updated atomically by the hosts. The expiry is compared with the clock of the database, so
+// the hosts don't rely on their clocks being in sync.
+const (
+	_createLeaseTable = `CREATE TABLE IF NOT EXISTS sequencer_lease (
+		id      INTEGER PRIMARY KEY,
+		holder  TEXT NOT NULL,
+		address TEXT NOT NULL,
+		token   BIGINT NOT NULL,
+		expiry  TIMESTAMPTZ NOT NULL
+	)`
+
+	// takes the lease if it's free or expired, or renews it for its holder, the token changes with every new grant
+	_acquireLease = `INSERT INTO sequencer_lease (id, holder, address, token, expiry)
+		VALUES (1, $1, $2, 1, now() + $3 * interval '1 millisecond')
+		ON CONFLICT (id) DO UPDATE SET
+			token = CASE WHEN sequencer_lease.holder = EXCLUDED.holder AND sequencer_lease.expiry > now()
+				THEN sequencer_lease.token ELSE sequencer_lease.token + 1 END,
+			holder = EXCLUDED.holder,
+			address = EXCLUDED.address,
+			expiry = EXCLUDED.expiry
+		WHERE sequencer_lease.holder = EXCLUDED.holder OR sequencer_lease.expiry <= now()
+		RETURNING holder, address, token, expiry`
+
+	_releaseLease = `UPDATE sequencer_lease SET expiry = now() WHERE id = 1 AND holder = $1 AND token = $2`
+	_selectLease  = `SELECT holder, address, token, expiry FROM sequencer_lease WHERE id = 1`
+)
+
+// PostgresLease is a lease stored in a Postgres database shared by the sequencer hosts
+type PostgresLease struct {
+	db *sql.DB
+}
+
+// NewPostgresLease connects to the database at the URL and creates the lease table if needed
+func NewPostgresLease(dbURL string) (*PostgresLease, error) {
+	if dbURL == "" {
+		return nil, errors.New("the sequencer lease database URL was not set")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the sequencer lease database: %w", err)
+	}
+	return newPostgresLease(db)
+}
+
+func newPostgresLease(db *sql.DB) (*PostgresLease, error) {
+	if _, err := db.Exec(_createLeaseTable); err != nil {
+		return nil, fmt.Errorf("failed to create the sequencer lease table: %w", err)
+	}
+	return &PostgresLease{db: db}, nil
+}
+
+func (l *PostgresLease) Acquire(ctx context.Context, holder string, address string, duration time.Duration) (*LeaseRecord, error) {
+	record, err := scanLease(l.db.QueryRowContext(ctx, _acquireLease, holder, address, duration.Milliseconds()))
+	if errors.Is(err, sql.ErrNoRows) {
+		// another host holds the lease
+		current, err := l.Current(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return current, ErrLeaseHeld
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire the sequencer lease: %w", err)
+	}
+	return record, nil
+}
+
+func (l *PostgresLease) Release(ctx context.Context, holder string, token uint64) error {
+	if _, err := l.db.ExecContext(ctx, _releaseLease, holder, token); err != nil {
+		return fmt.Errorf("failed to release the sequencer lease: %w", err)
+	}
+	return nil
+}
+
+func (l *PostgresLease) Current(ctx context.Context) (*LeaseRecord, error) {
+	record, err := scanLease(l.db.QueryRowContext(ctx, _selectLease))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil //nolint:nilnil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the sequencer lease: %w", err)
+	}
+	return record, nil
+}
+
+func (l *PostgresLease) Close() error {
+	return l.db.Close()
+}
+
+func scanLease(row *sql.Row) (*LeaseRecord, error) {
+	var record LeaseRecord
+	if err := row.Scan(&record.Holder, &record.Address, &record.Token, &record.Expiry); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
@@ -0,0 +1,227 @@
+package failover
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLease(t *testing.T) {
+	server := &fakeLeaseServer{}
+	// the hosts share the database, but not their connections
+	lease, err := newPostgresLease(sql.OpenDB(server))
+	require.NoError(t, err)
+	other, err := newPostgresLease(sql.OpenDB(server))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	current, err := lease.Current(ctx)
+	require.NoError(t, err)
+	require.Nil(t, current)
+
+	record, err := lease.Acquire(ctx, "host1", "address1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, "host1", record.Holder)
+	require.Equal(t, "address1", record.Address)
+	require.Equal(t, uint64(1), record.Token)
+	require.WithinDuration(t, time.Now().Add(100*time.Millisecond), record.Expiry, 50*time.Millisecond)
+
+	// the holder keeps its token when it renews
+	record, err = lease.Acquire(ctx, "host1", "address1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), record.Token)
+
+	// the row isn't updated for another host, which gets the current record
+	record, err = other.Acquire(ctx, "host2", "address2", 100*time.Millisecond)
+	require.ErrorIs(t, err, ErrLeaseHeld)
+	require.Equal(t, "host1", record.Holder)
+	require.Equal(t, "address1", record.Address)
+	require.Equal(t, uint64(1), record.Token)
+
+	// the expired lease is granted with a new token, even to its previous holder
+	time.Sleep(150 * time.Millisecond)
+	record, err = lease.Acquire(ctx, "host1", "address1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), record.Token)
+
+	// releasing with a stale token has no effect
+	require.NoError(t, lease.Release(ctx, "host1", 1))
+	_, err = other.Acquire(ctx, "host2", "address2", 100*time.Millisecond)
+	require.ErrorIs(t, err, ErrLeaseHeld)
+
+	require.NoError(t, lease.Release(ctx, "host1", 2))
+	record, err = other.Acquire(ctx, "host2", "address2", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, "host2", record.Holder)
+	require.Equal(t, "address2", record.Address)
+	require.Equal(t, uint64(3), record.Token)
+
+	current, err = lease.Current(ctx)
+	require.NoError(t, err)
+	require.Equal(t, record, current)
+
+	// the errors of the database are not mistaken for a lease held by another host
+	server.setFailing(true)
+	_, err = lease.Acquire(ctx, "host1", "address1", 100*time.Millisecond)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrLeaseHeld)
+}
+
+func TestPostgresLeaseRace(t *testing.T) {
+	server := &fakeLeaseServer{}
+	ctx := context.Background()
+	const hosts = 10
+
+	var wg sync.WaitGroup
+	granted := make(chan string, hosts)
+	for i := 0; i < hosts; i++ {
+		lease, err := newPostgresLease(sql.OpenDB(server))
+		require.NoError(t, err)
+		wg.Add(1)
+		go func(holder string) {
+			defer wg.Done()
+			record, err := lease.Acquire(ctx, holder, holder, time.Minute)
+			if err == nil {
+				granted <- record.Holder
+			}
+		}(fmt.Sprintf("host%d", i))
+	}
+	wg.Wait()
+	close(granted)
+	require.Len(t, granted, 1)
+}
+
+// fakeLeaseServer - an in-process stand-in for the PostgreSQL database, which executes the statements of the lease like
+// PostgreSQL does. The statements are serialised, as the row lock taken by the upsert serialises them in PostgreSQL.
+type fakeLeaseServer struct {
+	mu      sync.Mutex
+	table   bool
+	row     *LeaseRecord
+	failing bool
+}
+
+func (f *fakeLeaseServer) setFailing(failing bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing = failing
+}
+
+func (f *fakeLeaseServer) Connect(context.Context) (driver.Conn, error) {
+	return &fakeLeaseConn{server: f}, nil
+}
+
+func (f *fakeLeaseServer) Driver() driver.Driver {
+	return nil
+}
+
+func (f *fakeLeaseServer) exec(query string, args []driver.NamedValue) (*fakeLeaseRows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failing {
+		return nil, fmt.Errorf("connection refused")
+	}
+	now := time.Now()
+	switch query {
+	case _createLeaseTable:
+		f.table = true
+		return &fakeLeaseRows{}, nil
+
+	case _acquireLease:
+		if !f.table {
+			return nil, fmt.Errorf(`relation "sequencer_lease" does not exist`)
+		}
+		holder, address := args[0].Value.(string), args[1].Value.(string)
+		expiry := now.Add(time.Duration(args[2].Value.(int64)) * time.Millisecond)
+		switch {
+		case f.row == nil:
+			// INSERT
+			f.row = &LeaseRecord{Holder: holder, Address: address, Token: 1, Expiry: expiry}
+		case f.row.Holder == holder || !f.row.Expiry.After(now):
+			// ON CONFLICT (id) DO UPDATE ... WHERE holder = EXCLUDED.holder OR expiry <= now()
+			if f.row.Holder != holder || !f.row.Expiry.After(now) {
+				f.row.Token++
+			}
+			f.row.Holder, f.row.Address, f.row.Expiry = holder, address, expiry
+		default:
+			// the conflicting row isn't updated, so nothing is returned
+			return &fakeLeaseRows{columns: leaseColumns}, nil
+		}
+		return f.rowValues(), nil
+
+	case _releaseLease:
+		if f.row != nil && f.row.Holder == args[0].Value && uint64(args[1].Value.(int64)) == f.row.Token {
+			f.row.Expiry = now
+		}
+		return &fakeLeaseRows{}, nil
+
+	case _selectLease:
+		if f.row == nil {
+			return &fakeLeaseRows{columns: leaseColumns}, nil
+		}
+		return f.rowValues(), nil
+	}
+	return nil, fmt.Errorf("unexpected statement: %s", query)
+}
+
+var leaseColumns = []string{"holder", "address", "token", "expiry"}
+
+func (f *fakeLeaseServer) rowValues() *fakeLeaseRows {
+	values := []driver.Value{f.row.Holder, f.row.Address, int64(f.row.Token), f.row.Expiry}
+	return &fakeLeaseRows{columns: leaseColumns, values: [][]driver.Value{values}}
+}
+
+type fakeLeaseConn struct {
+	server *fakeLeaseServer
+}
+
+func (c *fakeLeaseConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+
+func (c *fakeLeaseConn) Close() error {
+	return nil
+}
+
+func (c *fakeLeaseConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions are not supported")
+}
+
+func (c *fakeLeaseConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := c.server.exec(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeLeaseConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.server.exec(query, args)
+}
+
+type fakeLeaseRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeLeaseRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeLeaseRows) Close() error {
+	return nil
+}
+
+func (r *fakeLeaseRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/ten-protocol/go-ten/go/host/l2"
 
 	"github.com/ten-protocol/go-ten/go/host/enclave"
+	"github.com/ten-protocol/go-ten/go/host/failover"
 	"github.com/ten-protocol/go-ten/go/host/l1"
 
 	"github.com/naoina/toml"
@@ -61,7 +62,7 @@ func (bl batchListener) HandleBatch(batch *common.ExtBatch) {
 	bl.newHeads <- batch.Header
 }
 
-func NewHost(config *hostconfig.HostConfig, hostServices *ServicesRegistry, p2p hostcommon.P2PHostService, ethClient ethadapter.EthClient, l1Repo hostcommon.L1RepoService, enclaveClients []common.Enclave, ethWallet wallet.Wallet, contractRegistry contractlib.ContractRegistryLib, logger gethlog.Logger, regMetrics gethmetrics.Registry, blobResolver l1.BlobResolver) hostcommon.Host {
+func NewHost(config *hostconfig.HostConfig, hostServices *ServicesRegistry, p2p hostcommon.P2PHostService, ethClient ethadapter.EthClient, l1Repo hostcommon.L1RepoService, enclaveClients []common.Enclave, ethWallet wallet.Wallet, contractRegistry contractlib.ContractRegistryLib, logger gethlog.Logger, regMetrics gethmetrics.Registry, blobResolver l1.BlobResolver, sequencerLease failover.Lease, healthProbe failover.HealthProbe) hostcommon.Host {
 	hostStorage := storage.NewHostStorageFromConfig(config, logger)
 	l1Repo.SetBlockResolver(hostStorage)
 	hostIdentity := hostcommon.NewIdentity(config)
@@ -125,6 +126,20 @@ func NewHost(config *hostconfig.HostConfig, hostServices *ServicesRegistry, p2p
 	hostServices.RegisterService(hostcommon.EnclaveServiceName, enclService)
 	hostServices.RegisterService(hostcommon.LogSubscriptionServiceName, subsService)
 
+	if config.IsSequencerFailoverEnabled && hostIdentity.IsSequencer {
+		if sequencerLease == nil || healthProbe == nil {
+			logger.Crit("the sequencer failover requires the sequencer lease and the health probe of the other sequencer hosts")
+		}
+		// the sequencer hosts start on standby, following the batches until one of them is elected active sequencer
+		p2p.SetStandby(true)
+		enclService.EnableFailover(failover.Config{
+			HostID:          config.ID,
+			HealthAddress:   config.SequencerHealthAddress,
+			LeaseDuration:   config.SequencerLeaseDuration,
+			LivenessTimeout: config.SequencerLivenessTimeout,
+		}, sequencerLease, healthProbe)
+	}
+
 	var prof *profiler.Profiler
 	if config.ProfilerEnabled {
 		prof = profiler.NewProfiler(profiler.DefaultHostPort, logger)
@@ -309,14 +324,35 @@ func (h *host) ConfirmedHeadBatch() (*common.BatchHeader, error) {
 	return b.Header, nil
 }
 
+func (h *host) Peers() []*hostcommon.PeerInfo {
+	return h.services.P2P().Peers()
+}
+
+func (h *host) PeerScores() []hostcommon.PeerScore {
+	return h.services.P2P().PeerScores()
+}
+
 // Checks the host config is valid.
 func (h *host) validateConfig() {
 	if h.config.IsGenesis && h.config.NodeType != common.Sequencer {
 		h.logger.Crit("genesis node must be the sequencer")
 	}
-	if !h.config.IsGenesis && h.config.NodeType == common.Sequencer {
+	if !h.config.IsGenesis && h.config.NodeType == common.Sequencer && !h.config.IsSequencerFailoverEnabled {
+		// with the failover, the other sequencer hosts are standbys for the genesis one
 		h.logger.Crit("only the genesis node can be a sequencer")
 	}
+	if h.config.IsSequencerFailoverEnabled && h.config.NodeType == common.Sequencer {
+		if h.config.SequencerLeaseDuration <= 0 || h.config.SequencerLivenessTimeout <= 0 {
+			h.logger.Crit("the sequencer failover requires a lease duration and a liveness timeout")
+		}
+		if h.config.SequencerHealthAddress == "" {
+			h.logger.Crit("the sequencer failover requires the health address of the host")
+		}
+		if h.config.SequencerP2PAddress != "" && h.config.P2PPublicAddress != h.config.SequencerP2PAddress {
+			// the peers reach the active host through the sequencer address, and check it is the address the host claims
+			h.logger.Crit("with the sequencer failover, the public P2P address of the sequencer hosts must be the sequencer P2P address")
+		}
+	}
 
 	if h.config.P2PPublicAddress == "" {
 		h.logger.Crit("the host must specify a public P2P address")
@@ -537,6 +537,134 @@ func (r *DataService) FetchBlockByHeight(height *big.Int) (*types.Header, error)
 	return r.ethClient.HeaderByNumber(height)
 }
 
+// FetchEnclaveRegistryEvents returns the enclave registrations, attestations and sequencer permission changes recorded
+// by the enclave registry in the given range of blocks, in the order of the logs.
+func (r *DataService) FetchEnclaveRegistryEvents(fromHeight *big.Int, toHeight *big.Int) ([]*common.EnclaveRegistryEvent, error) {
+	registryAddr := r.contractRegistry.EnclaveRegistryLib().GetContractAddr()
+	logs, err := r.ethClient.GetLogs(ethereum.FilterQuery{
+		FromBlock: fromHeight,
+		ToBlock:   toHeight,
+		Addresses: []gethcommon.Address{*registryAddr},
+		Topics: [][]gethcommon.Hash{{
+			ethadapter.NetworkSecretInitializedEventID,
+			ethadapter.NetworkSecretRequestedID,
+			ethadapter.NetworkSecretRespondedID,
+			ethadapter.SequencerEnclaveGrantedEventID,
+			ethadapter.SequencerEnclaveRevokedEventID,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch enclave registry logs from %d to %d: %w", fromHeight, toHeight, err)
+	}
+
+	events := make([]*common.EnclaveRegistryEvent, 0, len(logs))
+	for _, l := range logs {
+		if len(l.Topics) == 0 || l.Removed {
+			continue
+		}
+		event, err := r.toEnclaveRegistryEvent(l)
+		if err != nil {
+			// a malformed registration only affects the enclave which submitted it
+			r.logger.Warn("Could not decode enclave registry log", "txHash", l.TxHash, log.ErrKey, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *DataService) toEnclaveRegistryEvent(l types.Log) (*common.EnclaveRegistryEvent, error) {
+	event := &common.EnclaveRegistryEvent{BlockNumber: l.BlockNumber}
+	switch l.Topics[0] {
+	case ethadapter.NetworkSecretInitializedEventID:
+		// the genesis attestation is only recorded in the transaction
+		tx, _, err := r.ethClient.TransactionByHash(l.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching transaction: %w", err)
+		}
+		decodedTx, err := r.contractRegistry.EnclaveRegistryLib().DecodeTx(tx)
+		if err != nil {
+			return nil, err
+		}
+		initTx, ok := decodedTx.(*common.L1InitializeSecretTx)
+		if !ok {
+			return nil, errors.New("transaction is not a secret initialization")
+		}
+		att, err := common.DecodeAttestation(initTx.Attestation)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode genesis attestation: %w", err)
+		}
+		event.Type = common.InitialiseSecretTx
+		event.EnclaveID, err = getEnclaveIdFromLog(l)
+		if err != nil {
+			return nil, err
+		}
+		event.HostAddress = att.HostAddress
+		event.HostID, err = txSender(tx)
+		if err != nil {
+			return nil, err
+		}
+	case ethadapter.NetworkSecretRequestedID:
+		values, err := ethadapter.EnclaveRegistryABI.Unpack(ethadapter.NetworkSecretRequestedEventName, l.Data)
+		if err != nil || len(values) != 1 {
+			return nil, fmt.Errorf("could not unpack secret request: %w", err)
+		}
+		report, ok := values[0].(string)
+		if !ok {
+			return nil, errors.New("unexpected secret request report type")
+		}
+		encodedAtt, err := ethadapter.Base64DecodeFromString(report)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode attestation request: %w", err)
+		}
+		att, err := common.DecodeAttestation(encodedAtt)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode attestation: %w", err)
+		}
+		// the host which submitted the request authenticates its P2P connections with the same key
+		tx, _, err := r.ethClient.TransactionByHash(l.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching transaction: %w", err)
+		}
+		event.Type = common.SecretRequestTx
+		event.EnclaveID = att.EnclaveID
+		event.HostAddress = att.HostAddress
+		event.HostID, err = txSender(tx)
+		if err != nil {
+			return nil, err
+		}
+	case ethadapter.NetworkSecretRespondedID:
+		// both the attester and the requester are indexed
+		if len(l.Topics) < 3 {
+			return nil, errors.New("secret response log has no requester")
+		}
+		event.Type = common.SecretResponseTx
+		event.EnclaveID = gethcommon.BytesToAddress(l.Topics[2].Bytes())
+	case ethadapter.SequencerEnclaveGrantedEventID, ethadapter.SequencerEnclaveRevokedEventID:
+		enclaveID, err := getEnclaveIdFromLog(l)
+		if err != nil {
+			return nil, err
+		}
+		event.Type = common.SequencerAddedTx
+		if l.Topics[0] == ethadapter.SequencerEnclaveRevokedEventID {
+			event.Type = common.SequencerRevokedTx
+		}
+		event.EnclaveID = enclaveID
+	default:
+		return nil, fmt.Errorf("unexpected log topic %s", l.Topics[0])
+	}
+	return event, nil
+}
+
+// txSender returns the L1 account which signed the transaction
+func txSender(tx *types.Transaction) (gethcommon.Address, error) {
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return gethcommon.Address{}, fmt.Errorf("could not recover the transaction sender: %w", err)
+	}
+	return sender, nil
+}
+
 // getEnclaveIdFromLog gets the enclave ID from the log topic
 func getEnclaveIdFromLog(log types.Log) (gethcommon.Address, error) {
 	// the enclaveID field is not indexed, we read it from the data field
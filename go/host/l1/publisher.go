@@ -9,6 +9,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ten-protocol/go-ten/contracts/generated/NetworkEnclaveRegistry"
 	"github.com/ten-protocol/go-ten/go/ethadapter/contractlib"
 
 	"github.com/ethereum/go-ethereum/params"
@@ -318,6 +320,31 @@ func (p *Publisher) ResyncImportantContracts() error {
 	return nil
 }
 
+// IsSequencerEnclave reads the sequencer permission of the enclave from the enclave registry contract
+func (p *Publisher) IsSequencerEnclave(enclaveID common.EnclaveID) (bool, error) {
+	if p.contractRegistry.IsMock() {
+		// the mock L1 has no enclave registry, it trusts every enclave
+		return true, nil
+	}
+	registry, err := NetworkEnclaveRegistry.NewNetworkEnclaveRegistryCaller(*p.contractRegistry.EnclaveRegistryLib().GetContractAddr(), p.ethClient.EthClient())
+	if err != nil {
+		return false, fmt.Errorf("could not create enclave registry caller: %w", err)
+	}
+	return registry.IsSequencer(&bind.CallOpts{}, enclaveID)
+}
+
+// IsEnclaveAttested reads whether the enclave is attested from the enclave registry contract
+func (p *Publisher) IsEnclaveAttested(enclaveID common.EnclaveID) (bool, error) {
+	if p.contractRegistry.IsMock() {
+		return true, nil
+	}
+	registry, err := NetworkEnclaveRegistry.NewNetworkEnclaveRegistryCaller(*p.contractRegistry.EnclaveRegistryLib().GetContractAddr(), p.ethClient.EthClient())
+	if err != nil {
+		return false, fmt.Errorf("could not create enclave registry caller: %w", err)
+	}
+	return registry.IsAttested(&bind.CallOpts{}, enclaveID)
+}
+
 // publishDynamicTxWithRetry will keep trying unless the L1 seems to be unavailable or the tx is otherwise rejected
 // this method is guarded by a lock to ensure that only one transaction is attempted at a time to avoid nonce conflicts
 // todo (@matt) this method should take a context so we can try to cancel if the tx is no longer required
@@ -175,8 +175,9 @@ func (r *Repository) FetchBatchBySeqNo(ctx context.Context, seqNo *big.Int) (*co
 	b, err := r.storage.FetchBatchBySeqNo(seqNo.Uint64())
 	if err != nil {
 		if errors.Is(err, errutil.ErrNotFound) && seqNo.Cmp(r.latestBatchSeqNo) < 0 {
-			if r.isSequencer {
-				// sequencer does not request batches from peers, it checks if its enclave has the batch
+			if r.isSequencer && !r.sl.P2P().IsStandby() {
+				// sequencer does not request batches from peers, it checks if its enclave has the batch (a standby
+				// sequencer host follows the active one like a validator)
 				return r.fetchBatchFallbackToEnclave(ctx, seqNo)
 			}
 			// we haven't seen this batch before, but it is older than the latest batch we have seen so far
@@ -256,6 +257,7 @@ func (r *Repository) fetchBatchFallbackToEnclave(ctx context.Context, seqNo *big
 
 // RequestMissingBatches requests batches from peers from the specified sequence number.
 // It is an asynchronous request and the repository does not expect to be notified of the result.
+// The P2P service sends the request to the sequencer, or in gossip mode to one of the best scoring peers.
 func (r *Repository) requestMissingBatchesFromPeers(fromSeqNo *big.Int) {
 	r.p2pReqMutex.Lock()
 	defer r.p2pReqMutex.Unlock()
@@ -0,0 +1,314 @@
+package p2p
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/go/common/retry"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+)
+
+var errConnClosed = errors.New("connection closed")
+
+// authTransport keeps a long-lived connection to each peer, over which any number of framed messages are sent.
+// Every connection is authenticated with the L1 keys of both hosts when it is opened (see handshake), and every frame
+// sent over it carries a MAC with the keys of the session agreed in the handshake, so the messages are attributed to the
+// authenticated peer rather than to the sender they claim, and can't be injected into the connection by anyone else.
+//
+// The authenticated identities are checked with verifyPeer, and the connections of the peers which fail the check are
+// closed, so only the hosts registered on L1 can connect. The dialed peers must also claim the address they were dialed on.
+//
+// Messages are sent over the connections this host dialed, so a peer can't redirect the messages for another address
+// to itself by claiming that address in its handshake. Messages are read from all the connections.
+type authTransport struct {
+	privateKey    *ecdsa.PrivateKey
+	publicAddress string
+	timeout       time.Duration
+	maxFrameSize  int
+	verifyPeer    func(peer *peerIdentity) error
+
+	listener    net.Listener
+	handler     msgHandler
+	stopControl *stopcontrol.StopControl
+	logger      gethlog.Logger
+
+	connsMutex sync.Mutex
+	outbound   map[string]*peerConn   // the connections dialed by this host, by the address they were dialed on
+	inbound    map[*peerConn]struct{} // the connections dialed by the peers
+}
+
+func newAuthTransport(privateKeyString string, publicAddress string, timeout time.Duration, verifyPeer func(peer *peerIdentity) error, stopControl *stopcontrol.StopControl, logger gethlog.Logger) (*authTransport, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyString)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the host private key: %w", err)
+	}
+	return &authTransport{
+		privateKey:    privateKey,
+		publicAddress: publicAddress,
+		timeout:       timeout,
+		maxFrameSize:  _maxFrameSize,
+		verifyPeer:    verifyPeer,
+		stopControl:   stopControl,
+		logger:        logger,
+		outbound:      make(map[string]*peerConn),
+		inbound:       make(map[*peerConn]struct{}),
+	}, nil
+}
+
+func (t *authTransport) listen(bindAddress string, handler msgHandler) error {
+	t.handler = handler
+	listener, err := net.Listen(tcp, bindAddress)
+	if err != nil {
+		return fmt.Errorf("could not listen for P2P connections on %s: %w", bindAddress, err)
+	}
+	t.listener = listener
+	go t.acceptConnections()
+	return nil
+}
+
+func (t *authTransport) send(address string, encodedMsg []byte) error {
+	if t.stopControl.IsStopping() {
+		return retry.FailFast(errConnClosed)
+	}
+	if len(encodedMsg) > t.maxFrameSize {
+		return retry.FailFast(fmt.Errorf("message of %d bytes exceeds the maximum frame size of %d bytes", len(encodedMsg), t.maxFrameSize))
+	}
+	conn, err := t.connTo(address)
+	if err != nil {
+		return err
+	}
+	err = conn.write(encodedMsg, t.timeout, t.maxFrameSize)
+	if err != nil {
+		// the next message will redial the peer
+		t.logger.Debug("could not send message to peer", "peer", address, log.ErrKey, err)
+		t.closeConn(conn)
+		return err
+	}
+	return nil
+}
+
+func (t *authTransport) close() error {
+	var err error
+	if t.listener != nil {
+		err = t.listener.Close()
+	}
+	t.connsMutex.Lock()
+	conns := make([]*peerConn, 0, len(t.outbound)+len(t.inbound))
+	for _, conn := range t.outbound {
+		conns = append(conns, conn)
+	}
+	for conn := range t.inbound {
+		conns = append(conns, conn)
+	}
+	t.connsMutex.Unlock()
+	for _, conn := range conns {
+		t.closeConn(conn)
+	}
+	return err
+}
+
+// connTo returns the open connection to the address, dialing and authenticating a new one if there is none
+func (t *authTransport) connTo(address string) (*peerConn, error) {
+	t.connsMutex.Lock()
+	conn, ok := t.outbound[address]
+	t.connsMutex.Unlock()
+	if ok {
+		return conn, nil
+	}
+
+	netConn, err := net.DialTimeout(tcp, address, t.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn, err = t.authenticate(netConn)
+	if err != nil {
+		return nil, fmt.Errorf("could not authenticate peer %s: %w", address, err)
+	}
+	if conn.peer.Address != address {
+		_ = conn.close()
+		return nil, fmt.Errorf("peer dialed on %s claimed the address %s", address, conn.peer.Address)
+	}
+	conn.dialedAddress = address
+
+	t.connsMutex.Lock()
+	if existing, ok := t.outbound[address]; ok {
+		// another message dialed the peer concurrently, so we use its connection
+		t.connsMutex.Unlock()
+		_ = conn.close()
+		return existing, nil
+	}
+	t.outbound[address] = conn
+	t.connsMutex.Unlock()
+	if t.stopControl.IsStopping() {
+		// the transport was closed during the handshake
+		t.closeConn(conn)
+		return nil, retry.FailFast(errConnClosed)
+	}
+
+	t.logger.Debug("Opened P2P connection", "peer", address, "hostID", conn.peer.HostID)
+	go t.readMessages(conn)
+	return conn, nil
+}
+
+func (t *authTransport) acceptConnections() {
+	for !t.stopControl.IsStopping() {
+		// blocks here until a connection is made
+		netConn, err := t.listener.Accept()
+		if err != nil {
+			if !t.stopControl.IsStopping() {
+				t.logger.Debug("Could not form P2P connection", log.ErrKey, err)
+			}
+			return
+		}
+		go func() {
+			conn, err := t.authenticate(netConn)
+			if err != nil {
+				t.logger.Debug("Could not authenticate P2P connection", "remoteAddr", netConn.RemoteAddr(), log.ErrKey, err)
+				return
+			}
+			t.connsMutex.Lock()
+			t.inbound[conn] = struct{}{}
+			t.connsMutex.Unlock()
+			if t.stopControl.IsStopping() {
+				// the transport was closed during the handshake
+				t.closeConn(conn)
+				return
+			}
+			t.logger.Debug("Accepted P2P connection", "peer", conn.peer.Address, "hostID", conn.peer.HostID)
+			t.readMessages(conn)
+		}()
+	}
+}
+
+// authenticate runs the handshake on a new connection, closing it if the peer can't be authenticated or verified
+func (t *authTransport) authenticate(netConn net.Conn) (*peerConn, error) {
+	if tcpConn, ok := netConn.(*net.TCPConn); ok {
+		// detects the peers which disappeared without closing the connection
+		_ = tcpConn.SetKeepAlive(true)
+	}
+	conn := newPeerConn(netConn)
+	peer, session, err := handshake(netConn, conn.reader, conn.writer, t.privateKey, t.publicAddress, t.timeout)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	if err := t.verifyPeer(peer); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	conn.peer = *peer
+	conn.session = session
+	return conn, nil
+}
+
+// dropUnverified closes the connections of the peers which don't pass verifyPeer anymore
+func (t *authTransport) dropUnverified() {
+	t.connsMutex.Lock()
+	var dropped []*peerConn
+	for _, conn := range t.outbound {
+		if t.verifyPeer(&conn.peer) != nil {
+			dropped = append(dropped, conn)
+		}
+	}
+	for conn := range t.inbound {
+		if t.verifyPeer(&conn.peer) != nil {
+			dropped = append(dropped, conn)
+		}
+	}
+	t.connsMutex.Unlock()
+	for _, conn := range dropped {
+		t.logger.Info("Closing the P2P connection of a host which is not registered anymore", "peer", conn.peer.Address, "hostID", conn.peer.HostID)
+		t.closeConn(conn)
+	}
+}
+
+// readMessages passes the messages from the connection to the handler until the connection is closed
+func (t *authTransport) readMessages(conn *peerConn) {
+	defer t.closeConn(conn)
+	for {
+		frame, err := readFrame(conn.reader, t.maxFrameSize+_macSize)
+		if err != nil {
+			if !t.stopControl.IsStopping() && !conn.isClosed() {
+				t.logger.Debug("P2P connection closed", "peer", conn.peer.Address, log.ErrKey, err)
+			}
+			return
+		}
+		encodedMsg, err := conn.session.open(frame)
+		if err != nil {
+			t.logger.Warn("Closing the P2P connection which received an unauthenticated frame", "peer", conn.peer.Address, log.ErrKey, err)
+			return
+		}
+		t.handler(&conn.peer, encodedMsg)
+	}
+}
+
+func (t *authTransport) closeConn(conn *peerConn) {
+	t.connsMutex.Lock()
+	if conn.dialedAddress != "" && t.outbound[conn.dialedAddress] == conn {
+		delete(t.outbound, conn.dialedAddress)
+	}
+	delete(t.inbound, conn)
+	t.connsMutex.Unlock()
+	_ = conn.close()
+}
+
+// peerConn is an authenticated connection. The writes are serialised, so the frames of concurrent messages don't interleave
+type peerConn struct {
+	conn          net.Conn
+	reader        *bufio.Reader
+	writer        *bufio.Writer
+	peer          peerIdentity
+	session       *session
+	dialedAddress string // empty for the connections dialed by the peer
+
+	writeMutex sync.Mutex
+	closeOnce  sync.Once
+	closed     chan struct{}
+}
+
+func newPeerConn(conn net.Conn) *peerConn {
+	return &peerConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+		closed: make(chan struct{}),
+	}
+}
+
+func (c *peerConn) write(msg []byte, timeout time.Duration, maxFrameSize int) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if c.isClosed() {
+		return errConnClosed
+	}
+	if err := c.conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	return writeFrame(c.writer, c.session.seal(msg), maxFrameSize+_macSize)
+}
+
+func (c *peerConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *peerConn) close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.conn.Close()
+	})
+	return err
+}
@@ -0,0 +1,97 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/signature"
+)
+
+// errInvalidBatch is returned for the batches which aren't signed by a sequencer enclave, rather than when the
+// signer couldn't be checked
+var errInvalidBatch = errors.New("invalid batch")
+
+// how long the sequencer permission of an enclave read from L1 is trusted, which bounds how long a revoked sequencer
+// enclave can still get its batches relayed
+var _sequencerCacheTTL = time.Minute
+
+// sequencerRegistry answers whether an enclave is permissioned to sign batches
+type sequencerRegistry interface {
+	IsSequencerEnclave(enclaveID common.EnclaveID) (bool, error)
+}
+
+type sequencerPermission struct {
+	isSequencer bool
+	expiry      time.Time
+}
+
+// batchVerifier checks that the batches received from peers are signed by a sequencer enclave, so the forged batches
+// are dropped before they reach the enclaves. The enclaves verify the signatures again when they process the batches.
+type batchVerifier struct {
+	registry func() sequencerRegistry
+
+	mutex       sync.Mutex
+	permissions map[common.EnclaveID]sequencerPermission
+}
+
+func newBatchVerifier(registry func() sequencerRegistry) *batchVerifier {
+	return &batchVerifier{
+		registry:    registry,
+		permissions: make(map[common.EnclaveID]sequencerPermission),
+	}
+}
+
+func (v *batchVerifier) verify(batches []*common.ExtBatch) error {
+	for _, batch := range batches {
+		if err := v.verifyBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *batchVerifier) verifyBatch(batch *common.ExtBatch) error {
+	if batch.Header == nil {
+		return fmt.Errorf("%w: batch has no header", errInvalidBatch)
+	}
+	hash := batch.Hash()
+	if len(batch.Header.Signature) == 0 {
+		return fmt.Errorf("%w: batch %s is not signed", errInvalidBatch, hash)
+	}
+	// the recovery mutates the signature, which belongs to the batch
+	sig := make([]byte, len(batch.Header.Signature))
+	copy(sig, batch.Header.Signature)
+	signer, err := signature.RecoverAddress(hash.Bytes(), sig)
+	if err != nil {
+		return fmt.Errorf("%w: invalid signature on batch %s: %w", errInvalidBatch, hash, err)
+	}
+	isSequencer, err := v.isSequencer(*signer)
+	if err != nil {
+		return fmt.Errorf("could not check the signer of batch %s: %w", hash, err)
+	}
+	if !isSequencer {
+		return fmt.Errorf("%w: batch %s is signed by %s, which is not a sequencer enclave", errInvalidBatch, hash, signer)
+	}
+	return nil
+}
+
+func (v *batchVerifier) isSequencer(enclaveID common.EnclaveID) (bool, error) {
+	v.mutex.Lock()
+	permission, ok := v.permissions[enclaveID]
+	v.mutex.Unlock()
+	if ok && time.Now().Before(permission.expiry) {
+		return permission.isSequencer, nil
+	}
+
+	isSequencer, err := v.registry().IsSequencerEnclave(enclaveID)
+	if err != nil {
+		return false, err
+	}
+	v.mutex.Lock()
+	v.permissions[enclaveID] = sequencerPermission{isSequencer: isSequencer, expiry: time.Now().Add(_sequencerCacheTTL)}
+	v.mutex.Unlock()
+	return isSequencer, nil
+}
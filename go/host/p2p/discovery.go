@@ -0,0 +1,270 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/host"
+	"github.com/ten-protocol/go-ten/go/common/log"
+)
+
+// With discovery enabled the hosts build their peer table from the enclave registry on L1: the enclaves submit the P2P
+// address of their host with their attestation when they request the network secret, so adding a node to the network
+// doesn't require reconfiguring the others. Only the hosts of the attested enclaves are used as peers.
+//
+// The registry also records the L1 account which submitted each attestation, so with authenticated P2P the hosts only
+// accept the connections of the peers whose handshake matches a registered host (see verifyPeer). As the registry doesn't
+// check who submits a secret request, a host can't take over an enclave by requesting its secret again (see apply).
+//
+// The registry has no de-registration event, so the attested enclaves are checked against the registry on every refresh,
+// and the enclaves which aren't attested anymore are dropped with their host (unless it runs other attested enclaves).
+
+var (
+	_discoveryInterval   = 30 * time.Second
+	_discoveryBlockRange = int64(1000) // the number of L1 blocks the registry logs are requested for at once
+)
+
+// peerTable is the list of the enclaves registered on L1, with the address of the host they run behind
+type peerTable struct {
+	mutex    sync.RWMutex
+	peers    map[common.EnclaveID]*host.PeerInfo
+	disputed map[common.EnclaveID]bool // the enclaves whose secret was requested by several L1 accounts
+}
+
+func newPeerTable() *peerTable {
+	return &peerTable{
+		peers:    make(map[common.EnclaveID]*host.PeerInfo),
+		disputed: make(map[common.EnclaveID]bool),
+	}
+}
+
+// apply updates the table with a change to the enclave registry. It returns an error when the host of a secret request
+// is not recorded.
+//
+// Anyone can request the secret with a copy of the attestation report of an enclave, the registry only records which L1
+// account sent the request. So the requests for an enclave which is already attested are ignored, and an enclave whose
+// secret was requested by several L1 accounts gets no host, as it can't be known which request the response answered.
+func (t *peerTable) apply(event *common.EnclaveRegistryEvent) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	peer, ok := t.peers[event.EnclaveID]
+	if !ok {
+		peer = &host.PeerInfo{EnclaveID: event.EnclaveID}
+		t.peers[event.EnclaveID] = peer
+	}
+	var err error
+	switch {
+	case event.HostAddress == "":
+	case event.Type == common.SecretRequestTx && peer.IsAttested:
+		err = fmt.Errorf("ignoring the secret request of host %s for enclave %s, which is already attested", event.HostID, event.EnclaveID)
+	case t.disputed[event.EnclaveID]:
+		err = fmt.Errorf("ignoring the secret request of host %s for enclave %s, which was requested by several hosts", event.HostID, event.EnclaveID)
+	case event.Type == common.SecretRequestTx && peer.HostAddress != "" && peer.HostID != event.HostID:
+		err = fmt.Errorf("the secret of enclave %s was requested by hosts %s and %s, the enclave gets no host", event.EnclaveID, peer.HostID, event.HostID)
+		t.disputed[event.EnclaveID] = true
+		peer.HostAddress = ""
+		peer.HostID = gethcommon.Address{}
+	default:
+		peer.HostAddress = event.HostAddress
+		peer.HostID = event.HostID
+	}
+	switch event.Type {
+	case common.InitialiseSecretTx:
+		// the genesis enclave is attested when it initializes the network
+		peer.IsAttested = true
+	case common.SecretResponseTx:
+		peer.IsAttested = true
+	case common.SequencerAddedTx:
+		peer.IsSequencer = true
+	case common.SequencerRevokedTx:
+		peer.IsSequencer = false
+	}
+	peer.L1Block = event.BlockNumber
+	return err
+}
+
+func (t *peerTable) remove(enclaveID common.EnclaveID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.peers, enclaveID)
+}
+
+// list returns a copy of the entries, sorted by enclave ID
+func (t *peerTable) list() []*host.PeerInfo {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	peers := make([]*host.PeerInfo, 0, len(t.peers))
+	for _, peer := range t.peers {
+		peerCopy := *peer
+		peers = append(peers, &peerCopy)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].EnclaveID.Cmp(peers[j].EnclaveID) < 0
+	})
+	return peers
+}
+
+// addresses returns the distinct host addresses of the attested enclaves, and those of the sequencer enclaves
+func (t *peerTable) addresses() (map[string]bool, map[string]bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	all := make(map[string]bool)
+	sequencers := make(map[string]bool)
+	for _, peer := range t.peers {
+		if !peer.IsAttested || peer.HostAddress == "" {
+			continue
+		}
+		all[peer.HostAddress] = true
+		if peer.IsSequencer {
+			sequencers[peer.HostAddress] = true
+		}
+	}
+	return all, sequencers
+}
+
+// isRegistered returns whether the host runs an attested enclave which was registered with the address, by a
+// transaction signed with the L1 key of the host
+func (t *peerTable) isRegistered(address string, hostID gethcommon.Address) bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	for _, peer := range t.peers {
+		if peer.IsAttested && peer.HostAddress == address && peer.HostID == hostID {
+			return true
+		}
+	}
+	return false
+}
+
+// Peers returns the peer table discovered from the L1 enclave registry
+func (p *Service) Peers() []*host.PeerInfo {
+	return p.peerTable.list()
+}
+
+// discoverPeers keeps the peer table in sync with the L1 enclave registry until the host is stopped
+func (p *Service) discoverPeers() {
+	for {
+		select {
+		case <-p.stopControl.Done():
+			return
+		case <-time.After(_discoveryInterval):
+		}
+		if err := p.refreshPeerTable(); err != nil {
+			p.logger.Warn("Could not refresh the peer table from L1", log.ErrKey, err)
+		}
+	}
+}
+
+// verifyPeer checks the identity authenticated by the handshake of a connection against the peer table, so only the
+// hosts of the attested enclaves can connect, with the address and the L1 key they were registered with
+func (p *Service) verifyPeer(peer *peerIdentity) error {
+	if !p.peerTable.isRegistered(peer.Address, peer.HostID) {
+		return fmt.Errorf("host %s with address %s is not registered in the enclave registry", peer.HostID, peer.Address)
+	}
+	return nil
+}
+
+// refreshPeerTable reads the registry events since the last refresh, drops the enclaves which aren't attested anymore,
+// and updates the peers the messages are sent to
+func (p *Service) refreshPeerTable() error {
+	l1Data := p.sl.L1Data()
+	if p.nextDiscoveryHeight == nil {
+		start := big.NewInt(0)
+		if p.l1StartHash != (gethcommon.Hash{}) {
+			startBlock, err := l1Data.FetchBlock(context.Background(), p.l1StartHash)
+			if err != nil {
+				return fmt.Errorf("could not fetch the L1 start block: %w", err)
+			}
+			start = startBlock.Number
+		}
+		p.nextDiscoveryHeight = start
+	}
+	head, err := l1Data.FetchBlockByHeight(nil)
+	if err != nil {
+		return fmt.Errorf("could not fetch the L1 head: %w", err)
+	}
+
+	for p.nextDiscoveryHeight.Cmp(head.Number) <= 0 {
+		to := new(big.Int).Add(p.nextDiscoveryHeight, big.NewInt(_discoveryBlockRange-1))
+		if to.Cmp(head.Number) > 0 {
+			to = head.Number
+		}
+		events, err := l1Data.FetchEnclaveRegistryEvents(p.nextDiscoveryHeight, to)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := p.peerTable.apply(event); err != nil {
+				p.logger.Warn("Could not record the host of an enclave registry event", log.ErrKey, err)
+			}
+		}
+		p.nextDiscoveryHeight = new(big.Int).Add(to, big.NewInt(1))
+	}
+
+	for _, peer := range p.peerTable.list() {
+		if !peer.IsAttested {
+			continue
+		}
+		isAttested, err := p.sl.L1Publisher().IsEnclaveAttested(peer.EnclaveID)
+		if err != nil {
+			return fmt.Errorf("could not check the attestation of enclave %s: %w", peer.EnclaveID, err)
+		}
+		if !isAttested {
+			p.logger.Info("Dropping peer whose enclave is not attested anymore", "enclaveID", peer.EnclaveID, "peer", peer.HostAddress)
+			p.peerTable.remove(peer.EnclaveID)
+		}
+	}
+
+	p.syncDiscoveredPeers()
+	if authTransport, ok := p.transport.(*authTransport); ok {
+		authTransport.dropUnverified()
+	}
+	return nil
+}
+
+// syncDiscoveredPeers adds the hosts of the attested enclaves to the peers, and removes the hosts which were dropped from
+// the table. The validators also follow the sequencer host when the registry has a single one.
+func (p *Service) syncDiscoveredPeers() {
+	discovered, sequencers := p.peerTable.addresses()
+	delete(discovered, p.ourPublicAddress)
+	delete(sequencers, p.ourPublicAddress)
+
+	p.peerAddressesMutex.Lock()
+	for address := range p.discoveredAddresses {
+		if !discovered[address] {
+			delete(p.peerAddresses, address)
+		}
+	}
+	p.discoveredAddresses = discovered
+	if !p.isActiveSequencer() && len(sequencers) == 1 {
+		for address := range sequencers {
+			if address != p.sequencerAddress {
+				p.logger.Info("Using the sequencer host discovered from L1", "sequencer", address)
+				p.sequencerAddress = address
+			}
+		}
+	}
+	p.peerAddressesMutex.Unlock()
+
+	addresses := make([]string, 0, len(discovered))
+	for address := range discovered {
+		addresses = append(addresses, address)
+	}
+	if p.isActiveSequencer() {
+		// the sequencer broadcasts to every discovered host, without waiting for them to register
+		p.peerAddressesMutex.Lock()
+		for _, address := range addresses {
+			if _, ok := p.peerAddresses[address]; !ok {
+				p.peerAddresses[address] = 0
+			}
+		}
+		p.peerAddressesMutex.Unlock()
+	} else if p.isGossipEnabled {
+		p.addPeers(addresses)
+	}
+}
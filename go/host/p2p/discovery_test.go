@@ -0,0 +1,134 @@
+package p2p
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/host"
+	hostconfig "github.com/ten-protocol/go-ten/go/host/config"
+)
+
+// serves the enclave registry events up to the head
+type testL1Data struct {
+	host.L1DataService
+	head   int64
+	events []*common.EnclaveRegistryEvent
+}
+
+func (d *testL1Data) FetchBlockByHeight(height *big.Int) (*types.Header, error) {
+	if height == nil {
+		height = big.NewInt(d.head)
+	}
+	return &types.Header{Number: height}, nil
+}
+
+func (d *testL1Data) FetchBlock(context.Context, common.L1BlockHash) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(0)}, nil
+}
+
+func (d *testL1Data) FetchEnclaveRegistryEvents(fromHeight *big.Int, toHeight *big.Int) ([]*common.EnclaveRegistryEvent, error) {
+	var events []*common.EnclaveRegistryEvent
+	for _, event := range d.events {
+		if event.BlockNumber >= fromHeight.Uint64() && event.BlockNumber <= toHeight.Uint64() {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func TestPeerDiscovery(t *testing.T) {
+	seqEnclave := gethcommon.HexToAddress("0x1")
+	validatorEnclave := gethcommon.HexToAddress("0x2")
+	newValidatorEnclave := gethcommon.HexToAddress("0x3")
+	l1Data := &testL1Data{head: 2000, events: []*common.EnclaveRegistryEvent{
+		{Type: common.InitialiseSecretTx, EnclaveID: seqEnclave, HostAddress: "sequencer:10000", BlockNumber: 1},
+		{Type: common.SequencerAddedTx, EnclaveID: seqEnclave, BlockNumber: 1},
+		{Type: common.SecretRequestTx, EnclaveID: validatorEnclave, HostAddress: "validator1:10000", BlockNumber: 2},
+		{Type: common.SecretResponseTx, EnclaveID: validatorEnclave, BlockNumber: 3},
+		// the registry logs are read in several ranges
+		{Type: common.SecretRequestTx, EnclaveID: newValidatorEnclave, HostAddress: "validator2:10000", BlockNumber: 1500},
+	}}
+	publisher := &testPublisher{deregistered: map[common.EnclaveID]bool{}}
+	locator := &testServiceLocator{publisher: publisher, l1Data: l1Data}
+	withDiscovery := func(cfg *hostconfig.HostConfig) {
+		// the hosts aren't started, so they don't register their identity
+		cfg.IsP2PAuthenticated = false
+		cfg.IsP2PDiscoveryEnabled = true
+		cfg.IsP2PGossipEnabled = true
+	}
+
+	sequencer := newTestHost(t, common.Sequencer, "sequencer:10000", "sequencer:10000", locator, withDiscovery)
+	// the validator isn't configured with the sequencer address
+	validator := newTestHost(t, common.Validator, "validator2:10000", "", locator, withDiscovery)
+
+	require.NoError(t, sequencer.refreshPeerTable())
+	require.NoError(t, validator.refreshPeerTable())
+
+	peers := sequencer.Peers()
+	require.Len(t, peers, 3)
+	require.Equal(t, &host.PeerInfo{EnclaveID: seqEnclave, HostAddress: "sequencer:10000", IsAttested: true, IsSequencer: true, L1Block: 1}, peers[0])
+	require.Equal(t, &host.PeerInfo{EnclaveID: validatorEnclave, HostAddress: "validator1:10000", IsAttested: true, L1Block: 3}, peers[1])
+	require.False(t, peers[2].IsAttested)
+
+	// the enclaves are only used as peers once they are attested
+	require.Equal(t, map[string]int{"validator1:10000": 0}, sequencer.peerAddresses)
+	require.Equal(t, "sequencer:10000", validator.getSequencer())
+	require.Equal(t, map[string]int{"validator1:10000": 0}, validator.peerAddresses)
+
+	t.Run("attested enclaves are added", func(t *testing.T) {
+		l1Data.events = append(l1Data.events, &common.EnclaveRegistryEvent{Type: common.SecretResponseTx, EnclaveID: newValidatorEnclave, BlockNumber: 2100})
+		l1Data.head = 2100
+		require.NoError(t, sequencer.refreshPeerTable())
+		require.Equal(t, map[string]int{"validator1:10000": 0, "validator2:10000": 0}, sequencer.peerAddresses)
+	})
+
+	t.Run("de-registered enclaves are dropped", func(t *testing.T) {
+		publisher.deregistered[validatorEnclave] = true
+		require.NoError(t, sequencer.refreshPeerTable())
+		require.NoError(t, validator.refreshPeerTable())
+		require.Len(t, sequencer.Peers(), 2)
+		require.Equal(t, map[string]int{"validator2:10000": 0}, sequencer.peerAddresses)
+		require.Empty(t, validator.peerAddresses)
+	})
+
+	t.Run("revoked sequencer enclaves are updated", func(t *testing.T) {
+		l1Data.events = append(l1Data.events, &common.EnclaveRegistryEvent{Type: common.SequencerRevokedTx, EnclaveID: seqEnclave, BlockNumber: 2200})
+		l1Data.head = 2200
+		require.NoError(t, sequencer.refreshPeerTable())
+		require.False(t, sequencer.Peers()[0].IsSequencer)
+		require.Equal(t, uint64(2200), sequencer.Peers()[0].L1Block)
+	})
+}
+
+func TestPeerTableSecretRequests(t *testing.T) {
+	enclave := gethcommon.HexToAddress("0x1")
+	disputedEnclave := gethcommon.HexToAddress("0x2")
+	hostID := gethcommon.HexToAddress("0xa")
+	attackerID := gethcommon.HexToAddress("0xb")
+	table := newPeerTable()
+
+	// the host may request the secret again, e.g. after a restart
+	require.NoError(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretRequestTx, EnclaveID: enclave, HostAddress: "host:10000", HostID: hostID, BlockNumber: 1}))
+	require.NoError(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretRequestTx, EnclaveID: enclave, HostAddress: "host:10001", HostID: hostID, BlockNumber: 2}))
+	require.NoError(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretResponseTx, EnclaveID: enclave, BlockNumber: 3}))
+
+	// another account replays the attestation of the attested enclave with its own key and address
+	require.Error(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretRequestTx, EnclaveID: enclave, HostAddress: "attacker:10000", HostID: attackerID, BlockNumber: 4}))
+	require.True(t, table.isRegistered("host:10001", hostID))
+	require.False(t, table.isRegistered("attacker:10000", attackerID))
+
+	// the enclave requested by two accounts before it was attested gets no host
+	require.NoError(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretRequestTx, EnclaveID: disputedEnclave, HostAddress: "host2:10000", HostID: hostID, BlockNumber: 5}))
+	require.Error(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretRequestTx, EnclaveID: disputedEnclave, HostAddress: "attacker:10000", HostID: attackerID, BlockNumber: 6}))
+	require.Error(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretRequestTx, EnclaveID: disputedEnclave, HostAddress: "host2:10000", HostID: hostID, BlockNumber: 7}))
+	require.NoError(t, table.apply(&common.EnclaveRegistryEvent{Type: common.SecretResponseTx, EnclaveID: disputedEnclave, BlockNumber: 8}))
+	require.False(t, table.isRegistered("host2:10000", hostID))
+	require.False(t, table.isRegistered("attacker:10000", attackerID))
+	all, _ := table.addresses()
+	require.Equal(t, map[string]bool{"host:10001": true}, all)
+}
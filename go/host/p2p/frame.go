@@ -0,0 +1,46 @@
+package p2p
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const _frameHeaderSize = 4
+
+var _maxFrameSize = 32 * 1024 * 1024 // messages larger than this are rejected, and the connection they arrived on is closed
+
+// writeFrame writes the message prefixed by its length, so many messages can share a connection
+func writeFrame(w *bufio.Writer, msg []byte, maxSize int) error {
+	if len(msg) > maxSize {
+		return fmt.Errorf("message of %d bytes exceeds the maximum frame size of %d bytes", len(msg), maxSize)
+	}
+	var header [_frameHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(msg)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// readFrame reads the next message. The size is checked before the message is read, so a peer can't make us allocate
+// more than maxSize bytes
+func readFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	var header [_frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if uint64(size) > uint64(maxSize) {
+		return nil, fmt.Errorf("frame of %d bytes exceeds the maximum frame size of %d bytes", size, maxSize)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,185 @@
+package p2p
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/host"
+	"github.com/ten-protocol/go-ten/go/common/log"
+)
+
+// In gossip mode the sequencer sends each new batch to a few random validators, and the validators relay the batches
+// to a few random peers once their enclave validated them. The validators learn about each other from the L1 enclave
+// registry (see discovery), or from the sequencer when they register for broadcasts, and serve each other's batch requests.
+// The peers shared by the sequencer are only accepted over an authenticated connection, and the validators which relay
+// batches are only added back as peers when they are registered, so the peer tables can't be filled by spoofed hosts.
+
+var (
+	_defaultGossipFanout   = 4
+	_gossipSeenBatches     = 4096 // the number of recent batch hashes remembered to drop the duplicates
+	_maxGossipPeers        = 64   // validators stop adding peers to their table at this size
+	_maxPeersInMsg         = 32   // the number of peers the sequencer shares with a registering validator
+	_gossipBatchRate       = 20.0 // the live batch messages accepted from a peer per second
+	_gossipBatchBurst      = 100
+	_gossipBatchReqRate    = 1.0 // the batch requests served for a peer per second
+	_gossipBatchReqBurst   = 5
+	_gossipPeerRefreshSize = 2 // validators ask the sequencer for more peers when they know fewer than fanout * this
+)
+
+// the list of peers shared by the sequencer with the validators in gossip mode
+type peersMsg struct {
+	Addresses []string
+}
+
+type seenBatch struct {
+	from    string // the peer the batch was first received from, which it isn't relayed back to
+	relayed bool
+}
+
+type gossip struct {
+	fanout int
+
+	mutex       sync.Mutex // makes the check and the update of the seen batches atomic
+	seenBatches *lru.BasicLRU[common.L2BatchHash, *seenBatch]
+
+	batchLimiter    *peerRateLimiter
+	batchReqLimiter *peerRateLimiter
+}
+
+func newGossip(fanout int) *gossip {
+	if fanout <= 0 {
+		fanout = _defaultGossipFanout
+	}
+	seen := lru.NewBasicLRU[common.L2BatchHash, *seenBatch](_gossipSeenBatches)
+	return &gossip{
+		fanout:          fanout,
+		seenBatches:     &seen,
+		batchLimiter:    newPeerRateLimiter(_gossipBatchRate, _gossipBatchBurst),
+		batchReqLimiter: newPeerRateLimiter(_gossipBatchReqRate, _gossipBatchReqBurst),
+	}
+}
+
+// unseen returns the batches which weren't received before
+func (g *gossip) unseen(batches []*common.ExtBatch) []*common.ExtBatch {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	unseen := make([]*common.ExtBatch, 0, len(batches))
+	for _, batch := range batches {
+		if !g.seenBatches.Contains(batch.Hash()) {
+			unseen = append(unseen, batch)
+		}
+	}
+	return unseen
+}
+
+// markSeen records the verified live batches, which will be relayed once they are validated
+func (g *gossip) markSeen(batches []*common.ExtBatch, from string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, batch := range batches {
+		if !g.seenBatches.Contains(batch.Hash()) {
+			g.seenBatches.Add(batch.Hash(), &seenBatch{from: from})
+		}
+	}
+}
+
+// toRelay returns whether the batch was received live and not relayed yet, and the peer it was received from
+func (g *gossip) toRelay(hash common.L2BatchHash) (bool, string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	seen, ok := g.seenBatches.Get(hash)
+	if !ok || seen.relayed {
+		return false, ""
+	}
+	seen.relayed = true
+	return true, seen.from
+}
+
+// HandleBatch is called when the enclave validated a batch. In gossip mode the validators relay the batches they
+// received live to a few random peers, so the batches spread without all the validators receiving them from the sequencer.
+// The batches caught up from requests or rollups are not relayed.
+func (p *Service) HandleBatch(batch *common.ExtBatch) {
+	shouldRelay, from := p.gossip.toRelay(batch.Hash())
+	if !shouldRelay {
+		return
+	}
+	encodedBatchMsg, err := rlp.EncodeToBytes(host.BatchMsg{Batches: []*common.ExtBatch{batch}, IsLive: true})
+	if err != nil {
+		p.logger.Error("could not encode batch to relay", log.ErrKey, err)
+		return
+	}
+	msg := message{Sender: p.ourPublicAddress, Type: msgTypeBatches, Contents: encodedBatchMsg}
+	if err := p.broadcast(msg, p.randomPeers(p.gossip.fanout, from)); err != nil {
+		p.logger.Warn("could not relay batch", log.BatchHashKey, batch.Hash(), log.ErrKey, err)
+	}
+}
+
+// randomPeers returns up to n of the known peers in a random order, excluding the given address and the banned peers
+func (p *Service) randomPeers(n int, exclude string) []string {
+	p.peerAddressesMutex.RLock()
+	addresses := make([]string, 0, len(p.peerAddresses))
+	for address := range p.peerAddresses {
+		if address != exclude {
+			addresses = append(addresses, address)
+		}
+	}
+	p.peerAddressesMutex.RUnlock()
+	peers := addresses[:0]
+	for _, address := range addresses {
+		if !p.isBanned(address) {
+			peers = append(peers, address)
+		}
+	}
+
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > n {
+		peers = peers[:n]
+	}
+	return peers
+}
+
+// sendPeers - the sequencer shares some of the registered validators with a validator which registered for broadcasts
+func (p *Service) sendPeers(to string) {
+	encodedPeers, err := rlp.EncodeToBytes(peersMsg{Addresses: p.randomPeers(_maxPeersInMsg, to)})
+	if err != nil {
+		p.logger.Error("could not encode peers", log.ErrKey, err)
+		return
+	}
+	err = p.send(message{Sender: p.ourPublicAddress, Type: msgTypePeers, Contents: encodedPeers}, to)
+	if err != nil {
+		p.logger.Debug("could not send peers", "peer", to, log.ErrKey, err)
+	}
+}
+
+// addPeers - the validators add the discovered peers, or the peers shared by the sequencer, to their table
+func (p *Service) addPeers(addresses []string) {
+	p.peerAddressesMutex.Lock()
+	defer p.peerAddressesMutex.Unlock()
+	for _, address := range addresses {
+		if len(p.peerAddresses) >= _maxGossipPeers {
+			return
+		}
+		if address == "" || address == p.ourPublicAddress || address == p.sequencerAddress {
+			continue
+		}
+		if _, ok := p.peerAddresses[address]; !ok {
+			p.peerAddresses[address] = 0
+		}
+	}
+}
+
+// isDiscoveredPeer returns whether the address is the host of an attested enclave in the L1 enclave registry
+func (p *Service) isDiscoveredPeer(address string) bool {
+	p.peerAddressesMutex.RLock()
+	defer p.peerAddressesMutex.RUnlock()
+	return p.discoveredAddresses[address]
+}
+
+func (p *Service) numPeers() int {
+	p.peerAddressesMutex.RLock()
+	defer p.peerAddressesMutex.RUnlock()
+	return len(p.peerAddresses)
+}
@@ -0,0 +1,164 @@
+package p2p
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/host"
+	hostconfig "github.com/ten-protocol/go-ten/go/host/config"
+)
+
+// validates the batches as soon as they arrive, which makes the validator relay them
+type relayingBatchHandler struct {
+	service *Service
+	batches chan *common.ExtBatch
+}
+
+func (h *relayingBatchHandler) HandleBatches(batches []*common.ExtBatch, _ bool) {
+	for _, batch := range batches {
+		h.batches <- batch
+		go h.service.HandleBatch(batch)
+	}
+}
+
+type respondingBatchRequestHandler struct {
+	service *Service
+	batches []*common.ExtBatch
+}
+
+func (h *respondingBatchRequestHandler) HandleBatchRequest(requestID string, _ *big.Int) {
+	_ = h.service.RespondToBatchRequest(requestID, h.batches)
+}
+
+func TestGossip(t *testing.T) {
+	sequencerEnclaveKey := newKey(t)
+	locator := &testServiceLocator{publisher: &testPublisher{sequencers: map[common.EnclaveID]bool{
+		crypto.PubkeyToAddress(sequencerEnclaveKey.PublicKey): true,
+	}}}
+	withGossip := func(cfg *hostconfig.HostConfig) {
+		cfg.IsP2PGossipEnabled = true
+		cfg.P2PGossipFanout = 2
+	}
+
+	seqAddress := freeAddress(t)
+	sequencer := newTestHost(t, common.Sequencer, seqAddress, seqAddress, locator, withGossip)
+	validators := make([]*Service, 3)
+	for i := range validators {
+		validators[i] = newTestHost(t, common.Validator, freeAddress(t), seqAddress, locator, withGossip)
+	}
+
+	require.NoError(t, sequencer.Start())
+	defer sequencer.Stop() //nolint:errcheck
+	handlers := make([]*relayingBatchHandler, 3)
+	for i := range validators {
+		handlers[i] = &relayingBatchHandler{service: validators[i], batches: make(chan *common.ExtBatch, 10)}
+		validators[i].SubscribeForBatches(handlers[i])
+		require.NoError(t, validators[i].Start())
+		defer validators[i].Stop() //nolint:errcheck
+	}
+
+	// the validators registered first only learn about the others when they register again
+	require.Eventually(t, func() bool { return sequencer.numPeers() == len(validators) }, 5*time.Second, 10*time.Millisecond)
+	for _, validator := range validators {
+		require.NoError(t, validator.RegisterForBroadcasts())
+	}
+	for _, validator := range validators {
+		require.Eventually(t, func() bool { return validator.numPeers() == len(validators)-1 }, 5*time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("batches reach all the validators once", func(t *testing.T) {
+		batch := signedBatch(t, sequencerEnclaveKey, 1)
+		require.NoError(t, sequencer.BroadcastBatches([]*common.ExtBatch{batch}))
+		for i, handler := range handlers {
+			select {
+			case received := <-handler.batches:
+				require.Equal(t, batch.Hash(), received.Hash())
+			case <-time.After(5 * time.Second):
+				t.Fatalf("batch not received by validator %d", i)
+			}
+		}
+		time.Sleep(time.Second)
+		for i, handler := range handlers {
+			require.Empty(t, handler.batches, "duplicate batch received by validator %d", i)
+		}
+	})
+
+	t.Run("validators serve batch requests", func(t *testing.T) {
+		batch := signedBatch(t, sequencerEnclaveKey, 2)
+		validators[1].SubscribeForBatchRequests(&respondingBatchRequestHandler{service: validators[1], batches: []*common.ExtBatch{batch}})
+
+		encodedRequest, err := rlp.EncodeToBytes(&common.BatchRequest{Requester: validators[0].ourPublicAddress, FromSeqNo: big.NewInt(2)})
+		require.NoError(t, err)
+		msg := message{Sender: validators[0].ourPublicAddress, Type: msgTypeBatchRequest, Contents: encodedRequest}
+		require.NoError(t, validators[0].send(msg, validators[1].ourPublicAddress))
+
+		select {
+		case received := <-handlers[0].batches:
+			require.Equal(t, batch.Hash(), received.Hash())
+		case <-time.After(5 * time.Second):
+			t.Fatal("batch response not received")
+		}
+	})
+}
+
+func TestGossipPeersConfirmed(t *testing.T) {
+	sequencerEnclaveKey := newKey(t)
+	locator := &testServiceLocator{publisher: &testPublisher{sequencers: map[common.EnclaveID]bool{
+		crypto.PubkeyToAddress(sequencerEnclaveKey.PublicKey): true,
+	}}}
+	validator := newTestHost(t, common.Validator, "validator:10000", "sequencer:10000", locator, func(cfg *hostconfig.HostConfig) {
+		cfg.IsP2PAuthenticated = false
+		cfg.IsP2PGossipEnabled = true
+	})
+	validator.SubscribeForBatches(&testBatchHandler{batches: make(chan []*common.ExtBatch, 10)})
+	encode := func(sender string, msgType msgType, contents any) []byte {
+		encodedContents, err := rlp.EncodeToBytes(contents)
+		require.NoError(t, err)
+		encoded, err := rlp.EncodeToBytes(message{Sender: sender, Type: msgType, Contents: encodedContents})
+		require.NoError(t, err)
+		return encoded
+	}
+
+	t.Run("peers are only accepted from the authenticated sequencer", func(t *testing.T) {
+		peers := peersMsg{Addresses: []string{"spoofed:10000"}}
+		validator.handle(nil, encode("sequencer:10000", msgTypePeers, peers))
+		validator.handle(&peerIdentity{Address: "relayer:10000"}, encode("relayer:10000", msgTypePeers, peers))
+		require.Zero(t, validator.numPeers())
+
+		validator.handle(&peerIdentity{Address: "sequencer:10000"}, encode("sequencer:10000", msgTypePeers, peers))
+		require.Equal(t, 1, validator.numPeers())
+	})
+
+	t.Run("relayers are only added when they are registered", func(t *testing.T) {
+		batchMsg := func(seqNo int64) *host.BatchMsg {
+			return &host.BatchMsg{Batches: []*common.ExtBatch{signedBatch(t, sequencerEnclaveKey, seqNo)}, IsLive: true}
+		}
+		validator.handle(nil, encode("relayer:10000", msgTypeBatches, batchMsg(1)))
+		require.Equal(t, 1, validator.numPeers())
+
+		validator.peerAddressesMutex.Lock()
+		validator.discoveredAddresses = map[string]bool{"relayer:10000": true}
+		validator.peerAddressesMutex.Unlock()
+		validator.handle(nil, encode("relayer:10000", msgTypeBatches, batchMsg(2)))
+		require.Equal(t, 2, validator.numPeers())
+	})
+}
+
+func TestPeerRateLimiter(t *testing.T) {
+	limiter := newPeerRateLimiter(1, 3)
+	for i := 0; i < 3; i++ {
+		require.True(t, limiter.allow("peer1"))
+	}
+	require.False(t, limiter.allow("peer1"))
+	// the peers have separate buckets
+	require.True(t, limiter.allow("peer2"))
+
+	time.Sleep(1100 * time.Millisecond)
+	require.True(t, limiter.allow("peer1"))
+	require.False(t, limiter.allow("peer1"))
+}
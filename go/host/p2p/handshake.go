@@ -0,0 +1,130 @@
+package p2p
+
+import (
+	"bufio"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ten-protocol/go-ten/go/common/signature"
+)
+
+const (
+	_handshakeVersion      = 2
+	_handshakeNonceSize    = 32
+	_maxHandshakeFrameSize = 1024
+)
+
+// prefixed to the signed handshake data, so the signature can't be replayed in another context
+var _handshakeDomain = []byte("ten-p2p-handshake")
+
+// peerIdentity is the authenticated identity of the host at the other end of a connection
+type peerIdentity struct {
+	Address string             // the public P2P address of the peer, which it uses as sender of its messages
+	HostID  gethcommon.Address // the address of the L1 key of the peer host
+}
+
+// sent first by both sides of a connection. The session key is an ephemeral X25519 public key, from which the keys
+// authenticating the frames of the connection are derived
+type handshakeHello struct {
+	Version    uint
+	Nonce      []byte
+	SessionKey []byte
+}
+
+// sent by both sides after the hellos. The signature is over the nonce of the other side, so it can't be replayed, and
+// over the session key of the signer, so a man in the middle can't replace it with its own
+type handshakeAuth struct {
+	Address   string
+	Signature []byte
+}
+
+// handshake authenticates both ends of a new connection with the L1 keys of the hosts, and agrees on the session which
+// authenticates the frames sent after it. It is symmetric, so it is run the same way by the host that dialed and the
+// host that accepted the connection.
+func handshake(conn net.Conn, r *bufio.Reader, w *bufio.Writer, key *ecdsa.PrivateKey, ourAddress string, timeout time.Duration) (*peerIdentity, *session, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, nil, err
+	}
+	// the connection is long-lived, so the deadline only applies to the handshake
+	defer conn.SetDeadline(time.Time{}) //nolint:errcheck
+
+	ourNonce := make([]byte, _handshakeNonceSize)
+	if _, err := rand.Read(ourNonce); err != nil {
+		return nil, nil, fmt.Errorf("could not generate handshake nonce: %w", err)
+	}
+	sessionKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate session key: %w", err)
+	}
+	ourSessionKey := sessionKey.PublicKey().Bytes()
+	if err := writeRLPFrame(w, &handshakeHello{Version: _handshakeVersion, Nonce: ourNonce, SessionKey: ourSessionKey}); err != nil {
+		return nil, nil, fmt.Errorf("could not send handshake hello: %w", err)
+	}
+	var hello handshakeHello
+	if err := readRLPFrame(r, &hello); err != nil {
+		return nil, nil, fmt.Errorf("could not read handshake hello: %w", err)
+	}
+	if hello.Version != _handshakeVersion {
+		return nil, nil, fmt.Errorf("unsupported handshake version %d", hello.Version)
+	}
+	if len(hello.Nonce) != _handshakeNonceSize {
+		return nil, nil, fmt.Errorf("invalid handshake nonce length %d", len(hello.Nonce))
+	}
+
+	peerSessionKey, err := ecdh.X25519().NewPublicKey(hello.SessionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid handshake session key: %w", err)
+	}
+
+	sig, err := signature.Sign(handshakeHash(hello.Nonce, ourAddress, ourSessionKey), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeRLPFrame(w, &handshakeAuth{Address: ourAddress, Signature: sig}); err != nil {
+		return nil, nil, fmt.Errorf("could not send handshake auth: %w", err)
+	}
+	var auth handshakeAuth
+	if err := readRLPFrame(r, &auth); err != nil {
+		return nil, nil, fmt.Errorf("could not read handshake auth: %w", err)
+	}
+	if auth.Address == "" {
+		return nil, nil, fmt.Errorf("peer did not provide its address")
+	}
+	hostID, err := signature.RecoverAddress(handshakeHash(ourNonce, auth.Address, hello.SessionKey), auth.Signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid handshake signature: %w", err)
+	}
+
+	secret, err := sessionKey.ECDH(peerSessionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not derive the session secret: %w", err)
+	}
+	return &peerIdentity{Address: auth.Address, HostID: *hostID}, newSession(secret, ourNonce, hello.Nonce), nil
+}
+
+func handshakeHash(nonce []byte, address string, sessionKey []byte) []byte {
+	return crypto.Keccak256(_handshakeDomain, nonce, []byte(address), sessionKey)
+}
+
+func writeRLPFrame(w *bufio.Writer, val any) error {
+	encoded, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	return writeFrame(w, encoded, _maxHandshakeFrameSize)
+}
+
+func readRLPFrame(r *bufio.Reader, val any) error {
+	encoded, err := readFrame(r, _maxHandshakeFrameSize)
+	if err != nil {
+		return err
+	}
+	return rlp.DecodeBytes(encoded, val)
+}
@@ -3,12 +3,14 @@ package p2p
 import (
 	"context"
 	"fmt"
-	"io"
 	"math/big"
-	"net"
+	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 	"github.com/ten-protocol/go-ten/go/common/measure"
 	"github.com/ten-protocol/go-ten/go/common/retry"
@@ -32,15 +34,17 @@ const (
 	msgTypeBatches
 	msgTypeBatchRequest
 	msgTypeRegisterForBroadcasts
+	msgTypePeers
 	// bounds for msgType validation (must update if adding new type)
 	_minMsgType = msgTypeTx
-	_maxMsgType = msgTypeRegisterForBroadcasts
+	_maxMsgType = msgTypePeers
 )
 
 var (
 	_alertPeriod             = 5 * time.Minute
 	_maxPeerFailures         = 3               // peer removed from broadcast pool after this many failures
 	_maxWaitWithoutBroadcast = 2 * time.Minute // validators will re-register for broadcasts after this period of silence
+	_preferredRequestTargets = 3               // batch requests go to one of this many best scoring peers
 )
 
 // A P2P message's type.
@@ -48,7 +52,7 @@ type msgType uint8
 
 // Associates an encoded message to its type.
 type message struct {
-	Sender   string // todo (#1619) - this needs to be authed in the future
+	Sender   string // only trusted when the transport authenticates the peers
 	Type     msgType
 	Contents []byte
 }
@@ -56,17 +60,35 @@ type message struct {
 type p2pServiceLocator interface {
 	L1Publisher() host.L1Publisher
 	L2Repo() host.L2BatchRepository
+	L1Data() host.L1DataService
 }
 
 // NewSocketP2PLayer - returns the Socket implementation of the P2P
+// When config.IsP2PAuthenticated is set, the hosts keep authenticated, long-lived connections to each other rather
+// than opening a connection for every message.
+// When config.IsP2PGossipEnabled is set, the validators relay the new batches to each other (see gossip).
+// When config.IsP2PDiscoveryEnabled is set, the peers are discovered from the L1 enclave registry (see discovery).
 func NewSocketP2PLayer(config *hostconfig.HostConfig, serviceLocator p2pServiceLocator, logger gethlog.Logger, metricReg gethmetrics.Registry) *Service {
+	stopControl := stopcontrol.New()
 	return &Service{
 		batchSubscribers: subscription.NewManager[host.P2PBatchHandler](),
 		txSubscribers:    subscription.NewManager[host.P2PTxHandler](),
 		batchReqHandlers: subscription.NewManager[host.P2PBatchRequestHandler](),
 
-		stopControl: stopcontrol.New(),
+		stopControl: stopControl,
 		sl:          serviceLocator,
+		transport:   newSocketTransport(config.P2PConnectionTimeout, stopControl, logger),
+		batchVerifier: newBatchVerifier(func() sequencerRegistry {
+			return serviceLocator.L1Publisher()
+		}),
+		isAuthenticated:  config.IsP2PAuthenticated,
+		privateKeyString: config.PrivateKeyString,
+		isGossipEnabled:  config.IsP2PGossipEnabled,
+		gossip:           newGossip(config.P2PGossipFanout),
+
+		isDiscoveryEnabled: config.IsP2PDiscoveryEnabled,
+		peerTable:          newPeerTable(),
+		l1StartHash:        config.L1StartHash,
 
 		isSequencer:      config.NodeType == common.Sequencer,
 		ourBindAddress:   config.P2PBindAddress,
@@ -79,6 +101,7 @@ func NewSocketP2PLayer(config *hostconfig.HostConfig, serviceLocator p2pServiceL
 
 		// monitoring
 		peerTracker:     newPeerTracker(),
+		scorer:          newPeerScorer(metricReg, logger),
 		metricsRegistry: metricReg,
 		logger:          logger,
 
@@ -91,18 +114,31 @@ type Service struct {
 	txSubscribers    *subscription.Manager[host.P2PTxHandler]
 	batchReqHandlers *subscription.Manager[host.P2PBatchRequestHandler]
 
-	listener    net.Listener
+	transport   transport
 	stopControl *stopcontrol.StopControl
 
-	sl p2pServiceLocator
+	sl               p2pServiceLocator
+	batchVerifier    *batchVerifier
+	isAuthenticated  bool   // whether the connections are authenticated with the L1 keys of the hosts
+	privateKeyString string // the L1 key of the host, which authenticates it to its peers
+	isGossipEnabled  bool   // whether the validators relay the batches to each other
+	gossip           *gossip
+
+	isDiscoveryEnabled  bool // whether the peers are discovered from the L1 enclave registry
+	peerTable           *peerTable
+	l1StartHash         gethcommon.Hash
+	nextDiscoveryHeight *big.Int        // the next L1 block the registry events are read from, only used by discoverPeers
+	discoveredAddresses map[string]bool // the peer addresses added from the peer table, guarded by peerAddressesMutex
 
 	isSequencer      bool
+	isStandby        atomic.Bool // a sequencer host which isn't the active sequencer follows the batches like a validator
 	ourBindAddress   string
 	ourPublicAddress string
 	peerAddresses    map[string]int // map of peer addresses to the number of times they have failed to send a message
 	p2pTimeout       time.Duration
 
 	peerTracker           *peerTracker
+	scorer                *peerScorer
 	metricsRegistry       gethmetrics.Registry
 	logger                gethlog.Logger
 	peerAddressesMutex    sync.RWMutex
@@ -112,19 +148,38 @@ type Service struct {
 }
 
 func (p *Service) Start() error {
+	if p.isAuthenticated {
+		if !p.isDiscoveryEnabled {
+			return errors.New("authenticated P2P requires the peer discovery, which registers the identities of the hosts")
+		}
+		authTransport, err := newAuthTransport(p.privateKeyString, p.ourPublicAddress, p.p2pTimeout, p.verifyPeer, p.stopControl, p.logger)
+		if err != nil {
+			return err
+		}
+		p.transport = authTransport
+	}
+
 	// We listen for P2P connections.
-	listener, err := net.Listen("tcp", p.ourBindAddress)
+	err := p.transport.listen(p.ourBindAddress, p.handle)
 	if err != nil {
-		return fmt.Errorf("could not listen for P2P connections on %s: %w", p.ourBindAddress, err)
+		return err
 	}
 
-	p.logger.Info("P2P server started listening", "bindAddress", p.ourBindAddress, "publicAddress", p.ourPublicAddress)
-
-	p.listener = listener
+	p.logger.Info("P2P server started listening", "bindAddress", p.ourBindAddress, "publicAddress", p.ourPublicAddress, "authenticated", p.isAuthenticated)
 
-	go p.handleConnections()
+	if p.isDiscoveryEnabled {
+		// the peers are known before registering with the sequencer, whose connection must be verified
+		if err := p.refreshPeerTable(); err != nil {
+			p.logger.Warn("Could not refresh the peer table from L1", log.ErrKey, err)
+		}
+		go p.discoverPeers()
+	}
 
-	if !p.isSequencer {
+	if !p.isActiveSequencer() {
+		if p.isGossipEnabled && !p.isIncomingP2PDisabled {
+			// validators relay the live batches once their enclave validated them
+			p.sl.L2Repo().SubscribeValidatedBatches(p)
+		}
 		// validators need to register with the sequencer for broadcasts
 		err := p.RegisterForBroadcasts()
 		if err != nil {
@@ -140,12 +195,9 @@ func (p *Service) Start() error {
 func (p *Service) Stop() error {
 	p.logger.Info("Shutting down P2P.")
 	p.stopControl.Stop()
-	if p.listener != nil {
-		// todo immediately shutting down the listener seems to impact other hosts shutdown process
-		time.Sleep(time.Second)
-		return p.listener.Close()
-	}
-	return nil
+	// todo immediately shutting down the listener seems to impact other hosts shutdown process
+	time.Sleep(time.Second)
+	return p.transport.close()
 }
 
 func (p *Service) HealthStatus(context.Context) host.HealthStatus {
@@ -177,7 +229,7 @@ func (p *Service) SubscribeForBatchRequests(handler host.P2PBatchRequestHandler)
 }
 
 func (p *Service) SendTxToSequencer(tx common.EncryptedTx) error {
-	if p.isSequencer {
+	if p.isActiveSequencer() {
 		return errors.New("sequencer cannot send tx to itself")
 	}
 	msg := message{Sender: p.ourPublicAddress, Type: msgTypeTx, Contents: tx}
@@ -188,7 +240,7 @@ func (p *Service) BroadcastBatches(batches []*common.ExtBatch) error {
 	if p.isIncomingP2PDisabled {
 		return nil
 	}
-	if !p.isSequencer {
+	if !p.isActiveSequencer() {
 		return errors.New("only sequencer can broadcast batches")
 	}
 	batchMsg := host.BatchMsg{
@@ -202,14 +254,18 @@ func (p *Service) BroadcastBatches(batches []*common.ExtBatch) error {
 	}
 
 	msg := message{Sender: p.ourPublicAddress, Type: msgTypeBatches, Contents: encodedBatchMsg}
-	return p.broadcast(msg)
+	if p.isGossipEnabled {
+		// the validators relay the batches to each other
+		return p.broadcast(msg, p.randomPeers(p.gossip.fanout, ""))
+	}
+	return p.broadcast(msg, p.randomPeers(p.numPeers(), ""))
 }
 
 func (p *Service) RequestBatchesFromSequencer(fromSeqNo *big.Int) error {
 	if p.isIncomingP2PDisabled {
 		return nil
 	}
-	if p.isSequencer {
+	if p.isActiveSequencer() {
 		return errors.New("sequencer cannot request batches from itself")
 	}
 	batchRequest := &common.BatchRequest{
@@ -224,15 +280,65 @@ func (p *Service) RequestBatchesFromSequencer(fromSeqNo *big.Int) error {
 	}
 
 	msg := message{Sender: p.ourPublicAddress, Type: msgTypeBatchRequest, Contents: encodedBatchRequest}
-	// todo (#718) - allow missing batches to be requested from peers other than sequencer?
-	return p.send(msg, p.getSequencer())
+	target := p.batchRequestTarget()
+	if p.isAuthenticated {
+		// the responses are only scored when they are received over an authenticated connection
+		p.scorer.requestSent(target)
+	}
+	return p.send(msg, target)
+}
+
+// batchRequestTarget - in gossip mode the validators serve each other's batch requests, so the catch-up load is spread
+// across the sequencer and the known peers. The request goes to one of the best scoring peers, which answer the requests
+// quickly, picked at random so the load is still spread. A peer which doesn't have the batches doesn't respond, and the
+// request will be retried with another target.
+func (p *Service) batchRequestTarget() string {
+	sequencer := p.getSequencer()
+	if !p.isGossipEnabled {
+		return sequencer
+	}
+	candidates := append(p.randomPeers(p.numPeers(), ""), sequencer)
+	ranks := make(map[string]float64, len(candidates))
+	for _, candidate := range candidates {
+		ranks[candidate] = p.scorer.rank(candidate)
+	}
+	// the peers were shuffled, so the order of the peers with the same rank is random
+	sort.SliceStable(candidates, func(i, j int) bool { return ranks[candidates[i]] > ranks[candidates[j]] })
+	return candidates[rand.Intn(min(len(candidates), _preferredRequestTargets))] //nolint:gosec
+}
+
+// SetStandby - with the sequencer failover, the sequencer hosts which aren't the active sequencer are on standby: they
+// register with the sequencer address for the batches like the validators, so they are in sync when they take over.
+// The hosts don't retarget on a failover: all the sequencer hosts advertise the same sequencer address, which the
+// routing in front of them (e.g. a load balancer with health checks) must point at the active sequencer host.
+func (p *Service) SetStandby(standby bool) {
+	p.isStandby.Store(standby)
+}
+
+func (p *Service) IsStandby() bool {
+	return p.isStandby.Load()
+}
+
+func (p *Service) isActiveSequencer() bool {
+	return p.isSequencer && !p.isStandby.Load()
+}
+
+// PeerScores returns the scores of the authenticated peers the host received messages from
+func (p *Service) PeerScores() []host.PeerScore {
+	return p.scorer.list()
+}
+
+// isBanned - the bans are only enforced when the connections are authenticated, otherwise the sender could be spoofed to
+// get an honest peer banned. The sequencer is never banned since the validators can't progress without it.
+func (p *Service) isBanned(peer string) bool {
+	return p.isAuthenticated && peer != p.getSequencer() && p.scorer.isBanned(peer)
 }
 
 func (p *Service) RespondToBatchRequest(requestID string, batches []*common.ExtBatch) error {
 	if p.isIncomingP2PDisabled {
 		return nil
 	}
-	if !p.isSequencer {
+	if !p.isActiveSequencer() && !p.isGossipEnabled {
 		return errors.New("only sequencer can respond to batch requests")
 	}
 	batchMsg := &host.BatchMsg{
@@ -256,7 +362,7 @@ func (p *Service) RegisterForBroadcasts() error {
 	if p.isIncomingP2PDisabled {
 		return fmt.Errorf("incoming P2P is disabled, can't register for broadcasts")
 	}
-	if p.isSequencer {
+	if p.isActiveSequencer() {
 		return errors.New("sequencer cannot register for broadcasts")
 	}
 	// note: contents are not read, but p2p server expects message contents to be non-empty
@@ -269,10 +375,17 @@ func (p *Service) RegisterForBroadcasts() error {
 // if there's more than 100 failures on a given fail type
 // if there's a known peer for which a message hasn't been received
 func (p *Service) verifyHealth() error {
-	if p.isIncomingP2PDisabled || p.isSequencer {
+	if p.isIncomingP2PDisabled || p.isActiveSequencer() {
 		// sequencer is not expected to periodically receive p2p messages so this health check is ignored
 		return nil
 	}
+	if p.isGossipEnabled {
+		// the batches arrive from random peers, so we only expect them to keep arriving from some peer
+		if !p.lastReceivedBroadcast.IsZero() && time.Since(p.lastReceivedBroadcast) > _alertPeriod {
+			return errors.New("no batch received from peers")
+		}
+		return nil
+	}
 	var noMsgReceivedPeers []string
 	for peer, lastMsgTimestamp := range p.peerTracker.receivedMessagesByPeer() {
 		if time.Now().After(lastMsgTimestamp.Add(_alertPeriod)) {
@@ -291,43 +404,32 @@ func (p *Service) verifyHealth() error {
 	return nil
 }
 
-// Listens for connections and handles them in a separate goroutine.
-func (p *Service) handleConnections() {
-	for !p.stopControl.IsStopping() {
-		// blocks here until a connection is made
-		conn, err := p.listener.Accept()
-		if err != nil {
-			if !p.stopControl.IsStopping() {
-				p.logger.Debug("Could not form P2P connection", log.ErrKey, err)
-			}
-			return
+// Decodes a P2P message received from a peer, and pushes it to the correct channel.
+// The peer is nil if the transport doesn't authenticate the connections.
+func (p *Service) handle(peer *peerIdentity, encodedMsg []byte) {
+	msg := message{}
+	err := rlp.DecodeBytes(encodedMsg, &msg)
+	if err != nil {
+		p.logger.Debug("Failed to decode message received from peer: ", log.ErrKey, err)
+		if peer != nil {
+			p.scorer.decodeFailure(peer)
 		}
-		go p.handle(conn)
-	}
-}
-
-// Receives and decodes a P2P message, and pushes it to the correct channel.
-func (p *Service) handle(conn net.Conn) {
-	if conn != nil {
-		defer conn.Close()
+		return
 	}
-
-	encodedMsg, err := io.ReadAll(conn)
-	if err != nil {
-		p.logger.Debug("Failed to read message from peer", log.ErrKey, err)
+	if peer != nil && msg.Sender != peer.Address {
+		p.logger.Warn("Dropping message with a sender different from the authenticated peer",
+			"peer", peer.Address, "hostID", peer.HostID, "sender", msg.Sender)
+		p.scorer.decodeFailure(peer)
 		return
 	}
-
-	msg := message{}
-	err = rlp.DecodeBytes(encodedMsg, &msg)
-	if err != nil {
-		p.logger.Debug("Failed to decode message received from peer: ", log.ErrKey, err)
+	if p.isBanned(msg.Sender) {
+		p.logger.Trace("Dropping message from banned peer", "peer", msg.Sender)
 		return
 	}
 
 	switch msg.Type {
 	case msgTypeTx:
-		if !p.isSequencer {
+		if !p.isActiveSequencer() {
 			p.logger.Error("Received transaction from peer, but not a sequencer node")
 			return
 		}
@@ -336,7 +438,7 @@ func (p *Service) handle(conn net.Conn) {
 			txSubs.HandleTransaction(msg.Contents)
 		}
 	case msgTypeBatches:
-		if p.isSequencer {
+		if p.isActiveSequencer() {
 			p.logger.Error("received batch from peer, but this is a sequencer node")
 			return
 		}
@@ -344,50 +446,101 @@ func (p *Service) handle(conn net.Conn) {
 		err := rlp.DecodeBytes(msg.Contents, &batchMsg)
 		if err != nil {
 			p.logger.Warn("unable to decode batch received from peer", log.ErrKey, err)
+			p.scorer.decodeFailure(peer)
 			// nothing to send to subscribers
 			break
 		}
-		// todo - check the batch signature
+		isGossip := p.isGossipEnabled && batchMsg.IsLive
+		if isGossip {
+			if !p.gossip.batchLimiter.allow(msg.Sender) {
+				p.logger.Debug("Dropping batches from peer over its rate limit", "peer", msg.Sender)
+				p.scorer.rateLimited(peer)
+				break
+			}
+			// the same batch is relayed by several peers, we only pass it on once
+			batchMsg.Batches = p.gossip.unseen(batchMsg.Batches)
+			if len(batchMsg.Batches) == 0 {
+				break
+			}
+		}
+		if err := p.batchVerifier.verify(batchMsg.Batches); err != nil {
+			p.logger.Warn("Dropping batches received from peer", "peer", msg.Sender, log.ErrKey, err)
+			if errors.Is(err, errInvalidBatch) {
+				p.scorer.invalidSignature(peer)
+			}
+			break
+		}
+		if batchMsg.IsLive {
+			p.scorer.validBatches(peer)
+		} else {
+			p.scorer.responseReceived(peer)
+		}
+		if isGossip {
+			p.gossip.markSeen(batchMsg.Batches, msg.Sender)
+			if msg.Sender != p.getSequencer() && p.isDiscoveredPeer(msg.Sender) {
+				// the registered peers which relay batches to us are validators we can relay to, even if we dropped them
+				p.addPeers([]string{msg.Sender})
+			}
+		}
 		p.lastReceivedBroadcast = time.Now()
 		for _, batchSubs := range p.batchSubscribers.Subscribers() {
 			go batchSubs.HandleBatches(batchMsg.Batches, batchMsg.IsLive)
 		}
 	case msgTypeBatchRequest:
-		if !p.isSequencer {
+		if !p.isActiveSequencer() && !p.isGossipEnabled {
 			p.logger.Error("received batch request from peer, but not a sequencer node")
 			return
 		}
+		p.scorer.requestReceived(peer)
+		if p.isGossipEnabled && !p.gossip.batchReqLimiter.allow(msg.Sender) {
+			p.logger.Debug("Dropping batch request from peer over its rate limit", "peer", msg.Sender)
+			p.scorer.rateLimited(peer)
+			return
+		}
 		// this is an incoming request, p2p service is responsible for finding the response and returning it
-		go p.handleBatchRequest(msg.Contents)
+		go p.handleBatchRequest(msg.Sender, peer, msg.Contents)
 	case msgTypeRegisterForBroadcasts:
-		if !p.isSequencer {
+		if !p.isActiveSequencer() {
 			p.logger.Error("received register for broadcasts from peer, but not a sequencer node")
 			return
 		}
+		if p.isGossipEnabled {
+			// the validator learns about its peers from the sequencer
+			go p.sendPeers(msg.Sender)
+		}
 		// add the peer to the list of peers
 		p.peerAddressesMutex.Lock()
 		p.peerAddresses[msg.Sender] = 0
 		p.peerAddressesMutex.Unlock()
+	case msgTypePeers:
+		if p.isActiveSequencer() || !p.isGossipEnabled {
+			p.logger.Error("received peers from peer, but not a gossiping validator node")
+			return
+		}
+		if peer == nil || msg.Sender != p.getSequencer() {
+			// the peer table is only shared by the sequencer, which can't be spoofed on an authenticated connection
+			p.logger.Warn("Dropping peers not received from the sequencer over an authenticated connection", "peer", msg.Sender)
+			return
+		}
+		var peers peersMsg
+		if err := rlp.DecodeBytes(msg.Contents, &peers); err != nil {
+			p.logger.Warn("unable to decode peers received from peer", log.ErrKey, err)
+			p.scorer.decodeFailure(peer)
+			return
+		}
+		p.addPeers(peers.Addresses)
 	}
 	p.peerTracker.receivedPeerMsg(msg.Sender)
 }
 
-// Broadcasts a message to all peers.
-func (p *Service) broadcast(msg message) error {
+// Broadcasts a message to the given peers.
+func (p *Service) broadcast(msg message, addresses []string) error {
 	msgEncoded, err := rlp.EncodeToBytes(msg)
 	if err != nil {
 		return fmt.Errorf("could not encode message to send to peers. Cause: %w", err)
 	}
 
-	// clone currently known addresses
-	p.peerAddressesMutex.RLock()
-	currentAddresses := make([]string, len(p.peerAddresses))
-	for address := range p.peerAddresses {
-		currentAddresses = append(currentAddresses, address)
-	}
-	p.peerAddressesMutex.RUnlock()
-
-	for _, address := range currentAddresses {
+	for _, address := range addresses {
 		closureAddr := address
 		go func() {
 			err := p.sendBytesWithRetry(closureAddr, msgEncoded)
@@ -446,39 +599,29 @@ func (p *Service) send(msg message, to string) error {
 func (p *Service) sendBytesWithRetry(address string, msgEncoded []byte) error {
 	// retry for about 2 seconds
 	err := retry.Do(func() error {
-		return p.sendBytes(address, msgEncoded)
+		return p.transport.send(address, msgEncoded)
 	}, retry.NewDoublingBackoffStrategy(100*time.Millisecond, 5))
 	return err
 }
 
-// Sends the bytes to the provided address.
-func (p *Service) sendBytes(address string, tx []byte) error {
-	conn, err := net.DialTimeout(tcp, address, p.p2pTimeout)
-	if conn != nil {
-		defer conn.Close()
-	}
-	if err != nil {
-		p.logger.Debug(fmt.Sprintf("could not connect to peer on address %s", address), log.ErrKey, err)
-		return err
-	}
-
-	_, err = conn.Write(tx)
-	if err != nil {
-		p.logger.Debug(fmt.Sprintf("could not send message to peer on address %s", address), log.ErrKey, err)
-		return err
-	}
-	return nil
-}
-
 func (p *Service) getSequencer() string {
+	// the sequencer address can be updated by the discovery
+	p.peerAddressesMutex.RLock()
+	defer p.peerAddressesMutex.RUnlock()
 	return p.sequencerAddress
 }
 
-func (p *Service) handleBatchRequest(encodedBatchRequest common.EncodedBatchRequest) {
+func (p *Service) handleBatchRequest(sender string, peer *peerIdentity, encodedBatchRequest common.EncodedBatchRequest) {
 	var batchRequest *common.BatchRequest
 	err := rlp.DecodeBytes(encodedBatchRequest, &batchRequest)
 	if err != nil {
 		p.logger.Warn("unable to decode batch request received from peer using RLP", log.ErrKey, err)
+		p.scorer.decodeFailure(peer)
+		return
+	}
+	if peer != nil && batchRequest.Requester != sender {
+		// the response goes to the requester, so it must be the authenticated peer
+		p.logger.Warn("Dropping batch request for another requester", "peer", sender, "requester", batchRequest.Requester)
 		return
 	}
 
@@ -497,6 +640,10 @@ func (p *Service) EnsureSubscribedToSequencer() {
 		case <-p.stopControl.Done():
 			return // host is stopping
 		case <-time.After(_maxWaitWithoutBroadcast / 2):
+			if p.isActiveSequencer() {
+				// a standby sequencer host which took over
+				continue
+			}
 			if time.Since(p.lastReceivedBroadcast) > _maxWaitWithoutBroadcast {
 				p.logger.Info("No broadcast received from sequencer, re-registering.")
 				err := p.RegisterForBroadcasts()
@@ -504,6 +651,12 @@ func (p *Service) EnsureSubscribedToSequencer() {
 					// just log the error, we'll retry on the next iteration
 					p.logger.Error("Failed to re-register for broadcasts", log.ErrKey, err)
 				}
+			} else if p.isGossipEnabled && p.numPeers() < p.gossip.fanout*_gossipPeerRefreshSize {
+				// registering again gets more peers from the sequencer
+				err := p.RegisterForBroadcasts()
+				if err != nil {
+					p.logger.Debug("Failed to refresh peers from sequencer", log.ErrKey, err)
+				}
 			}
 		}
 	}
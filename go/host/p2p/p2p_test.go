@@ -0,0 +1,397 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"math"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/host"
+	"github.com/ten-protocol/go-ten/go/common/signature"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+	hostconfig "github.com/ten-protocol/go-ten/go/host/config"
+)
+
+// the test hosts only use the L1 publisher to check the sequencer enclaves, the L2 repo to subscribe for validated batches
+// and the L1 data to discover their peers
+type testServiceLocator struct {
+	publisher *testPublisher
+	l1Data    *testL1Data
+}
+
+func (s *testServiceLocator) L1Publisher() host.L1Publisher  { return s.publisher }
+func (s *testServiceLocator) L2Repo() host.L2BatchRepository { return &testRepo{} }
+func (s *testServiceLocator) L1Data() host.L1DataService     { return s.l1Data }
+
+type testRepo struct {
+	host.L2BatchRepository
+}
+
+func (r *testRepo) SubscribeValidatedBatches(host.L2BatchHandler) func() { return func() {} }
+
+type testPublisher struct {
+	host.L1Publisher
+	sequencers   map[common.EnclaveID]bool
+	deregistered map[common.EnclaveID]bool
+}
+
+func (p *testPublisher) IsSequencerEnclave(enclaveID common.EnclaveID) (bool, error) {
+	return p.sequencers[enclaveID], nil
+}
+
+func (p *testPublisher) IsEnclaveAttested(enclaveID common.EnclaveID) (bool, error) {
+	return !p.deregistered[enclaveID], nil
+}
+
+type testBatchHandler struct {
+	batches chan []*common.ExtBatch
+}
+
+func (h *testBatchHandler) HandleBatches(batches []*common.ExtBatch, _ bool) {
+	h.batches <- batches
+}
+
+type testTxHandler struct {
+	txs chan common.EncryptedTx
+}
+
+func (h *testTxHandler) HandleTransaction(tx common.EncryptedTx) {
+	h.txs <- tx
+}
+
+func TestAuthenticatedP2P(t *testing.T) {
+	sequencerEnclaveKey := newKey(t)
+	validatorHostKey, relayerHostKey := newKey(t), newKey(t)
+	relayerAddress := freeAddress(t)
+	locator := &testServiceLocator{publisher: &testPublisher{
+		sequencers:   map[common.EnclaveID]bool{crypto.PubkeyToAddress(sequencerEnclaveKey.PublicKey): true},
+		deregistered: map[common.EnclaveID]bool{},
+	}}
+	locator.register(relayerHostKey, relayerAddress)
+	withKey := func(key *ecdsa.PrivateKey) func(*hostconfig.HostConfig) {
+		return func(cfg *hostconfig.HostConfig) { cfg.PrivateKeyString = keyString(key) }
+	}
+
+	seqAddress, validatorAddress := freeAddress(t), freeAddress(t)
+	sequencer := newTestHost(t, common.Sequencer, seqAddress, seqAddress, locator, nil)
+	validator := newTestHost(t, common.Validator, validatorAddress, seqAddress, locator, withKey(validatorHostKey))
+
+	txHandler := &testTxHandler{txs: make(chan common.EncryptedTx, 10)}
+	sequencer.SubscribeForTx(txHandler)
+	require.NoError(t, sequencer.Start())
+	defer sequencer.Stop() //nolint:errcheck
+
+	batchHandler := &testBatchHandler{batches: make(chan []*common.ExtBatch, 10)}
+	validator.SubscribeForBatches(batchHandler)
+	require.NoError(t, validator.Start())
+	defer validator.Stop() //nolint:errcheck
+
+	// the validator registered for broadcasts over its authenticated connection
+	require.Eventually(t, func() bool {
+		sequencer.peerAddressesMutex.RLock()
+		defer sequencer.peerAddressesMutex.RUnlock()
+		_, ok := sequencer.peerAddresses[validator.ourPublicAddress]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	t.Run("messages share one connection", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			require.NoError(t, validator.SendTxToSequencer(common.EncryptedTx{byte(i + 1)}))
+		}
+		for i := 0; i < 5; i++ {
+			select {
+			case tx := <-txHandler.txs:
+				require.Equal(t, common.EncryptedTx{byte(i + 1)}, tx)
+			case <-time.After(5 * time.Second):
+				t.Fatal("transaction not received")
+			}
+		}
+		transport := validator.transport.(*authTransport)
+		transport.connsMutex.Lock()
+		defer transport.connsMutex.Unlock()
+		require.Len(t, transport.outbound, 1)
+	})
+
+	t.Run("batches signed by the sequencer enclave are delivered", func(t *testing.T) {
+		batch := signedBatch(t, sequencerEnclaveKey, 1)
+		require.NoError(t, sequencer.BroadcastBatches([]*common.ExtBatch{batch}))
+		select {
+		case batches := <-batchHandler.batches:
+			require.Len(t, batches, 1)
+			require.Equal(t, batch.Hash(), batches[0].Hash())
+		case <-time.After(5 * time.Second):
+			t.Fatal("batch not received")
+		}
+	})
+
+	t.Run("batches not signed by a sequencer enclave are dropped", func(t *testing.T) {
+		require.NoError(t, sequencer.BroadcastBatches([]*common.ExtBatch{signedBatch(t, newKey(t), 2)}))
+		unsigned := signedBatch(t, sequencerEnclaveKey, 3)
+		unsigned.Header.Signature = nil
+		require.NoError(t, sequencer.BroadcastBatches([]*common.ExtBatch{unsigned}))
+		select {
+		case <-batchHandler.batches:
+			t.Fatal("invalid batch delivered")
+		case <-time.After(time.Second):
+		}
+	})
+
+	t.Run("messages from a spoofed sender are dropped", func(t *testing.T) {
+		// a registered host which claims to be the validator in its messages
+		impostor := newPeerTransport(t, relayerHostKey, relayerAddress)
+		encoded, err := rlp.EncodeToBytes(message{Sender: validator.ourPublicAddress, Type: msgTypeTx, Contents: []byte{42}})
+		require.NoError(t, err)
+		require.NoError(t, impostor.send(seqAddress, encoded))
+		select {
+		case <-txHandler.txs:
+			t.Fatal("spoofed message delivered")
+		case <-time.After(time.Second):
+		}
+	})
+
+	t.Run("hosts which are not registered are rejected", func(t *testing.T) {
+		// both claim the address of the validator, which was registered with another key
+		for name, key := range map[string]*ecdsa.PrivateKey{"unknown": newKey(t), "relayer": relayerHostKey} {
+			impostor := newPeerTransport(t, key, validatorAddress)
+			encoded, err := rlp.EncodeToBytes(message{Sender: validatorAddress, Type: msgTypeTx, Contents: []byte{43}})
+			require.NoError(t, err)
+			_ = impostor.send(seqAddress, encoded)
+			select {
+			case <-txHandler.txs:
+				t.Fatalf("message of the %s host delivered", name)
+			case <-time.After(time.Second):
+			}
+		}
+	})
+
+	t.Run("dialed hosts must claim the dialed address", func(t *testing.T) {
+		// a registered host listening on another address
+		otherAddress := freeAddress(t)
+		relayer := newPeerTransport(t, relayerHostKey, relayerAddress)
+		require.NoError(t, relayer.listen(otherAddress, func(*peerIdentity, []byte) {}))
+		err := sequencer.transport.send(otherAddress, []byte{44})
+		require.ErrorContains(t, err, "claimed the address")
+	})
+
+	t.Run("unauthenticated connections are rejected", func(t *testing.T) {
+		conn, err := net.Dial(tcp, seqAddress)
+		require.NoError(t, err)
+		defer conn.Close()
+		encoded, err := rlp.EncodeToBytes(message{Sender: validator.ourPublicAddress, Type: msgTypeTx, Contents: []byte{43}})
+		require.NoError(t, err)
+		_, _ = conn.Write(encoded)
+		select {
+		case <-txHandler.txs:
+			t.Fatal("unauthenticated message delivered")
+		case <-time.After(time.Second):
+		}
+	})
+
+	t.Run("hosts which are not registered anymore are disconnected", func(t *testing.T) {
+		locator.publisher.deregistered[crypto.PubkeyToAddress(validatorHostKey.PublicKey)] = true
+		require.NoError(t, sequencer.refreshPeerTable())
+		transport := sequencer.transport.(*authTransport)
+		transport.connsMutex.Lock()
+		defer transport.connsMutex.Unlock()
+		require.NotContains(t, transport.outbound, validatorAddress)
+		for conn := range transport.inbound {
+			require.NotEqual(t, validatorAddress, conn.peer.Address)
+		}
+	})
+}
+
+func TestFrameSizeLimit(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	require.NoError(t, writeFrame(w, make([]byte, 100), 100))
+	require.Error(t, writeFrame(w, make([]byte, 101), 100))
+
+	msg, err := readFrame(bufio.NewReader(bytes.NewReader(buf.Bytes())), 100)
+	require.NoError(t, err)
+	require.Len(t, msg, 100)
+
+	// the size is checked before the frame is read
+	_, err = readFrame(bufio.NewReader(bytes.NewReader(buf.Bytes())), 99)
+	require.ErrorContains(t, err, "exceeds the maximum frame size")
+}
+
+func TestSessionFrames(t *testing.T) {
+	listener, err := net.Listen(tcp, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	dialerKey, acceptorKey := newKey(t), newKey(t)
+
+	type handshakeResult struct {
+		peer    *peerIdentity
+		session *session
+		err     error
+	}
+	accepted := make(chan handshakeResult, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			accepted <- handshakeResult{err: err}
+			return
+		}
+		defer conn.Close()
+		peer, session, err := handshake(conn, bufio.NewReader(conn), bufio.NewWriter(conn), acceptorKey, "acceptor:10000", time.Second)
+		accepted <- handshakeResult{peer: peer, session: session, err: err}
+	}()
+	conn, err := net.Dial(tcp, listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	acceptorPeer, dialer, err := handshake(conn, bufio.NewReader(conn), bufio.NewWriter(conn), dialerKey, "dialer:10000", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(acceptorKey.PublicKey), acceptorPeer.HostID)
+	result := <-accepted
+	require.NoError(t, result.err)
+	require.Equal(t, peerIdentity{Address: "dialer:10000", HostID: crypto.PubkeyToAddress(dialerKey.PublicKey)}, *result.peer)
+	acceptor := result.session
+
+	frame := dialer.seal([]byte("first"))
+	tampered := bytes.Clone(frame)
+	tampered[0] ^= 1
+	_, err = acceptor.open(tampered)
+	require.ErrorContains(t, err, "invalid frame MAC")
+	msg, err := acceptor.open(frame)
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), msg)
+
+	// a frame can't be replayed, or sent back to its sender
+	_, err = acceptor.open(frame)
+	require.ErrorContains(t, err, "invalid frame MAC")
+	_, err = dialer.open(dialer.seal([]byte("second")))
+	require.ErrorContains(t, err, "invalid frame MAC")
+
+	// the frames sealed by the other end are accepted in both directions
+	msg, err = dialer.open(acceptor.seal([]byte("reply")))
+	require.NoError(t, err)
+	require.Equal(t, []byte("reply"), msg)
+}
+
+func TestInflatedFencingToken(t *testing.T) {
+	sequencerEnclaveKey := newKey(t)
+	locator := &testServiceLocator{publisher: &testPublisher{sequencers: map[common.EnclaveID]bool{
+		crypto.PubkeyToAddress(sequencerEnclaveKey.PublicKey): true,
+	}}}
+	validator := newTestHost(t, common.Validator, "validator:10000", "sequencer:10000", locator, func(cfg *hostconfig.HostConfig) {
+		cfg.IsP2PAuthenticated = false
+	})
+	handler := &testBatchHandler{batches: make(chan []*common.ExtBatch, 10)}
+	validator.SubscribeForBatches(handler)
+	send := func(sender string, batchMsg any) {
+		encodedBatchMsg, err := rlp.EncodeToBytes(batchMsg)
+		require.NoError(t, err)
+		encoded, err := rlp.EncodeToBytes(message{Sender: sender, Type: msgTypeBatches, Contents: encodedBatchMsg})
+		require.NoError(t, err)
+		validator.handle(nil, encoded)
+	}
+
+	// a peer replays a batch of the sequencer with the highest possible lease token, which nobody signed
+	send("relay:10000", struct {
+		Batches      []*common.ExtBatch
+		IsLive       bool
+		FencingToken uint64
+	}{Batches: []*common.ExtBatch{signedBatch(t, sequencerEnclaveKey, 1)}, IsLive: true, FencingToken: math.MaxUint64})
+
+	// the live batches of the sequencer are still accepted
+	send("sequencer:10000", &host.BatchMsg{Batches: []*common.ExtBatch{signedBatch(t, sequencerEnclaveKey, 2)}, IsLive: true})
+	for {
+		select {
+		case batches := <-handler.batches:
+			if batches[0].Header.SequencerOrderNo.Uint64() == 2 {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the batches of the sequencer were dropped")
+		}
+	}
+}
+
+func newTestHost(t *testing.T, nodeType common.NodeType, address string, seqAddress string, locator *testServiceLocator, configure func(*hostconfig.HostConfig)) *Service {
+	cfg := &hostconfig.HostConfig{
+		NodeType:             nodeType,
+		P2PBindAddress:       address,
+		P2PPublicAddress:     address,
+		SequencerP2PAddress:  seqAddress,
+		P2PConnectionTimeout: time.Second,
+		IsP2PAuthenticated:   true,
+		PrivateKeyString:     newKeyString(t),
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	if cfg.IsP2PAuthenticated {
+		// the authenticated hosts only accept the hosts registered on L1, so the hosts must be created before they are started
+		cfg.IsP2PDiscoveryEnabled = true
+		key, err := crypto.HexToECDSA(cfg.PrivateKeyString)
+		require.NoError(t, err)
+		locator.register(key, cfg.P2PPublicAddress)
+	}
+	return NewSocketP2PLayer(cfg, locator, gethlog.New(), nil)
+}
+
+// register adds an attested enclave to the L1 registry, whose host has the key and the address. The enclave ID is the
+// address of the host key.
+func (s *testServiceLocator) register(hostKey *ecdsa.PrivateKey, address string) {
+	if s.l1Data == nil {
+		s.l1Data = &testL1Data{head: 1}
+	}
+	hostID := crypto.PubkeyToAddress(hostKey.PublicKey)
+	s.l1Data.events = append(s.l1Data.events,
+		&common.EnclaveRegistryEvent{Type: common.SecretRequestTx, EnclaveID: hostID, HostAddress: address, HostID: hostID, BlockNumber: 1},
+		&common.EnclaveRegistryEvent{Type: common.SecretResponseTx, EnclaveID: hostID, BlockNumber: 1},
+	)
+}
+
+func signedBatch(t *testing.T, key *ecdsa.PrivateKey, seqNo int64) *common.ExtBatch {
+	header := &common.BatchHeader{
+		Number:           big.NewInt(seqNo),
+		SequencerOrderNo: big.NewInt(seqNo),
+		BaseFee:          big.NewInt(1),
+		ParentHash:       gethcommon.BigToHash(big.NewInt(seqNo - 1)),
+	}
+	sig, err := signature.Sign(header.Hash().Bytes(), key)
+	require.NoError(t, err)
+	header.Signature = sig
+	return &common.ExtBatch{Header: header}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return key
+}
+
+func newKeyString(t *testing.T) string {
+	return keyString(newKey(t))
+}
+
+func keyString(key *ecdsa.PrivateKey) string {
+	return gethcommon.Bytes2Hex(crypto.FromECDSA(key))
+}
+
+// newPeerTransport returns the transport of a host which accepts any peer, closed with the test
+func newPeerTransport(t *testing.T, key *ecdsa.PrivateKey, address string) *authTransport {
+	transport, err := newAuthTransport(keyString(key), address, time.Second, func(*peerIdentity) error { return nil }, stopcontrol.New(), gethlog.New())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = transport.close() })
+	return transport
+}
+
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen(tcp, "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
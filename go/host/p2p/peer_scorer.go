@@ -0,0 +1,234 @@
+package p2p
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	gethmetrics "github.com/ethereum/go-ethereum/metrics"
+	"github.com/ten-protocol/go-ten/go/common/host"
+)
+
+// The peers are scored from the messages received from them: the malformed messages, the forged batches and the messages
+// over the rate limits lower the score, the valid batches and the answered batch requests raise it. The score decays
+// towards zero, so old offences are forgiven. The peers whose score drops to the ban threshold are banned for a while.
+//
+// Only the peers authenticated by the handshake of their connection are scored, as the sender of the unauthenticated
+// messages could be claimed by anyone to lower the score of another peer or to fill the table.
+
+var (
+	_maxPeerScore             = 100.0
+	_banThreshold             = -100.0
+	_banDuration              = 10 * time.Minute
+	_scoreHalfLife            = 10 * time.Minute
+	_decodeFailurePenalty     = 10.0
+	_invalidSignaturePenalty  = 50.0
+	_rateLimitedPenalty       = 5.0
+	_unansweredRequestPenalty = 2.0
+	_validBatchesReward       = 1.0
+	_answeredRequestReward    = 2.0
+	_latencyWeight            = 10.0 // the score points a second of average latency costs a peer when requesting batches
+	_latencySmoothing         = 0.2  // the weight of a new latency sample in the average
+	_batchRequestTimeout      = 30 * time.Second
+	_scoredPeers              = 1024 // the least recently scored peers are forgotten
+)
+
+type peerScore struct {
+	host.PeerScore
+	updated     time.Time
+	requestSent time.Time // when our pending batch request was sent to the peer, zero if none
+}
+
+type peerScorer struct {
+	mutex  sync.Mutex
+	scores *lru.BasicLRU[string, *peerScore]
+
+	metricsRegistry gethmetrics.Registry
+	logger          gethlog.Logger
+}
+
+func newPeerScorer(metricsRegistry gethmetrics.Registry, logger gethlog.Logger) *peerScorer {
+	scores := lru.NewBasicLRU[string, *peerScore](_scoredPeers)
+	return &peerScorer{
+		scores:          &scores,
+		metricsRegistry: metricsRegistry,
+		logger:          logger,
+	}
+}
+
+func (s *peerScorer) decodeFailure(peer *peerIdentity) {
+	s.updateAuthenticated(peer, func(score *peerScore) float64 {
+		score.DecodeFailures++
+		return -_decodeFailurePenalty
+	})
+}
+
+func (s *peerScorer) invalidSignature(peer *peerIdentity) {
+	s.updateAuthenticated(peer, func(score *peerScore) float64 {
+		score.InvalidSignatures++
+		return -_invalidSignaturePenalty
+	})
+}
+
+func (s *peerScorer) rateLimited(peer *peerIdentity) {
+	s.updateAuthenticated(peer, func(score *peerScore) float64 {
+		score.RateLimited++
+		return -_rateLimitedPenalty
+	})
+}
+
+func (s *peerScorer) validBatches(peer *peerIdentity) {
+	s.updateAuthenticated(peer, func(*peerScore) float64 { return _validBatchesReward })
+}
+
+func (s *peerScorer) requestReceived(peer *peerIdentity) {
+	s.updateAuthenticated(peer, func(score *peerScore) float64 {
+		score.Requests++
+		return 0
+	})
+}
+
+// requestSent records a batch request sent to the peer, the previous request is penalised if it wasn't answered in time
+func (s *peerScorer) requestSent(peer string) {
+	s.update(peer, func(score *peerScore) float64 {
+		now := time.Now()
+		if score.requestSent.IsZero() {
+			score.requestSent = now
+			return 0
+		}
+		if now.Sub(score.requestSent) > _batchRequestTimeout {
+			score.requestSent = now
+			return -_unansweredRequestPenalty
+		}
+		// the pending request may still be answered
+		return 0
+	})
+}
+
+// responseReceived records the answer of the peer to our pending batch request, and its latency
+func (s *peerScorer) responseReceived(peer *peerIdentity) {
+	s.updateAuthenticated(peer, func(score *peerScore) float64 {
+		if score.requestSent.IsZero() {
+			return 0
+		}
+		latency := time.Since(score.requestSent)
+		if score.Latency == 0 {
+			score.Latency = latency
+		} else {
+			score.Latency = time.Duration(_latencySmoothing*float64(latency) + (1-_latencySmoothing)*float64(score.Latency))
+		}
+		score.requestSent = time.Time{}
+		return _answeredRequestReward
+	})
+}
+
+// updateAuthenticated updates the score of the peer, unless the message wasn't received over an authenticated connection
+func (s *peerScorer) updateAuthenticated(peer *peerIdentity, change func(score *peerScore) float64) {
+	if peer == nil {
+		return
+	}
+	s.update(peer.Address, change)
+}
+
+// update decays the score of the peer and applies the change, then bans the peer if the score reached the ban threshold
+func (s *peerScorer) update(peer string, change func(score *peerScore) float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	score, ok := s.scores.Get(peer)
+	if !ok {
+		if s.scores.Len() >= _scoredPeers {
+			// the metrics of the forgotten peer would otherwise be kept in the registry forever
+			if evicted, _, ok := s.scores.RemoveOldest(); ok {
+				s.unregisterMetrics(evicted)
+			}
+		}
+		score = &peerScore{PeerScore: host.PeerScore{Address: peer}, updated: now}
+		s.scores.Add(peer, score)
+	}
+	score.Score = decay(score.Score, now.Sub(score.updated))
+	score.Score = min(_maxPeerScore, score.Score+change(score))
+	score.updated = now
+
+	if score.Score <= _banThreshold && now.After(score.BannedUntil) {
+		score.BannedUntil = now.Add(_banDuration)
+		// the peer starts again from a low score, so repeated offences get it banned again quickly
+		score.Score = _banThreshold / 2
+		s.logger.Warn("Banning peer", "peer", peer, "until", score.BannedUntil,
+			"decodeFailures", score.DecodeFailures, "invalidSignatures", score.InvalidSignatures, "rateLimited", score.RateLimited)
+	}
+	s.publishMetrics(score)
+}
+
+func (s *peerScorer) isBanned(peer string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	score, ok := s.scores.Peek(peer)
+	return ok && time.Now().Before(score.BannedUntil)
+}
+
+// rank is the preference for sending a batch request to the peer, the latency counts against the score
+func (s *peerScorer) rank(peer string) float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	score, ok := s.scores.Peek(peer)
+	if !ok {
+		return 0
+	}
+	return decay(score.Score, time.Since(score.updated)) - _latencyWeight*score.Latency.Seconds()
+}
+
+// list returns the scores of the peers, from the highest
+func (s *peerScorer) list() []host.PeerScore {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	scores := make([]host.PeerScore, 0, s.scores.Len())
+	for _, peer := range s.scores.Keys() {
+		score, _ := s.scores.Peek(peer)
+		peerScore := score.PeerScore
+		peerScore.Score = decay(score.Score, now.Sub(score.updated))
+		if now.After(peerScore.BannedUntil) {
+			peerScore.BannedUntil = time.Time{}
+		}
+		scores = append(scores, peerScore)
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores
+}
+
+func (s *peerScorer) publishMetrics(score *peerScore) {
+	if s.metricsRegistry == nil {
+		return
+	}
+	prefix := peerMetricsPrefix(score.Address)
+	gethmetrics.GetOrRegisterGaugeFloat64(prefix+"score", s.metricsRegistry).Update(score.Score)
+	gethmetrics.GetOrRegisterGauge(prefix+"latency", s.metricsRegistry).Update(score.Latency.Milliseconds())
+	banned := int64(0)
+	if time.Now().Before(score.BannedUntil) {
+		banned = 1
+	}
+	gethmetrics.GetOrRegisterGauge(prefix+"banned", s.metricsRegistry).Update(banned)
+}
+
+func (s *peerScorer) unregisterMetrics(peer string) {
+	if s.metricsRegistry == nil {
+		return
+	}
+	prefix := peerMetricsPrefix(peer)
+	for _, name := range []string{"score", "latency", "banned"} {
+		s.metricsRegistry.Unregister(prefix + name)
+	}
+}
+
+func peerMetricsPrefix(peer string) string {
+	return "host/p2p/peer/" + peer + "/"
+}
+
+func decay(score float64, elapsed time.Duration) float64 {
+	return score * math.Pow(0.5, elapsed.Seconds()/_scoreHalfLife.Seconds())
+}
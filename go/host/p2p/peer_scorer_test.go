@@ -0,0 +1,94 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	gethmetrics "github.com/ethereum/go-ethereum/metrics"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	hostconfig "github.com/ten-protocol/go-ten/go/host/config"
+)
+
+func TestPeerScorer(t *testing.T) {
+	scorer := newPeerScorer(nil, gethlog.New())
+
+	t.Run("offenders are banned", func(t *testing.T) {
+		scorer.invalidSignature(&peerIdentity{Address: "forger"})
+		scorer.invalidSignature(&peerIdentity{Address: "forger"})
+		require.False(t, scorer.isBanned("forger"))
+		scorer.invalidSignature(&peerIdentity{Address: "forger"})
+		require.True(t, scorer.isBanned("forger"))
+
+		scores := scorer.list()
+		forger := scores[len(scores)-1]
+		require.Equal(t, "forger", forger.Address)
+		require.Equal(t, uint64(3), forger.InvalidSignatures)
+		require.True(t, forger.BannedUntil.After(time.Now()))
+	})
+
+	t.Run("answered requests and latency are scored", func(t *testing.T) {
+		scorer.requestSent("fast")
+		scorer.responseReceived(&peerIdentity{Address: "fast"})
+		scorer.requestSent("slow")
+		time.Sleep(200 * time.Millisecond)
+		scorer.responseReceived(&peerIdentity{Address: "slow"})
+		// a response without a pending request isn't rewarded
+		scorer.responseReceived(&peerIdentity{Address: "unsolicited"})
+
+		require.Greater(t, scorer.rank("fast"), scorer.rank("slow"))
+		require.Equal(t, 0.0, scorer.rank("unsolicited"))
+	})
+
+	t.Run("unauthenticated senders are not scored", func(t *testing.T) {
+		scores := len(scorer.list())
+		scorer.decodeFailure(nil)
+		scorer.validBatches(nil)
+		require.Len(t, scorer.list(), scores)
+	})
+
+	t.Run("scores decay", func(t *testing.T) {
+		require.InDelta(t, 50, decay(100, _scoreHalfLife), 0.001)
+		require.InDelta(t, -25, decay(-100, 2*_scoreHalfLife), 0.001)
+	})
+}
+
+func TestPeerScorerEviction(t *testing.T) {
+	defer func(scoredPeers int) { _scoredPeers = scoredPeers }(_scoredPeers)
+	_scoredPeers = 2
+	registry := gethmetrics.NewRegistry()
+	scorer := newPeerScorer(registry, gethlog.New())
+
+	for _, peer := range []string{"peer1", "peer2", "peer3"} {
+		scorer.validBatches(&peerIdentity{Address: peer})
+	}
+	require.Len(t, scorer.list(), 2)
+	// the metrics of the least recently scored peer were removed with its score
+	for _, name := range []string{"score", "latency", "banned"} {
+		require.Nil(t, registry.Get("host/p2p/peer/peer1/"+name))
+		require.NotNil(t, registry.Get("host/p2p/peer/peer3/"+name))
+	}
+}
+
+func TestBatchRequestTarget(t *testing.T) {
+	locator := &testServiceLocator{publisher: &testPublisher{}}
+	validator := newTestHost(t, common.Validator, "validator:10000", "sequencer:10000", locator, func(cfg *hostconfig.HostConfig) {
+		cfg.IsP2PGossipEnabled = true
+	})
+	validator.addPeers([]string{"good1", "good2", "limited1", "limited2", "banned"})
+	validator.scorer.validBatches(&peerIdentity{Address: "good1"})
+	validator.scorer.validBatches(&peerIdentity{Address: "good2"})
+	validator.scorer.rateLimited(&peerIdentity{Address: "limited1"})
+	validator.scorer.rateLimited(&peerIdentity{Address: "limited2"})
+	for i := 0; i < 11; i++ {
+		validator.scorer.decodeFailure(&peerIdentity{Address: "banned"})
+	}
+	require.True(t, validator.isBanned("banned"))
+
+	// the requests go to one of the three best scoring peers, the unscored sequencer being third
+	for i := 0; i < 100; i++ {
+		require.Contains(t, []string{"good1", "good2", "sequencer:10000"}, validator.batchRequestTarget())
+	}
+	require.NotContains(t, validator.randomPeers(10, ""), "banned")
+}
@@ -0,0 +1,54 @@
+package p2p
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+)
+
+// the number of peers whose rate is tracked, the least recently seen peers are forgotten
+const _rateLimiterPeers = 1024
+
+// peerRateLimiter is a token bucket per peer, refilled at a steady rate up to the burst size
+type peerRateLimiter struct {
+	ratePerSec float64
+	burst      float64
+
+	mutex   sync.Mutex
+	buckets *lru.BasicLRU[string, *tokenBucket]
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newPeerRateLimiter(ratePerSec float64, burst int) *peerRateLimiter {
+	buckets := lru.NewBasicLRU[string, *tokenBucket](_rateLimiterPeers)
+	return &peerRateLimiter{
+		ratePerSec: ratePerSec,
+		burst:      float64(burst),
+		buckets:    &buckets,
+	}
+}
+
+// allow takes a token from the bucket of the peer, returning false if it is empty
+func (l *peerRateLimiter) allow(peer string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets.Get(peer)
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets.Add(peer, bucket)
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.ratePerSec)
+	bucket.updated = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
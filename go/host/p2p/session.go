@@ -0,0 +1,64 @@
+package p2p
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const _macSize = sha256.Size
+
+// prefixed to the data the session keys are derived from, so they can't be used in another context
+var _sessionDomain = []byte("ten-p2p-session")
+
+// session authenticates the frames sent over a connection after the handshake. Its keys are derived from the secret
+// shared with the ephemeral keys exchanged in the handshake, so only the two authenticated hosts know them.
+// Each direction has its own key, and the frames are numbered, so a frame can't be reflected, replayed or reordered.
+//
+// The sealing and the opening of the frames are not safe for concurrent use. The writes of a connection are serialised,
+// and its frames are read by a single goroutine.
+type session struct {
+	sendKey []byte
+	recvKey []byte
+	sendSeq uint64
+	recvSeq uint64
+}
+
+func newSession(secret []byte, ourNonce []byte, peerNonce []byte) *session {
+	return &session{
+		sendKey: crypto.Keccak256(_sessionDomain, secret, ourNonce, peerNonce),
+		recvKey: crypto.Keccak256(_sessionDomain, secret, peerNonce, ourNonce),
+	}
+}
+
+// seal returns the message followed by the MAC of the next frame sent
+func (s *session) seal(msg []byte) []byte {
+	mac := frameMAC(s.sendKey, s.sendSeq, msg)
+	s.sendSeq++
+	return append(msg[:len(msg):len(msg)], mac...)
+}
+
+// open checks the MAC of the next frame received, and returns its message
+func (s *session) open(frame []byte) ([]byte, error) {
+	if len(frame) < _macSize {
+		return nil, errors.New("frame is shorter than its MAC")
+	}
+	msg, mac := frame[:len(frame)-_macSize], frame[len(frame)-_macSize:]
+	if !hmac.Equal(mac, frameMAC(s.recvKey, s.recvSeq, msg)) {
+		return nil, errors.New("invalid frame MAC")
+	}
+	s.recvSeq++
+	return msg, nil
+}
+
+func frameMAC(key []byte, seq uint64, msg []byte) []byte {
+	var seqBytes [8]byte
+	binary.BigEndian.PutUint64(seqBytes[:], seq)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(seqBytes[:])
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
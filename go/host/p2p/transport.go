@@ -0,0 +1,103 @@
+package p2p
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+)
+
+// msgHandler is called with every message received from a peer. The peer is nil when the transport doesn't authenticate
+// the connections, in which case the sender is only known from the message itself.
+type msgHandler func(peer *peerIdentity, encodedMsg []byte)
+
+// transport moves the encoded P2P messages between the hosts
+type transport interface {
+	// listen starts accepting the connections from peers, and passes their messages to the handler
+	listen(bindAddress string, handler msgHandler) error
+	// send delivers the message to the peer listening on the address
+	send(address string, encodedMsg []byte) error
+	close() error
+}
+
+// socketTransport opens a new TCP connection for every message, which is read until the sender closes it
+type socketTransport struct {
+	timeout     time.Duration
+	listener    net.Listener
+	stopControl *stopcontrol.StopControl
+	logger      gethlog.Logger
+}
+
+func newSocketTransport(timeout time.Duration, stopControl *stopcontrol.StopControl, logger gethlog.Logger) *socketTransport {
+	return &socketTransport{
+		timeout:     timeout,
+		stopControl: stopControl,
+		logger:      logger,
+	}
+}
+
+func (t *socketTransport) listen(bindAddress string, handler msgHandler) error {
+	listener, err := net.Listen(tcp, bindAddress)
+	if err != nil {
+		return fmt.Errorf("could not listen for P2P connections on %s: %w", bindAddress, err)
+	}
+	t.listener = listener
+	go t.handleConnections(handler)
+	return nil
+}
+
+func (t *socketTransport) send(address string, encodedMsg []byte) error {
+	conn, err := net.DialTimeout(tcp, address, t.timeout)
+	if conn != nil {
+		defer conn.Close()
+	}
+	if err != nil {
+		t.logger.Debug(fmt.Sprintf("could not connect to peer on address %s", address), log.ErrKey, err)
+		return err
+	}
+
+	_, err = conn.Write(encodedMsg)
+	if err != nil {
+		t.logger.Debug(fmt.Sprintf("could not send message to peer on address %s", address), log.ErrKey, err)
+		return err
+	}
+	return nil
+}
+
+func (t *socketTransport) close() error {
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Close()
+}
+
+// Listens for connections and handles them in a separate goroutine.
+func (t *socketTransport) handleConnections(handler msgHandler) {
+	for !t.stopControl.IsStopping() {
+		// blocks here until a connection is made
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if !t.stopControl.IsStopping() {
+				t.logger.Debug("Could not form P2P connection", log.ErrKey, err)
+			}
+			return
+		}
+		go t.handle(conn, handler)
+	}
+}
+
+// Receives a single message and passes it to the handler.
+func (t *socketTransport) handle(conn net.Conn, handler msgHandler) {
+	defer conn.Close()
+
+	encodedMsg, err := io.ReadAll(conn)
+	if err != nil {
+		t.logger.Debug("Failed to read message from peer", log.ErrKey, err)
+		return
+	}
+	handler(nil, encodedMsg)
+}
@@ -0,0 +1,26 @@
+package clientapi
+
+import (
+	"github.com/ten-protocol/go-ten/go/common/host"
+)
+
+// AdminAPI implements the JSON RPC operations for the node operators.
+type AdminAPI struct {
+	host host.Host
+}
+
+func NewAdminAPI(host host.Host) *AdminAPI {
+	return &AdminAPI{
+		host: host,
+	}
+}
+
+// Peers returns the peer table the host discovered from the L1 enclave registry.
+func (api *AdminAPI) Peers() []*host.PeerInfo {
+	return api.host.Peers()
+}
+
+// PeerScores returns the scores of the authenticated P2P peers the host received messages from.
+func (api *AdminAPI) PeerScores() []host.PeerScore {
+	return api.host.PeerScores()
+}
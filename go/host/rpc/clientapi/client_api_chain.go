@@ -2,12 +2,16 @@ package clientapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
 	"github.com/ten-protocol/go-ten/go/common/host"
 	"github.com/ten-protocol/go-ten/go/common/log"
 	"github.com/ten-protocol/go-ten/go/responses"
@@ -17,6 +21,9 @@ import (
 	gethlog "github.com/ethereum/go-ethereum/log"
 )
 
+// maxFeeHistory - the maximum number of batches that can be requested in a fee history
+const maxFeeHistory = 1024
+
 // ChainAPI exposes public chain data
 type ChainAPI struct {
 	host   host.Host
@@ -101,35 +108,94 @@ func (api *ChainAPI) MaxPriorityFeePerGas(_ context.Context) (*hexutil.Big, erro
 	return (*hexutil.Big)(header.BaseFee), err
 }
 
-// FeeHistory is a placeholder for an RPC method required by MetaMask/Remix.
-// rpc.DecimalOrHex -> []byte
-func (api *ChainAPI) FeeHistory(context.Context, string, rpc.BlockNumber, []float64) (*FeeHistoryResult, error) {
-	// todo (#1621) - return a non-dummy fee history
-	header, err := api.host.Storage().FetchHeadBatchHeader()
+// FeeHistory returns the base fee, the gas used ratio and the priority fee percentiles of a range of batches ending with lastBlock.
+// The rewards are derived from the aggregated fees published by the enclave for each batch, so they are rounded down
+// to the closest published percentile, and the fees paid by the individual transactions are not revealed.
+func (api *ChainAPI) FeeHistory(_ context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	if blockCount < 1 {
+		return &FeeHistoryResult{OldestBlock: (*hexutil.Big)(big.NewInt(0))}, nil
+	}
+	if blockCount > maxFeeHistory {
+		blockCount = maxFeeHistory
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid reward percentile: %f", p)
+		}
+		if i > 0 && p <= rewardPercentiles[i-1] {
+			return nil, fmt.Errorf("invalid reward percentile: #%d:%f >= #%d:%f", i-1, rewardPercentiles[i-1], i, p)
+		}
+	}
+
+	lastBatchHash, err := api.batchNumberToBatchHash(lastBlock)
 	if err != nil {
-		api.logger.Error("Unable to retrieve header for fee history.", log.ErrKey, err)
-		return nil, fmt.Errorf("unable to retrieve fee history")
+		return nil, fmt.Errorf("could not find batch with height %d. Cause: %w", lastBlock, err)
 	}
 
-	batches := make([]*common.BatchHeader, 0)
-	batches = append(batches, header)
+	// walk back from the last batch, and then reverse, so the batches are in ascending order
+	headers := make([]*common.BatchHeader, 0, blockCount)
+	batchHash := *lastBatchHash
+	for len(headers) < int(blockCount) {
+		header, err := api.host.Storage().FetchBatchHeaderByHash(batchHash)
+		if err != nil {
+			api.logger.Error("Unable to retrieve header for fee history.", log.BatchHashKey, batchHash, log.ErrKey, err)
+			return nil, fmt.Errorf("unable to retrieve fee history")
+		}
+		headers = append(headers, header)
+		if header.Number.Sign() == 0 {
+			break
+		}
+		batchHash = header.ParentHash
+	}
+	slices.Reverse(headers)
 
 	feeHist := &FeeHistoryResult{
-		OldestBlock:  (*hexutil.Big)(header.Number),
-		Reward:       [][]*hexutil.Big{},
-		BaseFee:      []*hexutil.Big{},
-		GasUsedRatio: []float64{},
+		OldestBlock:  (*hexutil.Big)(headers[0].Number),
+		BaseFee:      make([]*hexutil.Big, 0, len(headers)+1),
+		GasUsedRatio: make([]float64, 0, len(headers)),
 	}
-
-	for _, header := range batches {
-		// 0.9 - This number represents how full the block is. As we dont have a dynamic base fee, we tell whomever is requesting that
-		// we expect the baseFee to increase, rather than decrease in order to avoid underpriced transactions.
-		feeHist.GasUsedRatio = append(feeHist.GasUsedRatio, 0.9)
+	for _, header := range headers {
 		feeHist.BaseFee = append(feeHist.BaseFee, (*hexutil.Big)(header.BaseFee))
+
+		gasUsedRatio := float64(0)
+		if header.GasLimit > 0 {
+			gasUsedRatio = float64(header.GasUsed) / float64(header.GasLimit)
+		}
+		feeHist.GasUsedRatio = append(feeHist.GasUsedRatio, gasUsedRatio)
+
+		if len(rewardPercentiles) > 0 {
+			rewards, err := api.batchRewards(header, rewardPercentiles)
+			if err != nil {
+				api.logger.Error("Unable to retrieve fee stats for fee history.", log.BatchHashKey, header.Hash(), log.ErrKey, err)
+				return nil, fmt.Errorf("unable to retrieve fee history")
+			}
+			feeHist.Reward = append(feeHist.Reward, rewards)
+		}
 	}
+	// the base fee of the next batch. The base fee is not adjusted based on the gas used, so it is the same as the last one
+	feeHist.BaseFee = append(feeHist.BaseFee, (*hexutil.Big)(headers[len(headers)-1].BaseFee))
 	return feeHist, nil
 }
 
+// batchRewards - returns the priority fees paid at the requested percentiles in the batch.
+// Batches without fee stats (e.g. synced from the rollups before the host received them from its enclave, or with too few
+// transactions paying fees for the stats to be published) are reported with zero rewards.
+func (api *ChainAPI) batchRewards(header *common.BatchHeader, rewardPercentiles []float64) ([]*hexutil.Big, error) {
+	stats, err := api.host.Storage().FetchBatchFeeStats(header.Hash())
+	if err != nil {
+		if !errors.Is(err, errutil.ErrNotFound) {
+			return nil, err
+		}
+		api.logger.Debug("No fee stats for batch.", log.BatchHashKey, header.Hash())
+	}
+
+	rewards := make([]*hexutil.Big, len(rewardPercentiles))
+	for i, p := range rewardPercentiles {
+		rewards[i] = (*hexutil.Big)(stats.Reward(p))
+	}
+	return rewards, nil
+}
+
 // FeeHistoryResult is the structure returned by Geth `eth_feeHistory` API.
 type FeeHistoryResult struct {
 	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
@@ -20,7 +20,7 @@ const (
 	selectTxsAndBatch   = "SELECT t.hash FROM transaction_host t JOIN batch_host b ON t.b_sequence = b.sequence WHERE b.hash = "
 	selectBatchSeqByTx  = "SELECT b_sequence FROM transaction_host WHERE hash = "
 	selectTxBySeq       = "SELECT hash FROM transaction_host WHERE b_sequence = "
-	selectBatchTxs      = "SELECT t.hash, b.sequence, b.height, b.ext_batch FROM transaction_host t JOIN batch_host b ON t.b_sequence = b.sequence"
+	selectBatchTxs      = "SELECT t.hash, b.sequence, b.height, b.ext_batch, r.hash IS NOT NULL FROM transaction_host t JOIN batch_host b ON t.b_sequence = b.sequence LEFT JOIN transaction_reveal_host r ON r.hash = t.hash"
 	selectSumBatchSizes = "SELECT SUM(txs_size) FROM batch_host WHERE sequence >= "
 )
 
@@ -473,8 +473,9 @@ func fetchBatchTxs(db *sql.DB, whereQuery string, batchHash []byte) (*common.Tra
 			sequence int
 			height   int
 			extBatch []byte
+			revealed bool
 		)
-		err := rows.Scan(&fullHash, &sequence, &height, &extBatch)
+		err := rows.Scan(&fullHash, &sequence, &height, &extBatch, &revealed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan with query %s - %w", query, err)
 		}
@@ -487,6 +488,7 @@ func fetchBatchTxs(db *sql.DB, whereQuery string, batchHash []byte) (*common.Tra
 			BatchHeight:     big.NewInt(int64(height)),
 			BatchTimestamp:  extBatchDecoded.Header.Time,
 			Finality:        common.BatchFinal,
+			Revealed:        revealed,
 		}
 		transactions = append(transactions, transaction)
 	}
@@ -0,0 +1,50 @@
+package hostdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+)
+
+const selectBatchFeeStats = "SELECT stats FROM batch_fee_stats_host WHERE hash = "
+
+// AddBatchFeeStats adds the aggregated fees of a batch to the DB
+func AddBatchFeeStats(dbtx *dbTransaction, statements *SQLStatements, batchHash gethcommon.Hash, stats *common.BatchFeeStats) error {
+	encoded, err := rlp.EncodeToBytes(stats)
+	if err != nil {
+		return fmt.Errorf("could not encode batch fee stats: %w", err)
+	}
+
+	_, err = dbtx.Tx.Exec(statements.InsertBatchFeeStats, batchHash.Bytes(), encoded)
+	if err != nil {
+		if IsRowExistsError(err) {
+			return errutil.ErrAlreadyExists
+		}
+		return fmt.Errorf("host failed to insert batch fee stats: %w", err)
+	}
+	return nil
+}
+
+// GetBatchFeeStats returns the aggregated fees of the batch with the given hash
+func GetBatchFeeStats(db HostDB, batchHash gethcommon.Hash) (*common.BatchFeeStats, error) {
+	var encoded []byte
+	query := selectBatchFeeStats + db.GetSQLStatement().Placeholder
+	err := db.GetSQLDB().QueryRow(query, batchHash.Bytes()).Scan(&encoded)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errutil.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch batch fee stats - %w", err)
+	}
+
+	var stats common.BatchFeeStats
+	if err := rlp.DecodeBytes(encoded, &stats); err != nil {
+		return nil, fmt.Errorf("could not decode batch fee stats. Cause: %w", err)
+	}
+	return &stats, nil
+}
@@ -0,0 +1,46 @@
+package hostdb
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+)
+
+func TestCanStoreAndRetrieveBatchFeeStats(t *testing.T) {
+	db, _ := CreateSQLiteDB(t)
+	batch := CreateBatch(batchNumber, []common.L2TxHash{})
+	stats := &common.BatchFeeStats{Rewards: []*big.Int{big.NewInt(0), big.NewInt(5), big.NewInt(10)}}
+
+	dbtx, _ := db.NewDBTransaction()
+	err := AddBatchFeeStats(dbtx, db.GetSQLStatement(), batch.Hash(), stats)
+	if err != nil {
+		t.Errorf("could not store batch fee stats. Cause: %s", err)
+	}
+	dbtx.Write()
+
+	storedStats, err := GetBatchFeeStats(db, batch.Hash())
+	if err != nil {
+		t.Errorf("stored batch fee stats but could not retrieve them. Cause: %s", err)
+	}
+	if len(storedStats.Rewards) != len(stats.Rewards) {
+		t.Fatalf("batch fee stats were not stored correctly")
+	}
+	for i, reward := range stats.Rewards {
+		if storedStats.Rewards[i].Cmp(reward) != 0 {
+			t.Errorf("batch fee stats were not stored correctly")
+		}
+	}
+}
+
+func TestUnknownBatchFeeStatsReturnsNotFound(t *testing.T) {
+	db, _ := CreateSQLiteDB(t)
+
+	_, err := GetBatchFeeStats(db, gethcommon.Hash{})
+	if !errors.Is(err, errutil.ErrNotFound) {
+		t.Errorf("did not store batch fee stats but was able to retrieve them")
+	}
+}
@@ -10,6 +10,8 @@ type SQLStatements struct {
 	InsertRollup            string
 	InsertCrossChainMessage string
 	InsertBlock             string
+	InsertBatchFeeStats     string
+	InsertRevealedTx        string
 	Pagination              string
 	Placeholder             string
 }
@@ -28,6 +30,8 @@ func SQLiteSQLStatements() *SQLStatements {
 		UpdateTxCount:           "UPDATE transaction_count SET total=? WHERE id=1",
 		InsertRollup:            "INSERT INTO rollup_host (hash, start_seq, end_seq, time_stamp, ext_rollup, compression_block) values (?,?,?,?,?,?) RETURNING id",
 		InsertBlock:             "INSERT INTO block_host (hash, header) values (?,?)",
+		InsertBatchFeeStats:     "INSERT INTO batch_fee_stats_host (hash, stats) values (?,?)",
+		InsertRevealedTx:        "INSERT OR IGNORE INTO transaction_reveal_host (hash) values (?)",
 		InsertCrossChainMessage: "INSERT INTO cross_chain_message_host (message_hash, message_type, rollup_id) values (?,?,?)",
 		Pagination:              "LIMIT ? OFFSET ?",
 		Placeholder:             "?",
@@ -41,6 +45,8 @@ func PostgresSQLStatements() *SQLStatements {
 		UpdateTxCount:           "UPDATE transaction_count SET total=$1 WHERE id=1",
 		InsertRollup:            "INSERT INTO rollup_host (hash, start_seq, end_seq, time_stamp, ext_rollup, compression_block) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
 		InsertBlock:             "INSERT INTO block_host (hash, header) VALUES ($1, $2)",
+		InsertBatchFeeStats:     "INSERT INTO batch_fee_stats_host (hash, stats) VALUES ($1, $2)",
+		InsertRevealedTx:        "INSERT INTO transaction_reveal_host (hash) VALUES ($1) ON CONFLICT DO NOTHING",
 		InsertCrossChainMessage: "INSERT INTO cross_chain_message_host (message_hash, message_type, rollup_id) values ($1, $2, $3)",
 		Pagination:              "LIMIT $1 OFFSET $2",
 		Placeholder:             "$1",
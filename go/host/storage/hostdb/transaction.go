@@ -11,8 +11,8 @@ import (
 
 const (
 	selectTxCount = "SELECT total FROM transaction_count WHERE id = 1"
-	selectTx      = "SELECT hash, b_sequence FROM transaction_host WHERE hash = "
-	selectTxs     = "SELECT t.hash, b.ext_batch FROM transaction_host t JOIN batch_host b ON t.b_sequence = b.sequence ORDER BY b.height DESC "
+	selectTx      = "SELECT t.hash, t.b_sequence, r.hash IS NOT NULL FROM transaction_host t LEFT JOIN transaction_reveal_host r ON r.hash = t.hash WHERE t.hash = "
+	selectTxs     = "SELECT t.hash, b.ext_batch, r.hash IS NOT NULL FROM transaction_host t JOIN batch_host b ON t.b_sequence = b.sequence LEFT JOIN transaction_reveal_host r ON r.hash = t.hash ORDER BY b.height DESC "
 	countTxs      = "SELECT COUNT(b_sequence) AS row_count FROM transaction_host"
 )
 
@@ -28,8 +28,9 @@ func GetTransactionListing(db HostDB, pagination *common.QueryPagination) (*comm
 
 	for rows.Next() {
 		var fullHash, extBatch []byte
+		var revealed bool
 
-		err = rows.Scan(&fullHash, &extBatch)
+		err = rows.Scan(&fullHash, &extBatch, &revealed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan query %s - %w", query, err)
 		}
@@ -44,6 +45,7 @@ func GetTransactionListing(db HostDB, pagination *common.QueryPagination) (*comm
 			BatchTimestamp:  b.Header.Time,
 			// TODO @will this will be implemented under #3336
 			Finality: common.BatchFinal,
+			Revealed: revealed,
 		}
 		txs = append(txs, tx)
 	}
@@ -68,7 +70,8 @@ func GetTransaction(db HostDB, hash gethcommon.Hash) (*common.PublicTransaction,
 
 	var fullHash []byte
 	var seq int
-	err := db.GetSQLDB().QueryRow(query, hash.Bytes()).Scan(&fullHash, &seq)
+	var revealed bool
+	err := db.GetSQLDB().QueryRow(query, hash.Bytes()).Scan(&fullHash, &seq, &revealed)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve transaction sequence number: %w", err)
 	}
@@ -83,11 +86,23 @@ func GetTransaction(db HostDB, hash gethcommon.Hash) (*common.PublicTransaction,
 		BatchHeight:     batch.Header.Number,
 		BatchTimestamp:  batch.Header.Time,
 		Finality:        common.BatchFinal,
+		Revealed:        revealed,
 	}
 
 	return tx, nil
 }
 
+// AddRevealedTransactions marks the transactions whose receipts became public because of the delayed disclosure rules of the contracts
+func AddRevealedTransactions(dbtx *dbTransaction, statements *SQLStatements, txHashes []common.TxHash) error {
+	for _, txHash := range txHashes {
+		_, err := dbtx.Tx.Exec(statements.InsertRevealedTx, txHash.Bytes())
+		if err != nil {
+			return fmt.Errorf("host failed to insert revealed transaction: %w", err)
+		}
+	}
+	return nil
+}
+
 // GetTotalTxCount returns value from the transaction count table
 func GetTotalTxCount(db HostDB) (*big.Int, error) {
 	var totalCount int
@@ -111,6 +111,55 @@ func TestGetTransaction(t *testing.T) {
 	}
 }
 
+func TestRevealedTransactions(t *testing.T) {
+	db, _ := CreateSQLiteDB(t)
+	txHash1 := gethcommon.BytesToHash([]byte("magicStringOne"))
+	txHash2 := gethcommon.BytesToHash([]byte("magicStringTwo"))
+	batchOne := CreateBatch(batchNumber, []common.L2TxHash{txHash1, txHash2})
+	dbtx, _ := db.NewDBTransaction()
+	err := AddBatch(dbtx, db.GetSQLStatement(), &batchOne)
+	if err != nil {
+		t.Errorf("could not store batch. Cause: %s", err)
+	}
+	dbtx.Write()
+
+	// a transaction can be revealed multiple times, e.g. when the batch is streamed again
+	for i := 0; i < 2; i++ {
+		dbtx, _ = db.NewDBTransaction()
+		err = AddRevealedTransactions(dbtx, db.GetSQLStatement(), []common.TxHash{txHash2})
+		if err != nil {
+			t.Errorf("could not store revealed transaction. Cause: %s", err)
+		}
+		dbtx.Write()
+	}
+
+	tx1, err := GetTransaction(db, txHash1)
+	if err != nil {
+		t.Errorf("was not able to get transaction. Cause: %s", err)
+	}
+	tx2, err := GetTransaction(db, txHash2)
+	if err != nil {
+		t.Errorf("was not able to get transaction. Cause: %s", err)
+	}
+	if tx1.Revealed || !tx2.Revealed {
+		t.Errorf("revealed transactions were not retrieved correctly")
+	}
+
+	txListing, err := GetTransactionListing(db, &common.QueryPagination{Offset: 0, Size: 10})
+	if err != nil {
+		t.Errorf("could not get tx listing. Cause: %s", err)
+	}
+	revealed := 0
+	for _, tx := range txListing.TransactionsData {
+		if tx.Revealed {
+			revealed++
+		}
+	}
+	if revealed != 1 {
+		t.Errorf("revealed transactions were not listed correctly")
+	}
+}
+
 func TestCanRetrieveTotalNumberOfTransactions(t *testing.T) {
 	db, _ := CreateSQLiteDB(t)
 	txHashesOne := []common.L2TxHash{gethcommon.BytesToHash([]byte("magicStringOne")), gethcommon.BytesToHash([]byte("magicStringTwo"))}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS batch_fee_stats_host
+(
+    hash        BYTEA PRIMARY KEY,
+    stats       BYTEA         NOT NULL
+);
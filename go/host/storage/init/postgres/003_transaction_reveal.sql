@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS transaction_reveal_host
+(
+    hash        BYTEA PRIMARY KEY
+);
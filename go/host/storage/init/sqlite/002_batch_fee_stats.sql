@@ -0,0 +1,5 @@
+create table if not exists batch_fee_stats_host
+(
+    hash           binary(32) primary key,
+    stats          blob       NOT NULL
+);
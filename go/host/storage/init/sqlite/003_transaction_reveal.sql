@@ -0,0 +1,4 @@
+create table if not exists transaction_reveal_host
+(
+    hash           binary(32) primary key
+);
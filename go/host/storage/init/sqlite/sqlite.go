@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"embed"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -12,10 +13,7 @@ import (
 	_ "github.com/mattn/go-sqlite3" // this imports the sqlite driver to make the sql.Open() connection work
 )
 
-const (
-	tempDirName = "ten-persistence"
-	initFile    = "001_init.sql"
-)
+const tempDirName = "ten-persistence"
 
 //go:embed *.sql
 var sqlFiles embed.FS
@@ -39,22 +37,30 @@ func CreateTemporarySQLiteHostDB(dbPath string, dbOptions string) (*sql.DB, erro
 	// Sqlite fails with table locks when there are multiple connections
 	db.SetMaxOpenConns(1)
 
-	err = initialiseDB(db, initFile)
+	err = initialiseDB(db)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't initialise db - %w", err)
 	}
 	return db, nil
 }
 
-func initialiseDB(db *sql.DB, initFile string) error {
-	sqlInitFile, err := sqlFiles.ReadFile(initFile)
+// initialiseDB applies the migration files in order, like for postgres. They are applied on every start, so they must
+// be idempotent
+func initialiseDB(db *sql.DB) error {
+	migrationFiles, err := fs.Glob(sqlFiles, "*.sql")
 	if err != nil {
 		return err
 	}
-
-	_, err = db.Exec(string(sqlInitFile))
-	if err != nil {
-		return fmt.Errorf("failed to initialise sqlite %s - %w", sqlInitFile, err)
+	// Glob returns the files in lexical order
+	for _, migrationFile := range migrationFiles {
+		migration, err := sqlFiles.ReadFile(migrationFile)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(string(migration))
+		if err != nil {
+			return fmt.Errorf("failed to initialise sqlite %s - %w", migrationFile, err)
+		}
 	}
 	return nil
 }
@@ -18,6 +18,12 @@ type Storage interface {
 type BatchResolver interface {
 	// AddBatch stores the batch
 	AddBatch(batch *common.ExtBatch) error
+	// AddBatchFeeStats stores the aggregated fees of the batch, as calculated by the enclave
+	AddBatchFeeStats(batchHash gethcommon.Hash, stats *common.BatchFeeStats) error
+	// FetchBatchFeeStats returns the aggregated fees of the batch with the given hash
+	FetchBatchFeeStats(batchHash gethcommon.Hash) (*common.BatchFeeStats, error)
+	// AddRevealedTransactions marks the transactions which became public because of the delayed disclosure rules of the contracts
+	AddRevealedTransactions(txHashes []common.TxHash) error
 	// FetchBatchBySeqNo returns the batch with the given seq number
 	FetchBatchBySeqNo(seqNum uint64) (*common.ExtBatch, error)
 	// FetchBatchHashByHeight returns the batch hash given the batch number
@@ -54,6 +54,46 @@ func (s *storageImpl) AddBatch(batch *common.ExtBatch) error {
 	return nil
 }
 
+func (s *storageImpl) AddBatchFeeStats(batchHash gethcommon.Hash, stats *common.BatchFeeStats) error {
+	dbtx, err := s.db.NewDBTransaction()
+	if err != nil {
+		return err
+	}
+	defer dbtx.Rollback()
+
+	if err := hostdb.AddBatchFeeStats(dbtx, s.db.GetSQLStatement(), batchHash, stats); err != nil {
+		if errors.Is(err, errutil.ErrAlreadyExists) {
+			return nil
+		}
+		return fmt.Errorf("could not add batch fee stats to host. Cause: %w", err)
+	}
+
+	if err := dbtx.Write(); err != nil {
+		if hostdb.IsRowExistsError(err) {
+			return nil
+		}
+		return fmt.Errorf("could not commit batch fee stats tx. Cause: %w", err)
+	}
+	return nil
+}
+
+func (s *storageImpl) AddRevealedTransactions(txHashes []common.TxHash) error {
+	dbtx, err := s.db.NewDBTransaction()
+	if err != nil {
+		return err
+	}
+	defer dbtx.Rollback()
+
+	if err := hostdb.AddRevealedTransactions(dbtx, s.db.GetSQLStatement(), txHashes); err != nil {
+		return fmt.Errorf("could not add revealed transactions to host. Cause: %w", err)
+	}
+
+	if err := dbtx.Write(); err != nil {
+		return fmt.Errorf("could not commit revealed transactions tx. Cause: %w", err)
+	}
+	return nil
+}
+
 func (s *storageImpl) AddRollup(rollup *common.ExtRollup, extMetadata *common.ExtRollupMetadata, metadata *common.PublicRollupMetadata, block *types.Header) error {
 	_, err := hostdb.GetRollupHeader(s.db, rollup.Header.Hash())
 	if err == nil {
@@ -145,6 +185,10 @@ func (s *storageImpl) FetchBatchBySeqNo(seqNum uint64) (*common.ExtBatch, error)
 	return hostdb.GetBatchBySequenceNumber(s.db, seqNum)
 }
 
+func (s *storageImpl) FetchBatchFeeStats(batchHash gethcommon.Hash) (*common.BatchFeeStats, error) {
+	return hostdb.GetBatchFeeStats(s.db, batchHash)
+}
+
 func (s *storageImpl) FetchBatchHashByHeight(number *big.Int) (*gethcommon.Hash, error) {
 	return hostdb.GetBatchHashByNumber(s.db, number)
 }
@@ -24,6 +24,8 @@ const (
 	SubscribeNamespace       = "ten"
 	SubscriptionTypeLogs     = "logs"
 	SubscriptionTypeNewHeads = "newHeads"
+	// SubscriptionTypeAccountState - an authenticated subscription to the changes of the state of the account of the viewing key
+	SubscriptionTypeAccountState = "accountState"
 
 	GetBatchByTx             = "scan_getBatchByTx"
 	GetLatestRollupHeader    = "scan_getLatestRollupHeader"
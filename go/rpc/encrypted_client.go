@@ -102,11 +102,23 @@ func (c *EncRPCClient) Subscribe(ctx context.Context, namespace string, ch inter
 
 	switch args[0] {
 	case SubscriptionTypeLogs:
-		return c.logSubscription(ctx, namespace, ch, args...)
+		logSubscription, err := common.CreateAuthenticatedLogSubscriptionPayload(args, c.viewingKey)
+		if err != nil {
+			return nil, err
+		}
+		return c.logSubscription(ctx, namespace, ch, logSubscription)
+	case SubscriptionTypeAccountState:
+		// the notifications are sent over a log subscription, so they are encrypted with the viewing key like the logs
+		logSubscription, err := common.CreateAuthenticatedLogSubscriptionPayload(args[:1], c.viewingKey)
+		if err != nil {
+			return nil, err
+		}
+		logSubscription.AccountState = true
+		return c.logSubscription(ctx, namespace, ch, logSubscription)
 	case SubscriptionTypeNewHeads:
 		return c.newHeadSubscription(ctx, namespace, ch, args...)
 	default:
-		return nil, fmt.Errorf("only subscriptions of type %s and %s are supported", SubscriptionTypeLogs, SubscriptionTypeNewHeads)
+		return nil, fmt.Errorf("only subscriptions of type %s, %s and %s are supported", SubscriptionTypeLogs, SubscriptionTypeAccountState, SubscriptionTypeNewHeads)
 	}
 }
 
@@ -233,17 +245,12 @@ func (c *EncRPCClient) decryptResponse(encryptedBytes []byte) ([]byte, error) {
 }
 
 // creates a subscription to the TEN node, but decrypts the messages from that channel and forwards them to the `ch`
-func (c *EncRPCClient) logSubscription(ctx context.Context, namespace string, ch interface{}, args ...any) (*gethrpc.ClientSubscription, error) {
+func (c *EncRPCClient) logSubscription(ctx context.Context, namespace string, ch interface{}, logSubscription *common.LogSubscription) (*gethrpc.ClientSubscription, error) {
 	outboundChannel, ok := ch.(chan types.Log)
 	if !ok {
 		return nil, fmt.Errorf("expected a channel of type `chan types.Log`, got %T", ch)
 	}
 
-	logSubscription, err := common.CreateAuthenticatedLogSubscriptionPayload(args, c.viewingKey)
-	if err != nil {
-		return nil, err
-	}
-
 	encodedLogSubscription, err := json.Marshal(logSubscription)
 	if err != nil {
 		return nil, err
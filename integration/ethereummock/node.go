@@ -344,6 +344,10 @@ func (m *Node) GetLogs(fq ethereum.FilterQuery) ([]types.Log, error) {
 			// enclave ID address, padded out to 32 bytes to match standard eth fields
 			data = make([]byte, 32)
 			copy(data[12:], m.tenSeqEnclaveID[:])
+			if len(tx.Data()) == gethcommon.AddressLength {
+				// the tx grants the enclave it names, so several sequencer enclaves can be granted
+				copy(data[12:], tx.Data())
+			}
 		default:
 			continue
 		}
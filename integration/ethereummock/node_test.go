@@ -0,0 +1,38 @@
+package ethereummock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/ethadapter"
+	"github.com/ten-protocol/go-ten/integration/datagenerator"
+)
+
+func TestGrantSequencerEnclaveLogs(t *testing.T) {
+	node := NewMiner(datagenerator.RandomAddress(), MiningConfig{}, nil, nil, nil, gethlog.New())
+	promoted := datagenerator.RandomAddress()
+	node.PromoteEnclave(promoted)
+
+	// the sequencer enclaves after the first one are granted by naming them in the tx data
+	granted := datagenerator.RandomAddress()
+	grantSeqAddr := GrantSeqTxAddr
+	block := NewBlock(MockGenesisBlock, node.l2ID, []*types.Transaction{
+		types.NewTx(&types.LegacyTx{Nonce: 1, To: &grantSeqAddr}),
+		types.NewTx(&types.LegacyTx{Nonce: 2, To: &grantSeqAddr, Data: granted.Bytes()}),
+	}, 0)
+	require.NoError(t, node.BlockResolver.StoreBlock(context.Background(), block, nil))
+
+	blockHash := block.Hash()
+	logs, err := node.GetLogs(ethereum.FilterQuery{BlockHash: &blockHash})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	for i, expected := range []gethcommon.Address{promoted, granted} {
+		require.Equal(t, ethadapter.SequencerEnclaveGrantedEventID, logs[i].Topics[0])
+		require.Equal(t, expected, gethcommon.BytesToAddress(logs[i].Data))
+	}
+}
@@ -68,6 +68,7 @@ func (n *basicNetworkOfInMemoryNodes) Create(params *params.SimParams, stats *st
 			incomingP2PDisabled,
 			params.AvgBlockDuration,
 			params.BlobResolver,
+			nil,
 		)
 		tenClient := p2p.NewInMemTenClient(agg)
 
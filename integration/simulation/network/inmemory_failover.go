@@ -0,0 +1,297 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/host"
+	"github.com/ten-protocol/go-ten/go/common/retry"
+	"github.com/ten-protocol/go-ten/go/ethadapter"
+	"github.com/ten-protocol/go-ten/go/host/container"
+	"github.com/ten-protocol/go-ten/go/host/failover"
+	"github.com/ten-protocol/go-ten/go/obsclient"
+	"github.com/ten-protocol/go-ten/go/rpc"
+	testcommon "github.com/ten-protocol/go-ten/integration/common"
+	"github.com/ten-protocol/go-ten/integration/datagenerator"
+	"github.com/ten-protocol/go-ten/integration/ethereummock"
+	"github.com/ten-protocol/go-ten/integration/simulation/p2p"
+	"github.com/ten-protocol/go-ten/integration/simulation/params"
+	"github.com/ten-protocol/go-ten/integration/simulation/stats"
+)
+
+var errLeaseUnreachable = errors.New("the sequencer lease storage is unreachable")
+
+// inMemSequencerFailover is the failover configuration of an in memory sequencer host
+type inMemSequencerFailover struct {
+	lease           failover.Lease
+	probe           failover.HealthProbe
+	leaseDuration   time.Duration
+	livenessTimeout time.Duration
+}
+
+// FailoverNetworkOfInMemoryNodes is a network of in memory nodes where the first nodes are sequencer hosts, which elect
+// the active sequencer with a lease shared in memory. The other nodes are validators.
+type FailoverNetworkOfInMemoryNodes struct {
+	numberOfSequencers int
+	leaseDuration      time.Duration
+	livenessTimeout    time.Duration
+
+	lease            *failover.InMemoryLease
+	leaseUnreachable []*atomic.Bool // per sequencer host, whether it can reach the lease storage
+	stopped          []*atomic.Bool // per host, whether the host was stopped by the test
+
+	ethNodes  []*ethereummock.Node
+	tenNodes  []*container.HostContainer
+	p2pNodes  []host.P2PHostService
+	l2Clients []rpc.Client
+}
+
+func NewFailoverNetworkOfInMemoryNodes(numberOfSequencers int, leaseDuration time.Duration, livenessTimeout time.Duration) *FailoverNetworkOfInMemoryNodes {
+	return &FailoverNetworkOfInMemoryNodes{
+		numberOfSequencers: numberOfSequencers,
+		leaseDuration:      leaseDuration,
+		livenessTimeout:    livenessTimeout,
+		lease:              failover.NewInMemoryLease(),
+	}
+}
+
+// Create inits and starts the nodes. The genesis sequencer host is started first, and the other hosts once it is the
+// active sequencer, so it answers the secret requests of their enclaves.
+func (n *FailoverNetworkOfInMemoryNodes) Create(params *params.SimParams, stats *stats.Stats) (*RPCHandles, error) {
+	l1Clients := make([]ethadapter.EthClient, params.NumberOfNodes)
+	n.ethNodes = make([]*ethereummock.Node, params.NumberOfNodes)
+	n.tenNodes = make([]*container.HostContainer, params.NumberOfNodes)
+	n.p2pNodes = make([]host.P2PHostService, params.NumberOfNodes)
+	n.l2Clients = make([]rpc.Client, params.NumberOfNodes)
+	n.stopped = make([]*atomic.Bool, params.NumberOfNodes)
+	n.leaseUnreachable = make([]*atomic.Bool, n.numberOfSequencers)
+
+	p2pNetw := p2p.NewMockP2PNetwork(params.AvgBlockDuration, params.AvgNetworkLatency, 0)
+
+	// Invent some addresses to assign as the L1 erc20 contracts
+	dummyOBXAddress := datagenerator.RandomAddress()
+	params.Wallets.Tokens[testcommon.HOC].L1ContractAddress = &dummyOBXAddress
+	dummyETHAddress := datagenerator.RandomAddress()
+	params.Wallets.Tokens[testcommon.POC].L1ContractAddress = &dummyETHAddress
+	dummyBridge := datagenerator.RandomAddress()
+
+	for i := 0; i < params.NumberOfNodes; i++ {
+		n.stopped[i] = &atomic.Bool{}
+		nodeType := common.Validator
+		var sequencerFailover *inMemSequencerFailover
+		if i < n.numberOfSequencers {
+			nodeType = common.Sequencer
+			n.leaseUnreachable[i] = &atomic.Bool{}
+			n.p2pNodes[i] = p2pNetw.NewSequencerNode(i)
+			sequencerFailover = &inMemSequencerFailover{
+				lease:           &inMemHostLease{Lease: n.lease, unreachable: n.leaseUnreachable[i]},
+				probe:           n.probe,
+				leaseDuration:   n.leaseDuration,
+				livenessTimeout: n.livenessTimeout,
+			}
+		} else {
+			n.p2pNodes[i] = p2pNetw.NewNode(i)
+		}
+
+		miner := createMockEthNode(i, params.NumberOfNodes, params.AvgBlockDuration, params.AvgNetworkLatency, stats, params.BlobResolver)
+		n.tenNodes[i] = createInMemTenNode(
+			int64(i),
+			i == 0,
+			nodeType,
+			params.ContractRegistryLib,
+			params.Wallets.NodeWallets[i],
+			miner,
+			n.p2pNodes[i],
+			dummyBridge,
+			gethcommon.Hash{},
+			params.AvgBlockDuration/2,
+			false,
+			params.AvgBlockDuration,
+			params.BlobResolver,
+			sequencerFailover,
+		)
+		n.ethNodes[i] = miner
+		n.l2Clients[i] = p2p.NewInMemTenClient(n.tenNodes[i])
+		l1Clients[i] = miner
+	}
+
+	for i := 0; i < params.NumberOfNodes; i++ {
+		n.ethNodes[i].Network.(*ethereummock.MockEthNetwork).AllNodes = n.ethNodes
+	}
+	for _, m := range n.ethNodes {
+		t := m
+		go t.Start()
+		time.Sleep(params.AvgBlockDuration)
+	}
+
+	tenClients := make([]*obsclient.ObsClient, params.NumberOfNodes)
+	for idx, l2Client := range n.l2Clients {
+		tenClients[idx] = obsclient.NewObsClient(l2Client)
+	}
+
+	startupTimeout := retry.NewTimeoutStrategy(100*params.AvgBlockDuration, params.AvgBlockDuration)
+	n.startNode(0)
+	if err := n.grantSequencerEnclave(tenClients[0], startupTimeout); err != nil {
+		return nil, err
+	}
+	err := retry.Do(func() error {
+		if active := n.ActiveSequencers(); len(active) != 1 || active[0] != 0 {
+			return errors.New("the genesis sequencer host is not the active sequencer yet")
+		}
+		return nil
+	}, startupTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < params.NumberOfNodes; i++ {
+		n.startNode(i)
+		time.Sleep(params.AvgBlockDuration / 3)
+	}
+	for i := 1; i < n.numberOfSequencers; i++ {
+		if err := n.grantSequencerEnclave(tenClients[i], startupTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	return &RPCHandles{
+		EthClients:     l1Clients,
+		TenClients:     tenClients,
+		RPCClients:     n.l2Clients,
+		AuthObsClients: createAuthClientsPerWallet(n.l2Clients, params.Wallets),
+	}, nil
+}
+
+func (n *FailoverNetworkOfInMemoryNodes) startNode(i int) {
+	go func() {
+		if err := n.tenNodes[i].Start(); err != nil {
+			panic(err)
+		}
+	}()
+}
+
+// grantSequencerEnclave waits for the enclave of the sequencer host, and permissions it as a sequencer enclave on L1
+func (n *FailoverNetworkOfInMemoryNodes) grantSequencerEnclave(client *obsclient.ObsClient, timeout retry.Strategy) error {
+	var health host.HealthCheck
+	err := retry.Do(func() error {
+		var err error
+		health, err = client.Health()
+		if err != nil {
+			return err
+		}
+		if len(health.Enclaves) == 0 {
+			return fmt.Errorf("no enclaves available to promote on sequencer")
+		}
+		return nil
+	}, timeout)
+	if err != nil {
+		return err
+	}
+
+	enclaveID := health.Enclaves[0].EnclaveID
+	permMockAddr := ethereummock.GrantSeqTxAddr
+	return n.ethNodes[0].SendTransaction(types.NewTx(&types.LegacyTx{
+		To:   &permMockAddr,
+		Data: enclaveID.Bytes(),
+	}))
+}
+
+// ActiveSequencers returns the indexes of the running sequencer hosts which aren't on standby
+func (n *FailoverNetworkOfInMemoryNodes) ActiveSequencers() []int {
+	var active []int
+	for i := 0; i < n.numberOfSequencers; i++ {
+		if !n.stopped[i].Load() && !n.p2pNodes[i].IsStandby() {
+			active = append(active, i)
+		}
+	}
+	return active
+}
+
+// LeaseHolder returns the index of the sequencer host holding the lease and its fencing token
+func (n *FailoverNetworkOfInMemoryNodes) LeaseHolder() (int, uint64) {
+	record, _ := n.lease.Current(context.Background())
+	if record == nil {
+		return -1, 0
+	}
+	holder, err := strconv.Atoi(record.Holder)
+	if err != nil {
+		panic(err)
+	}
+	return holder, record.Token
+}
+
+// StopNode stops the host, a sequencer host releases the lease if it holds it
+func (n *FailoverNetworkOfInMemoryNodes) StopNode(i int) error {
+	n.stopped[i].Store(true)
+	return n.tenNodes[i].Stop()
+}
+
+// SetLeaseUnreachable cuts the sequencer host from the lease storage, or connects it again
+func (n *FailoverNetworkOfInMemoryNodes) SetLeaseUnreachable(i int, unreachable bool) {
+	n.leaseUnreachable[i].Store(unreachable)
+}
+
+// probe checks the health of the sequencer host with the ID, which is its health address
+func (n *FailoverNetworkOfInMemoryNodes) probe(ctx context.Context, address string) error {
+	i, err := strconv.Atoi(address)
+	if err != nil {
+		return err
+	}
+	health, err := n.tenNodes[i].Host().HealthCheck(ctx)
+	if err != nil {
+		return err
+	}
+	if !health.OverallHealth {
+		return fmt.Errorf("host is not healthy: %v", health.Errors)
+	}
+	return nil
+}
+
+func (n *FailoverNetworkOfInMemoryNodes) TearDown() {
+	running := make([]rpc.Client, 0, len(n.l2Clients))
+	for i, client := range n.l2Clients {
+		if !n.stopped[i].Load() {
+			running = append(running, client)
+		}
+	}
+	StopTenNodes(running)
+
+	for _, node := range n.ethNodes {
+		temp := node
+		go temp.Stop()
+	}
+}
+
+// inMemHostLease is the view of the shared lease from a sequencer host, which can be cut from the storage
+type inMemHostLease struct {
+	failover.Lease
+	unreachable *atomic.Bool
+}
+
+func (l *inMemHostLease) Acquire(ctx context.Context, holder string, address string, duration time.Duration) (*failover.LeaseRecord, error) {
+	if l.unreachable.Load() {
+		return nil, errLeaseUnreachable
+	}
+	return l.Lease.Acquire(ctx, holder, address, duration)
+}
+
+func (l *inMemHostLease) Release(ctx context.Context, holder string, token uint64) error {
+	if l.unreachable.Load() {
+		return errLeaseUnreachable
+	}
+	return l.Lease.Release(ctx, holder, token)
+}
+
+func (l *inMemHostLease) Current(ctx context.Context) (*failover.LeaseRecord, error) {
+	if l.unreachable.Load() {
+		return nil, errLeaseUnreachable
+	}
+	return l.Lease.Current(ctx)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ten-protocol/go-ten/go/host"
 	hostconfig "github.com/ten-protocol/go-ten/go/host/config"
 	hostcontainer "github.com/ten-protocol/go-ten/go/host/container"
+	"github.com/ten-protocol/go-ten/go/host/failover"
 	"github.com/ten-protocol/go-ten/go/host/l1"
 
 	"github.com/ten-protocol/go-ten/go/common"
@@ -63,6 +64,7 @@ func createInMemTenNode(
 	incomingP2PDisabled bool,
 	l1BlockTime time.Duration,
 	blobResolver l1.BlobResolver,
+	sequencerFailover *inMemSequencerFailover,
 ) *hostcontainer.HostContainer {
 	networkConfigAddr := contractRegistryLib.NetworkConfigLib().GetContractAddr()
 	addresses, _ := contractRegistryLib.NetworkConfigLib().GetContractAddresses()
@@ -82,6 +84,17 @@ func createInMemTenNode(
 		UseInMemoryDB:        true,
 	}
 
+	var sequencerLease failover.Lease
+	var healthProbe failover.HealthProbe
+	if sequencerFailover != nil {
+		hostConfig.IsSequencerFailoverEnabled = true
+		hostConfig.SequencerLeaseDuration = sequencerFailover.leaseDuration
+		hostConfig.SequencerLivenessTimeout = sequencerFailover.livenessTimeout
+		hostConfig.SequencerHealthAddress = hostConfig.ID
+		sequencerLease = sequencerFailover.lease
+		healthProbe = sequencerFailover.probe
+	}
+
 	contracts := contractRegistryLib.GetContractAddresses()
 
 	enclaveConfig := &enclaveconfig.EnclaveConfig{
@@ -113,7 +126,7 @@ func createInMemTenNode(
 	// create an in memory TEN node
 	metricsService := metrics.New(hostConfig.MetricsEnabled, hostConfig.MetricsHTTPPort, hostLogger)
 	l1Data := l1.NewL1DataService(ethClient, hostLogger, contractRegistryLib, blobResolver, hostConfig.L1StartHash)
-	currentContainer := hostcontainer.NewHostContainer(hostConfig, host.NewServicesRegistry(hostLogger), mockP2P, ethClient, l1Data, enclaveClients, ethWallet, nil, hostLogger, metricsService, blobResolver, contractRegistryLib)
+	currentContainer := hostcontainer.NewHostContainer(hostConfig, host.NewServicesRegistry(hostLogger), mockP2P, ethClient, l1Data, enclaveClients, ethWallet, nil, hostLogger, metricsService, blobResolver, contractRegistryLib, sequencerLease, healthProbe)
 
 	return currentContainer
 }
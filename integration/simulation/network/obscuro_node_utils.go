@@ -54,6 +54,7 @@ func startInMemoryTenNodes(params *params.SimParams, l1Clients []ethadapter.EthC
 			true,
 			params.AvgBlockDuration,
 			blobResolver,
+			nil,
 		)
 		tenHosts[i] = tenNodes[i].Host()
 	}
@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"strconv"
 	"sync/atomic"
@@ -29,6 +30,8 @@ type MockP2PNetwork struct {
 
 type MockP2PNetworkIntf interface {
 	NewNode(id int) host.P2PHostService
+	// NewSequencerNode adds a sequencer host, with the failover several sequencer hosts share the network
+	NewSequencerNode(id int) host.P2PHostService
 }
 
 func NewMockP2PNetwork(avgBlockDuration time.Duration, avgLatency time.Duration, nodeWithIncomingP2PDisabled int) MockP2PNetworkIntf {
@@ -41,21 +44,47 @@ func NewMockP2PNetwork(avgBlockDuration time.Duration, avgLatency time.Duration,
 }
 
 func (m *MockP2PNetwork) NewNode(id int) host.P2PHostService {
+	return m.newNode(id, strconv.Itoa(id) == _sequencerID)
+}
+
+func (m *MockP2PNetwork) NewSequencerNode(id int) host.P2PHostService {
+	return m.newNode(id, true)
+}
+
+func (m *MockP2PNetwork) newNode(id int, isSequencer bool) *MockP2P {
 	idStr := strconv.Itoa(id)
 	isIncomingP2PDisabled := m.nodeWithIncomingP2PDisabled != 0 && m.nodeWithIncomingP2PDisabled == id
 	node := NewMockP2P(m, idStr, isIncomingP2PDisabled)
+	node.isSequencer = isSequencer
 	m.nodes[idStr] = node
 	return node
 }
 
+// activeSequencer returns the running sequencer node which isn't on standby, it models the routing of the sequencer
+// address to the active sequencer host
+func (m *MockP2PNetwork) activeSequencer() *MockP2P {
+	for _, node := range m.nodes {
+		if node.isActiveSequencer() && atomic.LoadInt32(node.listenerInterrupt) == 0 {
+			return node
+		}
+	}
+	return nil
+}
+
 func (m *MockP2PNetwork) RequestBatchesFromSequencer(id string, fromSeqNo *big.Int) {
-	seqNode := m.nodes[_sequencerID]
-	async.Schedule(m.delay()/2, func() { seqNode.ReceiveBatchRequest(id, fromSeqNo) })
+	async.Schedule(m.delay()/2, func() {
+		if seqNode := m.activeSequencer(); seqNode != nil {
+			seqNode.ReceiveBatchRequest(id, fromSeqNo)
+		}
+	})
 }
 
 func (m *MockP2PNetwork) SendTransactionToSequencer(tx common.EncryptedTx) {
-	seqNode := m.nodes[_sequencerID]
-	async.Schedule(m.delay()/2, func() { seqNode.ReceiveTransaction(tx) })
+	async.Schedule(m.delay()/2, func() {
+		if seqNode := m.activeSequencer(); seqNode != nil {
+			seqNode.ReceiveTransaction(tx)
+		}
+	})
 }
 
 func (m *MockP2PNetwork) BroadcastBatch(fromNodeID string, batches []*common.ExtBatch) {
@@ -93,6 +122,9 @@ type MockP2P struct {
 
 	listenerInterrupt     *int32
 	isIncomingP2PDisabled bool
+
+	isSequencer bool
+	isStandby   atomic.Bool
 }
 
 // NewMockP2P returns an instance of a configured L2 Network (no nodes)
@@ -144,6 +176,9 @@ func (n *MockP2P) BroadcastBatches(batches []*common.ExtBatch) error {
 	if atomic.LoadInt32(n.listenerInterrupt) == 1 {
 		return nil
 	}
+	if !n.isActiveSequencer() {
+		return errors.New("only sequencer can broadcast batches")
+	}
 
 	n.network.BroadcastBatch(n.id, batches)
 
@@ -196,6 +231,29 @@ func (n *MockP2P) RespondToBatchRequest(requesterID string, batches []*common.Ex
 	return nil
 }
 
+// Peers - the in-memory network has no peer discovery
+func (n *MockP2P) Peers() []*host.PeerInfo {
+	return nil
+}
+
+// PeerScores - the in-memory network doesn't score its nodes
+func (n *MockP2P) PeerScores() []host.PeerScore {
+	return nil
+}
+
+// SetStandby - the requests to the sequencer are routed to the sequencer node which isn't on standby
+func (n *MockP2P) SetStandby(standby bool) {
+	n.isStandby.Store(standby)
+}
+
+func (n *MockP2P) IsStandby() bool {
+	return n.isStandby.Load()
+}
+
+func (n *MockP2P) isActiveSequencer() bool {
+	return n.isSequencer && !n.isStandby.Load()
+}
+
 // ReceiveTransaction is a mock method that simulates receiving a batch from a peer and then forwarding to all subscribers
 func (n *MockP2P) ReceiveTransaction(tx common.EncryptedTx) {
 	for _, sub := range n.txSubscribers.Subscribers() {
@@ -205,7 +263,7 @@ func (n *MockP2P) ReceiveTransaction(tx common.EncryptedTx) {
 
 // ReceiveBatches is a mock method that simulates receiving a batch from a peer and then forwarding to all subscribers
 func (n *MockP2P) ReceiveBatches(batches []*common.ExtBatch, isLive bool) {
-	if n.isIncomingP2PDisabled {
+	if n.isIncomingP2PDisabled || n.isActiveSequencer() {
 		return
 	}
 
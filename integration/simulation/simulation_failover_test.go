@@ -0,0 +1,129 @@
+package simulation
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/obsclient"
+	"github.com/ten-protocol/go-ten/integration"
+	"github.com/ten-protocol/go-ten/integration/ethereummock"
+	"github.com/ten-protocol/go-ten/integration/simulation/network"
+	"github.com/ten-protocol/go-ten/integration/simulation/params"
+	"github.com/ten-protocol/go-ten/integration/simulation/stats"
+)
+
+const (
+	_failoverSequencers      = 3
+	_failoverValidators      = 2
+	_failoverLeaseDuration   = 2 * time.Second
+	_failoverLivenessTimeout = 3 * time.Second
+	_failoverTimeout         = 30 * time.Second
+	_failoverBatchesProgress = 5 // the batches the validators must receive after a failover
+)
+
+// This test creates a network of in memory L1 and L2 nodes with several sequencer hosts, which elect the active sequencer
+// with a shared lease. The active host is made to fail in different ways, and the test checks that exactly one backup
+// host takes over each time, with a new fencing token, and that the validators keep following the batches.
+func TestInMemoryFailoverSimulation(t *testing.T) {
+	setupSimTestLog("failover")
+
+	numberOfNodes := _failoverSequencers + _failoverValidators
+	simParams := &params.SimParams{
+		NumberOfNodes:       numberOfNodes,
+		AvgBlockDuration:    180 * time.Millisecond,
+		ContractRegistryLib: ethereummock.NewContractRegistryLibMock(),
+		ERC20ContractLib:    ethereummock.NewERC20ContractLibMock(),
+		BlobResolver:        ethereummock.NewMockBlobResolver(),
+		Wallets:             params.NewSimWallets(1, numberOfNodes, integration.EthereumChainID, integration.TenChainID),
+		IsInMem:             true,
+		L1TenData:           &params.L1TenData{},
+	}
+	simParams.AvgNetworkLatency = simParams.AvgBlockDuration / 15
+
+	netw := network.NewFailoverNetworkOfInMemoryNodes(_failoverSequencers, _failoverLeaseDuration, _failoverLivenessTimeout)
+	rpcHandles, err := netw.Create(simParams, stats.NewStats(numberOfNodes))
+	require.NoError(t, err)
+	defer netw.TearDown()
+	validator := rpcHandles.TenClients[_failoverSequencers]
+
+	t.Run("a single host is elected at start", func(t *testing.T) {
+		waitForValidatorBatches(t, validator)
+		require.Equal(t, []int{0}, netw.ActiveSequencers())
+		holder, _ := netw.LeaseHolder()
+		require.Equal(t, 0, holder)
+	})
+
+	t.Run("a backup takes over when the active host stops", func(t *testing.T) {
+		_, token := netw.LeaseHolder()
+		require.NoError(t, netw.StopNode(0))
+		newActive := waitForTakeOver(t, netw, 0, token)
+		require.NotEqual(t, 0, newActive)
+		waitForValidatorBatches(t, validator)
+	})
+
+	t.Run("the active host is fenced when it can't reach the lease storage", func(t *testing.T) {
+		fenced, token := netw.LeaseHolder()
+		netw.SetLeaseUnreachable(fenced, true)
+		newActive := waitForTakeOver(t, netw, fenced, token)
+		require.NotEqual(t, fenced, newActive)
+		waitForValidatorBatches(t, validator)
+
+		// the active enclave of the fenced host was stopped, the host stays on standby until it is restarted
+		netw.SetLeaseUnreachable(fenced, false)
+		time.Sleep(_failoverLivenessTimeout)
+		require.Equal(t, []int{newActive}, netw.ActiveSequencers())
+
+		// the validators followed the batches of all the sequencer hosts on a single chain
+		requireSameHead(t, rpcHandles.TenClients[newActive], validator)
+		requireSameHead(t, rpcHandles.TenClients[newActive], rpcHandles.TenClients[_failoverSequencers+1])
+	})
+}
+
+// waitForTakeOver waits for a host other than the previous one to hold the lease with a newer token and to be the only
+// active sequencer, and returns it
+func waitForTakeOver(t *testing.T, netw *network.FailoverNetworkOfInMemoryNodes, previous int, previousToken uint64) int {
+	var active []int
+	var holder int
+	var token uint64
+	require.Eventually(t, func() bool {
+		active = netw.ActiveSequencers()
+		holder, token = netw.LeaseHolder()
+		return len(active) > 1 || len(active) == 1 && active[0] == holder && holder != previous
+	}, _failoverTimeout, 50*time.Millisecond)
+	require.Len(t, active, 1, "several sequencer hosts are active")
+	require.Greater(t, token, previousToken)
+	return holder
+}
+
+// waitForValidatorBatches waits for the validator to receive new batches
+func waitForValidatorBatches(t *testing.T, validator *obsclient.ObsClient) {
+	start, err := validator.BatchNumber()
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		head, err := validator.BatchNumber()
+		return err == nil && head >= start+_failoverBatchesProgress
+	}, _failoverTimeout, 100*time.Millisecond)
+}
+
+// requireSameHead checks that both nodes have the same batch at the height of the head of the first one
+func requireSameHead(t *testing.T, node *obsclient.ObsClient, other *obsclient.ObsClient) {
+	head, err := node.BatchNumber()
+	require.NoError(t, err)
+	var expected, actual common.L2BatchHash
+	require.Eventually(t, func() bool {
+		nodeHeader, err := node.GetBatchHeaderByNumber(big.NewInt(int64(head)))
+		if err != nil {
+			return false
+		}
+		otherHeader, err := other.GetBatchHeaderByNumber(big.NewInt(int64(head)))
+		if err != nil {
+			return false
+		}
+		expected, actual = nodeHeader.Hash(), otherHeader.Hash()
+		return true
+	}, _failoverTimeout, 100*time.Millisecond)
+	require.Equal(t, expected, actual)
+}
@@ -30,8 +30,12 @@ import (
 	gethcommon "github.com/ethereum/go-ethereum/common"
 
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/ten-protocol/go-ten/go/common"
@@ -116,7 +120,12 @@ func TestTenGateway(t *testing.T) {
 		"testDifferentMessagesOnRegister":      testDifferentMessagesOnRegister,
 		"testInvokeNonSensitiveMethod":         testInvokeNonSensitiveMethod,
 		// "testRateLimiter":                      testRateLimiter,
-		"testSessionKeys": testSessionKeys,
+		"testSessionKeys":              testSessionKeys,
+		"testPollingFilters":           testPollingFilters,
+		"testBlockAndPendingTxFilters": testBlockAndPendingTxFilters,
+		"testTxPoolNamespace":          testTxPoolNamespace,
+		"testFeeHistory":               testFeeHistory,
+		"testGetProof":                 testGetProof,
 	} {
 		t.Run(name, func(t *testing.T) {
 			test(t, startPort, httpURL, wsURL, w)
@@ -225,6 +234,200 @@ func testSessionKeys(t *testing.T, _ int, httpURL, wsURL string, w wallet.Wallet
 	require.Nil(t, rec2)
 }
 
+func testPollingFilters(t *testing.T, _ int, httpURL, wsURL string, w wallet.Wallet) {
+	user0, err := NewGatewayUser([]wallet.Wallet{w}, httpURL, wsURL)
+	require.NoError(t, err)
+	err = user0.RegisterAccounts()
+	require.NoError(t, err)
+
+	user1, err := NewGatewayUser([]wallet.Wallet{datagenerator.RandomWallet(integration.TenChainID)}, httpURL, wsURL)
+	require.NoError(t, err)
+	err = user1.RegisterAccounts()
+	require.NoError(t, err)
+
+	contractAddr := deployContract(t, w, user0)
+
+	// install a filter for all the events of the contract
+	var filterID string
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &filterID, "eth_newFilter", map[string]any{
+		"address": contractAddr,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, filterID)
+
+	// no events were emitted since the filter was created
+	var changes []types.Log
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &changes, "eth_getFilterChanges", filterID)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = integrationCommon.InteractWithSmartContract(user0.HTTPClient, user0.Wallets[0], eventsContractABI, "setMessage", "user0PollingEvent1", contractAddr)
+	require.NoError(t, err)
+	_, err = integrationCommon.InteractWithSmartContract(user0.HTTPClient, user0.Wallets[0], eventsContractABI, "setMessage", "user0PollingEvent2", contractAddr)
+	require.NoError(t, err)
+
+	// wait for the batch with the second transaction to be the head
+	time.Sleep(2 * time.Second)
+
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &changes, "eth_getFilterChanges", filterID)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(changes))
+	for _, l := range changes {
+		require.Equal(t, contractAddr, l.Address)
+	}
+
+	// the changes are returned only once
+	var noChanges []types.Log
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &noChanges, "eth_getFilterChanges", filterID)
+	require.NoError(t, err)
+	require.Empty(t, noChanges)
+
+	// the filter logs are not affected by the polling
+	var filterLogs []types.Log
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &filterLogs, "eth_getFilterLogs", filterID)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(filterLogs))
+
+	// filters are private to the user who created them
+	err = user1.HTTPClient.Client().CallContext(context.Background(), &changes, "eth_getFilterChanges", filterID)
+	require.Error(t, err)
+	var uninstalled bool
+	err = user1.HTTPClient.Client().CallContext(context.Background(), &uninstalled, "eth_uninstallFilter", filterID)
+	require.NoError(t, err)
+	require.False(t, uninstalled)
+
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &uninstalled, "eth_uninstallFilter", filterID)
+	require.NoError(t, err)
+	require.True(t, uninstalled)
+
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &changes, "eth_getFilterChanges", filterID)
+	require.Error(t, err)
+}
+
+func testBlockAndPendingTxFilters(t *testing.T, _ int, httpURL, wsURL string, w wallet.Wallet) {
+	user0, err := NewGatewayUser([]wallet.Wallet{w}, httpURL, wsURL)
+	require.NoError(t, err)
+	err = user0.RegisterAccounts()
+	require.NoError(t, err)
+
+	var blockFilterID string
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &blockFilterID, "eth_newBlockFilter")
+	require.NoError(t, err)
+	require.NotEmpty(t, blockFilterID)
+
+	var pendingTxFilterID string
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &pendingTxFilterID, "eth_newPendingTransactionFilter")
+	require.NoError(t, err)
+	require.NotEmpty(t, pendingTxFilterID)
+
+	// a few batches are produced in this interval
+	time.Sleep(3 * time.Second)
+
+	var hashes []gethcommon.Hash
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &hashes, "eth_getFilterChanges", blockFilterID)
+	require.NoError(t, err)
+	require.NotEmpty(t, hashes)
+	for _, hash := range hashes {
+		_, err = user0.HTTPClient.HeaderByHash(context.Background(), hash)
+		require.NoError(t, err)
+	}
+
+	// the user has no pending transactions
+	var pendingTxs []gethcommon.Hash
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &pendingTxs, "eth_getFilterChanges", pendingTxFilterID)
+	require.NoError(t, err)
+	require.Empty(t, pendingTxs)
+}
+
+func testTxPoolNamespace(t *testing.T, _ int, httpURL, wsURL string, w wallet.Wallet) {
+	user0, err := NewGatewayUser([]wallet.Wallet{datagenerator.RandomWallet(integration.TenChainID)}, httpURL, wsURL)
+	require.NoError(t, err)
+	err = user0.RegisterAccounts()
+	require.NoError(t, err)
+
+	var status map[string]hexutil.Uint
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &status, "txpool_status")
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint(0), status["pending"])
+	require.Equal(t, hexutil.Uint(0), status["queued"])
+
+	var content map[string]map[string]map[string]any
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &content, "txpool_content")
+	require.NoError(t, err)
+	require.Empty(t, content["pending"])
+	require.Empty(t, content["queued"])
+
+	// the content of other accounts can't be requested
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &content, "txpool_contentFrom", w.Address())
+	require.Error(t, err)
+}
+
+func testFeeHistory(t *testing.T, _ int, httpURL, wsURL string, w wallet.Wallet) {
+	user0, err := NewGatewayUser([]wallet.Wallet{w}, httpURL, wsURL)
+	require.NoError(t, err)
+	err = user0.RegisterAccounts()
+	require.NoError(t, err)
+
+	// make sure there is at least one batch with a transaction paying fees
+	deployContract(t, w, user0)
+
+	percentiles := []float64{10, 50, 90}
+	feeHistory, err := user0.HTTPClient.FeeHistory(context.Background(), 5, nil, percentiles)
+	require.NoError(t, err)
+	require.NotEmpty(t, feeHistory.GasUsedRatio)
+	// the base fee of the next batch is included
+	require.Len(t, feeHistory.BaseFee, len(feeHistory.GasUsedRatio)+1)
+	require.Len(t, feeHistory.Reward, len(feeHistory.GasUsedRatio))
+	for i, rewards := range feeHistory.Reward {
+		require.Len(t, rewards, len(percentiles))
+		require.LessOrEqual(t, feeHistory.GasUsedRatio[i], float64(1))
+	}
+
+	_, err = user0.HTTPClient.FeeHistory(context.Background(), 5, nil, []float64{50, 10})
+	require.Error(t, err)
+}
+
+func testGetProof(t *testing.T, _ int, httpURL, wsURL string, w wallet.Wallet) {
+	user0, err := NewGatewayUser([]wallet.Wallet{w}, httpURL, wsURL)
+	require.NoError(t, err)
+	err = user0.RegisterAccounts()
+	require.NoError(t, err)
+
+	var header struct {
+		Number *hexutil.Big    `json:"number"`
+		Root   gethcommon.Hash `json:"stateRoot"`
+	}
+	err = user0.HTTPClient.Client().CallContext(context.Background(), &header, "eth_getBlockByNumber", "latest", false)
+	require.NoError(t, err)
+
+	// the proof of the own account can be requested, and it verifies against the state root of the batch
+	proofClient := gethclient.New(user0.HTTPClient.Client())
+	proof, err := proofClient.GetProof(context.Background(), w.Address(), nil, header.Number.ToInt())
+	require.NoError(t, err)
+	require.Equal(t, w.Address(), proof.Address)
+	require.NotEmpty(t, proof.AccountProof)
+
+	proofDB := memorydb.New()
+	for _, node := range proof.AccountProof {
+		encodedNode := gethcommon.FromHex(node)
+		require.NoError(t, proofDB.Put(crypto.Keccak256(encodedNode), encodedNode))
+	}
+	account, err := trie.VerifyProof(header.Root, crypto.Keccak256(w.Address().Bytes()), proofDB)
+	require.NoError(t, err)
+	require.NotEmpty(t, account)
+
+	// the proofs of other accounts can't be requested
+	_, err = proofClient.GetProof(context.Background(), datagenerator.RandomAddress(), nil, header.Number.ToInt())
+	require.Error(t, err)
+
+	// the commitments to the storage of a private contract are withheld
+	contractAddress := deployContract(t, w, user0)
+	proof, err = proofClient.GetProof(context.Background(), contractAddress, nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, proof.AccountProof)
+	require.Equal(t, gethcommon.Hash{}, proof.StorageHash)
+}
+
 func deployContract(t *testing.T, w wallet.Wallet, user0 *GatewayUser) gethcommon.Address {
 	// deploy events contract
 	deployTx := &types.LegacyTx{
@@ -72,6 +72,8 @@ func (api *adminAPI) StartHTTP(host *string, port *int, cors *string, apis *stri
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			batchConcurrency:       api.node.config.BatchConcurrency,
+			batchKey:               api.node.config.BatchKey,
 			httpBodyLimit:          engineAPIBodyLimit,
 		},
 		ExposedParam: "token",
@@ -152,6 +154,8 @@ func (api *adminAPI) StartWS(host *string, port *int, allowedOrigins *string, ap
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			batchConcurrency:       api.node.config.BatchConcurrency,
+			batchKey:               api.node.config.BatchKey,
 			httpBodyLimit:          engineAPIBodyLimit,
 		},
 		ExposedParam: "token",
@@ -164,6 +164,13 @@ type Config struct {
 	// BatchResponseMaxSize is the maximum number of bytes returned from a batched rpc call.
 	BatchResponseMaxSize int `toml:",omitempty"`
 
+	// BatchConcurrency is the maximum number of batch calls executed in parallel for a user.
+	// 0 executes the calls of a batch sequentially.
+	BatchConcurrency int `toml:",omitempty"`
+
+	// BatchKey identifies the client the concurrent batch calls are bounded by. The client IP is used when it is nil.
+	BatchKey rpc.BatchKeyFunc `toml:"-"`
+
 	// JWTSecret is the path to the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
 
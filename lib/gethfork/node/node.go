@@ -355,6 +355,8 @@ func (n *Node) startRPC() error {
 	rpcConfig := rpcEndpointConfig{
 		batchItemLimit:         n.config.BatchRequestLimit,
 		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		batchConcurrency:       n.config.BatchConcurrency,
+		batchKey:               n.config.BatchKey,
 		httpBodyLimit:          engineAPIBodyLimit,
 	}
 
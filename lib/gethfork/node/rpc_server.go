@@ -25,6 +25,13 @@ type RPCConfig struct {
 
 	// ExposedURLParamNames - url prams that are available in the services
 	ExposedURLParamNames []string
+
+	// BatchItemLimit - the maximum number of requests in a JSON-RPC batch. 0 means unlimited
+	BatchItemLimit int
+	// BatchConcurrency - the maximum number of batch requests executed in parallel for a user. 0 executes them sequentially
+	BatchConcurrency int
+	// BatchKey - identifies the client the concurrent batch requests are bounded by. The client IP is used when it is nil
+	BatchKey rpc.BatchKeyFunc
 }
 
 // Route defines the path plus handler for a given path
@@ -52,6 +59,9 @@ func NewServer(config *RPCConfig, logger gethlog.Logger) Server {
 		Logger:               logger,
 		ExposedURLParamNames: config.ExposedURLParamNames,
 		TLSConfig:            config.TLSConfig,
+		BatchRequestLimit:    config.BatchItemLimit,
+		BatchConcurrency:     config.BatchConcurrency,
+		BatchKey:             config.BatchKey,
 	}
 	if config.EnableHTTP {
 		rpcConfig.HTTPHost = config.Host
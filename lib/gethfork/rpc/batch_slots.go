@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// BatchKeyFunc returns the identity the parallel batch calls of a request are bounded by. It should only
+// identify a user once their credentials were verified, and fall back to the client address otherwise, so
+// the clients can't get more slots by sending made up credentials.
+type BatchKeyFunc func(ctx context.Context) string
+
+// batchSlots bounds the number of batch calls executed in parallel for each client, across all the
+// batches of the client. The clients are identified by the BatchKeyFunc, or by their IP when it is not set.
+type batchSlots struct {
+	limit int
+	key   BatchKeyFunc
+
+	mutex   sync.Mutex
+	clients map[string]*clientSlots
+}
+
+type clientSlots struct {
+	slots chan struct{}
+	refs  int // the number of batches of the client being executed
+}
+
+func newBatchSlots(limit int, key BatchKeyFunc) *batchSlots {
+	if key == nil {
+		key = peerIP
+	}
+	return &batchSlots{limit: limit, key: key, clients: make(map[string]*clientSlots)}
+}
+
+// peerIP - the IP of the client connection
+func peerIP(ctx context.Context) string {
+	remoteAddr := PeerInfoFromContext(ctx).RemoteAddr
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// acquire returns the key of the client of the request and its slots. A call takes a slot by sending
+// to the channel and frees it by receiving from it. Must be followed by release when the batch is finished.
+func (b *batchSlots) acquire(ctx context.Context) (string, chan struct{}) {
+	client := b.key(ctx)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c, found := b.clients[client]
+	if !found {
+		c = &clientSlots{slots: make(chan struct{}, b.limit)}
+		b.clients[client] = c
+	}
+	c.refs++
+	return client, c.slots
+}
+
+// release forgets the slots of the client when none of their batches is executing
+func (b *batchSlots) release(client string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.clients[client]
+	c.refs--
+	if c.refs == 0 {
+		delete(b.clients, client)
+	}
+}
@@ -67,6 +67,7 @@ type handler struct {
 	allowSubscribe       bool
 	batchRequestLimit    int
 	batchResponseMaxSize int
+	batchSlots           *batchSlots // bounds the parallel execution of the batch calls. nil executes them sequentially
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -78,7 +79,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, batchRequestLimit, batchResponseMaxSize int, userID []byte) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, batchRequestLimit, batchResponseMaxSize int, batchSlots *batchSlots, userID []byte) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:                  reg,
@@ -93,6 +94,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		log:                  log.Root(),
 		batchRequestLimit:    batchRequestLimit,
 		batchResponseMaxSize: batchResponseMaxSize,
+		batchSlots:           batchSlots,
 		UserID:               userID,
 	}
 	if conn.remoteAddr() != "" {
@@ -104,37 +106,21 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 
 // batchCallBuffer manages in progress call messages and their responses during a batch
 // call. Calls need to be synchronized between the processing and timeout-triggering
-// goroutines.
+// goroutines. The calls can be answered in any order, but the responses are written in
+// the order of the calls.
 type batchCallBuffer struct {
 	mutex sync.Mutex
 	calls []*jsonrpcMessage
-	resp  []*jsonrpcMessage
+	resp  []*jsonrpcMessage // the response of each call. nil for unanswered calls and notifications
 	wrote bool
 }
 
-// nextCall returns the next unprocessed message.
-func (b *batchCallBuffer) nextCall() *jsonrpcMessage {
+// pushResponse sets the response of the i-th call.
+func (b *batchCallBuffer) pushResponse(i int, answer *jsonrpcMessage) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	if len(b.calls) == 0 {
-		return nil
-	}
-	// The popping happens in `pushAnswer`. The in progress call is kept
-	// so we can return an error for it in case of timeout.
-	msg := b.calls[0]
-	return msg
-}
-
-// pushResponse adds the response to last call returned by nextCall.
-func (b *batchCallBuffer) pushResponse(answer *jsonrpcMessage) {
-	b.mutex.Lock()
-	defer b.mutex.Unlock()
-
-	if answer != nil {
-		b.resp = append(b.resp, answer)
-	}
-	b.calls = b.calls[1:]
+	b.resp[i] = answer
 }
 
 // write sends the responses.
@@ -151,9 +137,9 @@ func (b *batchCallBuffer) respondWithError(ctx context.Context, conn jsonWriter,
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	for _, msg := range b.calls {
-		if !msg.isNotification() {
-			b.resp = append(b.resp, msg.errorResponse(err))
+	for i, msg := range b.calls {
+		if b.resp[i] == nil && !msg.isNotification() {
+			b.resp[i] = msg.errorResponse(err)
 		}
 	}
 	b.doWrite(ctx, conn, true)
@@ -166,8 +152,14 @@ func (b *batchCallBuffer) doWrite(ctx context.Context, conn jsonWriter, isErrorR
 		return
 	}
 	b.wrote = true // can only write once
-	if len(b.resp) > 0 {
-		conn.writeJSON(ctx, b.resp, isErrorResponse)
+	resp := make([]*jsonrpcMessage, 0, len(b.resp))
+	for _, r := range b.resp {
+		if r != nil {
+			resp = append(resp, r)
+		}
+	}
+	if len(resp) > 0 {
+		conn.writeJSON(ctx, resp, isErrorResponse)
 	}
 }
 
@@ -204,7 +196,7 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		var (
 			timer      *time.Timer
 			cancel     context.CancelFunc
-			callBuffer = &batchCallBuffer{calls: calls, resp: make([]*jsonrpcMessage, 0, len(calls))}
+			callBuffer = &batchCallBuffer{calls: calls, resp: make([]*jsonrpcMessage, len(calls))}
 		)
 
 		cp.ctx, cancel = context.WithCancel(cp.ctx)
@@ -221,26 +213,10 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			})
 		}
 
-		responseBytes := 0
-		for {
-			// No need to handle rest of calls if timed out.
-			if cp.ctx.Err() != nil {
-				break
-			}
-			msg := callBuffer.nextCall()
-			if msg == nil {
-				break
-			}
-			resp := h.handleCallMsg(cp, msg)
-			callBuffer.pushResponse(resp)
-			if resp != nil && h.batchResponseMaxSize != 0 {
-				responseBytes += len(resp.Result)
-				if responseBytes > h.batchResponseMaxSize {
-					err := &internalServerError{errcodeResponseTooLarge, errMsgResponseTooLarge}
-					callBuffer.respondWithError(cp.ctx, h.conn, err)
-					break
-				}
-			}
+		if h.batchSlots == nil {
+			h.runBatchCalls(cp, callBuffer)
+		} else {
+			h.runBatchCallsParallel(cp, callBuffer, cancel)
 		}
 		if timer != nil {
 			timer.Stop()
@@ -254,6 +230,73 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	})
 }
 
+// runBatchCalls executes the calls of a batch one after the other.
+func (h *handler) runBatchCalls(cp *callProc, callBuffer *batchCallBuffer) {
+	responseBytes := 0
+	for i, msg := range callBuffer.calls {
+		// No need to handle rest of calls if timed out.
+		if cp.ctx.Err() != nil {
+			break
+		}
+		resp := h.handleCallMsg(cp, msg)
+		callBuffer.pushResponse(i, resp)
+		if resp != nil && h.batchResponseMaxSize != 0 {
+			responseBytes += len(resp.Result)
+			if responseBytes > h.batchResponseMaxSize {
+				err := &internalServerError{errcodeResponseTooLarge, errMsgResponseTooLarge}
+				callBuffer.respondWithError(cp.ctx, h.conn, err)
+				break
+			}
+		}
+	}
+}
+
+// runBatchCallsParallel executes the calls of a batch in parallel. The number of calls in flight is
+// bounded per client, across all the batches of the client. A failed call doesn't affect the other calls
+// of the batch, which are answered individually.
+func (h *handler) runBatchCallsParallel(cp *callProc, callBuffer *batchCallBuffer, cancel context.CancelFunc) {
+	client, slots := h.batchSlots.acquire(cp.ctx)
+	defer h.batchSlots.release(client)
+
+	var (
+		wg            sync.WaitGroup
+		mutex         sync.Mutex // protects cp.notifiers and responseBytes
+		responseBytes int
+	)
+loop:
+	for i, msg := range callBuffer.calls {
+		// wait for a free slot. No need to handle rest of calls if timed out.
+		select {
+		case slots <- struct{}{}:
+		case <-cp.ctx.Done():
+			break loop
+		}
+		wg.Add(1)
+		go func(i int, msg *jsonrpcMessage) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// each call collects its own subscriptions, which are merged into the batch
+			callCp := &callProc{ctx: cp.ctx}
+			resp := h.handleCallMsg(callCp, msg)
+			callBuffer.pushResponse(i, resp)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			cp.notifiers = append(cp.notifiers, callCp.notifiers...)
+			if resp != nil && h.batchResponseMaxSize != 0 {
+				responseBytes += len(resp.Result)
+				if responseBytes > h.batchResponseMaxSize {
+					cancel()
+					err := &internalServerError{errcodeResponseTooLarge, errMsgResponseTooLarge}
+					callBuffer.respondWithError(cp.ctx, h.conn, err)
+				}
+			}
+		}(i, msg)
+	}
+	wg.Wait()
+}
+
 func (h *handler) respondWithBatchTooLarge(cp *callProc, batch []*jsonrpcMessage) {
 	resp := errorMessage(&invalidRequestError{errMsgBatchTooLarge})
 	// Find the first call and add its "id" field to the error.
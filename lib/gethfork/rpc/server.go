@@ -55,6 +55,7 @@ type Server struct {
 	run                atomic.Bool
 	batchItemLimit     int
 	batchResponseLimit int
+	batchSlots         *batchSlots
 	httpBodyLimit      int
 }
 
@@ -84,6 +85,18 @@ func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
 	s.batchResponseLimit = maxResponseSize
 }
 
+// SetBatchConcurrency sets the maximum number of batch calls executed in parallel for a client,
+// across all the batches of the client. The clients are identified by the key function, or by
+// their IP when it is nil. When it is not set, the calls of a batch are executed one after the other.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetBatchConcurrency(limit int, key BatchKeyFunc) {
+	if limit > 0 {
+		s.batchSlots = newBatchSlots(limit, key)
+	}
+}
+
 // SetHTTPBodyLimit sets the size limit for HTTP requests.
 //
 // This method should be called before processing any requests via ServeHTTP.
@@ -116,6 +129,7 @@ func (s *Server) ServeCodec(codec ServerCodec, _ CodecOption, userID []byte) {
 		idgen:              s.idgen,
 		batchItemLimit:     s.batchItemLimit,
 		batchResponseLimit: s.batchResponseLimit,
+		batchSlots:         s.batchSlots,
 		UserID:             userID,
 	}
 	c := initClient(codec, &s.services, cfg)
@@ -150,7 +164,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services, s.batchItemLimit, s.batchResponseLimit, nil)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.batchItemLimit, s.batchResponseLimit, s.batchSlots, nil)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -234,9 +248,11 @@ type PeerInfo struct {
 		// Protocol version, i.e. "HTTP/1.1". This is not set for WebSocket.
 		Version string
 		// Header values sent by the client.
-		UserAgent string
-		Origin    string
-		Host      string
+		UserAgent     string
+		Origin        string
+		Host          string
+		ForwardedFor  string
+		Authorization string
 	}
 }
 
@@ -325,6 +325,8 @@ func newWebsocketCodec(conn *websocket.Conn, host string, req http.Header, readL
 	wc.info.HTTP.Host = host
 	wc.info.HTTP.Origin = req.Get("Origin")
 	wc.info.HTTP.UserAgent = req.Get("User-Agent")
+	wc.info.HTTP.ForwardedFor = req.Get("X-Forwarded-For")
+	wc.info.HTTP.Authorization = req.Get("Authorization")
 	// Start pinger.
 	conn.SetPongHandler(func(appData string) error {
 		select {
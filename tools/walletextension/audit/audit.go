@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+)
+
+// Redaction - how the params of the requests are written to the audit trail
+type Redaction string
+
+const (
+	RedactNone Redaction = "none" // the params are written as they were received
+	RedactHash Redaction = "hash" // only the keyed hash of the params is written, so identical requests can still be correlated
+	RedactDrop Redaction = "drop" // the params are not written
+)
+
+const (
+	RateLimitAllowed  = "allowed"
+	RateLimitRejected = "rejected"
+)
+
+// Record - one line of the audit trail, written for every JSON-RPC request served by the gateway
+type Record struct {
+	Time      time.Time            `json:"time"`
+	User      string               `json:"user,omitempty"` // the hashed user id. Empty for the unauthenticated requests
+	Method    string               `json:"method"`
+	Params    json.RawMessage      `json:"params,omitempty"`
+	Accounts  []gethcommon.Address `json:"accounts,omitempty"` // the accounts of the user used to execute the request
+	TxHashes  []gethcommon.Hash    `json:"txHashes,omitempty"` // the transactions submitted by the request
+	RateLimit string               `json:"rateLimit"`
+	Error     string               `json:"error,omitempty"`
+	LatencyMs int64                `json:"latencyMs"`
+}
+
+// HashUserID - the user ids are secrets, so only their hash is written to the audit trail
+func HashUserID(userID []byte) string {
+	return common.HashForLogging(userID)
+}
+
+// Config - an empty path and URL disable the audit trail
+type Config struct {
+	Path       string        // the file the records are appended to. It is rotated when it reaches MaxSizeMB
+	MaxSizeMB  int           // the size at which the file is rotated
+	MaxBackups int           // the maximum number of rotated files kept. 0 keeps all the files within the retention
+	Retention  time.Duration // the rotated files older than this are removed. 0 keeps them forever
+	URL        string        // the HTTP endpoint the records are posted to, as JSON lines
+	Redaction  Redaction
+	HashKey    []byte // the secret of the deployment the params are hashed with. Required by the hash redaction
+}
+
+// Sink - a destination of the audit records. Write must not block the request which is audited for long
+type Sink interface {
+	Write(record *Record)
+	Close() error
+}
+
+// Logger - writes the audit records to the configured sinks. A nil Logger discards the records
+type Logger struct {
+	sinks     []Sink
+	redaction Redaction
+	hashKey   []byte
+	logger    gethlog.Logger
+}
+
+// HashKeyFromSecret - the key the params are hashed with, derived from a secret of the deployment.
+// The params are often guessable (e.g. an address), so a plain hash could be reversed by hashing the candidates.
+func HashKeyFromSecret(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("gateway audit params"))
+	return mac.Sum(nil)
+}
+
+func NewLogger(config Config, logger gethlog.Logger) (*Logger, error) {
+	redaction := config.Redaction
+	switch redaction {
+	case "":
+		redaction = RedactHash
+	case RedactNone, RedactHash, RedactDrop:
+	default:
+		return nil, fmt.Errorf("unknown audit redaction: %s", redaction)
+	}
+
+	var sinks []Sink
+	if config.Path != "" {
+		sinks = append(sinks, NewFileSink(config.Path, config.MaxSizeMB, config.MaxBackups, config.Retention, logger))
+	}
+	if config.URL != "" {
+		sinks = append(sinks, NewHTTPSink(config.URL, logger))
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	if redaction == RedactHash && len(config.HashKey) == 0 {
+		return nil, errors.New("the hash redaction of the audit params requires a hash key")
+	}
+	return &Logger{sinks: sinks, redaction: redaction, hashKey: config.HashKey, logger: logger}, nil
+}
+
+// Log - redacts the params and writes the record to all the sinks
+func (l *Logger) Log(record *Record, params []any) {
+	if l == nil {
+		return
+	}
+	record.Params = l.redact(params)
+	for _, sink := range l.sinks {
+		sink.Write(record)
+	}
+}
+
+func (l *Logger) redact(params []any) json.RawMessage {
+	if l.redaction == RedactDrop || len(params) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		l.logger.Warn("could not encode the params of an audit record", log.ErrKey, err)
+		return nil
+	}
+	if l.redaction == RedactHash {
+		mac := hmac.New(sha256.New, l.hashKey)
+		mac.Write(encoded)
+		encoded, _ = json.Marshal(gethcommon.BytesToHash(mac.Sum(nil)))
+	}
+	return encoded
+}
+
+// Close - flushes the records and closes the sinks
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	var errs []error
+	for _, sink := range l.sinks {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
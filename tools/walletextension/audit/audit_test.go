@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
+)
+
+func queryAll(t *testing.T, path string, filter Filter) []*Record {
+	var records []*Record
+	require.NoError(t, Query(path, filter, func(record *Record) error {
+		records = append(records, record)
+		return nil
+	}))
+	return records
+}
+
+func TestFileSinkAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := NewLogger(Config{Path: path, Redaction: RedactNone}, gethlog.New())
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alice := HashUserID([]byte("alice"))
+	bob := HashUserID([]byte("bob"))
+	txHash := gethcommon.HexToHash("0x01")
+	logger.Log(&Record{Time: start, User: alice, Method: "eth_call", RateLimit: RateLimitAllowed}, []any{"0x01"})
+	logger.Log(&Record{Time: start.Add(time.Hour), User: bob, Method: "eth_sendRawTransaction", TxHashes: []gethcommon.Hash{txHash}, RateLimit: RateLimitAllowed}, nil)
+	logger.Log(&Record{Time: start.Add(2 * time.Hour), User: alice, Method: "eth_call", RateLimit: RateLimitRejected, Error: "rate limit exceeded"}, nil)
+	require.NoError(t, logger.Close())
+
+	// the records of a rotated file are returned first
+	rotated := filepath.Join(filepath.Dir(path), "audit-2023-12-31T00-00-00.000.jsonl")
+	line, err := json.Marshal(&Record{Time: start.Add(-time.Hour), User: alice, Method: "eth_getBalance"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(rotated, append(line, '\n'), 0o600))
+
+	records := queryAll(t, path, Filter{})
+	require.Len(t, records, 4)
+	require.Equal(t, "eth_getBalance", records[0].Method)
+	require.JSONEq(t, `["0x01"]`, string(records[1].Params))
+	require.Equal(t, []gethcommon.Hash{txHash}, records[2].TxHashes)
+
+	require.Len(t, queryAll(t, path, Filter{User: alice}), 3)
+	require.Len(t, queryAll(t, path, Filter{User: alice, Method: "eth_call"}), 2)
+	records = queryAll(t, path, Filter{From: start, To: start.Add(2 * time.Hour)})
+	require.Len(t, records, 2)
+	require.Equal(t, bob, records[1].User)
+}
+
+func TestRedaction(t *testing.T) {
+	params := []any{map[string]string{"to": "0x02"}}
+	encoded, err := json.Marshal(params)
+	require.NoError(t, err)
+	hashKey := HashKeyFromSecret([]byte("deployment secret"))
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write(encoded)
+	hash, err := json.Marshal(gethcommon.BytesToHash(mac.Sum(nil)))
+	require.NoError(t, err)
+
+	for redaction, expected := range map[Redaction]json.RawMessage{
+		RedactNone: encoded,
+		RedactHash: hash,
+		RedactDrop: nil,
+	} {
+		logger := &Logger{redaction: redaction, hashKey: hashKey, logger: gethlog.New()}
+		require.Equal(t, expected, logger.redact(params), redaction)
+	}
+
+	// the hashes of the params depend on the secret of the deployment
+	other := &Logger{redaction: RedactHash, hashKey: HashKeyFromSecret([]byte("other secret")), logger: gethlog.New()}
+	require.NotEqual(t, hash, []byte(other.redact(params)))
+
+	// the params are hashed by default, which requires a key
+	logger, err := NewLogger(Config{Path: filepath.Join(t.TempDir(), "audit.jsonl"), HashKey: hashKey}, gethlog.New())
+	require.NoError(t, err)
+	require.Equal(t, RedactHash, logger.redaction)
+	_, err = NewLogger(Config{Path: filepath.Join(t.TempDir(), "audit.jsonl")}, gethlog.New())
+	require.Error(t, err)
+
+	_, err = NewLogger(Config{Path: "audit.jsonl", Redaction: "unknown"}, gethlog.New())
+	require.Error(t, err)
+
+	// the audit trail is disabled without a sink, and the nil logger discards the records
+	logger, err = NewLogger(Config{}, gethlog.New())
+	require.NoError(t, err)
+	require.Nil(t, logger)
+	logger.Log(&Record{}, params)
+	require.NoError(t, logger.Close())
+}
+
+func TestHTTPSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []Record
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		scanner := bufio.NewScanner(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		for scanner.Scan() {
+			var record Record
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+			received = append(received, record)
+		}
+	}))
+	defer server.Close()
+
+	logger, err := NewLogger(Config{URL: server.URL, HashKey: HashKeyFromSecret([]byte("deployment secret"))}, gethlog.New())
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		logger.Log(&Record{Time: time.Now(), Method: "eth_chainId", RateLimit: RateLimitAllowed}, nil)
+	}
+	// closing sends the buffered records
+	require.NoError(t, logger.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 3)
+	require.Equal(t, "eth_chainId", received[0].Method)
+}
+
+func TestHTTPSinkBackpressure(t *testing.T) {
+	// the sink is not running, so the buffer is only emptied by the test
+	sink := &HTTPSink{records: make(chan *Record, 1), logger: gethlog.New()}
+	sink.Write(&Record{Method: "eth_chainId"})
+
+	// a record which doesn't fit in the buffer waits for the sink to catch up
+	go func() {
+		time.Sleep(httpSinkBackpressure / 5)
+		<-sink.records
+	}()
+	sink.Write(&Record{Method: "eth_blockNumber"})
+	require.Zero(t, sink.dropped.Load())
+
+	// and is dropped if it doesn't
+	sink.Write(&Record{Method: "eth_gasPrice"})
+	require.Equal(t, int64(1), sink.dropped.Load())
+	require.Equal(t, "eth_blockNumber", (<-sink.records).Method)
+
+	resp := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, resp.Body.String(), "gateway_audit_dropped 1")
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxLineSize - the largest audit record which can be read back
+const maxLineSize = 16 * 1024 * 1024
+
+// Filter - the zero values match all the records
+type Filter struct {
+	User   string // the hashed user id
+	Method string
+	From   time.Time // inclusive
+	To     time.Time // exclusive
+}
+
+func (f Filter) Match(record *Record) bool {
+	switch {
+	case f.User != "" && record.User != f.User:
+		return false
+	case f.Method != "" && record.Method != f.Method:
+		return false
+	case !f.From.IsZero() && record.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !record.Time.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// Query - calls fn with the records matching the filter from the audit file and its rotated files, oldest first
+func Query(path string, filter Filter, fn func(*Record) error) error {
+	files, err := auditFiles(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := queryFile(file, filter, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditFiles - the rotated files, which are named <name>-<timestamp>.<ext> so they sort by time, followed by the current file
+func auditFiles(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the audit directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) {
+			files = append(files, filepath.Join(filepath.Dir(path), name))
+		}
+	}
+	sort.Strings(files)
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no audit files found for %s", path)
+	}
+	return files, nil
+}
+
+func queryFile(path string, filter Filter, fn func(*Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		// the file was removed by the rotation after it was listed
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("invalid audit record at %s:%d: %w", path, line, err)
+		}
+		if !filter.Match(&record) {
+			continue
+		}
+		if err := fn(&record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	httpSinkBufferSize    = 10_000
+	httpSinkBatchSize     = 500
+	httpSinkFlushInterval = time.Second
+	httpSinkTimeout       = 10 * time.Second
+	// the maximum time a request waits for space in the buffer of the HTTP sink before its record is dropped
+	httpSinkBackpressure = 50 * time.Millisecond
+)
+
+// FileSink - appends the records as JSON lines to a file which is rotated by size.
+// The rotated files are named <name>-<timestamp>.<ext> and are removed after the retention.
+type FileSink struct {
+	file   *lumberjack.Logger
+	logger gethlog.Logger
+}
+
+func NewFileSink(path string, maxSizeMB int, maxBackups int, retention time.Duration, logger gethlog.Logger) *FileSink {
+	maxAgeDays := 0
+	if retention > 0 {
+		// lumberjack counts the retention in days
+		maxAgeDays = int((retention + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+	return &FileSink{
+		file: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    maxSizeMB,
+			MaxBackups: maxBackups,
+			MaxAge:     maxAgeDays,
+		},
+		logger: logger,
+	}
+}
+
+func (s *FileSink) Write(record *Record) {
+	line, err := encodeLine(record)
+	if err != nil {
+		s.logger.Error("could not encode audit record", log.ErrKey, err)
+		return
+	}
+	// a single write per record, so the concurrent records are not interleaved
+	if _, err := s.file.Write(line); err != nil {
+		s.logger.Error("could not write audit record", log.ErrKey, err)
+	}
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink - posts the records in batches of JSON lines to an HTTP endpoint.
+// The records are buffered, so a slow endpoint doesn't delay the requests. When the buffer is full, the requests wait
+// briefly for the endpoint to catch up, and then their records are dropped. The dropped records are counted in the
+// gateway_audit_dropped metric. They are still written to the file sink, when it is configured.
+type HTTPSink struct {
+	url     string
+	client  *http.Client
+	records chan *Record
+	done    chan struct{}
+	logger  gethlog.Logger
+
+	mu      sync.RWMutex // guards the channel while it is closed
+	closed  bool
+	dropped atomic.Int64
+}
+
+func NewHTTPSink(url string, logger gethlog.Logger) *HTTPSink {
+	s := &HTTPSink{
+		url:     url,
+		client:  &http.Client{Timeout: httpSinkTimeout},
+		records: make(chan *Record, httpSinkBufferSize),
+		done:    make(chan struct{}),
+		logger:  logger,
+	}
+	go s.run()
+	return s
+}
+
+func (s *HTTPSink) Write(record *Record) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.records <- record:
+		return
+	default:
+	}
+
+	// the buffer is full, so the request waits for the sink to catch up
+	timer := time.NewTimer(httpSinkBackpressure)
+	defer timer.Stop()
+	select {
+	case s.records <- record:
+	case <-timer.C:
+		s.dropped.Add(1)
+		metrics.RecordAuditDropped()
+	}
+}
+
+// Close - sends the buffered records and stops the sink
+func (s *HTTPSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *HTTPSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(httpSinkFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Record, 0, httpSinkBatchSize)
+	for {
+		select {
+		case record, ok := <-s.records:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= httpSinkBatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (s *HTTPSink) flush(batch []*Record) {
+	if dropped := s.dropped.Swap(0); dropped > 0 {
+		s.logger.Warn("audit records dropped because the HTTP sink is too slow", "count", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	var body bytes.Buffer
+	for _, record := range batch {
+		line, err := encodeLine(record)
+		if err != nil {
+			s.logger.Error("could not encode audit record", log.ErrKey, err)
+			continue
+		}
+		body.Write(line)
+	}
+	if err := s.post(body.Bytes()); err != nil {
+		s.logger.Error("could not send audit records", "count", len(batch), log.ErrKey, err)
+	}
+}
+
+func (s *HTTPSink) post(body []byte) error {
+	resp, err := s.client.Post(s.url, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func encodeLine(record *Record) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
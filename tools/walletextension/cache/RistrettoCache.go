@@ -2,9 +2,11 @@ package cache
 
 import (
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/dgraph-io/ristretto/v2"
@@ -20,6 +22,14 @@ type ristrettoCache struct {
 	quit               chan struct{}
 	lastEviction       atomic.Pointer[time.Time]
 	shortLivingEnabled *atomic.Bool
+
+	watchedLock     sync.RWMutex
+	watchedAccounts map[common.Address]*watchedAccount
+}
+
+type watchedAccount struct {
+	since        time.Time // since when the changes of the state of the account are reported
+	lastEviction time.Time // when a batch last changed the state of the account
 }
 
 // NewRistrettoCacheWithEviction returns a new ristrettoCache.
@@ -39,6 +49,7 @@ func NewRistrettoCacheWithEviction(nrElems int, logger log.Logger) (Cache, error
 		quit:               make(chan struct{}),
 		lastEviction:       atomic.Pointer[time.Time]{},
 		shortLivingEnabled: &atomic.Bool{},
+		watchedAccounts:    make(map[common.Address]*watchedAccount),
 	}
 	now := time.Now()
 	c.lastEviction.Store(&now)
@@ -75,6 +86,50 @@ func (c *ristrettoCache) IsEvicted(key []byte, originalTTL time.Duration) bool {
 	return c.lastEviction.Load().After(cachedTime)
 }
 
+func (c *ristrettoCache) WatchAccount(account common.Address) {
+	c.watchedLock.Lock()
+	defer c.watchedLock.Unlock()
+	now := time.Now()
+	c.watchedAccounts[account] = &watchedAccount{since: now, lastEviction: now}
+}
+
+func (c *ristrettoCache) UnwatchAccount(account common.Address) {
+	c.watchedLock.Lock()
+	defer c.watchedLock.Unlock()
+	delete(c.watchedAccounts, account)
+}
+
+func (c *ristrettoCache) EvictAccountState(account common.Address) {
+	c.watchedLock.Lock()
+	defer c.watchedLock.Unlock()
+	if watched, found := c.watchedAccounts[account]; found {
+		watched.lastEviction = time.Now()
+	}
+}
+
+func (c *ristrettoCache) IsAccountStateEvicted(accounts []common.Address, since time.Time) bool {
+	if !c.shortLivingEnabled.Load() {
+		return true
+	}
+	// no batch was received since the request
+	if c.lastEviction.Load().Before(since) {
+		return false
+	}
+	if len(accounts) == 0 {
+		return true
+	}
+	c.watchedLock.RLock()
+	defer c.watchedLock.RUnlock()
+	for _, account := range accounts {
+		watched, found := c.watchedAccounts[account]
+		// a change of the state of the account may have been missed if it was not watched for the whole time
+		if !found || watched.since.After(since) || !watched.lastEviction.Before(since) {
+			return true
+		}
+	}
+	return false
+}
+
 // Set adds the key and value to the cache.
 func (c *ristrettoCache) Set(key []byte, value any, ttl time.Duration) bool {
 	return c.cache.SetWithTTL(key, value, defaultCost, ttl)
@@ -6,7 +6,9 @@ import (
 
 	"golang.org/x/sync/singleflight"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
 )
 
 type Cache interface {
@@ -19,6 +21,20 @@ type Cache interface {
 	// IsEvicted - based on the eviction event and the time of caching, calculates whether the key was evicted
 	IsEvicted(key []byte, originalTTL time.Duration) bool
 
+	// WatchAccount - notify the cache that from now on, the batches which change the state of the account are reported
+	// with EvictAccountState, so the LatestAccountState responses which only depend on it survive the other batches.
+	WatchAccount(account common.Address)
+
+	// UnwatchAccount - notify the cache that the changes of the state of the account are no longer reported
+	UnwatchAccount(account common.Address)
+
+	// EvictAccountState - notify the cache that a batch changed the state of a watched account
+	EvictAccountState(account common.Address)
+
+	// IsAccountStateEvicted - whether a response depending on the state of the accounts, requested at the given time,
+	// was evicted. It is evicted by the next batch, unless all the accounts were watched since the request.
+	IsAccountStateEvicted(accounts []common.Address, since time.Time) bool
+
 	Set(key []byte, value any, ttl time.Duration) bool
 	Get(key []byte) (value any, ok bool)
 	Remove(key []byte)
@@ -34,14 +50,36 @@ const (
 	NoCache     Strategy = iota
 	LatestBatch Strategy = iota
 	LongLiving  Strategy = iota
-
-	longCacheTTL  = 5 * time.Hour
-	shortCacheTTL = 1 * time.Minute
+	// LatestAccountState - the response only depends on the latest state of the Cfg.Accounts, so it is cached per user
+	// until a batch changes one of them, or until the next batch if they are not watched.
+	// Unlike LatestBatch, a batch received while the request executes evicts the response.
+	LatestAccountState Strategy = iota
+
+	longCacheTTL         = 5 * time.Hour
+	shortCacheTTL        = 1 * time.Minute
+	accountStateCacheTTL = 5 * time.Minute
 )
 
 type Cfg struct {
 	Type        Strategy
 	DynamicType func() Strategy
+	// Accounts - the accounts whose state the LatestAccountState responses depend on
+	Accounts []common.Address
+}
+
+// Strategy - the caching strategy of the request
+func (c *Cfg) Strategy() Strategy {
+	if c.DynamicType != nil {
+		return c.DynamicType()
+	}
+	return c.Type
+}
+
+// accountStateEntry - a LatestAccountState response together with the time the request started,
+// so a batch received while the request was executing evicts it
+type accountStateEntry struct {
+	value    any
+	cachedAt time.Time
 }
 
 // Global singleflight group
@@ -56,15 +94,15 @@ func WithCache[R any](cache Cache, cfg *Cfg, cacheKey []byte, onCacheMiss func()
 
 	// serialises and optimizes access to the cache for the same key
 	res, err, _ := sfGroup.Do(sfKey, func() (interface{}, error) {
-		cacheType := cfg.Type
-		if cfg.DynamicType != nil {
-			cacheType = cfg.DynamicType()
-		}
-
+		cacheType := cfg.Strategy()
 		if cacheType == NoCache {
 			return onCacheMiss()
 		}
 
+		if cacheType == LatestAccountState {
+			return withAccountStateCache(cache, cfg.Accounts, cacheKey, onCacheMiss)
+		}
+
 		// we implement a custom cache eviction logic for the cache strategy of type LatestBatch.
 		// when a new batch is created, all entries with "LatestBatch" are considered evicted.
 		// elements not cached for a specific batch are not evicted
@@ -82,9 +120,11 @@ func WithCache[R any](cache Cache, cfg *Cfg, cacheKey []byte, onCacheMiss func()
 				if !ok {
 					return nil, fmt.Errorf("unexpected error. Invalid format cached. %v", cachedValue)
 				}
+				metrics.RecordCacheHit()
 				return returnValue, nil
 			}
 		}
+		metrics.RecordCacheMiss()
 
 		result, err := onCacheMiss()
 
@@ -109,6 +149,27 @@ func WithCache[R any](cache Cache, cfg *Cfg, cacheKey []byte, onCacheMiss func()
 	return result, nil
 }
 
+func withAccountStateCache[R any](cache Cache, accounts []common.Address, cacheKey []byte, onCacheMiss func() (*R, error)) (any, error) {
+	if cachedValue, foundInCache := cache.Get(cacheKey); foundInCache {
+		entry, ok := cachedValue.(*accountStateEntry)
+		if !ok {
+			return nil, fmt.Errorf("unexpected error. Invalid format cached. %v", cachedValue)
+		}
+		if !cache.IsAccountStateEvicted(accounts, entry.cachedAt) {
+			metrics.RecordCacheHit()
+			return entry.value, nil
+		}
+	}
+	metrics.RecordCacheMiss()
+
+	requestStart := time.Now()
+	result, err := onCacheMiss()
+	if err == nil && result != nil {
+		cache.Set(cacheKey, &accountStateEntry{value: result, cachedAt: requestStart}, accountStateCacheTTL)
+	}
+	return result, err
+}
+
 type noOpCache struct{}
 
 func NewNoOpCache() Cache {
@@ -123,6 +184,16 @@ func (c *noOpCache) IsEvicted(key []byte, originalTTL time.Duration) bool {
 	return false
 }
 
+func (c *noOpCache) WatchAccount(common.Address) {}
+
+func (c *noOpCache) UnwatchAccount(common.Address) {}
+
+func (c *noOpCache) EvictAccountState(common.Address) {}
+
+func (c *noOpCache) IsAccountStateEvicted([]common.Address, time.Time) bool {
+	return true
+}
+
 func (c *noOpCache) Set(key []byte, value any, ttl time.Duration) bool {
 	return false
 }
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountStateCache(t *testing.T) {
+	c, err := NewCache(1000, gethlog.New())
+	require.NoError(t, err)
+
+	calls := 0
+	balance := func(user string) *uint64 {
+		res, err := WithCache(c, &Cfg{Type: LatestAccountState}, []byte(user), func() (*uint64, error) {
+			calls++
+			value := uint64(calls)
+			return &value, nil
+		})
+		require.NoError(t, err)
+		// ristretto applies the writes asynchronously
+		time.Sleep(20 * time.Millisecond)
+		return res
+	}
+
+	require.Equal(t, uint64(1), *balance("alice"))
+	require.Equal(t, uint64(2), *balance("bob"))
+
+	// the state is served from the cache until the next batch
+	require.Equal(t, uint64(1), *balance("alice"))
+	require.Equal(t, uint64(2), *balance("bob"))
+	require.Equal(t, 2, calls)
+
+	// a new batch evicts the state of all the users
+	c.EvictShortLiving()
+	require.Equal(t, uint64(3), *balance("alice"))
+	require.Equal(t, uint64(4), *balance("bob"))
+
+	// nothing is served from the cache while the new heads are delayed
+	c.DisableShortLiving()
+	require.Equal(t, uint64(5), *balance("alice"))
+}
+
+func TestAccountStateEvictedDuringRequest(t *testing.T) {
+	c, err := NewCache(1000, gethlog.New())
+	require.NoError(t, err)
+	cfg := &Cfg{Type: LatestAccountState}
+
+	// the batch arrives while the backend call is executing, so the response can be stale
+	_, err = WithCache(c, cfg, []byte("alice"), func() (*uint64, error) {
+		c.EvictShortLiving()
+		value := uint64(1)
+		return &value, nil
+	})
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+
+	res, err := WithCache(c, cfg, []byte("alice"), func() (*uint64, error) {
+		value := uint64(2)
+		return &value, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), *res)
+}
+
+func TestWatchedAccountState(t *testing.T) {
+	c, err := NewCache(1000, gethlog.New())
+	require.NoError(t, err)
+	alice, bob := common.HexToAddress("0xa"), common.HexToAddress("0xb")
+
+	calls := 0
+	balance := func(account common.Address) uint64 {
+		res, err := WithCache(c, &Cfg{Type: LatestAccountState, Accounts: []common.Address{account}}, account.Bytes(), func() (*uint64, error) {
+			calls++
+			value := uint64(calls)
+			return &value, nil
+		})
+		require.NoError(t, err)
+		// ristretto applies the writes asynchronously
+		time.Sleep(20 * time.Millisecond)
+		return *res
+	}
+
+	c.WatchAccount(alice)
+	time.Sleep(time.Millisecond)
+	require.Equal(t, uint64(1), balance(alice))
+	require.Equal(t, uint64(2), balance(bob))
+
+	// the batches which don't change the watched account don't evict its state
+	c.EvictShortLiving()
+	require.Equal(t, uint64(1), balance(alice))
+	require.Equal(t, uint64(3), balance(bob))
+
+	// the state is evicted by the batch which changed the account
+	c.EvictShortLiving()
+	c.EvictAccountState(alice)
+	require.Equal(t, uint64(4), balance(alice))
+	require.Equal(t, uint64(4), balance(alice))
+
+	// once the account is no longer watched, every batch evicts its state
+	c.UnwatchAccount(alice)
+	c.EvictShortLiving()
+	require.Equal(t, uint64(5), balance(alice))
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APIKeyScope - what a request authenticated with an API key is allowed to do
+type APIKeyScope string
+
+const (
+	APIKeyScopeRead        APIKeyScope = "read"        // the calls which don't send transactions or change the session keys
+	APIKeyScopeSendTx      APIKeyScope = "sendTx"      // sending transactions, signed by the client or by an active session key
+	APIKeyScopeSessionKeys APIKeyScope = "sessionKeys" // managing the session keys and sending transactions signed by them
+)
+
+// APIKeyPrefix - makes the API keys recognisable, e.g. by secret scanners
+const APIKeyPrefix = "tenk_"
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// GWAPIKey - a named, revocable credential which authenticates the server-side clients of a user
+// with the "Authorization: Bearer <key>" header instead of the user id
+type GWAPIKey struct {
+	ID        string // the public identifier of the key, derived from its hash
+	Name      string
+	Scopes    []APIKeyScope
+	Hash      []byte // the SHA-256 hash of the key. The key itself is only returned to the user when it is created
+	CreatedAt time.Time
+	RevokedAt *time.Time // nil while the key is valid
+}
+
+// NewAPIKey - generates a new key. Returns the key, which must be handed to the user, and its stored representation
+func NewAPIKey(name string, scopes []APIKeyScope, now time.Time) (string, *GWAPIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", nil, errors.New("the API key name is required")
+	}
+	if err := ValidateAPIKeyScopes(scopes); err != nil {
+		return "", nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("could not generate the API key: %w", err)
+	}
+	key := APIKeyPrefix + hex.EncodeToString(secret)
+	hash := HashAPIKey(key)
+	return key, &GWAPIKey{
+		ID:        hex.EncodeToString(hash[:8]),
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hash,
+		CreatedAt: now,
+	}, nil
+}
+
+// HashAPIKey - the keys are high entropy random values, so a plain hash is enough to store them
+func HashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// ValidateAPIKeyScopes - a key needs at least one scope, and all the scopes must be known
+func ValidateAPIKeyScopes(scopes []APIKeyScope) error {
+	if len(scopes) == 0 {
+		return errors.New("the API key needs at least one scope")
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case APIKeyScopeRead, APIKeyScopeSendTx, APIKeyScopeSessionKeys:
+		default:
+			return fmt.Errorf("unknown API key scope: %s", scope)
+		}
+	}
+	return nil
+}
+
+func (k *GWAPIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// HasAnyScope - whether the key is allowed to make a call which requires one of the scopes
+func (k *GWAPIKey) HasAnyScope(scopes ...APIKeyScope) bool {
+	for _, scope := range scopes {
+		if slices.Contains(k.Scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyInfo - the details of an API key returned to the user
+type APIKeyInfo struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Scopes    []APIKeyScope `json:"scopes"`
+	CreatedAt time.Time     `json:"createdAt"`
+	RevokedAt *time.Time    `json:"revokedAt,omitempty"`
+}
+
+func (k *GWAPIKey) Info() APIKeyInfo {
+	return APIKeyInfo{
+		ID:        k.ID,
+		Name:      k.Name,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
+
+// APIKeysInfo - the details of all the API keys of the user, including the revoked ones, oldest first
+func (u GWUser) APIKeysInfo() []APIKeyInfo {
+	res := make([]APIKeyInfo, 0, len(u.APIKeys))
+	for _, key := range u.APIKeys {
+		res = append(res, key.Info())
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].CreatedAt.Equal(res[j].CreatedAt) {
+			return res[i].ID < res[j].ID
+		}
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res
+}
+
+// GetAPIKeyByHash - returns the valid API key of the user with the given hash
+func (u GWUser) GetAPIKeyByHash(hash []byte) (*GWAPIKey, error) {
+	id := hex.EncodeToString(hash[:8])
+	key, found := u.APIKeys[id]
+	if !found || !slices.Equal(key.Hash, hash) || key.IsRevoked() {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
@@ -24,6 +24,22 @@ type Config struct {
 	RateLimitUserComputeTime       time.Duration
 	RateLimitWindow                time.Duration
 	RateLimitMaxConcurrentRequests int
+	RateLimitIPComputeTime         time.Duration      // the compute time of the unauthenticated calls from one IP. 0 disables the IP limits
+	RateLimitMethodWeights         map[string]float64 // the compute time of a method is multiplied by its weight
+	RateLimitSharedState           bool               // the rate limit state is kept in the database, so it is shared by the gateway instances
+	TrustedProxyHops               int                // the number of proxies in front of the gateway which append to the X-Forwarded-For header
+
+	BatchMaxSize        int // the maximum number of requests in a JSON-RPC batch. 0 means unlimited
+	BatchMaxConcurrency int // the maximum number of batch requests of a user executed in parallel. 0 executes them sequentially
+
+	MetricsAddress string // the address the Prometheus metrics are served on, separately from the RPC endpoints. Empty disables them
+
+	AuditLogPath       string        // the file the structured audit records are appended to. Empty disables the file
+	AuditLogURL        string        // the HTTP endpoint the structured audit records are posted to. Empty disables the endpoint
+	AuditLogMaxSizeMB  int           // the audit file is rotated when it reaches this size
+	AuditLogMaxBackups int           // the maximum number of rotated audit files. 0 keeps all of them within the retention
+	AuditLogRetention  time.Duration // the rotated audit files older than this are removed. 0 keeps them forever
+	AuditLogRedaction  string        // how the params of the requests are audited: none, hash or drop
 
 	InsideEnclave                bool // Indicates if the program is running inside an enclave
 	EncryptionKeySource          string
@@ -31,4 +47,5 @@ type Config struct {
 	TLSDomain                    string
 	EncryptingCertificateEnabled bool
 	DisableCaching               bool
+	FilterTimeout                time.Duration // Polling filters which are not polled within this interval are removed
 }
@@ -23,6 +23,7 @@ const (
 	PathRevoke                    = "/revoke/"
 	PathHealth                    = "/health/"
 	PathSessionKeys               = "/session-key/"
+	PathAPIKeys                   = "/api-keys/"
 	PathNetworkHealth             = "/network-health/"
 	PathNetworkConfig             = "/network-config/"
 	PathKeyExchange               = "/key-exchange/"
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// SessionKeyFunding - the automatic management of the native balance of a session key.
+// The transfers are signed by the gateway with the session keys of the user.
+type SessionKeyFunding struct {
+	// SweepTo - the account of the user which receives the balance of the key when it is deleted or expires.
+	// When missing, the balance is swept to the only registered account of the user.
+	SweepTo *common.Address  `json:"sweepTo,omitempty"`
+	TopUp   *SessionKeyTopUp `json:"topUp,omitempty"`
+}
+
+// SessionKeyTopUp - transfers Amount from another session key of the user when the balance falls below Threshold.
+// The gateway doesn't hold the keys of the accounts registered by the user, so the primary account
+// which funds the top-ups is a session key. The transfers are subject to the policy of the funding key.
+type SessionKeyTopUp struct {
+	From      common.Address `json:"from"`
+	Threshold *hexutil.Big   `json:"threshold"`
+	Amount    *hexutil.Big   `json:"amount"`
+}
+
+// Validate - checks that the funding refers to the accounts of the user
+func (f *SessionKeyFunding) Validate(user *GWUser, sessionKey common.Address) error {
+	if f == nil {
+		return nil
+	}
+	if f.SweepTo != nil {
+		if _, found := user.Accounts[*f.SweepTo]; !found {
+			return fmt.Errorf("the sweep account %s is not registered", f.SweepTo.Hex())
+		}
+	}
+	if f.TopUp != nil {
+		if f.TopUp.From == sessionKey {
+			return fmt.Errorf("a session key can't top up itself")
+		}
+		if from, found := user.SessionKeys[f.TopUp.From]; !found || from.IsDeleted() {
+			return fmt.Errorf("the top up account %s is not a session key of the user", f.TopUp.From.Hex())
+		}
+		if f.TopUp.Threshold == nil || f.TopUp.Amount == nil || f.TopUp.Amount.ToInt().Sign() <= 0 {
+			return fmt.Errorf("the top up threshold and a positive amount are required")
+		}
+	}
+	return nil
+}
+
+// SessionKeyFundsTransfer - a sweep or a top up executed by the gateway
+type SessionKeyFundsTransfer struct {
+	TxHash *common.Hash    `json:"txHash,omitempty"`
+	To     *common.Address `json:"to,omitempty"`
+	Amount *hexutil.Big    `json:"amount,omitempty"`
+	Time   uint64          `json:"time"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// SessionKeyStatus - the result of sessionkeys_status
+type SessionKeyStatus struct {
+	SessionKeyInfo
+	Expired   bool                     `json:"expired"`
+	Balance   *hexutil.Big             `json:"balance,omitempty"` // missing when the balance could not be read
+	LastSweep *SessionKeyFundsTransfer `json:"lastSweep,omitempty"`
+	LastTopUp *SessionKeyFundsTransfer `json:"lastTopUp,omitempty"`
+}
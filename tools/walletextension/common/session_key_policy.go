@@ -0,0 +1,106 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SelectorLen - the length of the function selector at the start of the calldata
+const SelectorLen = 4
+
+var ErrSpendCapExceeded = errors.New("session key spend cap exceeded")
+
+// SessionKeyPolicy - the restrictions enforced by the gateway before signing a transaction with a session key.
+// All fields are optional. A missing field means there is no restriction.
+type SessionKeyPolicy struct {
+	ExpiresAt      uint64             `json:"expiresAt,omitempty"` // unix timestamp in seconds after which the key can't sign anymore
+	MaxValuePerTx  *hexutil.Big       `json:"maxValuePerTx,omitempty"`
+	SpendCap       *hexutil.Big       `json:"spendCap,omitempty"` // the maximum value transferred cumulatively by all transactions signed with the key
+	MaxGasPrice    *hexutil.Big       `json:"maxGasPrice,omitempty"`
+	AllowedTargets []SessionKeyTarget `json:"allowedTargets,omitempty"`
+}
+
+// SessionKeyTarget - a contract which can be called with a session key.
+// When Selectors is empty, all the functions of the contract can be called.
+type SessionKeyTarget struct {
+	Contract  common.Address  `json:"contract"`
+	Selectors []hexutil.Bytes `json:"selectors,omitempty"`
+}
+
+// Validate - checks that the policy is well-formed
+func (p *SessionKeyPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, target := range p.AllowedTargets {
+		for _, selector := range target.Selectors {
+			if len(selector) != SelectorLen {
+				return fmt.Errorf("invalid selector %s for contract %s. Selectors must have %d bytes", selector, target.Contract, SelectorLen)
+			}
+		}
+	}
+	return nil
+}
+
+// Authorize - returns an error if the policy does not allow the transaction to be signed.
+// spent is the value already transferred by the transactions signed with the key.
+func (p *SessionKeyPolicy) Authorize(tx *types.Transaction, spent *big.Int, now time.Time) error {
+	if p == nil {
+		return nil
+	}
+	if p.isExpired(now) {
+		return fmt.Errorf("session key expired at %s", time.Unix(int64(p.ExpiresAt), 0).UTC())
+	}
+	if p.MaxValuePerTx != nil && tx.Value().Cmp(p.MaxValuePerTx.ToInt()) > 0 {
+		return fmt.Errorf("transaction value %s exceeds the maximum value per transaction %s", tx.Value(), p.MaxValuePerTx.ToInt())
+	}
+	if p.SpendCap != nil {
+		total := new(big.Int).Add(tx.Value(), spent)
+		if total.Cmp(p.SpendCap.ToInt()) > 0 {
+			return ErrSpendCapExceeded
+		}
+	}
+	// for legacy transactions the fee cap is the gas price
+	if p.MaxGasPrice != nil && tx.GasFeeCap().Cmp(p.MaxGasPrice.ToInt()) > 0 {
+		return fmt.Errorf("transaction gas price %s exceeds the maximum gas price %s", tx.GasFeeCap(), p.MaxGasPrice.ToInt())
+	}
+	if len(p.AllowedTargets) > 0 && !p.isTargetAllowed(tx.To(), tx.Data()) {
+		return fmt.Errorf("the transaction target is not allowed by the session key policy")
+	}
+	return nil
+}
+
+func (p *SessionKeyPolicy) isExpired(now time.Time) bool {
+	return p.ExpiresAt != 0 && uint64(now.Unix()) >= p.ExpiresAt
+}
+
+func (p *SessionKeyPolicy) isTargetAllowed(to *common.Address, data []byte) bool {
+	// contract deployments are not allowed when the targets are restricted
+	if to == nil {
+		return false
+	}
+	for _, target := range p.AllowedTargets {
+		if target.Contract != *to {
+			continue
+		}
+		if len(target.Selectors) == 0 {
+			return true
+		}
+		if len(data) < SelectorLen {
+			return false
+		}
+		for _, selector := range target.Selectors {
+			if bytes.Equal(selector, data[:SelectorLen]) {
+				return true
+			}
+		}
+	}
+	return false
+}
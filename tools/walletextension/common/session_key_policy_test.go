@@ -0,0 +1,67 @@
+package common
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testContract = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	testSelector = hexutil.Bytes{0xa9, 0x05, 0x9c, 0xbb}
+)
+
+func testTx(to *common.Address, value int64, gasFeeCap int64, data []byte) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		To:        to,
+		Value:     big.NewInt(value),
+		GasFeeCap: big.NewInt(gasFeeCap),
+		GasTipCap: big.NewInt(1),
+		Gas:       21_000,
+		Data:      data,
+	})
+}
+
+func TestSessionKeyPolicy_Authorize(t *testing.T) {
+	now := time.Unix(1_000, 0)
+	policy := &SessionKeyPolicy{
+		ExpiresAt:     2_000,
+		MaxValuePerTx: (*hexutil.Big)(big.NewInt(100)),
+		SpendCap:      (*hexutil.Big)(big.NewInt(150)),
+		MaxGasPrice:   (*hexutil.Big)(big.NewInt(10)),
+		AllowedTargets: []SessionKeyTarget{
+			{Contract: testContract, Selectors: []hexutil.Bytes{testSelector}},
+		},
+	}
+	call := append(testSelector, 1, 2, 3)
+	other := common.HexToAddress("0x02")
+
+	require.NoError(t, policy.Authorize(testTx(&testContract, 100, 10, call), big.NewInt(50), now))
+
+	require.ErrorContains(t, policy.Authorize(testTx(&testContract, 100, 10, call), big.NewInt(0), time.Unix(2_000, 0)), "expired")
+	require.ErrorContains(t, policy.Authorize(testTx(&testContract, 101, 10, call), big.NewInt(0), now), "maximum value")
+	require.ErrorIs(t, policy.Authorize(testTx(&testContract, 100, 10, call), big.NewInt(51), now), ErrSpendCapExceeded)
+	require.ErrorContains(t, policy.Authorize(testTx(&testContract, 0, 11, call), big.NewInt(0), now), "gas price")
+	require.ErrorContains(t, policy.Authorize(testTx(&other, 0, 10, call), big.NewInt(0), now), "not allowed")
+	require.ErrorContains(t, policy.Authorize(testTx(&testContract, 0, 10, []byte{1, 2, 3, 4}), big.NewInt(0), now), "not allowed")
+	require.ErrorContains(t, policy.Authorize(testTx(nil, 0, 10, call), big.NewInt(0), now), "not allowed")
+
+	// all the functions of the contract are allowed when no selectors are configured
+	policy.AllowedTargets[0].Selectors = nil
+	require.NoError(t, policy.Authorize(testTx(&testContract, 0, 10, nil), big.NewInt(0), now))
+
+	var unrestricted *SessionKeyPolicy
+	require.NoError(t, unrestricted.Authorize(testTx(nil, 1_000_000, 1_000, nil), big.NewInt(1_000_000), now))
+}
+
+func TestSessionKeyPolicy_Validate(t *testing.T) {
+	policy := &SessionKeyPolicy{AllowedTargets: []SessionKeyTarget{{Contract: testContract, Selectors: []hexutil.Bytes{{1, 2}}}}}
+	require.Error(t, policy.Validate())
+	policy.AllowedTargets[0].Selectors = []hexutil.Bytes{testSelector}
+	require.NoError(t, policy.Validate())
+}
@@ -1,17 +1,64 @@
 package common
 
 import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
 	"github.com/ethereum/go-ethereum/crypto/ecies"
 	"github.com/ten-protocol/go-ten/go/common/viewingkey"
 	"golang.org/x/exp/maps"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 // GWSessionKey - an account key-pair registered for a user
 type GWSessionKey struct {
 	Account    *GWAccount
 	PrivateKey *ecies.PrivateKey // the private key corresponding to the account
+	Active     bool              // only the active session keys can sign transactions
+	Policy     *SessionKeyPolicy // the restrictions enforced before signing. Nil when the key is unrestricted
+	Spent      *big.Int          // the value transferred by the transactions signed with the key
+	Funding    *SessionKeyFunding
+	Sweep      *SessionKeyFundsTransfer // set when the key is deleted. The key is removed once the sweep is included
+}
+
+// IsDeleted - the key is kept until the transfer of its funds is included, and can't be used in the meantime
+func (sk *GWSessionKey) IsDeleted() bool {
+	return sk.Sweep != nil
+}
+
+// IsExpired - the key can't sign transactions after the expiry of its policy
+func (sk *GWSessionKey) IsExpired(now time.Time) bool {
+	return sk.Policy != nil && sk.Policy.isExpired(now)
+}
+
+// SessionKeyInfo - the details of a session key returned to the user
+type SessionKeyInfo struct {
+	Address common.Address     `json:"address"`
+	Active  bool               `json:"active"`
+	Policy  *SessionKeyPolicy  `json:"policy,omitempty"`
+	Spent   *hexutil.Big       `json:"spent"`
+	Funding *SessionKeyFunding `json:"funding,omitempty"`
+	Deleted bool               `json:"deleted,omitempty"`
+}
+
+func (sk *GWSessionKey) Info() SessionKeyInfo {
+	spent := new(big.Int)
+	if sk.Spent != nil {
+		spent.Set(sk.Spent)
+	}
+	return SessionKeyInfo{
+		Address: *sk.Account.Address,
+		Active:  sk.Active,
+		Policy:  sk.Policy,
+		Spent:   (*hexutil.Big)(spent),
+		Funding: sk.Funding,
+		Deleted: sk.IsDeleted(),
+	}
 }
 
 type GWAccount struct {
@@ -22,17 +69,17 @@ type GWAccount struct {
 }
 
 type GWUser struct {
-	ID         []byte
-	Accounts   map[common.Address]*GWAccount
-	UserKey    []byte
-	SessionKey *GWSessionKey
-	ActiveSK   bool // the session key is active, and it must be used to sign all incoming transactions, and used as the preferred account
+	ID          []byte
+	Accounts    map[common.Address]*GWAccount
+	UserKey     []byte
+	SessionKeys map[common.Address]*GWSessionKey
+	APIKeys     map[string]*GWAPIKey // by the id of the key
 }
 
 func (u GWUser) AllAccounts() map[common.Address]*GWAccount {
 	res := maps.Clone(u.Accounts)
-	if u.SessionKey != nil {
-		res[*u.SessionKey.Account.Address] = u.SessionKey.Account
+	for addr, sk := range u.SessionKeys {
+		res[addr] = sk.Account
 	}
 	return res
 }
@@ -40,3 +87,55 @@ func (u GWUser) AllAccounts() map[common.Address]*GWAccount {
 func (u GWUser) GetAllAddresses() []common.Address {
 	return maps.Keys(u.AllAccounts())
 }
+
+// SessionKeysInfo - the details of all the session keys of the user, sorted by address
+func (u GWUser) SessionKeysInfo() []SessionKeyInfo {
+	res := make([]SessionKeyInfo, 0, len(u.SessionKeys))
+	for _, sk := range u.SessionKeys {
+		res = append(res, sk.Info())
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i].Address.Bytes(), res[j].Address.Bytes()) < 0
+	})
+	return res
+}
+
+// ActiveSessionKeys - the session keys which can sign transactions
+func (u GWUser) ActiveSessionKeys() []*GWSessionKey {
+	res := make([]*GWSessionKey, 0)
+	for _, sk := range u.SessionKeys {
+		if sk.Active {
+			res = append(res, sk)
+		}
+	}
+	return res
+}
+
+// GetSessionKey - returns the session key with the given address.
+// When the address is nil, it returns the only session key of the user. The deleted keys are not returned.
+func (u GWUser) GetSessionKey(address *common.Address) (*GWSessionKey, error) {
+	if address != nil {
+		sk, found := u.SessionKeys[*address]
+		if !found {
+			return nil, fmt.Errorf("session key %s not found", address.Hex())
+		}
+		if sk.IsDeleted() {
+			return nil, fmt.Errorf("session key %s is being deleted", address.Hex())
+		}
+		return sk, nil
+	}
+	keys := make([]*GWSessionKey, 0, len(u.SessionKeys))
+	for _, sk := range u.SessionKeys {
+		if !sk.IsDeleted() {
+			keys = append(keys, sk)
+		}
+	}
+	switch len(keys) {
+	case 0:
+		return nil, fmt.Errorf("please create a session key")
+	case 1:
+		return keys[0], nil
+	default:
+		return nil, fmt.Errorf("the user has multiple session keys. Please specify the session key address")
+	}
+}
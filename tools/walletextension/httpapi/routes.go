@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	tencommon "github.com/ten-protocol/go-ten/go/common"
 	"github.com/ten-protocol/go-ten/tools/walletextension/keymanager"
 	"github.com/ten-protocol/go-ten/tools/walletextension/services"
@@ -90,6 +91,30 @@ func NewHTTPRoutes(walletExt *services.Services) []node.Route {
 			Name: common.APIVersion1 + common.PathSessionKeys + "list",
 			Func: httpHandler(walletExt, listSKRequestHandler),
 		},
+		{
+			Name: common.APIVersion1 + common.PathSessionKeys + "policy",
+			Func: httpHandler(walletExt, policySKRequestHandler),
+		},
+		{
+			Name: common.APIVersion1 + common.PathSessionKeys + "funding",
+			Func: httpHandler(walletExt, fundingSKRequestHandler),
+		},
+		{
+			Name: common.APIVersion1 + common.PathSessionKeys + "status",
+			Func: httpHandler(walletExt, statusSKRequestHandler),
+		},
+		{
+			Name: common.APIVersion1 + common.PathAPIKeys + "create",
+			Func: httpHandler(walletExt, createAPIKeyRequestHandler),
+		},
+		{
+			Name: common.APIVersion1 + common.PathAPIKeys + "list",
+			Func: httpHandler(walletExt, listAPIKeysRequestHandler),
+		},
+		{
+			Name: common.APIVersion1 + common.PathAPIKeys + "revoke",
+			Func: httpHandler(walletExt, revokeAPIKeyRequestHandler),
+		},
 	}
 }
 
@@ -563,49 +588,129 @@ func getMessageRequestHandler(walletExt *services.Services, conn UserConn) {
 	}
 }
 
+// sessionKeyRequest - the optional json body of the session key requests.
+// The address can be omitted when the user has a single session key.
+type sessionKeyRequest struct {
+	Address *gethcommon.Address       `json:"address,omitempty"`
+	Policy  *common.SessionKeyPolicy  `json:"policy,omitempty"`
+	Funding *common.SessionKeyFunding `json:"funding,omitempty"`
+}
+
 func listSKRequestHandler(walletExt *services.Services, conn UserConn) {
+	withUser(walletExt, conn, func(user *common.GWUser, _ *sessionKeyRequest) ([]byte, error) {
+		return json.Marshal(user.SessionKeysInfo())
+	})
 }
 
 func createSKRequestHandler(walletExt *services.Services, conn UserConn) {
-	withUser(walletExt, conn, func(user *common.GWUser) ([]byte, error) {
-		sk, err := walletExt.SKManager.CreateSessionKey(user)
+	withUser(walletExt, conn, func(user *common.GWUser, req *sessionKeyRequest) ([]byte, error) {
+		sk, err := walletExt.SKManager.CreateSessionKey(user, req.Policy)
 		if err != nil {
-			handleError(conn, walletExt.Logger(), fmt.Errorf("could not create session key: %w", err))
-			return nil, err
+			return nil, fmt.Errorf("could not create session key: %w", err)
 		}
 		return []byte(hexutils.BytesToHex(sk.Account.Address.Bytes())), nil
 	})
 }
 
 func deleteSKRequestHandler(walletExt *services.Services, conn UserConn) {
-	withUser(walletExt, conn, func(user *common.GWUser) ([]byte, error) {
-		res, err := walletExt.SKManager.DeleteSessionKey(user)
+	withUser(walletExt, conn, func(user *common.GWUser, req *sessionKeyRequest) ([]byte, error) {
+		res, err := walletExt.SKManager.DeleteSessionKey(user, req.Address)
 		return []byte{boolToByte(res)}, err
 	})
 }
 
 func activateSKRequestHandler(walletExt *services.Services, conn UserConn) {
-	withUser(walletExt, conn, func(user *common.GWUser) ([]byte, error) {
-		res, err := walletExt.SKManager.ActivateSessionKey(user)
+	withUser(walletExt, conn, func(user *common.GWUser, req *sessionKeyRequest) ([]byte, error) {
+		res, err := walletExt.SKManager.ActivateSessionKey(user, req.Address)
 		return []byte{boolToByte(res)}, err
 	})
 }
 
 func deactivateSKRequestHandler(walletExt *services.Services, conn UserConn) {
-	withUser(walletExt, conn, func(user *common.GWUser) ([]byte, error) {
-		res, err := walletExt.SKManager.DeactivateSessionKey(user)
+	withUser(walletExt, conn, func(user *common.GWUser, req *sessionKeyRequest) ([]byte, error) {
+		res, err := walletExt.SKManager.DeactivateSessionKey(user, req.Address)
 		return []byte{boolToByte(res)}, err
 	})
 }
 
-// extracts the user from the request, and writes the response to the connection
-func withUser(walletExt *services.Services, conn UserConn, withUser func(user *common.GWUser) ([]byte, error)) {
-	_, err := conn.ReadRequest()
+func policySKRequestHandler(walletExt *services.Services, conn UserConn) {
+	withUser(walletExt, conn, func(user *common.GWUser, req *sessionKeyRequest) ([]byte, error) {
+		res, err := walletExt.SKManager.SetSessionKeyPolicy(user, req.Address, req.Policy)
+		return []byte{boolToByte(res)}, err
+	})
+}
+
+func fundingSKRequestHandler(walletExt *services.Services, conn UserConn) {
+	withUser(walletExt, conn, func(user *common.GWUser, req *sessionKeyRequest) ([]byte, error) {
+		res, err := walletExt.SKManager.SetSessionKeyFunding(user, req.Address, req.Funding)
+		return []byte{boolToByte(res)}, err
+	})
+}
+
+func statusSKRequestHandler(walletExt *services.Services, conn UserConn) {
+	withUser(walletExt, conn, func(user *common.GWUser, _ *sessionKeyRequest) ([]byte, error) {
+		status, err := walletExt.SKManager.Status(conn.GetHTTPRequest().Context(), user)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(status)
+	})
+}
+
+// apiKeyRequest - the json body of the API key requests. The API keys are managed with the user id, not with an API key
+type apiKeyRequest struct {
+	ID     string               `json:"id,omitempty"`
+	Name   string               `json:"name,omitempty"`
+	Scopes []common.APIKeyScope `json:"scopes,omitempty"`
+}
+
+// createAPIKeyResponse - the key is only returned when it is created
+type createAPIKeyResponse struct {
+	Key string `json:"key"`
+	common.APIKeyInfo
+}
+
+func createAPIKeyRequestHandler(walletExt *services.Services, conn UserConn) {
+	withUser(walletExt, conn, func(user *common.GWUser, req *apiKeyRequest) ([]byte, error) {
+		key, apiKey, err := walletExt.CreateAPIKey(user.ID, req.Name, req.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("could not create API key: %w", err)
+		}
+		return json.Marshal(createAPIKeyResponse{Key: key, APIKeyInfo: apiKey.Info()})
+	})
+}
+
+func listAPIKeysRequestHandler(walletExt *services.Services, conn UserConn) {
+	withUser(walletExt, conn, func(user *common.GWUser, _ *apiKeyRequest) ([]byte, error) {
+		return json.Marshal(user.APIKeysInfo())
+	})
+}
+
+func revokeAPIKeyRequestHandler(walletExt *services.Services, conn UserConn) {
+	withUser(walletExt, conn, func(user *common.GWUser, req *apiKeyRequest) ([]byte, error) {
+		if err := walletExt.RevokeAPIKey(user.ID, req.ID); err != nil {
+			return nil, fmt.Errorf("could not revoke API key: %w", err)
+		}
+		return []byte(common.SuccessMsg), nil
+	})
+}
+
+// extracts the user and the json request from the request, and writes the response to the connection
+func withUser[R any](walletExt *services.Services, conn UserConn, withUser func(user *common.GWUser, req *R) ([]byte, error)) {
+	body, err := conn.ReadRequest()
 	if err != nil {
 		handleError(conn, walletExt.Logger(), fmt.Errorf("error reading request: %w", err))
 		return
 	}
 
+	var req R
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			handleError(conn, walletExt.Logger(), fmt.Errorf("could not parse request: %w", err))
+			return
+		}
+	}
+
 	userID, err := getUserID(conn)
 	if err != nil {
 		handleError(conn, walletExt.Logger(), fmt.Errorf("user ('u') not found in query parameters"))
@@ -619,7 +724,7 @@ func withUser(walletExt *services.Services, conn UserConn, withUser func(user *c
 		return
 	}
 
-	resp, err := withUser(user)
+	resp, err := withUser(user, &req)
 	if err != nil {
 		handleError(conn, walletExt.Logger(), fmt.Errorf("could not process request: %w", err))
 		return
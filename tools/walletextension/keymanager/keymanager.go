@@ -108,6 +108,12 @@ func GetEncryptionKey(config common.Config, logger gethlog.Logger) ([]byte, erro
 	return encryptionKey, nil
 }
 
+// SealEncryptionKey seals the key as the encryption key of the gateway, replacing the existing one.
+// It is used when the key is rotated. Only seals if running in an SGX enclave
+func SealEncryptionKey(key []byte, isEnclave bool) error {
+	return trySealKey(key, encryptionKeyFile, isEnclave)
+}
+
 // tryUnsealKey attempts to unseal an encryption key from disk
 // Returns (key, found, error)
 func tryUnsealKey(keyPath string, isEnclave bool) ([]byte, bool, error) {
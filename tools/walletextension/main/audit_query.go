@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/ten-protocol/go-ten/go/common/viewingkey"
+	"github.com/ten-protocol/go-ten/tools/walletextension/audit"
+)
+
+// auditQueryCmd - the subcommand which prints the audit records matching the filters as JSON lines.
+// Usage: gateway audit-query -auditLogPath /var/log/gateway/audit.jsonl -user <user id> -from 2024-01-01T00:00:00Z
+const auditQueryCmd = "audit-query"
+
+func parseAuditQueryArgs(args []string) (string, audit.Filter, error) {
+	fs := flag.NewFlagSet(auditQueryCmd, flag.ContinueOnError)
+	path := fs.String(auditLogPathName, "", "The audit file of the gateway. The rotated files next to it are read as well")
+	user := fs.String("user", "", "The user id, or the hashed user id from the audit records")
+	method := fs.String("method", "", "The JSON-RPC method")
+	from := fs.String("from", "", "The RFC3339 time of the first record (inclusive)")
+	to := fs.String("to", "", "The RFC3339 time after the last record (exclusive)")
+	if err := fs.Parse(args); err != nil {
+		return "", audit.Filter{}, err
+	}
+	if *path == "" {
+		return "", audit.Filter{}, fmt.Errorf("the %s flag is required", auditLogPathName)
+	}
+
+	filter := audit.Filter{Method: *method}
+	var err error
+	if filter.User, err = auditUser(*user); err != nil {
+		return "", audit.Filter{}, err
+	}
+	if filter.From, err = parseAuditTime(*from); err != nil {
+		return "", audit.Filter{}, err
+	}
+	if filter.To, err = parseAuditTime(*to); err != nil {
+		return "", audit.Filter{}, err
+	}
+	return *path, filter, nil
+}
+
+// auditUser - the records hold the hash of the user id, so a user id is hashed the same way
+func auditUser(user string) (string, error) {
+	if user == "" {
+		return "", nil
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(user, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid user: %w", err)
+	}
+	switch len(decoded) {
+	case viewingkey.UserIDLength:
+		return audit.HashUserID(decoded), nil
+	case gethcommon.HashLength:
+		return hex.EncodeToString(decoded), nil
+	default:
+		return "", fmt.Errorf("invalid user: expected a user id or its hash")
+	}
+}
+
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %s: %w", value, err)
+	}
+	return t, nil
+}
+
+// runAuditQuery prints the matching records to stdout
+func runAuditQuery(args []string) error {
+	path, filter, err := parseAuditQueryArgs(args)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	return audit.Query(path, filter, func(record *audit.Record) error {
+		return encoder.Encode(record)
+	})
+}
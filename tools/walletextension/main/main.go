@@ -12,6 +12,21 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == migrateUsersCmd {
+		if err := runMigrateUsers(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "user migration failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == auditQueryCmd {
+		if err := runAuditQuery(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "audit query failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	config := parseCLIArgs()
 	jsonConfig, _ := json.MarshalIndent(config, "", "  ")
 
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+
+	"github.com/ten-protocol/go-ten/go/common/log"
+	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/keymanager"
+	"github.com/ten-protocol/go-ten/tools/walletextension/storage"
+)
+
+// migrateUsersCmd - the subcommand which copies all the users from one database to another.
+// Usage: gateway migrate-users -sourceDBType sqlite -sourceDBPath ... -targetDBType postgres -targetDBConnectionURL ...
+const migrateUsersCmd = "migrate-users"
+
+const newKeyValue = "new"
+
+type migrateUsersConfig struct {
+	source        storageConfig
+	target        storageConfig
+	insideEnclave bool
+	dryRun        bool
+	logLevel      string
+}
+
+type storageConfig struct {
+	dbType          string
+	dbConnectionURL string
+	dbPath          string
+	key             string // hex encoded encryption key
+}
+
+func parseMigrateUsersArgs(args []string) (*migrateUsersConfig, error) {
+	fs := flag.NewFlagSet(migrateUsersCmd, flag.ContinueOnError)
+	cfg := &migrateUsersConfig{}
+	fs.StringVar(&cfg.source.dbType, "sourceDBType", dbTypeFlagDefault, "The type of the database the users are read from (sqlite, cosmosDB or postgres)")
+	fs.StringVar(&cfg.source.dbConnectionURL, "sourceDBConnectionURL", "", "The connection URL of the source database, for cosmosDB and postgres")
+	fs.StringVar(&cfg.source.dbPath, "sourceDBPath", "", "The path of the source database file, for sqlite")
+	fs.StringVar(&cfg.source.key, "sourceKey", "", "The hex encoded encryption key of the source database. Default: the sealed key of the gateway")
+	fs.StringVar(&cfg.target.dbType, "targetDBType", dbTypeFlagDefault, "The type of the database the users are written to (sqlite, cosmosDB or postgres)")
+	fs.StringVar(&cfg.target.dbConnectionURL, "targetDBConnectionURL", "", "The connection URL of the target database, for cosmosDB and postgres")
+	fs.StringVar(&cfg.target.dbPath, "targetDBPath", "", "The path of the target database file, for sqlite")
+	fs.StringVar(&cfg.target.key, "targetKey", "", "The hex encoded encryption key of the target database, or 'new' to generate a key and seal it as the gateway key after the migration. Default: the source key")
+	fs.BoolVar(&cfg.insideEnclave, insideEnclaveFlagName, insideEnclaveFlagDefault, insideEnclaveFlagUsage)
+	fs.BoolVar(&cfg.dryRun, "dryRun", false, "Read the source and compare it with the target without writing anything")
+	fs.StringVar(&cfg.logLevel, logLevelFlagName, logLevelFlagDefault, logLevelFlagUsage)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if cfg.target.key == newKeyValue && !cfg.insideEnclave {
+		return nil, fmt.Errorf("a new target key can only be generated inside the enclave, where it can be sealed")
+	}
+	return cfg, nil
+}
+
+// runMigrateUsers copies the users and, when a new key was generated, seals it as the gateway key
+func runMigrateUsers(args []string) error {
+	cfg, err := parseMigrateUsersArgs(args)
+	if err != nil {
+		return err
+	}
+	logger := log.New(log.WalletExtCmp, getLogLevelInt(cfg.logLevel), log.SysOut)
+
+	sourceKey, err := sourceEncryptionKey(cfg, logger)
+	if err != nil {
+		return err
+	}
+	targetKey, err := targetEncryptionKey(cfg, sourceKey)
+	if err != nil {
+		return err
+	}
+
+	source, err := storage.New(cfg.source.dbType, cfg.source.dbConnectionURL, cfg.source.dbPath, sourceKey, logger)
+	if err != nil {
+		return fmt.Errorf("unable to open the source database: %w", err)
+	}
+	target, err := storage.New(cfg.target.dbType, cfg.target.dbConnectionURL, cfg.target.dbPath, targetKey, logger)
+	if err != nil {
+		return fmt.Errorf("unable to open the target database: %w", err)
+	}
+
+	report, err := storage.MigrateUsers(source, target, cfg.dryRun, logger)
+	printMigrationReport(report)
+	if err != nil {
+		return err
+	}
+	if !cfg.dryRun && report.SourceChecksum != report.TargetChecksum {
+		return fmt.Errorf("the checksums of the source and the target don't match")
+	}
+
+	if cfg.target.key == newKeyValue && !cfg.dryRun {
+		if err := keymanager.SealEncryptionKey(targetKey, cfg.insideEnclave); err != nil {
+			return fmt.Errorf("the users were migrated, but the new key could not be sealed: %w", err)
+		}
+		logger.Info("Sealed the new encryption key. Restart the gateway with the target database to use it")
+	}
+	return nil
+}
+
+func sourceEncryptionKey(cfg *migrateUsersConfig, logger gethlog.Logger) ([]byte, error) {
+	if cfg.source.key != "" {
+		return decodeKey(cfg.source.key)
+	}
+	// unseal the key of the gateway, like on startup
+	return keymanager.GetEncryptionKey(wecommon.Config{DBType: cfg.source.dbType, InsideEnclave: cfg.insideEnclave}, logger)
+}
+
+func targetEncryptionKey(cfg *migrateUsersConfig, sourceKey []byte) ([]byte, error) {
+	switch cfg.target.key {
+	case "":
+		return sourceKey, nil
+	case newKeyValue:
+		return wecommon.GenerateRandomKey()
+	default:
+		return decodeKey(cfg.target.key)
+	}
+}
+
+func decodeKey(key string) ([]byte, error) {
+	decoded, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex encoded key: %w", err)
+	}
+	return decoded, nil
+}
+
+func printMigrationReport(report *storage.MigrationReport) {
+	if report == nil {
+		return
+	}
+	fmt.Printf("dry run:         %t\n", report.DryRun)
+	fmt.Printf("users:           %d\n", report.Users)
+	fmt.Printf("accounts:        %d\n", report.Accounts)
+	fmt.Printf("session keys:    %d\n", report.SessionKeys)
+	fmt.Printf("API keys:        %d\n", report.APIKeys)
+	fmt.Printf("copied:          %d\n", report.Copied)
+	fmt.Printf("already present: %d\n", report.Skipped)
+	fmt.Printf("undecryptable:   %d\n", report.Undecryptable)
+	fmt.Printf("source checksum: %s\n", report.SourceChecksum)
+	fmt.Printf("target checksum: %s\n", report.TargetChecksum)
+}
@@ -7,11 +7,11 @@ import (
 	"time"
 
 	gethlog "github.com/ethereum/go-ethereum/log"
-	"github.com/ten-protocol/go-ten/tools/walletextension/storage/database/cosmosdb"
+	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
 )
 
 const (
-	// Persistence intervals (how often metrics are saved to CosmosDB)
+	// Persistence intervals (how often metrics are saved to the database)
 	MetricsPersistInterval = 10 * time.Minute
 
 	// Cleanup intervals (how often inactive users are cleaned up)
@@ -49,14 +49,14 @@ type MetricsTracker struct {
 	activityBatch     map[string]time.Time // Batch for efficient storage updates
 	activityBatchLock sync.Mutex
 
-	storage           *cosmosdb.MetricsStorageCosmosDB
+	storage           dbcommon.MetricsStorage
 	persistTicker     *time.Ticker
 	batchUpdateTicker *time.Ticker
 	dailyStatsTicker  *time.Ticker
 	logger            gethlog.Logger
 }
 
-func NewMetricsTracker(storage *cosmosdb.MetricsStorageCosmosDB, logger gethlog.Logger) Metrics {
+func NewMetricsTracker(storage dbcommon.MetricsStorage, logger gethlog.Logger) Metrics {
 	mt := &MetricsTracker{
 		activityCache:     make(map[string]time.Time),
 		activityBatch:     make(map[string]time.Time),
@@ -194,7 +194,7 @@ func (mt *MetricsTracker) processBatchUpdates() {
 	}
 }
 
-// persistMetrics periodically saves global metrics to CosmosDB
+// persistMetrics periodically saves global metrics to the database
 func (mt *MetricsTracker) persistMetrics() {
 	for range mt.persistTicker.C {
 		mt.saveMetrics()
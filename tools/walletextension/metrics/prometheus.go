@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	gethmetrics "github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+)
+
+// The operational metrics of the gateway, exposed in the Prometheus format on /metrics.
+// They are kept in memory, independently of the metrics storage, so they are available with any database.
+// The names use "/" as separator, which is converted to "_" in the exposition (e.g. gateway_rpc_eth_call_success).
+
+// Subscription types
+const (
+	SubscriptionLogs                = "logs"
+	SubscriptionNewHeads            = "newHeads"
+	SubscriptionPendingTransactions = "newPendingTransactions"
+)
+
+var (
+	registry = gethmetrics.NewRegistry()
+
+	cacheHits    = gethmetrics.NewRegisteredCounter("gateway/cache/hits", registry)
+	cacheMisses  = gethmetrics.NewRegisteredCounter("gateway/cache/misses", registry)
+	auditDropped = gethmetrics.NewRegisteredCounter("gateway/audit/dropped", registry)
+
+	collectorsLock sync.Mutex
+	collectors     []func()
+)
+
+// Enable - the latency samples are only recorded after the geth metrics system is enabled
+func Enable() {
+	if !gethmetrics.Enabled() {
+		gethmetrics.Enable()
+	}
+}
+
+// RecordRPCCall - the latency of an RPC call of the method, split by success and failure
+func RecordRPCCall(method string, success bool, elapsed time.Duration) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	gethmetrics.GetOrRegisterTimer(fmt.Sprintf("gateway/rpc/%s/%s", method, result), registry).Update(elapsed)
+}
+
+func RecordCacheHit() {
+	cacheHits.Inc(1)
+}
+
+func RecordCacheMiss() {
+	cacheMisses.Inc(1)
+}
+
+// RecordAuditDropped - an audit record which was not sent to the HTTP endpoint, because it couldn't keep up
+func RecordAuditDropped() {
+	auditDropped.Inc(1)
+}
+
+// RecordRateLimited - a request rejected by the rate limiter. The kind is "user" or "ip"
+func RecordRateLimited(kind string, reason string) {
+	gethmetrics.GetOrRegisterCounter(fmt.Sprintf("gateway/ratelimit/%s/%s", kind, reason), registry).Inc(1)
+}
+
+// SubscriptionStarted - must be followed by SubscriptionEnded when the client unsubscribes
+func SubscriptionStarted(subscriptionType string) {
+	gethmetrics.GetOrRegisterGauge("gateway/subscriptions/"+subscriptionType, registry).Inc(1)
+}
+
+func SubscriptionEnded(subscriptionType string) {
+	gethmetrics.GetOrRegisterGauge("gateway/subscriptions/"+subscriptionType, registry).Dec(1)
+}
+
+// SetGauge - sets the value of a gauge, usually from a collector
+func SetGauge(name string, value int64) {
+	gethmetrics.GetOrRegisterGauge(name, registry).Update(value)
+}
+
+// RegisterCollector - registers a function which updates the gauges of a component (e.g. the backend connection pools)
+// before the metrics are exposed
+func RegisterCollector(collect func()) {
+	collectorsLock.Lock()
+	defer collectorsLock.Unlock()
+	collectors = append(collectors, collect)
+}
+
+// RegisterTrackerCollector - exposes the user statistics of the metrics tracker
+func RegisterTrackerCollector(tracker Metrics) {
+	RegisterCollector(func() {
+		SetGauge("gateway/users/total", int64(tracker.GetTotalUsers()))
+		SetGauge("gateway/users/accounts", int64(tracker.GetTotalAccountsRegistered()))
+		SetGauge("gateway/users/monthly_active", int64(tracker.GetMonthlyActiveUsers()))
+	})
+}
+
+// Handler - serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	exposition := prometheus.Handler(registry)
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		collectorsLock.Lock()
+		for _, collect := range collectors {
+			collect()
+		}
+		collectorsLock.Unlock()
+		exposition.ServeHTTP(resp, req)
+	})
+}
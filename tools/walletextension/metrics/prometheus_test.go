@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	Enable()
+	RecordRPCCall("eth_call", true, 10*time.Millisecond)
+	RecordRPCCall("eth_call", false, 20*time.Millisecond)
+	RecordCacheHit()
+	RecordCacheMiss()
+	RecordRateLimited("ip", "compute")
+	SubscriptionStarted(SubscriptionLogs)
+	SubscriptionStarted(SubscriptionLogs)
+	SubscriptionEnded(SubscriptionLogs)
+	RegisterCollector(func() {
+		SetGauge("gateway/backend/http/active", 3)
+	})
+
+	resp := httptest.NewRecorder()
+	Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	body := resp.Body.String()
+	require.Contains(t, body, "gateway_rpc_eth_call_success_count 1")
+	require.Contains(t, body, "gateway_rpc_eth_call_failure_count 1")
+	require.Contains(t, body, "gateway_cache_hits 1")
+	require.Contains(t, body, "gateway_cache_misses 1")
+	require.Contains(t, body, "gateway_ratelimit_ip_compute 1")
+	require.Contains(t, body, "gateway_subscriptions_logs 1")
+	require.Contains(t, body, "gateway_backend_http_active 3")
+}
@@ -0,0 +1,38 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// LimitExceededCode - the JSON-RPC error code for requests rejected because of rate limiting (EIP-1474 "Limit exceeded")
+const LimitExceededCode = -32005
+
+// LimitExceededError is returned when a request is rate limited.
+// It is serialised as a JSON-RPC error with the LimitExceededCode and the retry-after hint in the data.
+type LimitExceededError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+// LimitExceededData - the data of the JSON-RPC error
+type LimitExceededData struct {
+	RetryAfter uint64 `json:"retryAfter"` // seconds
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: %s. retry after %ds", e.Reason, e.retryAfterSeconds())
+}
+
+func (e *LimitExceededError) ErrorCode() int {
+	return LimitExceededCode
+}
+
+func (e *LimitExceededError) ErrorData() interface{} {
+	return LimitExceededData{RetryAfter: e.retryAfterSeconds()}
+}
+
+func (e *LimitExceededError) retryAfterSeconds() uint64 {
+	return uint64(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
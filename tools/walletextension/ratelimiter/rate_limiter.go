@@ -1,232 +1,220 @@
 package ratelimiter
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"strings"
 	"sync"
 	"time"
 
 	gethlog "github.com/ethereum/go-ethereum/log"
 
-	"github.com/google/uuid"
-
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
 )
 
-// RequestInterval represents an interval for a request with a start and optional end timestamp.
-type RequestInterval struct {
-	Start time.Time
-	End   *time.Time // can be nil if the request is not over yet
-}
+const (
+	// slotTTL - the concurrent request slots of the requests which were never finished (e.g. the gateway instance crashed)
+	// are released after this interval
+	slotTTL = 1 * time.Minute
 
-// RateLimitUser represents a user with a map of current requests.
-type RateLimitUser struct {
-	CurrentRequests map[uuid.UUID]RequestInterval
-	mu              sync.RWMutex
-}
+	// minRequestCost - the minimum compute time charged for a request, so the requests served from the cache are not free
+	minRequestCost = time.Millisecond
 
-// zeroUUID is a zero UUID returned when no new request is added.
-var zeroUUID uuid.UUID
+	// storeTimeout - the maximum time spent on a call to the store
+	storeTimeout = 2 * time.Second
+)
 
-// AddRequest adds a new request interval to a user's current requests and returns the UUID.
-func (rl *RateLimiter) AddRequest(userID common.Address, interval RequestInterval) uuid.UUID {
-	// If the userComputeTime is 0, do nothing (rate limiting is disabled)
-	if rl.GetUserComputeTime() == 0 {
-		return zeroUUID
-	}
-	rl.mu.Lock()
-	user, exists := rl.users[userID]
-	if !exists {
-		user = &RateLimitUser{
-			CurrentRequests: make(map[uuid.UUID]RequestInterval),
-			mu:              sync.RWMutex{},
-		}
-		rl.users[userID] = user
-	}
-	rl.mu.Unlock()
+// Config - the limits enforced by the rate limiter
+type Config struct {
+	UserComputeTime       time.Duration      // the compute time a user can use within the window. 0 disables rate limiting
+	IPComputeTime         time.Duration      // the compute time the unauthenticated calls from one IP can use within the window. 0 disables the IP limits
+	Window                time.Duration      // the sliding window over which the compute time is summed
+	MaxConcurrentRequests uint32             // the requests of a user or IP which can run at the same time. 0 means no limit
+	MethodWeights         map[string]float64 // the compute time of a method is multiplied by its weight
+}
 
-	id := uuid.New()
-	user.mu.Lock()
-	user.CurrentRequests[id] = interval
-	user.mu.Unlock()
+type RateLimiter struct {
+	cfg   Config
+	store Store
 
-	return id
+	mu                  sync.Mutex
+	totalRequests       uint64
+	rateLimitedRequests uint64
+	logger              gethlog.Logger
 }
 
-// SetRequestEnd updates the end time of a request interval given its UUID.
-func (rl *RateLimiter) SetRequestEnd(userID common.Address, id uuid.UUID) {
-	// If the userComputeTime is 0, do nothing (rate limiting is disabled)
-	if rl.GetUserComputeTime() == 0 {
-		return
-	}
+// Request - a request admitted by the rate limiter. Done must be called when the request is finished
+type Request struct {
+	rl        *RateLimiter
+	key       string
+	slotID    string
+	weight    float64
+	startTime time.Time
+}
 
-	rl.mu.RLock()
-	user, userExists := rl.users[userID]
-	rl.mu.RUnlock()
-
-	if userExists {
-		user.mu.Lock()
-		if request, requestExists := user.CurrentRequests[id]; requestExists {
-			now := time.Now()
-			request.End = &now
-			user.CurrentRequests[id] = request
-		} else {
-			rl.logger.Info("Request with ID  not found for user.", "id", id, "user", userID)
-		}
-		user.mu.Unlock()
-	} else {
-		rl.logger.Info("User not found while trying to update the request.", "user", userID)
+func NewRateLimiter(cfg Config, store Store, logger gethlog.Logger) *RateLimiter {
+	rl := &RateLimiter{
+		cfg:    cfg,
+		store:  store,
+		logger: logger,
 	}
-}
 
-// CountOpenRequests counts the number of requests without an End time set.
-func (rl *RateLimiter) CountOpenRequests(userID common.Address) int {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
-
-	var count int
-	if user, exists := rl.users[userID]; exists {
-		user.mu.RLock()
-		for _, interval := range user.CurrentRequests {
-			if interval.End == nil {
-				count++
-			}
-		}
-		user.mu.RUnlock()
+	// If rate limiting is disabled we don't need to prune and log rate limited stats
+	if rl.isEnabled() {
+		go rl.logRateLimitedStats()
+		go rl.periodicPrune()
 	}
-	return count
-}
 
-// SumComputeTime sums the compute time for requests within the rate limiter's window
-// and returns it as uint32 milliseconds.
-func (rl *RateLimiter) SumComputeTime(userID common.Address) time.Duration {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+	return rl
+}
 
-	var totalComputeTime time.Duration
-	if user, exists := rl.users[userID]; exists {
-		user.mu.RLock() // lock the user to prevent changes while reading
-		defer user.mu.RUnlock()
-
-		cutoff := time.Now().Add(-rl.window)
-		for _, interval := range user.CurrentRequests {
-			// if the request has ended and it's within the window, add the compute time
-			if interval.End != nil && interval.End.After(cutoff) {
-				totalComputeTime += interval.End.Sub(interval.Start)
-			}
-			// if the request hasn't ended yet, add the compute time until now
-			if interval.End == nil {
-				totalComputeTime += time.Since(interval.Start)
-			}
-		}
-	}
-	return totalComputeTime
+// UserKey - the key under which the state of an authenticated user is stored
+func UserKey(userID common.Address) string {
+	return "user:" + userID.Hex()
 }
 
-type RateLimiter struct {
-	mu                    sync.RWMutex
-	users                 map[common.Address]*RateLimitUser
-	userComputeTime       time.Duration
-	window                time.Duration
-	maxConcurrentRequests uint32
-	totalRequests         uint64
-	rateLimitedRequests   uint64
-	logger                gethlog.Logger
+// IPKey - the key under which the state of the unauthenticated calls from an IP is stored
+func IPKey(ip string) string {
+	return "ip:" + ip
 }
 
-// IncrementTotalRequests increments the total requests counter by 1 with thread safety.
-func (rl *RateLimiter) IncrementTotalRequests() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	rl.totalRequests++
+// keyKind - "user" or "ip"
+func keyKind(key string) string {
+	kind, _, _ := strings.Cut(key, ":")
+	return kind
 }
 
-// IncrementRateLimitedRequests increments the total requests counter by 1 with thread safety.
-func (rl *RateLimiter) IncrementRateLimitedRequests() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	rl.rateLimitedRequests++
+// isEnabled - a UserComputeTime of 0 turns off all the limits
+func (rl *RateLimiter) isEnabled() bool {
+	return rl.cfg.UserComputeTime != 0
 }
 
-// GetMaxConcurrentRequest returns the maximum number of concurrent requests allowed.
-func (rl *RateLimiter) GetMaxConcurrentRequest() uint32 {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
-	return rl.maxConcurrentRequests
+// AllowUser checks if the user is allowed to make a request for the method.
+// Returns a LimitExceededError when the user has used the compute time of the window or has too many concurrent requests
+func (rl *RateLimiter) AllowUser(ctx context.Context, userID common.Address, method string) (*Request, error) {
+	return rl.allow(ctx, UserKey(userID), rl.cfg.UserComputeTime, method)
 }
 
-// GetUserComputeTime returns the user compute time
-func (rl *RateLimiter) GetUserComputeTime() time.Duration {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
-	return rl.userComputeTime
+// AllowIP checks if an unauthenticated request from the IP is allowed
+func (rl *RateLimiter) AllowIP(ctx context.Context, ip string, method string) (*Request, error) {
+	return rl.allow(ctx, IPKey(ip), rl.cfg.IPComputeTime, method)
 }
 
-func NewRateLimiter(rateLimitUserComputeTime time.Duration, rateLimitWindow time.Duration, concurrentRequestsLimit uint32, logger gethlog.Logger) *RateLimiter {
-	rl := &RateLimiter{
-		users:                 make(map[common.Address]*RateLimitUser),
-		userComputeTime:       rateLimitUserComputeTime,
-		window:                rateLimitWindow,
-		maxConcurrentRequests: concurrentRequestsLimit,
-		logger:                logger,
+// allow - a nil request is returned when rate limiting is disabled for the key.
+// When the store is unavailable, the requests are allowed, so an outage of a shared store doesn't stop the gateway
+func (rl *RateLimiter) allow(ctx context.Context, key string, budget time.Duration, method string) (*Request, error) {
+	if !rl.isEnabled() || budget == 0 {
+		return nil, nil
 	}
+	// Increment the total requests counter for statistics
+	rl.incrementTotalRequests()
 
-	// If the userComputeTime is 0 (rate limiting is disabled) we don't need to prune and log rate limited stats
-	if rl.GetUserComputeTime() != 0 {
-		go rl.logRateLimitedStats()
-		go rl.periodicPrune()
+	storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+
+	// Check if the key is in limits of rate limiting
+	now := time.Now()
+	buckets, err := rl.store.Costs(storeCtx, key, now.Add(-rl.cfg.Window))
+	if err != nil {
+		rl.logger.Warn("Could not read the rate limit state. Allowing the request.", "key", key, "error", err)
+		return nil, nil
+	}
+	if used := sumCosts(buckets); used > budget {
+		rl.incrementRateLimitedRequests()
+		metrics.RecordRateLimited(keyKind(key), "compute")
+		rl.logger.Info("Rate limit threshold reached.", "key", key)
+		return nil, &LimitExceededError{
+			Reason:     "compute time limit reached",
+			RetryAfter: rl.retryAfter(buckets, used, budget, now),
+		}
 	}
 
-	return rl
+	request := &Request{rl: rl, key: key, weight: methodWeight(rl.cfg.MethodWeights, method), startTime: now}
+
+	// Check if the key has reached the maximum number of concurrent requests
+	if rl.cfg.MaxConcurrentRequests > 0 {
+		slotID, err := rl.store.Acquire(storeCtx, key, rl.cfg.MaxConcurrentRequests, slotTTL)
+		if err != nil {
+			rl.logger.Warn("Could not acquire a concurrent request slot. Allowing the request.", "key", key, "error", err)
+			return request, nil
+		}
+		if slotID == "" {
+			rl.incrementRateLimitedRequests()
+			metrics.RecordRateLimited(keyKind(key), "concurrency")
+			rl.logger.Info("Maximum number of concurrent requests reached.", "key", key)
+			return nil, &LimitExceededError{Reason: "too many concurrent requests", RetryAfter: time.Second}
+		}
+		request.slotID = slotID
+	}
+	return request, nil
 }
 
-// Allow checks if the user is allowed to make a request based on the rate limit threshold
-// before comparing to the threshold also decays the score of the user based on the decay rate
-func (rl *RateLimiter) Allow(userID common.Address) (bool, uuid.UUID) {
-	// If the userComputeTime is 0, allow all requests (rate limiting is disabled)
-	if rl.GetUserComputeTime() == 0 {
-		return true, zeroUUID
+// retryAfter - the time until enough of the oldest costs leave the window for the used compute time to be within the budget
+func (rl *RateLimiter) retryAfter(buckets []CostBucket, used time.Duration, budget time.Duration, now time.Time) time.Duration {
+	for _, bucket := range buckets {
+		used -= bucket.Cost
+		if used <= budget {
+			return bucket.Start.Add(time.Second + rl.cfg.Window).Sub(now)
+		}
 	}
-	// Increment the total requests counter for statistics
-	rl.IncrementTotalRequests()
+	return rl.cfg.Window
+}
 
-	// Check if the user has reached the maximum number of concurrent requests
-	if uint32(rl.CountOpenRequests(userID)) >= rl.GetMaxConcurrentRequest() {
-		rl.IncrementRateLimitedRequests()
-		rl.logger.Info("User has reached the maximum number of concurrent requests.", "uid", userID.Hex())
-		return false, zeroUUID
+// Done records the compute time of the request, multiplied by the weight of the method, and releases its concurrent request slot.
+// It can be called on a nil request.
+func (r *Request) Done() {
+	if r == nil {
+		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
 
-	// Check if user is in limits of rate limiting
-	userComputeTimeForUser := rl.SumComputeTime(userID)
-	if userComputeTimeForUser > rl.userComputeTime {
-		rl.IncrementRateLimitedRequests()
-		rl.logger.Info("User has reached the rate limit threshold.", "user", userID.Hex())
-		return false, zeroUUID
+	computeTime := time.Since(r.startTime)
+	if computeTime < minRequestCost {
+		computeTime = minRequestCost
+	}
+	cost := time.Duration(float64(computeTime) * r.weight)
+	if err := r.rl.store.AddCost(ctx, r.key, cost, time.Now()); err != nil {
+		r.rl.logger.Warn("Could not record the request cost.", "key", r.key, "error", err)
 	}
+	if r.slotID != "" {
+		if err := r.rl.store.Release(ctx, r.key, r.slotID); err != nil {
+			r.rl.logger.Warn("Could not release the concurrent request slot.", "key", r.key, "error", err)
+		}
+	}
+}
 
-	requestUUID := rl.AddRequest(userID, RequestInterval{Start: time.Now()})
-	return true, requestUUID
+func sumCosts(buckets []CostBucket) time.Duration {
+	var total time.Duration
+	for _, bucket := range buckets {
+		total += bucket.Cost
+	}
+	return total
 }
 
-// PruneRequests deletes all requests that have ended before the rate limiter's window.
-func (rl *RateLimiter) PruneRequests() {
+// incrementTotalRequests increments the total requests counter by 1 with thread safety.
+func (rl *RateLimiter) incrementTotalRequests() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.totalRequests++
+}
+
+// incrementRateLimitedRequests increments the rate limited requests counter by 1 with thread safety.
+func (rl *RateLimiter) incrementRateLimitedRequests() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	rl.rateLimitedRequests++
+}
+
+// PruneRequests deletes the costs recorded before the rate limiter's window and the expired slots.
+func (rl *RateLimiter) PruneRequests() {
 	startTime := time.Now()
-	// delete all the requests that have
-	cutoff := time.Now().Add(-rl.window)
-	for userID, user := range rl.users {
-		user.mu.Lock()
-		for id, interval := range user.CurrentRequests {
-			if interval.End != nil && interval.End.Before(cutoff) {
-				delete(user.CurrentRequests, id)
-			}
-		}
-		if len(user.CurrentRequests) == 0 {
-			delete(rl.users, userID)
-		}
-		user.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := rl.store.Prune(ctx, startTime.Add(-rl.cfg.Window)); err != nil {
+		rl.logger.Warn("Could not prune the rate limit state", "error", err)
 	}
 	timeTaken := time.Since(startTime)
 	if timeTaken > 1*time.Second {
@@ -234,10 +222,10 @@ func (rl *RateLimiter) PruneRequests() {
 	}
 }
 
-// periodically prunes the requests that have ended before the rate limiter's window milliseconds
+// periodically prunes the state recorded before the rate limiter's window
 func (rl *RateLimiter) periodicPrune() {
 	for {
-		time.Sleep(rl.window / 2)
+		time.Sleep(rl.cfg.Window / 2)
 		rl.PruneRequests()
 	}
 }
@@ -0,0 +1,158 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+var testUser = common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+func newTestRateLimiter(cfg Config, store Store) *RateLimiter {
+	// the rate limiter is built directly so the background pruning and logging are not started
+	return &RateLimiter{cfg: cfg, store: store, logger: gethlog.New()}
+}
+
+func TestMethodWeightsUseTheBudgetFaster(t *testing.T) {
+	store := NewMemoryStore()
+	rl := newTestRateLimiter(Config{UserComputeTime: 10 * time.Millisecond, Window: time.Minute, MethodWeights: DefaultMethodWeights}, store)
+	ctx := context.Background()
+
+	req, err := rl.AllowUser(ctx, testUser, "eth_getLogs")
+	require.NoError(t, err)
+	req.Done()
+
+	buckets, err := store.Costs(ctx, UserKey(testUser), time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, sumCosts(buckets), 4*minRequestCost)
+
+	// the ten_ namespace matches the weight of the eth_ method
+	require.Equal(t, float64(4), methodWeight(DefaultMethodWeights, "ten_getLogs"))
+	require.Equal(t, float64(1), methodWeight(DefaultMethodWeights, "eth_chainId"))
+}
+
+func TestLimitExceededError(t *testing.T) {
+	store := NewMemoryStore()
+	rl := newTestRateLimiter(Config{UserComputeTime: 5 * time.Millisecond, Window: time.Minute, MethodWeights: DefaultMethodWeights}, store)
+	ctx := context.Background()
+
+	require.NoError(t, store.AddCost(ctx, UserKey(testUser), 10*time.Millisecond, time.Now()))
+
+	req, err := rl.AllowUser(ctx, testUser, "eth_call")
+	require.Nil(t, req)
+	var limitErr *LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, LimitExceededCode, limitErr.ErrorCode())
+	require.Greater(t, limitErr.RetryAfter, time.Duration(0))
+	require.LessOrEqual(t, limitErr.RetryAfter, time.Minute+time.Second)
+
+	data, err := json.Marshal(limitErr.ErrorData())
+	require.NoError(t, err)
+	var decoded LimitExceededData
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.GreaterOrEqual(t, decoded.RetryAfter, uint64(1))
+
+	// other users are not affected
+	req, err = rl.AllowUser(ctx, common.HexToAddress("0x2222222222222222222222222222222222222222"), "eth_call")
+	require.NoError(t, err)
+	req.Done()
+}
+
+func TestIPLimits(t *testing.T) {
+	store := NewMemoryStore()
+	rl := newTestRateLimiter(Config{UserComputeTime: time.Second, IPComputeTime: 5 * time.Millisecond, Window: time.Minute}, store)
+	ctx := context.Background()
+
+	require.NoError(t, store.AddCost(ctx, IPKey("10.0.0.1"), 10*time.Millisecond, time.Now()))
+	_, err := rl.AllowIP(ctx, "10.0.0.1", "eth_chainId")
+	require.Error(t, err)
+
+	req, err := rl.AllowIP(ctx, "10.0.0.2", "eth_chainId")
+	require.NoError(t, err)
+	require.NotNil(t, req)
+	req.Done()
+
+	// the IP limits can be disabled separately
+	rl = newTestRateLimiter(Config{UserComputeTime: time.Second, Window: time.Minute}, store)
+	req, err = rl.AllowIP(ctx, "10.0.0.1", "eth_chainId")
+	require.NoError(t, err)
+	require.Nil(t, req)
+}
+
+// TestSharedStore - two gateway instances using the same store enforce one budget
+func TestSharedStore(t *testing.T) {
+	store := NewMemoryStore()
+	cfg := Config{UserComputeTime: 3 * time.Millisecond, Window: time.Minute, MaxConcurrentRequests: 1}
+	instance1 := newTestRateLimiter(cfg, store)
+	instance2 := newTestRateLimiter(cfg, store)
+	ctx := context.Background()
+
+	// a request in progress on one instance counts towards the concurrency limit of the other
+	req, err := instance1.AllowUser(ctx, testUser, "eth_call")
+	require.NoError(t, err)
+	_, err = instance2.AllowUser(ctx, testUser, "eth_call")
+	require.Error(t, err)
+	req.Done()
+
+	// the costs recorded by one instance use the budget of the other
+	for i := 0; i < 10; i++ {
+		req, err := instance1.AllowUser(ctx, testUser, "eth_call")
+		if err != nil {
+			break
+		}
+		req.Done()
+	}
+	_, err = instance1.AllowUser(ctx, testUser, "eth_call")
+	require.Error(t, err)
+	_, err = instance2.AllowUser(ctx, testUser, "eth_call")
+	require.Error(t, err)
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	rl := newTestRateLimiter(Config{UserComputeTime: time.Second, Window: time.Minute, MaxConcurrentRequests: 2}, NewMemoryStore())
+	ctx := context.Background()
+
+	req1, err := rl.AllowUser(ctx, testUser, "eth_call")
+	require.NoError(t, err)
+	req2, err := rl.AllowUser(ctx, testUser, "eth_call")
+	require.NoError(t, err)
+	_, err = rl.AllowUser(ctx, testUser, "eth_call")
+	require.Error(t, err)
+
+	req1.Done()
+	req3, err := rl.AllowUser(ctx, testUser, "eth_call")
+	require.NoError(t, err)
+	req2.Done()
+	req3.Done()
+}
+
+func TestDisabled(t *testing.T) {
+	rl := newTestRateLimiter(Config{IPComputeTime: time.Second, Window: time.Minute}, NewMemoryStore())
+	req, err := rl.AllowUser(context.Background(), testUser, "eth_call")
+	require.NoError(t, err)
+	require.Nil(t, req)
+	req.Done()
+	req, err = rl.AllowIP(context.Background(), "10.0.0.1", "eth_call")
+	require.NoError(t, err)
+	require.Nil(t, req)
+}
+
+func TestParseMethodWeights(t *testing.T) {
+	weights, err := ParseMethodWeights("eth_getLogs=10, eth_chainId=0.5")
+	require.NoError(t, err)
+	require.Equal(t, float64(10), weights["eth_getLogs"])
+	require.Equal(t, 0.5, weights["eth_chainId"])
+	require.Equal(t, DefaultMethodWeights["eth_call"], weights["eth_call"])
+	require.Equal(t, float64(4), DefaultMethodWeights["eth_getLogs"])
+
+	_, err = ParseMethodWeights("eth_getLogs")
+	require.Error(t, err)
+	_, err = ParseMethodWeights("eth_getLogs=-1")
+	require.Error(t, err)
+}
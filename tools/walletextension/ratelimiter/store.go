@@ -0,0 +1,135 @@
+package ratelimiter
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CostBucket - the cost recorded for a key within one second
+type CostBucket struct {
+	Start time.Time
+	Cost  time.Duration
+}
+
+// Store - the state of the rate limiter.
+// The in-memory store is local to a gateway instance, while a shared store (e.g. the PostgreSQL database of the gateway)
+// lets several instances behind a load balancer enforce one budget.
+type Store interface {
+	// AddCost - records the cost of a finished request of the key, in the bucket of the given time
+	AddCost(ctx context.Context, key string, cost time.Duration, at time.Time) error
+	// Costs - the buckets of the key which start at or after since, ordered by time
+	Costs(ctx context.Context, key string, since time.Time) ([]CostBucket, error)
+	// Acquire - reserves one of the max concurrent request slots of the key until Release is called or the ttl expires.
+	// Returns an empty id when all the slots are taken
+	Acquire(ctx context.Context, key string, maxConcurrent uint32, ttl time.Duration) (string, error)
+	Release(ctx context.Context, key string, slotID string) error
+	// Prune - deletes the costs recorded before the given time and the expired slots
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// BucketStart - the start of the bucket which records the costs at the given time
+func BucketStart(at time.Time) time.Time {
+	return at.Truncate(time.Second)
+}
+
+type memoryKeyState struct {
+	buckets map[int64]time.Duration // unix second -> cost
+	slots   map[string]time.Time    // slot id -> expiry
+}
+
+type memoryStore struct {
+	mu   sync.Mutex
+	keys map[string]*memoryKeyState
+}
+
+// NewMemoryStore - the default store, which keeps the state in the memory of the gateway instance
+func NewMemoryStore() Store {
+	return &memoryStore{keys: make(map[string]*memoryKeyState)}
+}
+
+func (s *memoryStore) state(key string) *memoryKeyState {
+	state, found := s.keys[key]
+	if !found {
+		state = &memoryKeyState{buckets: make(map[int64]time.Duration), slots: make(map[string]time.Time)}
+		s.keys[key] = state
+	}
+	return state
+}
+
+func (s *memoryStore) AddCost(_ context.Context, key string, cost time.Duration, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state(key).buckets[BucketStart(at).Unix()] += cost
+	return nil
+}
+
+func (s *memoryStore) Costs(_ context.Context, key string, since time.Time) ([]CostBucket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, found := s.keys[key]
+	if !found {
+		return nil, nil
+	}
+	var buckets []CostBucket
+	for start, cost := range state.buckets {
+		if start >= BucketStart(since).Unix() {
+			buckets = append(buckets, CostBucket{Start: time.Unix(start, 0), Cost: cost})
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets, nil
+}
+
+func (s *memoryStore) Acquire(_ context.Context, key string, maxConcurrent uint32, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.state(key)
+	now := time.Now()
+	open := uint32(0)
+	for _, expiry := range state.slots {
+		if expiry.After(now) {
+			open++
+		}
+	}
+	if open >= maxConcurrent {
+		return "", nil
+	}
+	id := uuid.NewString()
+	state.slots[id] = now.Add(ttl)
+	return id, nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string, slotID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, found := s.keys[key]; found {
+		delete(state.slots, slotID)
+	}
+	return nil
+}
+
+func (s *memoryStore) Prune(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, state := range s.keys {
+		for start := range state.buckets {
+			if start < BucketStart(before).Unix() {
+				delete(state.buckets, start)
+			}
+		}
+		for id, expiry := range state.slots {
+			if !expiry.After(now) {
+				delete(state.slots, id)
+			}
+		}
+		if len(state.buckets) == 0 && len(state.slots) == 0 {
+			delete(s.keys, key)
+		}
+	}
+	return nil
+}
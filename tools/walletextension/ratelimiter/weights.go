@@ -0,0 +1,53 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// DefaultMethodWeights - the compute time of these methods is multiplied by the weight, so they use the budget faster.
+// The methods which are not listed have a weight of 1.
+var DefaultMethodWeights = map[string]float64{
+	"eth_call":               2,
+	"eth_sendRawTransaction": 2,
+	"eth_estimateGas":        4,
+	"eth_getLogs":            4,
+	"debug_traceCall":        8,
+	"debug_traceTransaction": 8,
+}
+
+// ParseMethodWeights parses a list of method=weight pairs separated by commas (e.g. "eth_getLogs=5,eth_call=2")
+// and returns the default weights overridden by the parsed ones
+func ParseMethodWeights(s string) (map[string]float64, error) {
+	weights := maps.Clone(DefaultMethodWeights)
+	if strings.TrimSpace(s) == "" {
+		return weights, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		method, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || method == "" {
+			return nil, fmt.Errorf("invalid method weight %q. Expected method=weight", pair)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for method %s: %q", method, value)
+		}
+		weights[method] = weight
+	}
+	return weights, nil
+}
+
+// methodWeight - the backend calls use the "ten_" namespace for the "eth_" methods, so both names match the same weight
+func methodWeight(weights map[string]float64, method string) float64 {
+	if weight, found := weights[method]; found {
+		return weight
+	}
+	if name, found := strings.CutPrefix(method, "ten_"); found {
+		if weight, found := weights["eth_"+name]; found {
+			return weight
+		}
+	}
+	return 1
+}
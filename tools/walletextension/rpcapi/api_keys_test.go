@@ -0,0 +1,56 @@
+package rpcapi
+
+import (
+	"context"
+	"testing"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/storage"
+)
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	encryptionKey, err := common.GenerateRandomKey()
+	require.NoError(t, err)
+	userStorage, err := storage.New("sqlite", "", "", encryptionKey, gethlog.New())
+	require.NoError(t, err)
+	_, url, w := startGatewayWithStorage(t, testGatewayConfig(), userStorage)
+
+	userID, err := w.GenerateAndStoreNewUser()
+	require.NoError(t, err)
+	readKey, readAPIKey, err := w.CreateAPIKey(userID, "indexer", []common.APIKeyScope{common.APIKeyScopeRead})
+	require.NoError(t, err)
+
+	dial := func(key string) *rpc.Client {
+		client, err := rpc.DialOptions(context.Background(), url, rpc.WithHeader("Authorization", "Bearer "+key))
+		require.NoError(t, err)
+		t.Cleanup(client.Close)
+		return client
+	}
+
+	// the key authenticates the user without the token
+	client := dial(readKey)
+	var sessionKeys []common.SessionKeyInfo
+	require.NoError(t, client.Call(&sessionKeys, "sessionkeys_list"))
+	require.Empty(t, sessionKeys)
+
+	// the calls outside the scopes of the key are rejected
+	var address string
+	err = client.Call(&address, "sessionkeys_create", nil)
+	require.ErrorContains(t, err, notAuthorised)
+
+	// an unknown key is rejected
+	require.Error(t, dial(common.APIKeyPrefix+"unknown").Call(&sessionKeys, "sessionkeys_list"))
+
+	// a revoked key is rejected
+	require.NoError(t, w.RevokeAPIKey(userID, readAPIKey.ID))
+	err = client.Call(&sessionKeys, "sessionkeys_list")
+	require.ErrorContains(t, err, common.ErrAPIKeyNotFound.Error())
+
+	user, err := userStorage.GetUser(userID)
+	require.NoError(t, err)
+	require.Len(t, user.APIKeysInfo(), 1)
+	require.NotNil(t, user.APIKeysInfo()[0].RevokedAt)
+}
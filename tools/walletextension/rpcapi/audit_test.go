@@ -0,0 +1,91 @@
+package rpcapi
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+	"github.com/ten-protocol/go-ten/tools/walletextension/audit"
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/storage"
+)
+
+func TestRequestsAudited(t *testing.T) {
+	config := testGatewayConfig()
+	config.RateLimitIPComputeTime = mockHostCallDuration / 2
+	config.AuditLogPath = filepath.Join(t.TempDir(), "audit.jsonl")
+	_, url := startGateway(t, config)
+	client, err := rpc.Dial(url)
+	require.NoError(t, err)
+	defer client.Close()
+
+	batch := feeHistoryBatch(testBatchMaxSize, false)
+	require.NoError(t, client.BatchCallContext(context.Background(), batch))
+
+	// the file sink writes the records before the responses are returned
+	var allowed, rejected int
+	require.NoError(t, audit.Query(config.AuditLogPath, audit.Filter{}, func(record *audit.Record) error {
+		require.Equal(t, "ten_feeHistory", record.Method)
+		require.Empty(t, record.User)
+		// the params are hashed by default
+		require.Len(t, record.Params, len(`"0x"`)+64)
+		switch record.RateLimit {
+		case audit.RateLimitAllowed:
+			allowed++
+			require.Empty(t, record.Error)
+		case audit.RateLimitRejected:
+			rejected++
+			require.NotEmpty(t, record.Error)
+		}
+		return nil
+	}))
+	require.Equal(t, testBatchConcurrency, allowed)
+	require.Equal(t, testBatchMaxSize-testBatchConcurrency, rejected)
+}
+
+func TestLogAndFilterRequestsAudited(t *testing.T) {
+	config := testGatewayConfig()
+	// the first request uses up the compute time of the user
+	config.RateLimitUserComputeTime = time.Nanosecond
+	config.AuditLogPath = filepath.Join(t.TempDir(), "audit.jsonl")
+	encryptionKey, err := common.GenerateRandomKey()
+	require.NoError(t, err)
+	userStorage, err := storage.New("sqlite", "", "", encryptionKey, gethlog.New())
+	require.NoError(t, err)
+	_, url, w := startGatewayWithStorage(t, config, userStorage)
+	userID, err := w.GenerateAndStoreNewUser()
+	require.NoError(t, err)
+	client, err := rpc.Dial(url + "?token=" + hexutil.Encode(userID))
+	require.NoError(t, err)
+	defer client.Close()
+
+	var filterID string
+	require.NoError(t, client.Call(&filterID, "eth_newBlockFilter"))
+	var logs []any
+	require.NoError(t, client.Call(&logs, "eth_getLogs", map[string]any{}))
+	time.Sleep(time.Millisecond)
+	require.Error(t, client.Call(&logs, "eth_getLogs", map[string]any{}))
+	// a poll of the filter is audited and rate limited once
+	var hashes []any
+	require.Error(t, client.Call(&hashes, "eth_getFilterChanges", filterID))
+
+	var records []*audit.Record
+	require.NoError(t, audit.Query(config.AuditLogPath, audit.Filter{User: audit.HashUserID(userID)}, func(record *audit.Record) error {
+		records = append(records, record)
+		return nil
+	}))
+	require.Len(t, records, 4)
+	require.Equal(t, "eth_newBlockFilter", records[0].Method)
+	require.Equal(t, "ten_getLogs", records[1].Method)
+	require.Equal(t, audit.RateLimitAllowed, records[1].RateLimit)
+	require.Equal(t, "ten_getLogs", records[2].Method)
+	require.Equal(t, audit.RateLimitRejected, records[2].RateLimit)
+	require.NotEmpty(t, records[2].Error)
+	require.Equal(t, "eth_getFilterChanges", records[3].Method)
+	require.Equal(t, audit.RateLimitRejected, records[3].RateLimit)
+}
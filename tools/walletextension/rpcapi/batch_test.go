@@ -0,0 +1,264 @@
+package rpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+	"github.com/ten-protocol/go-ten/lib/gethfork/node"
+	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
+	"github.com/ten-protocol/go-ten/tools/walletextension/ratelimiter"
+	"github.com/ten-protocol/go-ten/tools/walletextension/services"
+	"github.com/ten-protocol/go-ten/tools/walletextension/storage"
+)
+
+const (
+	testBatchMaxSize     = 5
+	testBatchConcurrency = 2
+	mockHostCallDuration = 50 * time.Millisecond
+	feeHistoryLastBlock  = 100
+)
+
+// mockHost - serves the "ten_" methods used by the tests instead of a TEN node and records the parallel calls
+type mockHost struct {
+	calls       atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (h *mockHost) RpcKey() []byte { //nolint:stylecheck
+	return []byte{1}
+}
+
+func (h *mockHost) FeeHistory(blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, _ []float64) *FeeHistoryResult {
+	h.calls.Add(1)
+	inFlight := h.inFlight.Add(1)
+	defer h.inFlight.Add(-1)
+	for {
+		current := h.maxInFlight.Load()
+		if inFlight <= current || h.maxInFlight.CompareAndSwap(current, inFlight) {
+			break
+		}
+	}
+	time.Sleep(mockHostCallDuration)
+	return &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(big.NewInt(lastBlock.Int64() - int64(blockCount) + 1)),
+		GasUsedRatio: make([]float64, blockCount),
+	}
+}
+
+// startGateway - starts a gateway serving the eth API in front of the mock host and returns its URL
+func startGateway(t *testing.T, config *common.Config) (*mockHost, string) {
+	encryptionKey, err := common.GenerateRandomKey()
+	require.NoError(t, err)
+	userStorage, err := storage.New("sqlite", "", "", encryptionKey, gethlog.New())
+	require.NoError(t, err)
+	host, url, _ := startGatewayWithStorage(t, config, userStorage)
+	return host, url
+}
+
+// startGatewayWithStorage - like startGateway, with the users stored in the given storage
+func startGatewayWithStorage(t *testing.T, config *common.Config, userStorage storage.UserStorage) (*mockHost, string, *services.Services) {
+	host := &mockHost{}
+	hostServer := rpc.NewServer()
+	require.NoError(t, hostServer.RegisterName("ten", host))
+	httpHost := httptest.NewServer(hostServer)
+	t.Cleanup(httpHost.Close)
+
+	logger := gethlog.New()
+	stopControl := stopcontrol.New()
+	w := services.NewServices(httpHost.URL, httpHost.URL, userStorage, stopControl, "test", logger, metrics.NewNoOpMetricsTracker(logger), config)
+	t.Cleanup(func() {
+		stopControl.Stop()
+		w.Stop()
+	})
+
+	port := freePort(t)
+	server := node.NewServer(&node.RPCConfig{
+		EnableHTTP:       true,
+		HTTPPort:         port,
+		HTTPPath:         common.APIVersion1 + "/",
+		Host:             "127.0.0.1",
+		BatchItemLimit:   config.BatchMaxSize,
+		BatchConcurrency: config.BatchMaxConcurrency,
+		BatchKey:         BatchKey(w),
+	}, logger)
+	server.RegisterAPIs([]rpc.API{
+		{Namespace: "eth", Service: NewEthereumAPI(w)},
+		{Namespace: "eth", Service: NewFilterAPI(w)},
+		{Namespace: "sessionkeys", Service: NewSessionKeyAPI(w)},
+	})
+	require.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+
+	return host, fmt.Sprintf("http://127.0.0.1:%d%s/", port, common.APIVersion1), w
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func testGatewayConfig() *common.Config {
+	return &common.Config{
+		RateLimitUserComputeTime:       10 * time.Second,
+		RateLimitWindow:                time.Minute,
+		RateLimitMaxConcurrentRequests: testBatchConcurrency,
+		RateLimitIPComputeTime:         10 * time.Second,
+		BatchMaxSize:                   testBatchMaxSize,
+		BatchMaxConcurrency:            testBatchConcurrency,
+		DisableCaching:                 true,
+	}
+}
+
+// feeHistoryBatch - a batch of fee history requests. The requests have different arguments, so they are not
+// served from the cache, unless sameArgs is set
+func feeHistoryBatch(size int, sameArgs bool) []rpc.BatchElem {
+	batch := make([]rpc.BatchElem, size)
+	for i := range batch {
+		blockCount := i + 1
+		if sameArgs {
+			blockCount = 1
+		}
+		batch[i] = rpc.BatchElem{
+			Method: "eth_feeHistory",
+			Args:   []any{hexutil.Uint64(blockCount), hexutil.Uint64(feeHistoryLastBlock), []float64{}},
+			Result: new(FeeHistoryResult),
+		}
+	}
+	return batch
+}
+
+func TestBatchExecutedInParallel(t *testing.T) {
+	host, url := startGateway(t, testGatewayConfig())
+	client, err := rpc.Dial(url)
+	require.NoError(t, err)
+	defer client.Close()
+
+	batch := feeHistoryBatch(testBatchMaxSize-1, false)
+	// an unknown method fails without affecting the rest of the batch
+	batch = append(batch, rpc.BatchElem{Method: "eth_unknownMethod", Result: new(hexutil.Big)})
+	require.NoError(t, client.BatchCallContext(context.Background(), batch))
+
+	// each response matches its request
+	for i, elem := range batch[:testBatchMaxSize-1] {
+		require.NoError(t, elem.Error)
+		require.Equal(t, int64(feeHistoryLastBlock-i), elem.Result.(*FeeHistoryResult).OldestBlock.ToInt().Int64())
+	}
+	require.Error(t, batch[testBatchMaxSize-1].Error)
+
+	// every call reached the host, but never more than the concurrency limit at once
+	require.Equal(t, int32(testBatchMaxSize-1), host.calls.Load())
+	require.Equal(t, int32(testBatchConcurrency), host.maxInFlight.Load())
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	host, url := startGateway(t, testGatewayConfig())
+	client, err := rpc.Dial(url)
+	require.NoError(t, err)
+	defer client.Close()
+
+	batch := feeHistoryBatch(testBatchMaxSize+1, false)
+	require.NoError(t, client.BatchCallContext(context.Background(), batch))
+	require.ErrorContains(t, batch[0].Error, "batch too large")
+	require.Equal(t, int32(0), host.calls.Load())
+}
+
+func TestBatchItemsRateLimited(t *testing.T) {
+	config := testGatewayConfig()
+	// the first calls use up the compute time of the IP
+	config.RateLimitIPComputeTime = mockHostCallDuration / 2
+	host, url := startGateway(t, config)
+	client, err := rpc.Dial(url)
+	require.NoError(t, err)
+	defer client.Close()
+
+	batch := feeHistoryBatch(testBatchMaxSize, false)
+	require.NoError(t, client.BatchCallContext(context.Background(), batch))
+
+	succeeded := 0
+	for _, elem := range batch {
+		if elem.Error == nil {
+			succeeded++
+			continue
+		}
+		var rpcErr rpc.Error
+		require.True(t, errors.As(elem.Error, &rpcErr))
+		require.Equal(t, ratelimiter.LimitExceededCode, rpcErr.ErrorCode())
+	}
+	require.Equal(t, testBatchConcurrency, succeeded)
+	require.Equal(t, int32(testBatchConcurrency), host.calls.Load())
+}
+
+func TestBatchItemsCached(t *testing.T) {
+	config := testGatewayConfig()
+	config.DisableCaching = false
+	host, url := startGateway(t, config)
+	client, err := rpc.Dial(url)
+	require.NoError(t, err)
+	defer client.Close()
+
+	batch := feeHistoryBatch(testBatchMaxSize, true)
+	require.NoError(t, client.BatchCallContext(context.Background(), batch))
+	for _, elem := range batch {
+		require.NoError(t, elem.Error)
+	}
+	calls := host.calls.Load()
+	require.Less(t, calls, int32(testBatchMaxSize))
+	// the cache applies the writes asynchronously
+	time.Sleep(20 * time.Millisecond)
+
+	// the items of the next batch are served from the cache
+	batch = feeHistoryBatch(testBatchMaxSize, true)
+	require.NoError(t, client.BatchCallContext(context.Background(), batch))
+	for _, elem := range batch {
+		require.NoError(t, elem.Error)
+	}
+	require.Equal(t, calls, host.calls.Load())
+}
+
+func TestBatchSlotsOfUnknownTokensShared(t *testing.T) {
+	config := testGatewayConfig()
+	// the rate limiter doesn't bound the calls in flight
+	config.RateLimitMaxConcurrentRequests = 2 * testBatchMaxSize
+	host, url := startGateway(t, config)
+
+	// the batches with made up tokens from one IP share the slots of the IP
+	var wg sync.WaitGroup
+	for i, token := range []string{"0x01", "0x02"} {
+		client, err := rpc.Dial(url + "?token=" + token)
+		require.NoError(t, err)
+		defer client.Close()
+		// the requests of the batches differ, so they are not merged
+		batch := feeHistoryBatch(testBatchMaxSize, false)
+		for _, elem := range batch {
+			elem.Args[1] = hexutil.Uint64(feeHistoryLastBlock + i)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, client.BatchCallContext(context.Background(), batch))
+			for _, elem := range batch {
+				require.NoError(t, elem.Error)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(testBatchConcurrency), host.maxInFlight.Load())
+}
@@ -57,11 +57,7 @@ func (api *BlockChainAPI) GetBalance(ctx context.Context, address gethcommon.Add
 		ctx,
 		api.we,
 		&AuthExecCfg{
-			cacheCfg: &cache.Cfg{
-				DynamicType: func() cache.Strategy {
-					return cacheBlockNumberOrHash(blockNrOrHash)
-				},
-			},
+			cacheCfg:           cacheAccountState(blockNrOrHash, address),
 			account:            &address,
 			tryUntilAuthorised: true, // the user can request the balance of a contract account
 		},
@@ -88,8 +84,27 @@ type StorageResult struct {
 	Proof []string     `json:"proof"`
 }
 
+// GetProof - returns the Merkle proofs of the account and of the storage keys.
+// The proofs of the accounts of the user are created with their viewing key, and for contracts any account can be used.
 func (api *BlockChainAPI) GetProof(ctx context.Context, address gethcommon.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
-	return nil, rpcNotImplemented
+	if storageKeys == nil {
+		storageKeys = []string{}
+	}
+	return ExecAuthRPC[AccountResult](
+		ctx,
+		api.we,
+		&AuthExecCfg{
+			account:            &address,
+			tryUntilAuthorised: true,
+			cacheCfg: &cache.Cfg{DynamicType: func() cache.Strategy {
+				return cacheBlockNumberOrHash(blockNrOrHash)
+			}},
+		},
+		tenrpc.ERPCGetProof,
+		address,
+		storageKeys,
+		blockNrOrHash,
+	)
 }
 
 func (api *BlockChainAPI) GetHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error) {
@@ -158,11 +173,7 @@ func (api *BlockChainAPI) GetCode(ctx context.Context, address gethcommon.Addres
 	resp, err := UnauthenticatedTenRPCCall[hexutil.Bytes](
 		ctx,
 		api.we,
-		&cache.Cfg{
-			DynamicType: func() cache.Strategy {
-				return cacheBlockNumberOrHash(blockNrOrHash)
-			},
-		},
+		cacheAccountState(blockNrOrHash, address),
 		"ten_getCode",
 		address,
 		blockNrOrHash,
@@ -184,7 +195,12 @@ func (api *BlockChainAPI) GetCode(ctx context.Context, address gethcommon.Addres
 //
 // In the future, we can support both CustomQueries and some debug version of eth_getStorageAt if needed.
 func (api *BlockChainAPI) GetStorageAt(ctx context.Context, address gethcommon.Address, params string, _ rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
-	user, err := extractUserForRequest(ctx, api.we)
+	scope := wecommon.APIKeyScopeRead
+	switch address.Hex() {
+	case common.CreateSessionKeyCQMethod, common.ActivateSessionKeyCQMethod, common.DeactivateSessionKeyCQMethod, common.DeleteSessionKeyCQMethod:
+		scope = wecommon.APIKeyScopeSessionKeys
+	}
+	user, err := extractUserWithScope(ctx, api.we, scope)
 	if err != nil {
 		return nil, err
 	}
@@ -206,20 +222,25 @@ func (api *BlockChainAPI) GetStorageAt(ctx context.Context, address gethcommon.A
 			return nil, fmt.Errorf("unable to marshal response object: %w", err)
 		}
 		return serialised, nil
+	// the session key custom queries operate on the only session key of the user.
+	// The sessionkeys_* methods support multiple keys and policies.
 	case common.CreateSessionKeyCQMethod:
-		sk, err := api.we.SKManager.CreateSessionKey(user)
+		if len(user.SessionKeys) > 0 {
+			return nil, fmt.Errorf("unable to create session key: user already has a session key")
+		}
+		sk, err := api.we.SKManager.CreateSessionKey(user, nil)
 		if err != nil {
 			return nil, fmt.Errorf("unable to create session key: %w", err)
 		}
 		return sk.Account.Address.Bytes(), nil
 	case common.ActivateSessionKeyCQMethod:
-		res, err := api.we.SKManager.ActivateSessionKey(user)
+		res, err := api.we.SKManager.ActivateSessionKey(user, nil)
 		return []byte{boolToByte(res)}, err
 	case common.DeactivateSessionKeyCQMethod:
-		res, err := api.we.SKManager.DeactivateSessionKey(user)
+		res, err := api.we.SKManager.DeactivateSessionKey(user, nil)
 		return []byte{boolToByte(res)}, err
 	case common.DeleteSessionKeyCQMethod:
-		res, err := api.we.SKManager.DeleteSessionKey(user)
+		res, err := api.we.SKManager.DeleteSessionKey(user, nil)
 		return []byte{boolToByte(res)}, err
 	default: // address was not a recognised custom query method address
 		resp, err := ExecAuthRPC[any](ctx, api.we, &AuthExecCfg{tryUntilAuthorised: true}, tenrpc.ERPCGetStorageAt, address, params, nil)
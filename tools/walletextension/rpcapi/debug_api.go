@@ -2,16 +2,23 @@ package rpcapi
 
 import (
 	"context"
+	"encoding/json"
+	"sort"
 
+	"golang.org/x/exp/maps"
+
+	"github.com/ten-protocol/go-ten/go/common/gethapi"
 	tenrpc "github.com/ten-protocol/go-ten/go/common/rpc"
 
 	"github.com/ten-protocol/go-ten/tools/walletextension/cache"
+	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
 
 	"github.com/ten-protocol/go-ten/tools/walletextension/services"
 
 	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/tracers"
 	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
 )
 
@@ -77,3 +84,102 @@ func (api *DebugAPI) EventLogRelevancy(ctx context.Context, crit common.FilterCr
 	}
 	return *l, nil
 }
+
+// TraceTransaction - returns the trace of a transaction, redacted to what the caller is entitled to see.
+// Only the sender of the transaction can trace it.
+func (api *DebugAPI) TraceTransaction(ctx context.Context, hash gethcommon.Hash, config *tracers.TraceConfig) (json.RawMessage, error) {
+	trace, err := ExecAuthRPC[json.RawMessage](
+		ctx,
+		api.we,
+		&AuthExecCfg{
+			cacheCfg: &cache.Cfg{
+				Type: cache.NoCache,
+			},
+			tryUntilAuthorised: true,
+		},
+		tenrpc.ERPCDebugTraceTransaction,
+		hash,
+		config,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return *trace, nil
+}
+
+// TraceCall - returns the trace of a call executed on top of the requested batch.
+// The "from" of the call must be one of the accounts of the user.
+func (api *DebugAPI) TraceCall(ctx context.Context, args gethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *tracers.TraceConfig) (json.RawMessage, error) {
+	trace, err := ExecAuthRPC[json.RawMessage](
+		ctx,
+		api.we,
+		&AuthExecCfg{
+			cacheCfg: &cache.Cfg{
+				Type: cache.NoCache,
+			},
+			computeFromCallback: func(user *wecommon.GWUser) *gethcommon.Address {
+				return searchFromAndData(user.GetAllAddresses(), args)
+			},
+			adjustArgs: func(acct *wecommon.GWAccount) []any {
+				argsClone := populateFrom(acct, args)
+				return []any{argsClone, blockNrOrHash, config}
+			},
+			tryAll: true,
+		},
+		tenrpc.ERPCDebugTraceCall,
+		args,
+		blockNrOrHash,
+		config,
+	)
+	if trace == nil {
+		return nil, err
+	}
+	return *trace, err
+}
+
+// TraceBlockByNumber - returns the traces of the transactions in the batch which were sent by the accounts of the user,
+// in the order in which they were executed.
+func (api *DebugAPI) TraceBlockByNumber(ctx context.Context, number rpc.BlockNumber, config *tracers.TraceConfig) ([]*tracers.TxTraceResult, error) {
+	user, err := extractUserForRequest(ctx, api.we)
+	if err != nil {
+		return nil, err
+	}
+
+	// each account can only trace its own transactions
+	traces := make([][]*tracers.TxTraceResult, 0)
+	for address := range user.AllAccounts() {
+		accountTraces, err := ExecAuthRPC[[]*tracers.TxTraceResult](
+			ctx,
+			api.we,
+			&AuthExecCfg{
+				account: &address,
+				cacheCfg: &cache.Cfg{
+					Type: cache.NoCache,
+				},
+			},
+			tenrpc.ERPCDebugTraceBlockByNumber,
+			number,
+			config,
+		)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, *accountTraces)
+	}
+	return mergeTxTraces(traces), nil
+}
+
+// mergeTxTraces - returns the deduplicated traces of the accounts, sorted by the index of the transaction in the batch
+func mergeTxTraces(traces [][]*tracers.TxTraceResult) []*tracers.TxTraceResult {
+	byHash := make(map[gethcommon.Hash]*tracers.TxTraceResult)
+	for _, accountTraces := range traces {
+		for _, trace := range accountTraces {
+			byHash[trace.TxHash] = trace
+		}
+	}
+	result := maps.Values(byHash)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TxIndex < result[j].TxIndex
+	})
+	return result
+}
@@ -0,0 +1,24 @@
+package rpcapi
+
+import (
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common/tracers"
+)
+
+func TestMergeTxTraces(t *testing.T) {
+	trace := func(index uint64) *tracers.TxTraceResult {
+		return &tracers.TxTraceResult{TxHash: gethcommon.BigToHash(new(big.Int).SetUint64(index + 1)), TxIndex: hexutil.Uint(index)}
+	}
+	// the traces of the accounts of the user, where the same transaction is returned for two accounts
+	merged := mergeTxTraces([][]*tracers.TxTraceResult{
+		{trace(1), trace(4)},
+		{},
+		{trace(0), trace(3), trace(1)},
+	})
+	require.Equal(t, []*tracers.TxTraceResult{trace(0), trace(1), trace(3), trace(4)}, merged)
+}
@@ -3,20 +3,26 @@ package rpcapi
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"sort"
 	"sync/atomic"
 	"time"
 
 	rpc2 "github.com/ten-protocol/go-ten/go/common/rpc"
+	enclaverpc "github.com/ten-protocol/go-ten/go/enclave/rpc"
 	tenrpc "github.com/ten-protocol/go-ten/go/rpc"
 
+	"github.com/ten-protocol/go-ten/tools/walletextension/audit"
 	"github.com/ten-protocol/go-ten/tools/walletextension/cache"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
 
 	"github.com/ten-protocol/go-ten/tools/walletextension/services"
 
 	"github.com/status-im/keycard-go/hexutils"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
+	tenlog "github.com/ten-protocol/go-ten/go/common/log"
 
 	subscriptioncommon "github.com/ten-protocol/go-ten/go/common/subscription"
 
@@ -29,6 +35,9 @@ import (
 	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
 )
 
+// the interval at which the mempool is polled for the pending transactions subscriptions
+const pendingTxPollInterval = time.Second
+
 type FilterAPI struct {
 	we     *services.Services
 	logger log.Logger
@@ -41,36 +50,146 @@ func NewFilterAPI(we *services.Services) *FilterAPI {
 	}
 }
 
-func (api *FilterAPI) NewPendingTransactionFilter(_ *bool) rpc.ID {
-	return "not supported"
+// NewPendingTransactionFilter - installs a polling filter for the pending transactions of the accounts of the user.
+// Fails when the gateway is connected to a validator node, which has no mempool.
+func (api *FilterAPI) NewPendingTransactionFilter(ctx context.Context, fullTx *bool) (id rpc.ID, err error) {
+	record := newAuditRecord("eth_newPendingTransactionFilter")
+	defer func() { auditRequest(api.we, record, []any{fullTx}, err) }()
+	user, err := extractUserForRequest(ctx, api.we)
+	if err != nil {
+		return "", err
+	}
+	record.User = audit.HashUserID(user.ID)
+	rateLimitedRequest, err := allowUser(ctx, api.we, record, user, record.Method)
+	if err != nil {
+		return "", err
+	}
+	defer rateLimitedRequest.Done()
+
+	// the transactions already in the mempool are not returned
+	pending, err := pendingTransactions(ctx, api.we, user)
+	if err != nil {
+		return "", err
+	}
+	seen := make(map[gethcommon.Hash]bool)
+	for _, tx := range pending {
+		seen[tx.Hash] = true
+	}
+
+	return api.we.Filters.Install(user.ID, &services.Filter{
+		Type:    services.PendingTxFilter,
+		SeenTxs: seen,
+		FullTx:  fullTx != nil && *fullTx,
+	})
 }
 
-func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
-	return nil, fmt.Errorf("not supported")
+// NewPendingTransactions - subscription to the pending transactions of the accounts of the user.
+// The mempool is polled, because the node doesn't push pending transactions.
+// Fails when the gateway is connected to a validator node, which has no mempool.
+func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (_ *rpc.Subscription, err error) {
+	record := newAuditRecord("eth_subscribe")
+	defer func() { auditRequest(api.we, record, []any{metrics.SubscriptionPendingTransactions, fullTx}, err) }()
+	subNotifier, user, err := getUserAndNotifier(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+	record.User = audit.HashUserID(user.ID)
+	rateLimitedRequest, err := allowUser(ctx, api.we, record, user, record.Method)
+	if err != nil {
+		return nil, err
+	}
+	defer rateLimitedRequest.Done()
+
+	// the transactions already in the mempool are not notified
+	pending, err := pendingTransactions(ctx, api.we, user)
+	if err != nil {
+		return nil, err
+	}
+	_, initial := newPendingTransactions(pending, nil)
+
+	subscription := subNotifier.CreateSubscription()
+	metrics.SubscriptionStarted(metrics.SubscriptionPendingTransactions)
+
+	unsubscribed := atomic.Bool{}
+	go subscriptioncommon.HandleUnsubscribe(subscription, func() {
+		unsubscribed.Store(true)
+		metrics.SubscriptionEnded(metrics.SubscriptionPendingTransactions)
+	})
+
+	go func() {
+		seen := initial
+		ticker := time.NewTicker(pendingTxPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if unsubscribed.Load() || api.we.IsStopping() {
+				return
+			}
+			// the subscription outlives the request. The polls are not audited or rate limited, like the notifications
+			// of the other subscriptions
+			pending, err := pendingTransactions(context.Background(), api.we, user)
+			if err != nil {
+				api.logger.Debug("Could not read pending transactions", tenlog.ErrKey, err)
+				continue
+			}
+			newTxs, current := newPendingTransactions(pending, seen)
+			seen = current
+			for _, tx := range newTxs {
+				var msg any = tx.Hash
+				if fullTx != nil && *fullTx {
+					msg = tx
+				}
+				if err := subNotifier.Notify(subscription.ID, msg); err != nil {
+					api.logger.Debug("Could not notify pending transaction", tenlog.ErrKey, err)
+					return
+				}
+			}
+		}
+	}()
+
+	return subscription, nil
 }
 
-func (api *FilterAPI) NewBlockFilter() rpc.ID {
-	// not implemented
-	return ""
+// NewBlockFilter - installs a polling filter for the hashes of the new batches
+func (api *FilterAPI) NewBlockFilter(ctx context.Context) (_ rpc.ID, err error) {
+	record := newAuditRecord("eth_newBlockFilter")
+	defer func() { auditRequest(api.we, record, nil, err) }()
+	user, err := extractUserForRequest(ctx, api.we)
+	if err != nil {
+		return "", err
+	}
+	record.User = audit.HashUserID(user.ID)
+	return api.we.Filters.Install(user.ID, &services.Filter{
+		Type:        services.BlockFilter,
+		BlockHashes: make([]gethcommon.Hash, 0),
+	})
 }
 
-func (api *FilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+func (api *FilterAPI) NewHeads(ctx context.Context) (_ *rpc.Subscription, err error) {
+	record := newAuditRecord("eth_subscribe")
+	defer func() { auditRequest(api.we, record, []any{metrics.SubscriptionNewHeads}, err) }()
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return nil, fmt.Errorf("creation of subscriptions is not supported")
 	}
 	subscription := notifier.CreateSubscription()
 	api.we.NewHeadsService.RegisterNotifier(notifier, subscription)
+	metrics.SubscriptionStarted(metrics.SubscriptionNewHeads)
+	go subscriptioncommon.HandleUnsubscribe(subscription, func() {
+		metrics.SubscriptionEnded(metrics.SubscriptionNewHeads)
+	})
 	return subscription, nil
 }
 
-func (api *FilterAPI) Logs(ctx context.Context, crit common.FilterCriteria) (*rpc.Subscription, error) {
+func (api *FilterAPI) Logs(ctx context.Context, crit common.FilterCriteria) (_ *rpc.Subscription, err error) {
 	services.Audit(api.we, services.DebugLevel, "start Logs subscription %v", crit)
+	record := newAuditRecord("eth_subscribe")
+	defer func() { auditRequest(api.we, record, []any{metrics.SubscriptionLogs, crit}, err) }()
 	subNotifier, user, err := getUserAndNotifier(ctx, api)
 	if err != nil {
 		services.Audit(api.we, services.DebugLevel, "Failed to get user and notifier: %v", err)
 		return nil, err
 	}
+	record.User = audit.HashUserID(user.ID)
 
 	// determine the accounts to use for the backend subscriptions
 	candidateAddresses := user.GetAllAddresses()
@@ -92,6 +211,7 @@ func (api *FilterAPI) Logs(ctx context.Context, crit common.FilterCriteria) (*rp
 			return nil, err
 		}
 		backendWSConnections = append(backendWSConnections, rpcWSClient)
+		record.Accounts = append(record.Accounts, address)
 
 		inCh := make(chan types.Log)
 		backendSubscription, err := rpcWSClient.Subscribe(ctx, tenrpc.SubscribeNamespace, inCh, "logs", crit)
@@ -107,6 +227,7 @@ func (api *FilterAPI) Logs(ctx context.Context, crit common.FilterCriteria) (*rp
 
 	dedupeBuffer := NewCircularBuffer(wecommon.DeduplicationBufferSize)
 	subscription := subNotifier.CreateSubscription()
+	metrics.SubscriptionStarted(metrics.SubscriptionLogs)
 
 	unsubscribedByClient := atomic.Bool{}
 	unsubscribedByBackend := atomic.Bool{}
@@ -143,6 +264,7 @@ func (api *FilterAPI) Logs(ctx context.Context, crit common.FilterCriteria) (*rp
 	// handles "unsubscribe" from the user
 	go subscriptioncommon.HandleUnsubscribe(subscription, func() {
 		unsubscribedByClient.Store(true)
+		metrics.SubscriptionEnded(metrics.SubscriptionLogs)
 		api.closeConnections(backendSubscriptions, backendWSConnections)
 	})
 
@@ -190,27 +312,65 @@ func searchForAddressInFilterCriteria(filterCriteria common.FilterCriteria, poss
 	return result
 }
 
-func (api *FilterAPI) NewFilter(crit common.FilterCriteria) (rpc.ID, error) {
-	return rpc.NewID(), rpcNotImplemented
+// NewFilter - installs a polling filter for the logs matching the criteria.
+// The logs are collected on every poll, using the accounts of the user.
+func (api *FilterAPI) NewFilter(ctx context.Context, crit common.FilterCriteria) (_ rpc.ID, err error) {
+	record := newAuditRecord("eth_newFilter")
+	defer func() { auditRequest(api.we, record, []any{crit}, err) }()
+	user, err := extractUserForRequest(ctx, api.we)
+	if err != nil {
+		return "", err
+	}
+	record.User = audit.HashUserID(user.ID)
+	if crit.BlockHash != nil {
+		return "", fmt.Errorf("filters can't be created for a block hash")
+	}
+
+	head, err := api.headBatchNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := api.we.Filters.Install(user.ID, &services.Filter{
+		Type:          services.LogsFilter,
+		Criteria:      crit,
+		LastSeenBatch: head,
+	})
+	if err != nil {
+		return "", err
+	}
+	services.Audit(api.we, services.DebugLevel, "Installed filter id=%s crit=%v", id, crit)
+	return id, nil
 }
 
-func (api *FilterAPI) GetLogs(ctx context.Context, crit common.FilterCriteria) ([]*types.Log, error) {
+func (api *FilterAPI) GetLogs(ctx context.Context, crit common.FilterCriteria) (_ []*types.Log, err error) {
 	method := rpc2.ERPCGetLogs
 	services.Audit(api.we, services.DebugLevel, "RPC start method=%s args=%v", method, ctx)
-	requestStartTime := time.Now()
+	record := newAuditRecord(method)
+	requestStartTime := record.Time
+	defer func() { auditRequest(api.we, record, []any{crit}, err) }()
 	user, err := extractUserForRequest(ctx, api.we)
 	if err != nil {
 		services.Audit(api.we, services.DebugLevel, "Failed to extract user: %v", err)
 		return nil, err
 	}
+	record.User = audit.HashUserID(user.ID)
 
-	rateLimitAllowed, requestUUID := api.we.RateLimiter.Allow(gethcommon.Address(user.ID))
-	defer api.we.RateLimiter.SetRequestEnd(gethcommon.Address(user.ID), requestUUID)
-	if !rateLimitAllowed {
-		services.Audit(api.we, services.DebugLevel, "Rate limit exceeded for user: %s", hexutils.BytesToHex(user.ID))
-		return nil, fmt.Errorf("rate limit exceeded")
+	rateLimitedRequest, err := allowUser(ctx, api.we, record, user, method)
+	if err != nil {
+		return nil, err
 	}
+	defer rateLimitedRequest.Done()
 
+	res, err := api.getLogs(ctx, user, crit)
+	metrics.RecordRPCCall(method, err == nil, time.Since(requestStartTime))
+	services.Audit(api.we, services.DebugLevel, "RPC call. uid=%s, method=%s args=%v result=%v error=%v time=%d", hexutils.BytesToHex(user.ID), method, crit, res, err, time.Since(requestStartTime).Milliseconds())
+	return res, err
+}
+
+// getLogs - returns the logs matching the criteria visible to any of the accounts of the user
+func (api *FilterAPI) getLogs(ctx context.Context, user *wecommon.GWUser, crit common.FilterCriteria) ([]*types.Log, error) {
+	method := rpc2.ERPCGetLogs
 	res, err := cache.WithCache(
 		api.we.RPCResponsesCache,
 		&cache.Cfg{
@@ -232,16 +392,7 @@ func (api *FilterAPI) GetLogs(ctx context.Context, crit common.FilterCriteria) (
 			// execute the get_Logs function
 			// dedupe and concatenate the results
 			for _, acct := range user.AllAccounts() {
-				eventLogs, err := services.WithEncRPCConnection(ctx, api.we.BackendRPC, acct, func(rpcClient *tenrpc.EncRPCClient) (*[]*types.Log, error) {
-					var result []*types.Log
-
-					// wrap the context with a timeout to prevent long executions
-					timeoutContext, cancelCtx := context.WithTimeout(ctx, maximumRPCCallDuration)
-					defer cancelCtx()
-
-					err := rpcClient.CallContext(timeoutContext, &result, method, common.SerializableFilterCriteria(crit))
-					return &result, err
-				})
+				eventLogs, err := backendCall[[]*types.Log](ctx, api.we, acct, method, common.SerializableFilterCriteria(crit))
 				if err != nil {
 					return nil, fmt.Errorf("could not read logs. cause: %w", err)
 				}
@@ -255,41 +406,221 @@ func (api *FilterAPI) GetLogs(ctx context.Context, crit common.FilterCriteria) (
 				}
 			}
 
-			result := make([]*types.Log, 0)
-			for _, eventLog := range allEventLogsMap {
-				result = append(result, eventLog)
-			}
-			sort.Slice(result, func(i, j int) bool {
-				if result[i].BlockNumber == result[j].BlockNumber {
-					return result[i].Index < result[j].Index
-				}
-				return result[i].BlockNumber < result[j].BlockNumber
-			})
+			result := sortLogs(allEventLogsMap)
 			return &result, nil
 		})
 	if err != nil {
 		return nil, err
 	}
-	services.Audit(api.we, services.DebugLevel, "RPC call. uid=%s, method=%s args=%v result=%v error=%v time=%d", hexutils.BytesToHex(user.ID), method, crit, res, err, time.Since(requestStartTime).Milliseconds())
-	return *res, err
+	return *res, nil
 }
 
-func (api *FilterAPI) UninstallFilter(id rpc.ID) bool {
-	// not implemented
-	return false
+func (api *FilterAPI) UninstallFilter(ctx context.Context, id rpc.ID) bool {
+	record := newAuditRecord("eth_uninstallFilter")
+	user, err := extractUserForRequest(ctx, api.we)
+	if err != nil {
+		auditRequest(api.we, record, []any{id}, err)
+		return false
+	}
+	record.User = audit.HashUserID(user.ID)
+	auditRequest(api.we, record, []any{id}, nil)
+	return api.we.Filters.Uninstall(user.ID, id)
 }
 
-func (api *FilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, error) {
-	//txRec, err := ExecAuthRPC[[]*types.Log](ctx, api.we, "GetFilterLogs", AuthExecCfg{account: args.From}, id)
-	//if txRec != nil {
-	//	return *txRec, err
-	//}
-	//return common.Hash{}, err
+// GetFilterLogs - returns all the logs matching the criteria of the filter
+func (api *FilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) (_ []*types.Log, err error) {
+	record := newAuditRecord("eth_getFilterLogs")
+	defer func() { auditRequest(api.we, record, []any{id}, err) }()
+	user, err := extractUserForRequest(ctx, api.we)
+	if err != nil {
+		return nil, err
+	}
+	record.User = audit.HashUserID(user.ID)
+	rateLimitedRequest, err := allowUser(ctx, api.we, record, user, record.Method)
+	if err != nil {
+		return nil, err
+	}
+	defer rateLimitedRequest.Done()
+
+	var crit common.FilterCriteria
+	err = api.we.Filters.Poll(user.ID, id, func(filter *services.Filter) error {
+		if filter.Type != services.LogsFilter {
+			return services.ErrFilterNotFound
+		}
+		crit = filter.Criteria
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return api.getLogs(ctx, user, crit)
+}
+
+// GetFilterChanges - returns the changes since the last poll, depending on the type of the filter:
+// the matching logs, the hashes of the new batches or the new pending transactions of the user.
+// The call is audited and rate limited once, not for each of the backend calls made for the accounts of the user.
+func (api *FilterAPI) GetFilterChanges(ctx context.Context, id rpc.ID) (_ interface{}, err error) {
+	record := newAuditRecord("eth_getFilterChanges")
+	defer func() { auditRequest(api.we, record, []any{id}, err) }()
+	user, err := extractUserForRequest(ctx, api.we)
+	if err != nil {
+		return nil, err
+	}
+	record.User = audit.HashUserID(user.ID)
+	rateLimitedRequest, err := allowUser(ctx, api.we, record, user, record.Method)
+	if err != nil {
+		return nil, err
+	}
+	defer rateLimitedRequest.Done()
+
+	var result interface{}
+	err = api.we.Filters.Poll(user.ID, id, func(filter *services.Filter) error {
+		switch filter.Type {
+		case services.LogsFilter:
+			head, err := api.headBatchNumber(ctx)
+			if err != nil {
+				return err
+			}
+			logs, err := api.logsSince(ctx, user, filter, head)
+			if err != nil {
+				return err
+			}
+			filter.LastSeenBatch = head
+			result = logs
 
-	// not implemented
-	return nil, nil
+		case services.BlockFilter:
+			result = filter.BlockHashes
+			filter.BlockHashes = make([]gethcommon.Hash, 0)
+
+		case services.PendingTxFilter:
+			pending, err := pendingTransactions(ctx, api.we, user)
+			if err != nil {
+				return err
+			}
+			newTxs, current := newPendingTransactions(pending, filter.SeenTxs)
+			filter.SeenTxs = current
+			if filter.FullTx {
+				result = newTxs
+				return nil
+			}
+			hashes := make([]gethcommon.Hash, 0, len(newTxs))
+			for _, tx := range newTxs {
+				hashes = append(hashes, tx.Hash)
+			}
+			result = hashes
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func (api *FilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
-	return nil, rpcNotImplemented
+// logsSince - returns the logs matching the filter from the batches after the last seen batch, up to the head
+func (api *FilterAPI) logsSince(ctx context.Context, user *wecommon.GWUser, filter *services.Filter, head uint64) ([]*types.Log, error) {
+	from := filter.LastSeenBatch + 1
+	to := head
+	// respect the block range requested by the user
+	if filter.Criteria.FromBlock != nil && filter.Criteria.FromBlock.Sign() > 0 && filter.Criteria.FromBlock.Uint64() > from {
+		from = filter.Criteria.FromBlock.Uint64()
+	}
+	if filter.Criteria.ToBlock != nil && filter.Criteria.ToBlock.Sign() >= 0 && filter.Criteria.ToBlock.Uint64() < to {
+		to = filter.Criteria.ToBlock.Uint64()
+	}
+	if from > to {
+		return []*types.Log{}, nil
+	}
+
+	crit := filter.Criteria
+	crit.FromBlock = new(big.Int).SetUint64(from)
+	crit.ToBlock = new(big.Int).SetUint64(to)
+
+	allEventLogsMap := make(map[LogKey]*types.Log)
+	for _, acct := range user.AllAccounts() {
+		eventLogs, err := backendCall[[]*types.Log](ctx, api.we, acct, rpc2.ERPCGetLogs, common.SerializableFilterCriteria(crit))
+		if err != nil {
+			return nil, fmt.Errorf("could not read logs. cause: %w", err)
+		}
+		for _, eventLog := range *eventLogs {
+			allEventLogsMap[LogKey{
+				BlockHash: eventLog.BlockHash,
+				TxHash:    eventLog.TxHash,
+				Index:     eventLog.Index,
+			}] = eventLog
+		}
+	}
+	return sortLogs(allEventLogsMap), nil
+}
+
+// pendingTransactions - returns the transactions from the mempool sent by any of the accounts of the user
+func pendingTransactions(ctx context.Context, we *services.Services, user *wecommon.GWUser) ([]*enclaverpc.RpcTransaction, error) {
+	result := make([]*enclaverpc.RpcTransaction, 0)
+	for _, acct := range user.AllAccounts() {
+		txs, err := backendCall[[]*enclaverpc.RpcTransaction](ctx, we, acct, rpc2.ERPCGetPendingTransactions)
+		if err != nil {
+			return nil, fmt.Errorf("could not read pending transactions. cause: %w", err)
+		}
+		result = append(result, *txs...)
+	}
+	return result, nil
+}
+
+// newPendingTransactions - returns the pending transactions which were not seen before, and the hashes of all the current ones.
+// The transactions which left the mempool are forgotten.
+func newPendingTransactions(pending []*enclaverpc.RpcTransaction, seen map[gethcommon.Hash]bool) ([]*enclaverpc.RpcTransaction, map[gethcommon.Hash]bool) {
+	newTxs := make([]*enclaverpc.RpcTransaction, 0)
+	current := make(map[gethcommon.Hash]bool, len(pending))
+	for _, tx := range pending {
+		if !seen[tx.Hash] {
+			newTxs = append(newTxs, tx)
+		}
+		current[tx.Hash] = true
+	}
+	return newTxs, current
+}
+
+func (api *FilterAPI) headBatchNumber(ctx context.Context) (uint64, error) {
+	nr, err := services.WithPlainRPCConnection(ctx, api.we.BackendRPC, func(client *rpc.Client) (*hexutil.Uint64, error) {
+		var nr hexutil.Uint64
+		timeoutContext, cancelCtx := context.WithTimeout(ctx, maximumRPCCallDuration)
+		defer cancelCtx()
+		err := client.CallContext(timeoutContext, &nr, tenrpc.BatchNumber)
+		return &nr, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not read the head batch. cause: %w", err)
+	}
+	return uint64(*nr), nil
+}
+
+// backendCall - executes the call with the viewing key of the account. Unlike ExecAuthRPC, the call is not audited,
+// rate limited or cached. It is used by the pollers of the subscriptions, which are not requests of the user, and for
+// the calls made for each of the accounts of the user by a filter request, which is audited and rate limited once.
+func backendCall[R any](ctx context.Context, we *services.Services, acct *wecommon.GWAccount, method string, args ...any) (*R, error) {
+	return services.WithEncRPCConnection(ctx, we.BackendRPC, acct, func(rpcClient *tenrpc.EncRPCClient) (*R, error) {
+		var result R
+
+		// wrap the context with a timeout to prevent long executions
+		timeoutContext, cancelCtx := context.WithTimeout(ctx, maximumRPCCallDuration)
+		defer cancelCtx()
+
+		err := rpcClient.CallContext(timeoutContext, &result, method, args...)
+		return &result, err
+	})
+}
+
+// sortLogs - returns the deduplicated logs in the order in which they were emitted
+func sortLogs(allEventLogsMap map[LogKey]*types.Log) []*types.Log {
+	result := make([]*types.Log, 0)
+	for _, eventLog := range allEventLogsMap {
+		result = append(result, eventLog)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BlockNumber == result[j].BlockNumber {
+			return result[i].Index < result[j].Index
+		}
+		return result[i].BlockNumber < result[j].BlockNumber
+	})
+	return result
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
 	"github.com/ten-protocol/go-ten/tools/walletextension/services"
 )
 
@@ -15,43 +17,85 @@ func NewSessionKeyAPI(we *services.Services) *SessionKeyAPI {
 	return &SessionKeyAPI{we}
 }
 
-// Create - returns hex-encoded checksum address of the newly created SK
-func (api *SessionKeyAPI) Create(ctx context.Context) (string, error) {
-	user, err := extractUserForRequest(ctx, api.we)
+// Create - returns hex-encoded checksum address of the newly created SK.
+// The optional policy restricts the transactions the SK can sign.
+func (api *SessionKeyAPI) Create(ctx context.Context, policy *common.SessionKeyPolicy) (string, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys)
 	if err != nil {
 		return "", err
 	}
 
-	sk, err := api.we.SKManager.CreateSessionKey(user)
+	sk, err := api.we.SKManager.CreateSessionKey(user, policy)
 	if err != nil {
 		return "", fmt.Errorf("unable to create session key: %w", err)
 	}
 	return (*sk.Account.Address).Hex(), nil
 }
 
-func (api *SessionKeyAPI) Activate(ctx context.Context) (bool, error) {
-	user, err := extractUserForRequest(ctx, api.we)
+// Activate - the address is optional when the user has a single SK. Same for the other methods.
+func (api *SessionKeyAPI) Activate(ctx context.Context, address *gethcommon.Address) (bool, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys)
 	if err != nil {
 		return false, err
 	}
 
-	return api.we.SKManager.ActivateSessionKey(user)
+	return api.we.SKManager.ActivateSessionKey(user, address)
 }
 
-func (api *SessionKeyAPI) Deactivate(ctx context.Context) (bool, error) {
-	user, err := extractUserForRequest(ctx, api.we)
+func (api *SessionKeyAPI) Deactivate(ctx context.Context, address *gethcommon.Address) (bool, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys)
 	if err != nil {
 		return false, err
 	}
 
-	return api.we.SKManager.DeactivateSessionKey(user)
+	return api.we.SKManager.DeactivateSessionKey(user, address)
 }
 
-func (api *SessionKeyAPI) Delete(ctx context.Context) (bool, error) {
-	user, err := extractUserForRequest(ctx, api.we)
+func (api *SessionKeyAPI) Delete(ctx context.Context, address *gethcommon.Address) (bool, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys)
 	if err != nil {
 		return false, err
 	}
 
-	return api.we.SKManager.DeleteSessionKey(user)
+	return api.we.SKManager.DeleteSessionKey(user, address)
+}
+
+// SetPolicy - replaces the policy of the SK. A nil policy removes all the restrictions.
+func (api *SessionKeyAPI) SetPolicy(ctx context.Context, address gethcommon.Address, policy *common.SessionKeyPolicy) (bool, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys)
+	if err != nil {
+		return false, err
+	}
+
+	return api.we.SKManager.SetSessionKeyPolicy(user, &address, policy)
+}
+
+// SetFunding - configures the sweep account and the automatic top up of the SK. A nil funding disables the top up.
+func (api *SessionKeyAPI) SetFunding(ctx context.Context, address gethcommon.Address, funding *common.SessionKeyFunding) (bool, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys)
+	if err != nil {
+		return false, err
+	}
+
+	return api.we.SKManager.SetSessionKeyFunding(user, &address, funding)
+}
+
+// Status - returns the session keys of the user with their balances, and the last sweeps and top ups executed by the gateway
+func (api *SessionKeyAPI) Status(ctx context.Context) ([]common.SessionKeyStatus, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys, common.APIKeyScopeRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.we.SKManager.Status(ctx, user)
+}
+
+// List - returns the session keys of the user, with their policies and the value they spent
+func (api *SessionKeyAPI) List(ctx context.Context) ([]common.SessionKeyInfo, error) {
+	user, err := extractUserWithScope(ctx, api.we, common.APIKeyScopeSessionKeys, common.APIKeyScopeRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.SessionKeysInfo(), nil
 }
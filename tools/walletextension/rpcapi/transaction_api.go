@@ -2,12 +2,15 @@ package rpcapi
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"math/big"
 
 	tenrpc "github.com/ten-protocol/go-ten/go/common/rpc"
 
+	"github.com/ten-protocol/go-ten/tools/walletextension/audit"
 	"github.com/ten-protocol/go-ten/tools/walletextension/cache"
 
+	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
 	"github.com/ten-protocol/go-ten/tools/walletextension/services"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -69,12 +72,8 @@ func (s *TransactionAPI) GetTransactionCount(ctx context.Context, address common
 		ctx,
 		s.we,
 		&AuthExecCfg{
-			account: &address,
-			cacheCfg: &cache.Cfg{
-				DynamicType: func() cache.Strategy {
-					return cacheBlockNumberOrHash(blockNrOrHash)
-				},
-			},
+			account:  &address,
+			cacheCfg: cacheAccountState(blockNrOrHash, address),
 		},
 		"ten_getTransactionCount",
 		address,
@@ -106,26 +105,52 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 }
 
 func (s *TransactionAPI) SendTransaction(ctx context.Context, args gethapi.TransactionArgs) (common.Hash, error) {
-	user, err := extractUserForRequest(ctx, s.we)
+	// the transaction is always signed by a session key
+	scopes := []wecommon.APIKeyScope{wecommon.APIKeyScopeSendTx, wecommon.APIKeyScopeSessionKeys}
+	user, err := extractUserWithScope(ctx, s.we, scopes...)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	if !user.ActiveSK {
-		return common.Hash{}, fmt.Errorf("please activate session key")
-	}
 
-	// when there is an active Session Key, sign all incoming transactions with that SK
-	signedTx, err := s.we.SKManager.SignTx(ctx, user, args.ToTransaction())
-	if err != nil {
-		return common.Hash{}, err
+	// when there is an active Session Key, sign all incoming transactions with that SK.
+	// The "from" field selects the SK when the user has multiple active keys.
+	return s.we.SKManager.SendTx(ctx, user, sessionKeyAddress(user, args.From), args.ToTransaction(), s.sendSignedTx(scopes))
+}
+
+// sendSignedTx - returns the function which sends the transactions signed by a session key
+func (s *TransactionAPI) sendSignedTx(scopes []wecommon.APIKeyScope) services.SendFunc {
+	return func(ctx context.Context, signedTx *types.Transaction) (common.Hash, error) {
+		blob, err := signedTx.MarshalBinary()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return s.sendRawTx(ctx, blob, scopes)
 	}
+}
 
-	blob, err := signedTx.MarshalBinary()
-	if err != nil {
-		return common.Hash{}, err
+// sessionKeyAddress - returns the address if it belongs to one of the session keys of the user
+func sessionKeyAddress(user *wecommon.GWUser, address *common.Address) *common.Address {
+	if address == nil {
+		return nil
+	}
+	if _, found := user.SessionKeys[*address]; !found {
+		return nil
 	}
+	return address
+}
 
-	return s.sendRawTx(ctx, blob)
+// rawTxSessionKey - returns the session key of the user which signed the raw transaction. When the transaction is not
+// signed by one of the session keys, it is signed by the only active session key, so it fails if there are several.
+func rawTxSessionKey(user *wecommon.GWUser, tx *types.Transaction, chainID int64) (*common.Address, error) {
+	if sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(chainID)), tx); err == nil {
+		if address := sessionKeyAddress(user, &sender); address != nil {
+			return address, nil
+		}
+	}
+	if len(user.ActiveSessionKeys()) > 1 {
+		return nil, errors.New("the user has multiple active session keys. Please sign the raw transaction with the session key which should send it")
+	}
+	return nil, nil
 }
 
 type SignTransactionResult struct {
@@ -138,45 +163,58 @@ func (s *TransactionAPI) FillTransaction(ctx context.Context, args gethapi.Trans
 }
 
 func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
-	user, err := extractUserForRequest(ctx, s.we)
+	// the API keys limited to the session keys can only send the transactions signed by one of them
+	user, err := extractUserWithScope(ctx, s.we, wecommon.APIKeyScopeSendTx, wecommon.APIKeyScopeSessionKeys)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	signedTxBlob := input
-	// when there is an active Session Key, sign all incoming transactions with that SK
-	if user.ActiveSK && user.SessionKey != nil {
+	// when there is an active Session Key, sign all incoming transactions with that SK.
+	// The signer of the raw transaction selects the SK when the user has multiple active keys.
+	if len(user.ActiveSessionKeys()) > 0 {
 		tx := new(types.Transaction)
 		if err = tx.UnmarshalBinary(input); err != nil {
 			return common.Hash{}, err
 		}
-		signedTx, err := s.we.SKManager.SignTx(ctx, user, tx)
-		if err != nil {
-			return common.Hash{}, err
-		}
-		signedTxBlob, err = signedTx.MarshalBinary()
+		address, err := rawTxSessionKey(user, tx, int64(s.we.Config.TenChainID))
 		if err != nil {
 			return common.Hash{}, err
 		}
+		scopes := []wecommon.APIKeyScope{wecommon.APIKeyScopeSendTx, wecommon.APIKeyScopeSessionKeys}
+		return s.we.SKManager.SendTx(ctx, user, address, tx, s.sendSignedTx(scopes))
 	}
 
-	return s.sendRawTx(ctx, signedTxBlob)
+	return s.sendRawTx(ctx, input, []wecommon.APIKeyScope{wecommon.APIKeyScopeSendTx})
 }
 
-func (s *TransactionAPI) sendRawTx(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
-	txRec, err := ExecAuthRPC[common.Hash](ctx, s.we, &AuthExecCfg{tryAll: true, timeout: sendTransactionDuration}, tenrpc.ERPCSendRawTransaction, input)
+func (s *TransactionAPI) sendRawTx(ctx context.Context, input hexutil.Bytes, scopes []wecommon.APIKeyScope) (common.Hash, error) {
+	txRec, err := ExecAuthRPC[common.Hash](ctx, s.we, &AuthExecCfg{tryAll: true, timeout: sendTransactionDuration, scopes: scopes, submitsTx: true}, tenrpc.ERPCSendRawTransaction, input)
 	if err != nil {
 		return common.Hash{}, err
 	}
 	return *txRec, err
 }
 
-func (s *TransactionAPI) PendingTransactions() ([]*rpc.RpcTransaction, error) {
-	return nil, rpcNotImplemented
+// PendingTransactions - returns the transactions from the mempool sent by the accounts of the user.
+// The request is audited and rate limited once, not for each of the accounts of the user.
+func (s *TransactionAPI) PendingTransactions(ctx context.Context) (_ []*rpc.RpcTransaction, err error) {
+	record := newAuditRecord(tenrpc.ERPCGetPendingTransactions)
+	defer func() { auditRequest(s.we, record, nil, err) }()
+	user, err := extractUserForRequest(ctx, s.we)
+	if err != nil {
+		return nil, err
+	}
+	record.User = audit.HashUserID(user.ID)
+	rateLimitedRequest, err := allowUser(ctx, s.we, record, user, record.Method)
+	if err != nil {
+		return nil, err
+	}
+	defer rateLimitedRequest.Done()
+	return pendingTransactions(ctx, s.we, user)
 }
 
 func (s *TransactionAPI) Resend(ctx context.Context, sendArgs gethapi.TransactionArgs, gasPrice *hexutil.Big, gasLimit *hexutil.Uint64) (common.Hash, error) {
-	txRec, err := ExecAuthRPC[common.Hash](ctx, s.we, &AuthExecCfg{account: sendArgs.From}, tenrpc.ERPCResend, sendArgs, gasPrice, gasLimit)
+	txRec, err := ExecAuthRPC[common.Hash](ctx, s.we, &AuthExecCfg{account: sendArgs.From, scopes: []wecommon.APIKeyScope{wecommon.APIKeyScopeSendTx}, submitsTx: true}, tenrpc.ERPCResend, sendArgs, gasPrice, gasLimit)
 	if txRec != nil {
 		return *txRec, err
 	}
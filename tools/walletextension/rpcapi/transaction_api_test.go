@@ -0,0 +1,53 @@
+package rpcapi
+
+import (
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/stretchr/testify/require"
+	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
+)
+
+const testChainID = 443
+
+func TestRawTxSessionKey(t *testing.T) {
+	user := &wecommon.GWUser{SessionKeys: make(map[gethcommon.Address]*wecommon.GWSessionKey)}
+	addSessionKey := func() *wecommon.GWSessionKey {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		sk := &wecommon.GWSessionKey{Account: &wecommon.GWAccount{Address: &address}, PrivateKey: ecies.ImportECDSA(key), Active: true}
+		user.SessionKeys[address] = sk
+		return sk
+	}
+	sign := func(sk *wecommon.GWSessionKey) *types.Transaction {
+		tx := types.NewTx(&types.LegacyTx{Gas: 21_000, To: &gethcommon.Address{}})
+		if sk == nil {
+			return tx
+		}
+		stx, err := types.SignTx(tx, types.LatestSignerForChainID(big.NewInt(testChainID)), sk.PrivateKey.ExportECDSA())
+		require.NoError(t, err)
+		return stx
+	}
+
+	// the only active key signs the transactions which were not signed by a session key
+	sk := addSessionKey()
+	address, err := rawTxSessionKey(user, sign(nil), testChainID)
+	require.NoError(t, err)
+	require.Nil(t, address)
+
+	// with multiple active keys, the key is selected by the signature
+	otherSK := addSessionKey()
+	address, err = rawTxSessionKey(user, sign(otherSK), testChainID)
+	require.NoError(t, err)
+	require.Equal(t, otherSK.Account.Address, address)
+	address, err = rawTxSessionKey(user, sign(sk), testChainID)
+	require.NoError(t, err)
+	require.Equal(t, sk.Account.Address, address)
+	_, err = rawTxSessionKey(user, sign(nil), testChainID)
+	require.ErrorContains(t, err, "multiple active session keys")
+}
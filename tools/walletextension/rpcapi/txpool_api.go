@@ -1,12 +1,20 @@
 package rpcapi
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	tenrpc "github.com/ten-protocol/go-ten/go/common/rpc"
 	rpc2 "github.com/ten-protocol/go-ten/go/enclave/rpc"
+	"github.com/ten-protocol/go-ten/tools/walletextension/cache"
 	"github.com/ten-protocol/go-ten/tools/walletextension/services"
 )
 
+// TxPoolAPI - the "txpool" namespace, restricted to the transactions of the accounts of the user.
+// The content of the mempool is private, so the transactions of other users are never returned.
+// Only the sequencer keeps a mempool, so the calls fail when the gateway is connected to a validator node.
 type TxPoolAPI struct {
 	we *services.Services
 }
@@ -15,22 +23,123 @@ func NewTxPoolAPI(we *services.Services) *TxPoolAPI {
 	return &TxPoolAPI{we}
 }
 
-func (s *TxPoolAPI) Content() map[string]map[string]map[string]*rpc2.RpcTransaction {
-	// not implemented
-	return nil
+// Content - returns the pending and queued transactions of all the accounts of the user
+func (s *TxPoolAPI) Content(ctx context.Context) (map[string]map[string]map[string]*rpc2.RpcTransaction, error) {
+	contents, err := s.userContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	content := map[string]map[string]map[string]*rpc2.RpcTransaction{
+		"pending": make(map[string]map[string]*rpc2.RpcTransaction),
+		"queued":  make(map[string]map[string]*rpc2.RpcTransaction),
+	}
+	for account, accountContent := range contents {
+		if len(accountContent.Pending) > 0 {
+			content["pending"][account.Hex()] = accountContent.Pending
+		}
+		if len(accountContent.Queued) > 0 {
+			content["queued"][account.Hex()] = accountContent.Queued
+		}
+	}
+	return content, nil
+}
+
+// ContentFrom - returns the pending and queued transactions of the address, which must be one of the accounts of the user
+func (s *TxPoolAPI) ContentFrom(ctx context.Context, addr common.Address) (map[string]map[string]*rpc2.RpcTransaction, error) {
+	accountContent, err := s.accountContent(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]map[string]*rpc2.RpcTransaction{
+		"pending": accountContent.Pending,
+		"queued":  accountContent.Queued,
+	}, nil
+}
+
+// Status - returns the number of pending and queued transactions of the accounts of the user
+func (s *TxPoolAPI) Status(ctx context.Context) (map[string]hexutil.Uint, error) {
+	contents, err := s.userContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, queued := 0, 0
+	for _, accountContent := range contents {
+		pending += len(accountContent.Pending)
+		queued += len(accountContent.Queued)
+	}
+	return map[string]hexutil.Uint{
+		"pending": hexutil.Uint(pending),
+		"queued":  hexutil.Uint(queued),
+	}, nil
 }
 
-func (s *TxPoolAPI) ContentFrom(_ common.Address) map[string]map[string]*rpc2.RpcTransaction {
-	// not implemented
-	return nil
+// Inspect - same as Content, but flattened into an easily inspectable format
+func (s *TxPoolAPI) Inspect(ctx context.Context) (map[string]map[string]map[string]string, error) {
+	contents, err := s.userContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Define a formatter to flatten a transaction into a string
+	format := func(tx *rpc2.RpcTransaction) string {
+		if tx.To != nil {
+			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To.Hex(), tx.Value.ToInt(), uint64(tx.Gas), tx.GasPrice.ToInt())
+		}
+		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value.ToInt(), uint64(tx.Gas), tx.GasPrice.ToInt())
+	}
+	flatten := func(txs map[string]*rpc2.RpcTransaction) map[string]string {
+		dump := make(map[string]string, len(txs))
+		for nonce, tx := range txs {
+			dump[nonce] = format(tx)
+		}
+		return dump
+	}
+
+	content := map[string]map[string]map[string]string{
+		"pending": make(map[string]map[string]string),
+		"queued":  make(map[string]map[string]string),
+	}
+	for account, accountContent := range contents {
+		if len(accountContent.Pending) > 0 {
+			content["pending"][account.Hex()] = flatten(accountContent.Pending)
+		}
+		if len(accountContent.Queued) > 0 {
+			content["queued"][account.Hex()] = flatten(accountContent.Queued)
+		}
+	}
+	return content, nil
 }
 
-func (s *TxPoolAPI) Status() map[string]hexutil.Uint {
-	// not implemented
-	return nil
+// userContent - returns the content of the mempool for each account of the user
+func (s *TxPoolAPI) userContent(ctx context.Context) (map[common.Address]*rpc2.TxPoolContent, error) {
+	user, err := extractUserForRequest(ctx, s.we)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make(map[common.Address]*rpc2.TxPoolContent)
+	for _, address := range user.GetAllAddresses() {
+		accountContent, err := s.accountContent(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		contents[address] = accountContent
+	}
+	return contents, nil
 }
 
-func (s *TxPoolAPI) Inspect() map[string]map[string]map[string]string {
-	// not implemented
-	return nil
+func (s *TxPoolAPI) accountContent(ctx context.Context, address common.Address) (*rpc2.TxPoolContent, error) {
+	return ExecAuthRPC[rpc2.TxPoolContent](
+		ctx,
+		s.we,
+		&AuthExecCfg{
+			account: &address,
+			cacheCfg: &cache.Cfg{
+				Type: cache.NoCache,
+			},
+		},
+		tenrpc.ERPCGetTxPoolContent,
+	)
 }
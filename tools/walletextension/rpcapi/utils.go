@@ -6,13 +6,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"strings"
 	"time"
 
+	"github.com/ten-protocol/go-ten/tools/walletextension/audit"
 	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/ratelimiter"
 
 	"github.com/ten-protocol/go-ten/tools/walletextension/cache"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
 
 	"github.com/ten-protocol/go-ten/tools/walletextension/services"
 
@@ -31,6 +35,7 @@ const (
 	ethCallAddrPadding  = "000000000000000000000000"
 
 	notAuthorised = "not authorised"
+	bearerPrefix  = "Bearer "
 	serverBusy    = "server busy. please retry later"
 
 	// hardcoding the maximum time for an RPC request
@@ -51,18 +56,33 @@ type AuthExecCfg struct {
 	adjustArgs func(acct *common.GWAccount) []any
 	cacheCfg   *cache.Cfg
 	timeout    time.Duration
+	// the scopes which allow a request authenticated with an API key to make the call. Defaults to the read scope
+	scopes []common.APIKeyScope
+	// the call returns the hash of a submitted transaction, which is written to the audit trail
+	submitsTx bool
 }
 
-func UnauthenticatedTenRPCCall[R any](ctx context.Context, w *services.Services, cfg *cache.Cfg, method string, args ...any) (*R, error) {
+func UnauthenticatedTenRPCCall[R any](ctx context.Context, w *services.Services, cfg *cache.Cfg, method string, args ...any) (res *R, err error) {
 	if ctx == nil {
 		return nil, errors.New("invalid call. nil Context")
 	}
 	services.Audit(w, services.DebugLevel, "RPC start method=%s args=%v", method, args)
 	requestStartTime := time.Now()
+	record := &audit.Record{Time: requestStartTime, Method: method, RateLimit: audit.RateLimitAllowed}
+	defer func() { auditRequest(w, record, args, err) }()
+
+	rateLimitedRequest, err := w.RateLimiter.AllowIP(ctx, clientIP(ctx, w), method)
+	if err != nil {
+		record.RateLimit = audit.RateLimitRejected
+		services.Audit(w, services.WarnLevel, "Rate limit exceeded for the unauthenticated call. method=%s %v", method, err)
+		return nil, err
+	}
+	defer rateLimitedRequest.Done()
+
 	cacheArgs := []any{method}
 	cacheArgs = append(cacheArgs, args...)
 
-	res, err := cache.WithCache(w.RPCResponsesCache, cfg, generateCacheKey(cacheArgs), func() (*R, error) {
+	res, err = cache.WithCache(w.RPCResponsesCache, cfg, generateCacheKey(cacheArgs), func() (*R, error) {
 		return services.WithPlainRPCConnection(ctx, w.BackendRPC, func(client *rpc.Client) (*R, error) {
 			var resp *R = new(R)
 			var err error
@@ -76,35 +96,58 @@ func UnauthenticatedTenRPCCall[R any](ctx context.Context, w *services.Services,
 		})
 	})
 	if err != nil {
+		metrics.RecordRPCCall(method, false, time.Since(requestStartTime))
 		services.Audit(w, services.ErrorLevel, "RPC call failed. method=%s args=%v error=%+v time=%d", method, args, err, time.Since(requestStartTime).Milliseconds())
 		return nil, err
 	}
 
+	metrics.RecordRPCCall(method, true, time.Since(requestStartTime))
 	services.Audit(w, services.InfoLevel, "RPC call succeeded. method=%s args=%v result=%+v time=%d", method, args, res, time.Since(requestStartTime).Milliseconds())
 	return res, err
 }
 
-func ExecAuthRPC[R any](ctx context.Context, w *services.Services, cfg *AuthExecCfg, method string, args ...any) (*R, error) {
+func ExecAuthRPC[R any](ctx context.Context, w *services.Services, cfg *AuthExecCfg, method string, args ...any) (res *R, err error) {
 	services.Audit(w, services.DebugLevel, "RPC start method=%s args=%v", method, args)
 	requestStartTime := time.Now()
-	user, err := extractUserForRequest(ctx, w)
+	record := &audit.Record{Time: requestStartTime, Method: method, RateLimit: audit.RateLimitAllowed}
+	defer func() { auditRequest(w, record, args, err) }()
+
+	scopes := cfg.scopes
+	if len(scopes) == 0 {
+		scopes = []common.APIKeyScope{common.APIKeyScopeRead}
+	}
+	user, err := extractUserWithScope(ctx, w, scopes...)
 	if err != nil {
 		return nil, err
 	}
+	record.User = audit.HashUserID(user.ID)
 
 	w.MetricsTracker.RecordUserActivity(user.ID)
 
-	rateLimitAllowed, requestUUID := w.RateLimiter.Allow(gethcommon.Address(user.ID))
-	if !rateLimitAllowed {
-		services.Audit(w, services.WarnLevel, "Rate limit exceeded for user: %s", hexutils.BytesToHex(user.ID))
-		return nil, fmt.Errorf("rate limit exceeded")
+	// the rate limit error is returned as is, so it is serialised with its JSON-RPC code and retry-after hint
+	rateLimitedRequest, err := w.RateLimiter.AllowUser(ctx, gethcommon.Address(user.ID), method)
+	if err != nil {
+		record.RateLimit = audit.RateLimitRejected
+		services.Audit(w, services.WarnLevel, "Rate limit exceeded for user: %s. %v", hexutils.BytesToHex(user.ID), err)
+		return nil, err
+	}
+	defer rateLimitedRequest.Done()
+
+	// the cached latest state of the accounts of the user is only evicted by the batches which change it
+	if cfg.cacheCfg != nil && cfg.cacheCfg.Strategy() == cache.LatestAccountState {
+		for _, address := range cfg.cacheCfg.Accounts {
+			if account, found := user.AllAccounts()[address]; found {
+				w.AccountStates.Watch(account)
+			}
+		}
 	}
-	defer w.RateLimiter.SetRequestEnd(gethcommon.Address(user.ID), requestUUID)
 
 	cacheArgs := []any{user.ID, method}
 	cacheArgs = append(cacheArgs, args...)
 
-	res, err := cache.WithCache(w.RPCResponsesCache, cfg.cacheCfg, generateCacheKey(cacheArgs), func() (*R, error) {
+	// the account which executed the request. Not set when the response is served from the cache
+	var usedAccount *gethcommon.Address
+	res, err = cache.WithCache(w.RPCResponsesCache, cfg.cacheCfg, generateCacheKey(cacheArgs), func() (*R, error) {
 		// determine candidate "from"
 		candidateAccts, err := getCandidateAccounts(user, w, cfg)
 		if err != nil {
@@ -148,14 +191,47 @@ func ExecAuthRPC[R any](ctx context.Context, w *services.Services, cfg *AuthExec
 				rpcErr = err
 				continue
 			}
+			usedAccount = acct.Address
 			return result, nil
 		}
 		return nil, rpcErr
 	})
+	if usedAccount != nil {
+		record.Accounts = []gethcommon.Address{*usedAccount}
+	}
+	if hash, ok := any(res).(*gethcommon.Hash); ok && hash != nil && cfg.submitsTx {
+		record.TxHashes = []gethcommon.Hash{*hash}
+	}
+	metrics.RecordRPCCall(method, err == nil, time.Since(requestStartTime))
 	services.Audit(w, services.InfoLevel, "RPC call. uid=%s, method=%s args=%v result=%s error=%s time=%d", hexutils.BytesToHex(user.ID), method, args, SafeGenericToString(res), err, time.Since(requestStartTime).Milliseconds())
 	return res, err
 }
 
+// newAuditRecord - the audit record of a request starting now, which was not rejected by the rate limiter yet
+func newAuditRecord(method string) *audit.Record {
+	return &audit.Record{Time: time.Now(), Method: method, RateLimit: audit.RateLimitAllowed}
+}
+
+// auditRequest - writes the structured audit record of a request once it completed
+func auditRequest(w *services.Services, record *audit.Record, args []any, err error) {
+	record.LatencyMs = time.Since(record.Time).Milliseconds()
+	if err != nil {
+		record.Error = err.Error()
+	}
+	w.AuditLog.Log(record, args)
+}
+
+// allowUser - applies the rate limit of the user to a request which is audited once, and records a rejection in its audit record
+func allowUser(ctx context.Context, w *services.Services, record *audit.Record, user *common.GWUser, method string) (*ratelimiter.Request, error) {
+	rateLimitedRequest, err := w.RateLimiter.AllowUser(ctx, gethcommon.Address(user.ID), method)
+	if err != nil {
+		record.RateLimit = audit.RateLimitRejected
+		services.Audit(w, services.DebugLevel, "Rate limit exceeded for user: %s. %v", hexutils.BytesToHex(user.ID), err)
+		return nil, err
+	}
+	return rateLimitedRequest, nil
+}
+
 func getCandidateAccounts(user *common.GWUser, we *services.Services, cfg *AuthExecCfg) ([]*common.GWAccount, error) {
 	candidateAccts := make([]*common.GWAccount, 0)
 	// for users with multiple accounts try to determine a candidate account based on the available information
@@ -202,7 +278,35 @@ func extractUserID(ctx context.Context, _ *services.Services) ([]byte, error) {
 	return userID, nil
 }
 
+// bearerToken - the API key sent in the "Authorization: Bearer <key>" header, if any
+func bearerToken(ctx context.Context) (string, bool) {
+	header := rpc.PeerInfoFromContext(ctx).HTTP.Authorization
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):]), true
+}
+
+// extractUserForRequest - returns the user making a request which only reads data
 func extractUserForRequest(ctx context.Context, w *services.Services) (*common.GWUser, error) {
+	return extractUserWithScope(ctx, w, common.APIKeyScopeRead)
+}
+
+// extractUserWithScope - returns the user authenticated by the API key of the request, when the key has one of the scopes,
+// or the user identified by the token in the URL otherwise
+func extractUserWithScope(ctx context.Context, w *services.Services, scopes ...common.APIKeyScope) (*common.GWUser, error) {
+	if key, ok := bearerToken(ctx); ok {
+		user, apiKey, err := w.UserForAPIKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+		if !apiKey.HasAnyScope(scopes...) {
+			services.Audit(w, services.WarnLevel, "API key without the required scope. user: %s, key: %s, required: %v", common.HashForLogging(user.ID), apiKey.ID, scopes)
+			return nil, fmt.Errorf("%s: the API key requires one of the scopes %v", notAuthorised, scopes)
+		}
+		return user, nil
+	}
+
 	userID, err := extractUserID(ctx, w)
 	if err != nil {
 		return nil, err
@@ -237,6 +341,21 @@ func cacheBlockNumberOrHash(blockNrOrHash rpc.BlockNumberOrHash) cache.Strategy
 	return cache.LongLiving
 }
 
+// cacheAccountState - the responses for the latest state of an account are cached until a batch changes the account,
+// or until the next batch if the account is not watched. The pending state also depends on the mempool, so it is only
+// cached until the next batch
+func cacheAccountState(blockNrOrHash rpc.BlockNumberOrHash, account gethcommon.Address) *cache.Cfg {
+	return &cache.Cfg{
+		Accounts: []gethcommon.Address{account},
+		DynamicType: func() cache.Strategy {
+			if number, ok := blockNrOrHash.Number(); ok && number == rpc.LatestBlockNumber {
+				return cache.LatestAccountState
+			}
+			return cacheBlockNumberOrHash(blockNrOrHash)
+		},
+	}
+}
+
 func cacheBlockNumber(lastBlock rpc.BlockNumber) cache.Strategy {
 	if lastBlock > 0 {
 		return cache.LongLiving
@@ -298,3 +417,37 @@ func structToString(v reflect.Value, t reflect.Type) string {
 
 	return fmt.Sprintf("%s{%s}", t.Name(), strings.Join(parts, ", "))
 }
+
+// clientIP - the IP of the caller, used to rate limit the unauthenticated calls.
+// Each proxy appends the address of its caller to the X-Forwarded-For header, so behind the configured number of proxies
+// the client is the entry added by the outermost one. The entries before it are sent by the client, and can't be trusted.
+func clientIP(ctx context.Context, w *services.Services) string {
+	peer := rpc.PeerInfoFromContext(ctx)
+	if w.Config.TrustedProxyHops > 0 && peer.HTTP.ForwardedFor != "" {
+		return forwardedClientIP(peer.HTTP.ForwardedFor, w.Config.TrustedProxyHops)
+	}
+	host, _, err := net.SplitHostPort(peer.RemoteAddr)
+	if err != nil {
+		return peer.RemoteAddr
+	}
+	return host
+}
+
+// forwardedClientIP - the entry of the X-Forwarded-For header added by the outermost of the trusted proxies.
+// Fewer entries than proxies means the request entered through an inner proxy, so all the entries were added by proxies.
+func forwardedClientIP(forwardedFor string, trustedProxyHops int) string {
+	entries := strings.Split(forwardedFor, ",")
+	return strings.TrimSpace(entries[max(0, len(entries)-trustedProxyHops)])
+}
+
+// BatchKey - the parallel batch requests of a user are bounded together once the credentials of the request resolve
+// to the user. The requests without valid credentials are bounded by the IP of the client.
+func BatchKey(w *services.Services) rpc.BatchKeyFunc {
+	return func(ctx context.Context) string {
+		user, err := extractUserWithScope(ctx, w, common.APIKeyScopeRead, common.APIKeyScopeSendTx, common.APIKeyScopeSessionKeys)
+		if err == nil {
+			return "user:" + hexutils.BytesToHex(user.ID)
+		}
+		return "ip:" + clientIP(ctx, w)
+	}
+}
@@ -0,0 +1,26 @@
+package rpcapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardedClientIP(t *testing.T) {
+	testCases := []struct {
+		name         string
+		forwardedFor string
+		hops         int
+		expected     string
+	}{
+		{name: "one proxy", forwardedFor: "10.0.0.1", hops: 1, expected: "10.0.0.1"},
+		{name: "entry spoofed by the client", forwardedFor: "1.2.3.4, 10.0.0.1", hops: 1, expected: "10.0.0.1"},
+		{name: "two proxies", forwardedFor: "1.2.3.4, 10.0.0.1, 192.168.0.1", hops: 2, expected: "10.0.0.1"},
+		{name: "entered through the inner proxy", forwardedFor: "10.0.0.1", hops: 2, expected: "10.0.0.1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, forwardedClientIP(tc.forwardedFor, tc.hops))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+	tenrpc "github.com/ten-protocol/go-ten/go/rpc"
+	"github.com/ten-protocol/go-ten/tools/walletextension/cache"
+	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
+)
+
+// accountStateWatchTimeout - the accounts whose state is not requested during this interval are no longer watched
+const accountStateWatchTimeout = 10 * time.Minute
+
+// AccountStateWatcher - watches the changes of the state of the accounts whose latest state is requested, so the cached
+// responses are only evicted by the batches which changed the account, instead of by every batch.
+// The changes are notified by the enclave over a subscription authenticated with the viewing key of the account, and
+// encrypted with it, so the accounts touched by a batch are only revealed to their owners.
+type AccountStateWatcher struct {
+	backendRPC  *BackendRPC
+	cache       cache.Cache
+	stopControl *stopcontrol.StopControl
+	logger      gethlog.Logger
+
+	mu       sync.Mutex
+	accounts map[gethcommon.Address]time.Time // the watched accounts, with the last time their state was requested
+}
+
+func NewAccountStateWatcher(backendRPC *BackendRPC, cache cache.Cache, stopControl *stopcontrol.StopControl, logger gethlog.Logger) *AccountStateWatcher {
+	return &AccountStateWatcher{
+		backendRPC:  backendRPC,
+		cache:       cache,
+		stopControl: stopControl,
+		logger:      logger,
+		accounts:    make(map[gethcommon.Address]time.Time),
+	}
+}
+
+// Watch - starts watching the account in the background, unless it is already watched
+func (w *AccountStateWatcher) Watch(account *wecommon.GWAccount) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, watched := w.accounts[*account.Address]
+	w.accounts[*account.Address] = time.Now()
+	if !watched {
+		go w.watch(account)
+	}
+}
+
+func (w *AccountStateWatcher) watch(account *wecommon.GWAccount) {
+	address := *account.Address
+	defer func() {
+		w.mu.Lock()
+		delete(w.accounts, address)
+		w.mu.Unlock()
+	}()
+
+	conn, err := w.backendRPC.ConnectWS(context.Background(), account)
+	if err != nil {
+		w.logger.Debug("Could not connect to watch the account state", log.ErrKey, err)
+		return
+	}
+	defer func() { _ = w.backendRPC.ReturnConnWS(conn.BackingClient()) }()
+	changes := make(chan types.Log)
+	subscription, err := conn.Subscribe(context.Background(), tenrpc.SubscribeNamespace, changes, tenrpc.SubscriptionTypeAccountState)
+	if err != nil {
+		w.logger.Debug("Could not subscribe to the account state", log.ErrKey, err)
+		return
+	}
+	defer subscription.Unsubscribe()
+
+	// the responses cached from now on are only evicted by the notified changes
+	w.cache.WatchAccount(address)
+	defer w.cache.UnwatchAccount(address)
+
+	idleCheck := time.NewTicker(accountStateWatchTimeout / 10)
+	defer idleCheck.Stop()
+	for {
+		select {
+		case <-changes:
+			w.cache.EvictAccountState(address)
+		case err := <-subscription.Err():
+			w.logger.Debug("Account state subscription closed", log.ErrKey, err)
+			return
+		case <-idleCheck.C:
+			w.mu.Lock()
+			idle := time.Since(w.accounts[address]) > accountStateWatchTimeout
+			w.mu.Unlock()
+			if idle {
+				return
+			}
+		case <-w.stopControl.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+)
+
+// MaxAPIKeysPerUser - the maximum number of valid API keys a user can have at the same time
+const MaxAPIKeysPerUser = 20
+
+// CreateAPIKey - generates a new API key for the user. The key is returned only once, the gateway stores its hash
+func (w *Services) CreateAPIKey(userID []byte, name string, scopes []common.APIKeyScope) (string, *common.GWAPIKey, error) {
+	user, err := w.Storage.GetUser(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	valid := 0
+	for _, key := range user.APIKeys {
+		if !key.IsRevoked() {
+			valid++
+		}
+	}
+	if valid >= MaxAPIKeysPerUser {
+		return "", nil, fmt.Errorf("maximum number of API keys (%d) reached", MaxAPIKeysPerUser)
+	}
+
+	key, apiKey, err := common.NewAPIKey(name, scopes, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	if err := w.Storage.AddAPIKey(userID, *apiKey); err != nil {
+		return "", nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+	Audit(w, InfoLevel, "API key created. user: %s, key: %s, name: %s, scopes: %v", common.HashForLogging(userID), apiKey.ID, apiKey.Name, apiKey.Scopes)
+	return key, apiKey, nil
+}
+
+// RevokeAPIKey - the revoked keys can't be used anymore, but they are kept with the user for auditing
+func (w *Services) RevokeAPIKey(userID []byte, keyID string) error {
+	if err := w.Storage.RevokeAPIKey(userID, keyID, time.Now()); err != nil {
+		return err
+	}
+	Audit(w, InfoLevel, "API key revoked. user: %s, key: %s", common.HashForLogging(userID), keyID)
+	return nil
+}
+
+// UserForAPIKey - returns the user authenticated by the key, and the details of the key
+func (w *Services) UserForAPIKey(key string) (*common.GWUser, *common.GWAPIKey, error) {
+	if !strings.HasPrefix(key, common.APIKeyPrefix) {
+		return nil, nil, common.ErrAPIKeyNotFound
+	}
+	hash := common.HashAPIKey(key)
+	user, err := w.Storage.GetUserByAPIKey(hash)
+	if err != nil {
+		if !errors.Is(err, common.ErrAPIKeyNotFound) {
+			w.Logger().Error("failed to read the user of an API key", "err", err)
+		}
+		return nil, nil, err
+	}
+	// the index of the storage can still hold a key which was just revoked, so the user record decides
+	apiKey, err := user.GetAPIKeyByHash(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	Audit(w, InfoLevel, "API key used. user: %s, key: %s", common.HashForLogging(user.ID), apiKey.ID)
+	return user, apiKey, nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
 	gethrpc "github.com/ten-protocol/go-ten/lib/gethfork/rpc"
 	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/metrics"
 )
 
 type BackendRPC struct {
@@ -110,6 +111,14 @@ func (rpc *BackendRPC) ReturnConn(conn tenrpc.Client) error {
 	return returnConn(rpc.rpcHTTPConnPool, conn, rpc.logger)
 }
 
+// CollectPoolMetrics - updates the gauges of the connections borrowed from and idle in the backend connection pools
+func (rpc *BackendRPC) CollectPoolMetrics() {
+	metrics.SetGauge("gateway/backend/http/active", int64(rpc.rpcHTTPConnPool.GetNumActive()))
+	metrics.SetGauge("gateway/backend/http/idle", int64(rpc.rpcHTTPConnPool.GetNumIdle()))
+	metrics.SetGauge("gateway/backend/ws/active", int64(rpc.rpcWSConnPool.GetNumActive()))
+	metrics.SetGauge("gateway/backend/ws/idle", int64(rpc.rpcWSConnPool.GetNumIdle()))
+}
+
 func (rpc *BackendRPC) Stop() {
 	rpc.rpcHTTPConnPool.Close(context.Background())
 	rpc.rpcWSConnPool.Close(context.Background())
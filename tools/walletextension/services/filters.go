@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/ten-protocol/go-ten/go/common"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+)
+
+// ErrFilterNotFound - returned when the filter doesn't exist, expired or belongs to another user
+var ErrFilterNotFound = errors.New("filter not found")
+
+// DefaultFilterTimeout - filters which are not polled during this interval are removed
+const DefaultFilterTimeout = 5 * time.Minute
+
+// MaxFiltersPerUser - the maximum number of filters a user can install. The filters are kept in memory until they expire
+const MaxFiltersPerUser = 100
+
+// FilterType - the type of the polling filter
+type FilterType byte
+
+const (
+	// LogsFilter - created with "eth_newFilter"
+	LogsFilter FilterType = iota
+	// BlockFilter - created with "eth_newBlockFilter"
+	BlockFilter
+	// PendingTxFilter - created with "eth_newPendingTransactionFilter"
+	PendingTxFilter
+)
+
+// Filter - a polling filter installed by a user
+type Filter struct {
+	ID       rpc.ID
+	Type     FilterType
+	Criteria common.FilterCriteria
+	// LastSeenBatch - the height of the last batch for which the changes were returned to the user
+	LastSeenBatch uint64
+	// BlockHashes - the hashes of the batches produced since the last poll. Only for block filters
+	BlockHashes []gethcommon.Hash
+	// SeenTxs - the pending transactions already returned to the user. Only for pending tx filters
+	SeenTxs map[gethcommon.Hash]bool
+	// FullTx - return the full pending transactions instead of the hashes. Only for pending tx filters
+	FullTx bool
+
+	lastPoll time.Time
+	mu       sync.Mutex // serialises the polls of the same filter
+}
+
+// FilterRegistry - keeps the polling filters of each user in memory.
+// The filters which are not polled within the timeout are removed.
+type FilterRegistry struct {
+	filters map[string]map[rpc.ID]*Filter // user id (hex) -> filter id -> filter
+	mu      sync.Mutex
+	timeout time.Duration
+	logger  gethlog.Logger
+}
+
+func NewFilterRegistry(timeout time.Duration, logger gethlog.Logger) *FilterRegistry {
+	if timeout == 0 {
+		timeout = DefaultFilterTimeout
+	}
+	return &FilterRegistry{
+		filters: make(map[string]map[rpc.ID]*Filter),
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Install - registers a new filter for the user and returns its id.
+// Fails when the user already has the maximum number of filters.
+func (fr *FilterRegistry) Install(userID []byte, filter *Filter) (rpc.ID, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	key := hexutils.BytesToHex(userID)
+	if len(fr.filters[key]) >= MaxFiltersPerUser {
+		return "", fmt.Errorf("user already has the maximum number of filters: %d. Please uninstall the unused filters", MaxFiltersPerUser)
+	}
+
+	filter.ID = rpc.NewID()
+	filter.lastPoll = time.Now()
+	if fr.filters[key] == nil {
+		fr.filters[key] = make(map[rpc.ID]*Filter)
+	}
+	fr.filters[key][filter.ID] = filter
+	return filter.ID, nil
+}
+
+// Poll - executes the function on the filter of the user, and resets the idle timer.
+// Concurrent polls of the same filter are executed one after the other, so the changes are returned only once.
+func (fr *FilterRegistry) Poll(userID []byte, id rpc.ID, fn func(filter *Filter) error) error {
+	fr.mu.Lock()
+	filter, found := fr.filters[hexutils.BytesToHex(userID)][id]
+	if found {
+		filter.lastPoll = time.Now()
+	}
+	fr.mu.Unlock()
+
+	if !found {
+		return ErrFilterNotFound
+	}
+
+	filter.mu.Lock()
+	defer filter.mu.Unlock()
+	return fn(filter)
+}
+
+// Uninstall - removes the filter of the user. Returns false if the filter does not exist.
+func (fr *FilterRegistry) Uninstall(userID []byte, id rpc.ID) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	key := hexutils.BytesToHex(userID)
+	if _, found := fr.filters[key][id]; !found {
+		return false
+	}
+	delete(fr.filters[key], id)
+	if len(fr.filters[key]) == 0 {
+		delete(fr.filters, key)
+	}
+	return true
+}
+
+// OnNewHead - records the new batch in all the block filters
+func (fr *FilterRegistry) OnNewHead(head *common.BatchHeader) {
+	fr.mu.Lock()
+	blockFilters := make([]*Filter, 0)
+	for _, userFilters := range fr.filters {
+		for _, filter := range userFilters {
+			if filter.Type == BlockFilter {
+				blockFilters = append(blockFilters, filter)
+			}
+		}
+	}
+	fr.mu.Unlock()
+
+	// the filters are updated outside the registry lock, because a poll can be in progress
+	hash := head.Hash()
+	for _, filter := range blockFilters {
+		filter.mu.Lock()
+		filter.BlockHashes = append(filter.BlockHashes, hash)
+		filter.mu.Unlock()
+	}
+}
+
+// evictIdleFilters - periodically removes the filters which were not polled within the timeout
+func (fr *FilterRegistry) evictIdleFilters(stopControl *stopcontrol.StopControl) {
+	ticker := time.NewTicker(fr.timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fr.mu.Lock()
+			for key, userFilters := range fr.filters {
+				for id, filter := range userFilters {
+					if time.Since(filter.lastPoll) > fr.timeout {
+						delete(userFilters, id)
+						fr.logger.Debug("Removed idle filter", log.SubIDKey, id)
+					}
+				}
+				if len(userFilters) == 0 {
+					delete(fr.filters, key)
+				}
+			}
+			fr.mu.Unlock()
+
+		case <-stopControl.Done():
+			fr.logger.Info("Stopping filter eviction")
+			return
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+)
+
+func TestFiltersPerUserAreLimited(t *testing.T) {
+	registry := NewFilterRegistry(0, gethlog.New())
+	user, otherUser := []byte{1}, []byte{2}
+
+	var firstID rpc.ID
+	for i := 0; i < MaxFiltersPerUser; i++ {
+		id, err := registry.Install(user, &Filter{Type: BlockFilter})
+		require.NoError(t, err)
+		if i == 0 {
+			firstID = id
+		}
+	}
+	_, err := registry.Install(user, &Filter{Type: BlockFilter})
+	require.ErrorContains(t, err, "maximum number of filters")
+
+	// the limit applies to each user, and an uninstalled filter frees a slot
+	_, err = registry.Install(otherUser, &Filter{Type: BlockFilter})
+	require.NoError(t, err)
+	require.True(t, registry.Uninstall(user, firstID))
+	_, err = registry.Install(user, &Filter{Type: BlockFilter})
+	require.NoError(t, err)
+}
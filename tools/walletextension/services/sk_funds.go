@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ten-protocol/go-ten/go/common/errutil"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	tenrpcapi "github.com/ten-protocol/go-ten/go/common/rpc"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
+	"github.com/ten-protocol/go-ten/go/obsclient"
+	tenrpc "github.com/ten-protocol/go-ten/go/rpc"
+	gethrpc "github.com/ten-protocol/go-ten/lib/gethfork/rpc"
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
+)
+
+const (
+	fundsCheckInterval   = 30 * time.Second
+	fundsTransferTimeout = 10 * time.Second
+	// a transfer which is not included after this time is considered dropped, so it can be sent again
+	transferPendingTimeout = 10 * fundsCheckInterval
+)
+
+// sessionKeyFundsEvents - the last automatic transfers of a session key. Kept in memory and reported by sessionkeys_status
+type sessionKeyFundsEvents struct {
+	lastSweep *common.SessionKeyFundsTransfer
+	lastTopUp *common.SessionKeyFundsTransfer
+}
+
+// skFundsBackend - the node calls used to move the funds of the session keys
+type skFundsBackend interface {
+	Balance(ctx context.Context, acct *common.GWAccount) (*big.Int, error)
+	Nonce(ctx context.Context, acct *common.GWAccount) (uint64, error)
+	GasPrice(ctx context.Context, acct *common.GWAccount) (*big.Int, error)
+	EstimateGas(ctx context.Context, acct *common.GWAccount, msg ethereum.CallMsg) (uint64, error)
+	SendRawTransaction(ctx context.Context, acct *common.GWAccount, tx *types.Transaction) (gethcommon.Hash, error)
+	// IsPending - returns true if the transaction has no receipt yet
+	IsPending(ctx context.Context, acct *common.GWAccount, txHash gethcommon.Hash) (bool, error)
+}
+
+// ManageFunds - the session keys are tracked once the gateway sees them (created, configured or used to sign).
+// The users with session keys which need automatic transfers are loaded from the storage at start, so the keys
+// which expired while the gateway was down are swept as well.
+func (m *skManager) ManageFunds(stopControl *stopcontrol.StopControl) {
+	ticker := time.NewTicker(fundsCheckInterval)
+	defer ticker.Stop()
+
+	var undecryptable *dbcommon.UndecryptableUsersError
+	if err := m.trackStoredUsers(); errors.As(err, &undecryptable) {
+		m.logger.Error("Unable to load the session keys of some users", "users", undecryptable.Count)
+	} else if err != nil {
+		m.logger.Error("Unable to load the session keys which need automatic transfers", log.ErrKey, err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			m.manageTrackedFunds()
+
+		case <-stopControl.Done():
+			m.logger.Info("Stopping session key funds management")
+			return
+		}
+	}
+}
+
+// trackStoredUsers - tracks the users with session keys which have a policy or a funding configuration, or are deleted
+func (m *skManager) trackStoredUsers() error {
+	return m.storage.ForEachUser(func(user *common.GWUser) error {
+		for _, sk := range user.SessionKeys {
+			if sk.Funding != nil || sk.Policy != nil || sk.IsDeleted() {
+				m.track(user.ID)
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func (m *skManager) track(userID []byte) {
+	m.fundsMu.Lock()
+	defer m.fundsMu.Unlock()
+	m.tracked[string(userID)] = struct{}{}
+}
+
+func (m *skManager) manageTrackedFunds() {
+	m.fundsMu.Lock()
+	userIDs := make([]string, 0, len(m.tracked))
+	for userID := range m.tracked {
+		userIDs = append(userIDs, userID)
+	}
+	m.fundsMu.Unlock()
+
+	for _, userID := range userIDs {
+		ctx, cancel := context.WithTimeout(context.Background(), fundsTransferTimeout)
+		needsTracking := m.manageUserFunds(ctx, []byte(userID))
+		cancel()
+		if !needsTracking {
+			m.fundsMu.Lock()
+			delete(m.tracked, userID)
+			m.fundsMu.Unlock()
+		}
+	}
+}
+
+// manageUserFunds - completes the deletion of the keys, sweeps the expired keys and tops up the keys with a low balance.
+// Returns whether the user still has session keys which need automatic transfers.
+func (m *skManager) manageUserFunds(ctx context.Context, userID []byte) bool {
+	user, err := m.storage.GetUser(userID)
+	if err != nil {
+		if errors.Is(err, dbcommon.ErrUserNotFound) {
+			return false
+		}
+		m.logger.Warn("Unable to read user for session key funds management", log.ErrKey, err)
+		return true
+	}
+
+	needsTracking := false
+	now := time.Now()
+	for address, sk := range user.SessionKeys {
+		if sk.IsDeleted() {
+			needsTracking = true
+			if err := m.sweepDeletedKey(ctx, user, sk); err != nil {
+				m.logger.Warn("Unable to sweep the funds of the deleted session key", "sk", address, log.ErrKey, err)
+			}
+			continue
+		}
+		if sk.IsExpired(now) {
+			if m.isSwept(address) {
+				continue
+			}
+			needsTracking = true
+			if _, err := m.sweep(ctx, user, sk); err != nil {
+				m.logger.Warn("Unable to sweep the funds of the expired session key", "sk", address, log.ErrKey, err)
+			}
+			continue
+		}
+		if sk.Policy != nil && sk.Policy.ExpiresAt != 0 {
+			needsTracking = true
+		}
+		if sk.Funding != nil && sk.Funding.TopUp != nil {
+			needsTracking = true
+			if err := m.topUp(ctx, user, sk); err != nil {
+				m.logger.Warn("Unable to top up the session key", "sk", address, log.ErrKey, err)
+			}
+		}
+	}
+	return needsTracking
+}
+
+// sweepDeletedKey - removes the deleted key once there is nothing left to sweep. Otherwise, the funds are swept and the
+// key is kept, marked as deleted, until the sweep is included. The funds are swept again if the sweep was dropped, or if
+// they are still on the key after the sweep was included.
+func (m *skManager) sweepDeletedKey(ctx context.Context, user *common.GWUser, sk *common.GWSessionKey) error {
+	if sk.Sweep != nil && time.Since(time.Unix(int64(sk.Sweep.Time), 0)) <= transferPendingTimeout {
+		pending, err := m.backend.IsPending(ctx, sk.Account, *sk.Sweep.TxHash)
+		if err != nil {
+			return fmt.Errorf("unable to read the status of the sweep: %w", err)
+		}
+		if pending {
+			return nil
+		}
+	}
+	transfer, err := m.sweep(ctx, user, sk)
+	if err != nil {
+		return err
+	}
+	if transfer.TxHash != nil {
+		if err := m.storage.SetSessionKeySweep(user.ID, *sk.Account.Address, transfer); err != nil {
+			return fmt.Errorf("unable to mark the session key as deleted: %w", err)
+		}
+		m.track(user.ID)
+		return nil
+	}
+	if err := m.storage.RemoveSessionKey(user.ID, *sk.Account.Address); err != nil {
+		return err
+	}
+	m.forgetTransfers(*sk.Account.Address)
+	return nil
+}
+
+// sweep - transfers the balance of the key, minus the transfer fee, to the sweep account of the user.
+// The transfer is not checked against the policy of the key, because the funds return to the user.
+func (m *skManager) sweep(ctx context.Context, user *common.GWUser, sk *common.GWSessionKey) (*common.SessionKeyFundsTransfer, error) {
+	transfer, err := m.executeSweep(ctx, user, sk)
+	if transfer == nil {
+		transfer = &common.SessionKeyFundsTransfer{}
+	}
+	transfer.Time = uint64(time.Now().Unix())
+	if err != nil {
+		transfer.Error = err.Error()
+	}
+	m.recordTransfer(*sk.Account.Address, func(events *sessionKeyFundsEvents) {
+		events.lastSweep = transfer
+	})
+	return transfer, err
+}
+
+func (m *skManager) executeSweep(ctx context.Context, user *common.GWUser, sk *common.GWSessionKey) (*common.SessionKeyFundsTransfer, error) {
+	balance, err := m.backend.Balance(ctx, sk.Account)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read balance: %w", err)
+	}
+	if balance.Sign() == 0 {
+		return &common.SessionKeyFundsTransfer{Amount: (*hexutil.Big)(new(big.Int))}, nil
+	}
+	to, err := sweepAccount(user, sk)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := m.backend.GasPrice(ctx, sk.Account)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read gas price: %w", err)
+	}
+	// the sweep account can be a contract, so the gas of the transfer is estimated
+	gas, err := m.backend.EstimateGas(ctx, sk.Account, ethereum.CallMsg{From: *sk.Account.Address, To: &to, Value: balance})
+	if err != nil {
+		return nil, fmt.Errorf("unable to estimate gas: %w", err)
+	}
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas))
+	if balance.Cmp(fee) <= 0 {
+		// nothing worth sweeping
+		return &common.SessionKeyFundsTransfer{Amount: (*hexutil.Big)(new(big.Int))}, nil
+	}
+
+	amount := new(big.Int).Sub(balance, fee)
+	txHash, err := m.transfer(ctx, user, sk, to, amount, gasPrice, gas, false)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Info("Swept the funds of the session key", "sk", sk.Account.Address, "to", to, "amount", amount, log.TxKey, txHash)
+	return &common.SessionKeyFundsTransfer{TxHash: &txHash, To: &to, Amount: (*hexutil.Big)(amount)}, nil
+}
+
+// topUp - transfers the configured amount from the funding key, if the balance of the key is below the threshold.
+// Nothing is sent while the previous top up is pending, because the balance doesn't include it yet.
+func (m *skManager) topUp(ctx context.Context, user *common.GWUser, sk *common.GWSessionKey) error {
+	cfg := sk.Funding.TopUp
+	pending, err := m.isTopUpPending(ctx, user, sk)
+	if err != nil {
+		return fmt.Errorf("unable to read the status of the previous top up: %w", err)
+	}
+	if pending {
+		return nil
+	}
+	balance, err := m.backend.Balance(ctx, sk.Account)
+	if err != nil {
+		return fmt.Errorf("unable to read balance: %w", err)
+	}
+	if balance.Cmp(cfg.Threshold.ToInt()) >= 0 {
+		return nil
+	}
+
+	transfer := &common.SessionKeyFundsTransfer{To: sk.Account.Address, Amount: cfg.Amount, Time: uint64(time.Now().Unix())}
+	err = func() error {
+		from, found := user.SessionKeys[cfg.From]
+		if !found || from.IsDeleted() {
+			return fmt.Errorf("the top up account %s is not a session key of the user", cfg.From.Hex())
+		}
+		gasPrice, err := m.backend.GasPrice(ctx, from.Account)
+		if err != nil {
+			return fmt.Errorf("unable to read gas price: %w", err)
+		}
+		gas, err := m.backend.EstimateGas(ctx, from.Account, ethereum.CallMsg{From: cfg.From, To: sk.Account.Address, Value: cfg.Amount.ToInt()})
+		if err != nil {
+			return fmt.Errorf("unable to estimate gas: %w", err)
+		}
+		txHash, err := m.transfer(ctx, user, from, *sk.Account.Address, cfg.Amount.ToInt(), gasPrice, gas, true)
+		if err != nil {
+			return err
+		}
+		transfer.TxHash = &txHash
+		return nil
+	}()
+	if err != nil {
+		transfer.Error = err.Error()
+	} else {
+		m.logger.Info("Topped up the session key", "sk", sk.Account.Address, "from", cfg.From, "amount", cfg.Amount.ToInt(), log.TxKey, transfer.TxHash)
+	}
+	m.recordTransfer(*sk.Account.Address, func(events *sessionKeyFundsEvents) {
+		events.lastTopUp = transfer
+	})
+	return err
+}
+
+// isTopUpPending - returns true if the last top up of the key was sent recently and has no receipt yet
+func (m *skManager) isTopUpPending(ctx context.Context, user *common.GWUser, sk *common.GWSessionKey) (bool, error) {
+	m.fundsMu.Lock()
+	var lastTopUp *common.SessionKeyFundsTransfer
+	if events, found := m.events[*sk.Account.Address]; found {
+		lastTopUp = events.lastTopUp
+	}
+	m.fundsMu.Unlock()
+	if lastTopUp == nil || lastTopUp.TxHash == nil || time.Since(time.Unix(int64(lastTopUp.Time), 0)) > transferPendingTimeout {
+		return false, nil
+	}
+	from, found := user.SessionKeys[sk.Funding.TopUp.From]
+	if !found {
+		return false, nil
+	}
+	return m.backend.IsPending(ctx, from.Account, *lastTopUp.TxHash)
+}
+
+// transfer - signs a native transfer with the session key and submits it.
+// When authorize is set, the transfer must be allowed by the policy of the key.
+func (m *skManager) transfer(ctx context.Context, user *common.GWUser, from *common.GWSessionKey, to gethcommon.Address, amount *big.Int, gasPrice *big.Int, gas uint64, authorize bool) (gethcommon.Hash, error) {
+	nonce, err := m.backend.Nonce(ctx, from.Account)
+	if err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("unable to read nonce: %w", err)
+	}
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gas,
+		To:       &to,
+		Value:    amount,
+	})
+
+	send := func(ctx context.Context, stx *types.Transaction) (gethcommon.Hash, error) {
+		return m.backend.SendRawTransaction(ctx, from.Account, stx)
+	}
+	if authorize {
+		return m.authorizeAndSend(ctx, user, from, tx, send)
+	}
+	return m.signAndSend(ctx, from, tx, send)
+}
+
+// Status - the balances are read from the node, and the transfers are the ones executed since the gateway started
+func (m *skManager) Status(ctx context.Context, user *common.GWUser) ([]common.SessionKeyStatus, error) {
+	now := time.Now()
+	infos := user.SessionKeysInfo()
+	res := make([]common.SessionKeyStatus, 0, len(infos))
+	for _, info := range infos {
+		sk := user.SessionKeys[info.Address]
+		status := common.SessionKeyStatus{SessionKeyInfo: info, Expired: sk.IsExpired(now)}
+		balance, err := m.backend.Balance(ctx, sk.Account)
+		if err != nil {
+			m.logger.Debug("Unable to read the balance of the session key", "sk", info.Address, log.ErrKey, err)
+		} else {
+			status.Balance = (*hexutil.Big)(balance)
+		}
+
+		m.fundsMu.Lock()
+		if events, found := m.events[info.Address]; found {
+			status.LastSweep = events.lastSweep
+			status.LastTopUp = events.lastTopUp
+		}
+		m.fundsMu.Unlock()
+
+		if sk.Funding != nil || sk.Policy != nil || sk.IsDeleted() {
+			m.track(user.ID)
+		}
+		res = append(res, status)
+	}
+	return res, nil
+}
+
+func (m *skManager) recordTransfer(address gethcommon.Address, update func(*sessionKeyFundsEvents)) {
+	m.fundsMu.Lock()
+	defer m.fundsMu.Unlock()
+	events, found := m.events[address]
+	if !found {
+		events = &sessionKeyFundsEvents{}
+		m.events[address] = events
+	}
+	update(events)
+}
+
+func (m *skManager) isSwept(address gethcommon.Address) bool {
+	m.fundsMu.Lock()
+	defer m.fundsMu.Unlock()
+	events, found := m.events[address]
+	return found && events.lastSweep != nil && events.lastSweep.Error == ""
+}
+
+func (m *skManager) forgetTransfers(address gethcommon.Address) {
+	m.fundsMu.Lock()
+	defer m.fundsMu.Unlock()
+	delete(m.events, address)
+}
+
+// sweepAccount - the configured sweep account, or the only account registered by the user
+func sweepAccount(user *common.GWUser, sk *common.GWSessionKey) (gethcommon.Address, error) {
+	if sk.Funding != nil && sk.Funding.SweepTo != nil {
+		return *sk.Funding.SweepTo, nil
+	}
+	if len(user.Accounts) == 1 {
+		for address := range user.Accounts {
+			return address, nil
+		}
+	}
+	return gethcommon.Address{}, fmt.Errorf("the user has multiple accounts. Please configure the sweep account of the session key")
+}
+
+// encRPCFundsBackend - executes the calls through the encrypted RPC, authenticated with the viewing key of the session key
+type encRPCFundsBackend struct {
+	rpc *BackendRPC
+}
+
+func (b *encRPCFundsBackend) Balance(ctx context.Context, acct *common.GWAccount) (*big.Int, error) {
+	balance, err := WithEncRPCConnection(ctx, b.rpc, acct, func(client *tenrpc.EncRPCClient) (*hexutil.Big, error) {
+		var balance hexutil.Big
+		err := client.CallContext(ctx, &balance, tenrpcapi.ERPCGetBalance, acct.Address, gethrpc.BlockNumberOrHashWithNumber(gethrpc.LatestBlockNumber))
+		return &balance, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return balance.ToInt(), nil
+}
+
+func (b *encRPCFundsBackend) Nonce(ctx context.Context, acct *common.GWAccount) (uint64, error) {
+	nonce, err := WithEncRPCConnection(ctx, b.rpc, acct, func(client *tenrpc.EncRPCClient) (*hexutil.Uint64, error) {
+		var nonce hexutil.Uint64
+		err := client.CallContext(ctx, &nonce, tenrpcapi.ERPCGetTransactionCount, acct.Address, gethrpc.BlockNumberOrHashWithNumber(gethrpc.PendingBlockNumber))
+		return &nonce, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(*nonce), nil
+}
+
+func (b *encRPCFundsBackend) GasPrice(ctx context.Context, acct *common.GWAccount) (*big.Int, error) {
+	gasPrice, err := WithEncRPCConnection(ctx, b.rpc, acct, func(client *tenrpc.EncRPCClient) (*hexutil.Big, error) {
+		var gasPrice hexutil.Big
+		err := client.CallContext(ctx, &gasPrice, tenrpc.GasPrice)
+		return &gasPrice, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gasPrice.ToInt(), nil
+}
+
+func (b *encRPCFundsBackend) EstimateGas(ctx context.Context, acct *common.GWAccount, msg ethereum.CallMsg) (uint64, error) {
+	gas, err := WithEncRPCConnection(ctx, b.rpc, acct, func(client *tenrpc.EncRPCClient) (*hexutil.Uint64, error) {
+		var gas hexutil.Uint64
+		err := client.CallContext(ctx, &gas, tenrpcapi.ERPCEstimateGas, obsclient.ToCallArg(msg))
+		return &gas, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(*gas), nil
+}
+
+func (b *encRPCFundsBackend) IsPending(ctx context.Context, acct *common.GWAccount, txHash gethcommon.Hash) (bool, error) {
+	pending, err := WithEncRPCConnection(ctx, b.rpc, acct, func(client *tenrpc.EncRPCClient) (*bool, error) {
+		var receipt *types.Receipt
+		err := client.CallContext(ctx, &receipt, tenrpcapi.ERPCGetTransactionReceipt, txHash)
+		if err != nil && strings.Contains(err.Error(), errutil.ErrNotFound.Error()) {
+			err = nil
+		}
+		pending := receipt == nil
+		return &pending, err
+	})
+	if err != nil {
+		return false, err
+	}
+	return *pending, nil
+}
+
+func (b *encRPCFundsBackend) SendRawTransaction(ctx context.Context, acct *common.GWAccount, tx *types.Transaction) (gethcommon.Hash, error) {
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		return gethcommon.Hash{}, err
+	}
+	txHash, err := WithEncRPCConnection(ctx, b.rpc, acct, func(client *tenrpc.EncRPCClient) (*gethcommon.Hash, error) {
+		var txHash gethcommon.Hash
+		err := client.CallContext(ctx, &txHash, tenrpcapi.ERPCSendRawTransaction, hexutil.Bytes(blob))
+		return &txHash, err
+	})
+	if err != nil {
+		return gethcommon.Hash{}, err
+	}
+	return *txHash, nil
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common/viewingkey"
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/storage"
+)
+
+const testChainID = 443
+
+var (
+	testGasPrice = big.NewInt(10)
+	transferFee  = big.NewInt(10 * 21_000)
+)
+
+// fakeFundsBackend - keeps the balances in memory and records the submitted transactions.
+// The value and the fee of a submitted transaction are deducted from the balance of the sender.
+type fakeFundsBackend struct {
+	balances map[gethcommon.Address]*big.Int
+	gas      uint64
+	sent     []*types.Transaction
+	pending  map[gethcommon.Hash]bool
+}
+
+func (b *fakeFundsBackend) Balance(_ context.Context, acct *common.GWAccount) (*big.Int, error) {
+	if balance, found := b.balances[*acct.Address]; found {
+		return balance, nil
+	}
+	return big.NewInt(0), nil
+}
+
+func (b *fakeFundsBackend) Nonce(context.Context, *common.GWAccount) (uint64, error) {
+	return uint64(len(b.sent)), nil
+}
+
+func (b *fakeFundsBackend) GasPrice(context.Context, *common.GWAccount) (*big.Int, error) {
+	return testGasPrice, nil
+}
+
+func (b *fakeFundsBackend) EstimateGas(context.Context, *common.GWAccount, ethereum.CallMsg) (uint64, error) {
+	return b.gas, nil
+}
+
+func (b *fakeFundsBackend) IsPending(_ context.Context, _ *common.GWAccount, txHash gethcommon.Hash) (bool, error) {
+	return b.pending[txHash], nil
+}
+
+func (b *fakeFundsBackend) SendRawTransaction(_ context.Context, acct *common.GWAccount, tx *types.Transaction) (gethcommon.Hash, error) {
+	if balance, found := b.balances[*acct.Address]; found {
+		fee := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+		b.balances[*acct.Address] = new(big.Int).Sub(balance, new(big.Int).Add(tx.Value(), fee))
+	}
+	b.sent = append(b.sent, tx)
+	return tx.Hash(), nil
+}
+
+func newTestSKManager(t *testing.T, accounts ...gethcommon.Address) (*skManager, *fakeFundsBackend, []byte) {
+	logger := gethlog.New()
+	key, err := common.GenerateRandomKey()
+	require.NoError(t, err)
+	userStorage, err := storage.New("sqlite", "", "", key, logger)
+	require.NoError(t, err)
+
+	userID := gethcommon.HexToAddress("0xabcd").Bytes()
+	require.NoError(t, userStorage.AddUser(userID, []byte{1}))
+	for _, account := range accounts {
+		require.NoError(t, userStorage.AddAccount(userID, account.Bytes(), []byte{1}, viewingkey.EIP712Signature))
+	}
+
+	backend := &fakeFundsBackend{balances: make(map[gethcommon.Address]*big.Int), gas: 21_000, pending: make(map[gethcommon.Hash]bool)}
+	m := NewSKManager(userStorage, nil, &common.Config{TenChainID: testChainID}, logger).(*skManager)
+	m.backend = backend
+	return m, backend, userID
+}
+
+func readUser(t *testing.T, m *skManager, userID []byte) *common.GWUser {
+	user, err := m.storage.GetUser(userID)
+	require.NoError(t, err)
+	return user
+}
+
+func TestDeleteSessionKeySweepsFunds(t *testing.T) {
+	account := gethcommon.HexToAddress("0x01")
+	m, backend, userID := newTestSKManager(t, account)
+
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	backend.balances[*sk.Account.Address] = big.NewInt(1_000_000)
+
+	deleted, err := m.DeleteSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	require.Len(t, backend.sent, 1)
+	sweepTx := backend.sent[0]
+	require.Equal(t, account, *sweepTx.To())
+	require.Equal(t, new(big.Int).Sub(big.NewInt(1_000_000), transferFee), sweepTx.Value())
+	sender, err := types.Sender(types.NewCancunSigner(big.NewInt(testChainID)), sweepTx)
+	require.NoError(t, err)
+	require.Equal(t, *sk.Account.Address, sender)
+
+	// the key is kept until the sweep is included, and can't be used in the meantime
+	backend.pending[sweepTx.Hash()] = true
+	userSK := readUser(t, m, userID).SessionKeys[*sk.Account.Address]
+	require.True(t, userSK.IsDeleted())
+	require.Equal(t, sweepTx.Hash(), *userSK.Sweep.TxHash)
+	_, err = m.ActivateSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.ErrorContains(t, err, "is being deleted")
+	require.Equal(t, map[string]struct{}{string(userID): {}}, m.tracked)
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, readUser(t, m, userID).SessionKeys, 1)
+	require.Len(t, backend.sent, 1)
+
+	// once the sweep is included, there is nothing left to sweep and the key is removed
+	backend.pending[sweepTx.Hash()] = false
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Empty(t, readUser(t, m, userID).SessionKeys)
+	require.Len(t, backend.sent, 1)
+	require.False(t, m.manageUserFunds(context.Background(), userID))
+}
+
+func TestDeletedSessionKeyIsSweptAgain(t *testing.T) {
+	m, backend, userID := newTestSKManager(t, gethcommon.HexToAddress("0x01"))
+
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	backend.balances[*sk.Account.Address] = big.NewInt(1_000_000)
+	_, err = m.DeleteSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.NoError(t, err)
+	require.Len(t, backend.sent, 1)
+
+	// the sweep was included without transferring the funds, e.g. it was reverted by the sweep account
+	backend.balances[*sk.Account.Address] = big.NewInt(1_000_000)
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 2)
+	userSK := readUser(t, m, userID).SessionKeys[*sk.Account.Address]
+	require.Equal(t, backend.sent[1].Hash(), *userSK.Sweep.TxHash)
+
+	// a restarted gateway completes the deletion
+	restarted := NewSKManager(m.storage, nil, &common.Config{TenChainID: testChainID}, gethlog.New()).(*skManager)
+	restarted.backend = backend
+	require.NoError(t, restarted.trackStoredUsers())
+	restarted.manageTrackedFunds()
+	require.Empty(t, readUser(t, m, userID).SessionKeys)
+	require.Len(t, backend.sent, 2)
+}
+
+func TestDeleteSessionKeyRequiresSweepAccount(t *testing.T) {
+	m, backend, userID := newTestSKManager(t, gethcommon.HexToAddress("0x01"), gethcommon.HexToAddress("0x02"))
+
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	backend.balances[*sk.Account.Address] = big.NewInt(1_000_000)
+
+	// the user has multiple accounts, so the funds can't be swept without a configured account
+	_, err = m.DeleteSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.ErrorContains(t, err, "sweep account")
+	require.Len(t, readUser(t, m, userID).SessionKeys, 1)
+
+	sweepTo := gethcommon.HexToAddress("0x02")
+	_, err = m.SetSessionKeyFunding(readUser(t, m, userID), sk.Account.Address, &common.SessionKeyFunding{SweepTo: &sweepTo})
+	require.NoError(t, err)
+	_, err = m.DeleteSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.NoError(t, err)
+	require.Len(t, backend.sent, 1)
+	require.Equal(t, sweepTo, *backend.sent[0].To())
+
+	// a key without funds is deleted without a transfer
+	sk, err = m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	_, err = m.DeleteSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.NoError(t, err)
+	require.Len(t, backend.sent, 1)
+}
+
+func TestExpiredSessionKeyIsSwept(t *testing.T) {
+	account := gethcommon.HexToAddress("0x01")
+	m, backend, userID := newTestSKManager(t, account)
+
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), &common.SessionKeyPolicy{ExpiresAt: 1})
+	require.NoError(t, err)
+	backend.balances[*sk.Account.Address] = big.NewInt(1_000_000)
+
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 1)
+	require.Equal(t, account, *backend.sent[0].To())
+
+	// the key is not swept again, and doesn't need to be tracked anymore
+	require.False(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 1)
+
+	status, err := m.Status(context.Background(), readUser(t, m, userID))
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	require.True(t, status[0].Expired)
+	require.Equal(t, backend.sent[0].Hash(), *status[0].LastSweep.TxHash)
+	require.Empty(t, status[0].LastSweep.Error)
+}
+
+func TestSessionKeyTopUp(t *testing.T) {
+	m, backend, userID := newTestSKManager(t, gethcommon.HexToAddress("0x01"))
+
+	funder, err := m.CreateSessionKey(readUser(t, m, userID), &common.SessionKeyPolicy{SpendCap: (*hexutil.Big)(big.NewInt(400))})
+	require.NoError(t, err)
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	backend.balances[*funder.Account.Address] = big.NewInt(1_000_000)
+	backend.balances[*sk.Account.Address] = big.NewInt(50)
+
+	_, err = m.SetSessionKeyFunding(readUser(t, m, userID), sk.Account.Address, &common.SessionKeyFunding{
+		TopUp: &common.SessionKeyTopUp{From: *sk.Account.Address, Threshold: (*hexutil.Big)(big.NewInt(100)), Amount: (*hexutil.Big)(big.NewInt(300))},
+	})
+	require.ErrorContains(t, err, "can't top up itself")
+	_, err = m.SetSessionKeyFunding(readUser(t, m, userID), sk.Account.Address, &common.SessionKeyFunding{
+		TopUp: &common.SessionKeyTopUp{From: *funder.Account.Address, Threshold: (*hexutil.Big)(big.NewInt(100)), Amount: (*hexutil.Big)(big.NewInt(300))},
+	})
+	require.NoError(t, err)
+
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 1)
+	require.Equal(t, *sk.Account.Address, *backend.sent[0].To())
+	require.Equal(t, big.NewInt(300), backend.sent[0].Value())
+
+	// the second top up would exceed the spend cap of the funding key
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 1)
+	status, err := m.Status(context.Background(), readUser(t, m, userID))
+	require.NoError(t, err)
+	for _, s := range status {
+		if s.Address == *sk.Account.Address {
+			require.Contains(t, s.LastTopUp.Error, "spend cap")
+		}
+	}
+
+	// no top up above the threshold
+	backend.balances[*sk.Account.Address] = big.NewInt(100)
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 1)
+}
+
+func TestSpendIsReleasedWhenTheTransactionIsNotSent(t *testing.T) {
+	m, _, userID := newTestSKManager(t, gethcommon.HexToAddress("0x01"))
+
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), &common.SessionKeyPolicy{SpendCap: (*hexutil.Big)(big.NewInt(400))})
+	require.NoError(t, err)
+	_, err = m.ActivateSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.NoError(t, err)
+
+	to := gethcommon.HexToAddress("0x02")
+	tx := types.NewTx(&types.LegacyTx{GasPrice: testGasPrice, Gas: 21_000, To: &to, Value: big.NewInt(300)})
+	failingSend := func(context.Context, *types.Transaction) (gethcommon.Hash, error) {
+		return gethcommon.Hash{}, errors.New("node unavailable")
+	}
+	_, err = m.SendTx(context.Background(), readUser(t, m, userID), sk.Account.Address, tx, failingSend)
+	require.ErrorContains(t, err, "node unavailable")
+	userSK, err := readUser(t, m, userID).GetSessionKey(sk.Account.Address)
+	require.NoError(t, err)
+	require.Zero(t, userSK.Spent.Sign())
+
+	// the spend of a sent transaction is kept
+	var sent *types.Transaction
+	send := func(_ context.Context, stx *types.Transaction) (gethcommon.Hash, error) {
+		sent = stx
+		return stx.Hash(), nil
+	}
+	txHash, err := m.SendTx(context.Background(), readUser(t, m, userID), sk.Account.Address, tx, send)
+	require.NoError(t, err)
+	require.Equal(t, sent.Hash(), txHash)
+	userSK, err = readUser(t, m, userID).GetSessionKey(sk.Account.Address)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(300), userSK.Spent)
+}
+
+func TestSweepReservesTheEstimatedFee(t *testing.T) {
+	account := gethcommon.HexToAddress("0x01")
+	m, backend, userID := newTestSKManager(t, account)
+
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	backend.balances[*sk.Account.Address] = big.NewInt(1_000_000)
+	// the sweep account is a contract which needs more gas than a plain transfer
+	backend.gas = 30_000
+
+	_, err = m.DeleteSessionKey(readUser(t, m, userID), sk.Account.Address)
+	require.NoError(t, err)
+	require.Len(t, backend.sent, 1)
+	require.Equal(t, uint64(30_000), backend.sent[0].Gas())
+	require.Equal(t, big.NewInt(1_000_000-30_000*10), backend.sent[0].Value())
+}
+
+func TestNoTopUpWhileThePreviousIsPending(t *testing.T) {
+	m, backend, userID := newTestSKManager(t, gethcommon.HexToAddress("0x01"))
+
+	funder, err := m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	sk, err := m.CreateSessionKey(readUser(t, m, userID), nil)
+	require.NoError(t, err)
+	backend.balances[*funder.Account.Address] = big.NewInt(1_000_000)
+	backend.balances[*sk.Account.Address] = big.NewInt(50)
+	_, err = m.SetSessionKeyFunding(readUser(t, m, userID), sk.Account.Address, &common.SessionKeyFunding{
+		TopUp: &common.SessionKeyTopUp{From: *funder.Account.Address, Threshold: (*hexutil.Big)(big.NewInt(100)), Amount: (*hexutil.Big)(big.NewInt(300))},
+	})
+	require.NoError(t, err)
+
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 1)
+
+	// the balance doesn't include the pending top up yet
+	backend.pending[backend.sent[0].Hash()] = true
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 1)
+
+	// once included, the balance is checked again
+	backend.pending[backend.sent[0].Hash()] = false
+	require.True(t, m.manageUserFunds(context.Background(), userID))
+	require.Len(t, backend.sent, 2)
+}
+
+func TestStoredSessionKeysAreTrackedAtStart(t *testing.T) {
+	m, _, userID := newTestSKManager(t, gethcommon.HexToAddress("0x01"))
+	_, err := m.CreateSessionKey(readUser(t, m, userID), &common.SessionKeyPolicy{ExpiresAt: 1})
+	require.NoError(t, err)
+	otherUserID := gethcommon.HexToAddress("0xef01").Bytes()
+	require.NoError(t, m.storage.AddUser(otherUserID, []byte{1}))
+
+	// a new gateway instance, which didn't see the session keys
+	restarted := NewSKManager(m.storage, nil, &common.Config{TenChainID: testChainID}, gethlog.New()).(*skManager)
+	require.NoError(t, restarted.trackStoredUsers())
+	require.Equal(t, map[string]struct{}{string(userID): {}}, restarted.tracked)
+}
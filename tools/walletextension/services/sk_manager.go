@@ -4,100 +4,183 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sync"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
 
 	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/ecies"
 	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ten-protocol/go-ten/go/common/log"
+	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
 	"github.com/ten-protocol/go-ten/go/common/viewingkey"
 	"github.com/ten-protocol/go-ten/tools/walletextension/common"
 	"github.com/ten-protocol/go-ten/tools/walletextension/storage"
 )
 
+// MaxSessionKeysPerUser - the maximum number of session keys a user can create, e.g. one per device or per dApp
+const MaxSessionKeysPerUser = 10
+
 // SKManager - session keys are Private Keys managed by the Gateway
-// At the moment, each user can have a single Session Key. Which is either active or inactive
-// when the SK is active, then all transactions submitted by that user will be signed with the session key
-// The SK is also considered an "Account" of that user
+// Each user can have multiple Session Keys, each of them either active or inactive.
+// An active SK signs the transactions submitted by that user, after checking them against the policy of the key.
+// The balance of a SK is swept back to the user when the SK is deleted or expires, and it can be topped up automatically
+// from another SK of the user.
+// Each SK is also considered an "Account" of that user
 // when the SK is created, it signs over the VK of the user so that it can interact with a node the standard way
 // From the POV of the Ten network - a session key is a normal account key
+//
+// The methods which receive the address of the session key fall back to the only session key of the user when it is nil.
 type SKManager interface {
-	CreateSessionKey(user *common.GWUser) (*common.GWSessionKey, error)
-	ActivateSessionKey(user *common.GWUser) (bool, error)
-	DeactivateSessionKey(user *common.GWUser) (bool, error)
-	DeleteSessionKey(user *common.GWUser) (bool, error)
-	SignTx(ctx context.Context, user *common.GWUser, input *types.Transaction) (*types.Transaction, error)
+	CreateSessionKey(user *common.GWUser, policy *common.SessionKeyPolicy) (*common.GWSessionKey, error)
+	ActivateSessionKey(user *common.GWUser, address *gethcommon.Address) (bool, error)
+	DeactivateSessionKey(user *common.GWUser, address *gethcommon.Address) (bool, error)
+	DeleteSessionKey(user *common.GWUser, address *gethcommon.Address) (bool, error)
+	SetSessionKeyPolicy(user *common.GWUser, address *gethcommon.Address, policy *common.SessionKeyPolicy) (bool, error)
+	SetSessionKeyFunding(user *common.GWUser, address *gethcommon.Address, funding *common.SessionKeyFunding) (bool, error)
+	// Status - returns the session keys of the user with their balances and the last automatic transfers
+	Status(ctx context.Context, user *common.GWUser) ([]common.SessionKeyStatus, error)
+	// SendTx - signs the transaction with the active session key of the user, if the policy of the key allows it, and sends it.
+	// When the user has multiple active session keys, the address of the key must be specified.
+	SendTx(ctx context.Context, user *common.GWUser, address *gethcommon.Address, input *types.Transaction, send SendFunc) (gethcommon.Hash, error)
+	// ManageFunds - sweeps the balance of the expired keys and tops up the keys with a low balance, until the gateway stops
+	ManageFunds(stopControl *stopcontrol.StopControl)
 }
 
+// SendFunc - submits a signed transaction
+type SendFunc func(ctx context.Context, stx *types.Transaction) (gethcommon.Hash, error)
+
 type skManager struct {
 	storage storage.UserStorage
+	backend skFundsBackend
 	config  *common.Config
 	logger  gethlog.Logger
+
+	fundsMu sync.Mutex
+	tracked map[string]struct{}                           // the users with session keys which need automatic transfers
+	events  map[gethcommon.Address]*sessionKeyFundsEvents // the last automatic transfers of each session key
 }
 
-func NewSKManager(storage storage.UserStorage, config *common.Config, logger gethlog.Logger) SKManager {
+func NewSKManager(storage storage.UserStorage, backend *BackendRPC, config *common.Config, logger gethlog.Logger) SKManager {
 	return &skManager{
 		storage: storage,
+		backend: &encRPCFundsBackend{rpc: backend},
 		config:  config,
 		logger:  logger,
+		tracked: make(map[string]struct{}),
+		events:  make(map[gethcommon.Address]*sessionKeyFundsEvents),
 	}
 }
 
 // CreateSessionKey - generates a fresh key and signs over the VK of the user with it
-func (m *skManager) CreateSessionKey(user *common.GWUser) (*common.GWSessionKey, error) {
-	if user.SessionKey != nil {
-		return nil, fmt.Errorf("user already has a session key")
+func (m *skManager) CreateSessionKey(user *common.GWUser, policy *common.SessionKeyPolicy) (*common.GWSessionKey, error) {
+	if len(user.SessionKeys) >= MaxSessionKeysPerUser {
+		return nil, fmt.Errorf("user already has the maximum number of session keys: %d", MaxSessionKeysPerUser)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
 	}
 	sk, err := m.createSK(user)
 	if err != nil {
 		return nil, err
 	}
+	sk.Policy = policy
 	err = m.storage.AddSessionKey(user.ID, *sk)
 	if err != nil {
 		return nil, err
 	}
+	if sk.Policy != nil && sk.Policy.ExpiresAt != 0 {
+		m.track(user.ID)
+	}
 	return sk, nil
 }
 
-func (m *skManager) ActivateSessionKey(user *common.GWUser) (bool, error) {
-	if user.SessionKey == nil {
-		return false, fmt.Errorf("please create a session key")
+func (m *skManager) ActivateSessionKey(user *common.GWUser, address *gethcommon.Address) (bool, error) {
+	sk, err := user.GetSessionKey(address)
+	if err != nil {
+		return false, err
 	}
-	if user.ActiveSK {
+	if sk.Active {
 		return false, fmt.Errorf("session key already activated")
 	}
-	err := m.storage.ActivateSessionKey(user.ID, true)
+	err = m.storage.ActivateSessionKey(user.ID, *sk.Account.Address, true)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func (m *skManager) DeactivateSessionKey(user *common.GWUser) (bool, error) {
-	if user.SessionKey == nil {
-		return false, fmt.Errorf("please create a session key")
+func (m *skManager) DeactivateSessionKey(user *common.GWUser, address *gethcommon.Address) (bool, error) {
+	sk, err := user.GetSessionKey(address)
+	if err != nil {
+		return false, err
 	}
-	if !user.ActiveSK {
+	if !sk.Active {
 		return false, fmt.Errorf("session key is not activated")
 	}
-	err := m.storage.ActivateSessionKey(user.ID, false)
+	err = m.storage.ActivateSessionKey(user.ID, *sk.Account.Address, false)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func (m *skManager) DeleteSessionKey(user *common.GWUser) (bool, error) {
-	if user.SessionKey == nil {
-		return false, fmt.Errorf("please create a session key")
+// DeleteSessionKey - the funds left on the key would be lost after it is removed, so they are swept first.
+// A key with funds is kept, marked as deleted, until the sweep is included. It can't be used in the meantime.
+func (m *skManager) DeleteSessionKey(user *common.GWUser, address *gethcommon.Address) (bool, error) {
+	sk, err := user.GetSessionKey(address)
+	if err != nil {
+		return false, err
 	}
-	if user.ActiveSK {
+	if sk.Active {
 		return false, fmt.Errorf("session key is active. Please deactivate first")
 	}
-	err := m.storage.RemoveSessionKey(user.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), fundsTransferTimeout)
+	defer cancel()
+	if err := m.sweepDeletedKey(ctx, user, sk); err != nil {
+		return false, fmt.Errorf("unable to sweep the funds of the session key: %w", err)
+	}
+	return true, nil
+}
+
+// SetSessionKeyPolicy - replaces the policy of the session key. The value already spent by the key is preserved.
+func (m *skManager) SetSessionKeyPolicy(user *common.GWUser, address *gethcommon.Address, policy *common.SessionKeyPolicy) (bool, error) {
+	sk, err := user.GetSessionKey(address)
+	if err != nil {
+		return false, err
+	}
+	if err := policy.Validate(); err != nil {
+		return false, err
+	}
+	err = m.storage.SetSessionKeyPolicy(user.ID, *sk.Account.Address, policy)
+	if err != nil {
+		return false, err
+	}
+	if policy != nil && policy.ExpiresAt != 0 {
+		m.track(user.ID)
+	}
+	return true, nil
+}
+
+// SetSessionKeyFunding - configures the sweep account and the automatic top up of the session key
+func (m *skManager) SetSessionKeyFunding(user *common.GWUser, address *gethcommon.Address, funding *common.SessionKeyFunding) (bool, error) {
+	sk, err := user.GetSessionKey(address)
+	if err != nil {
+		return false, err
+	}
+	if err := funding.Validate(user, *sk.Account.Address); err != nil {
+		return false, err
+	}
+	err = m.storage.SetSessionKeyFunding(user.ID, *sk.Account.Address, funding)
 	if err != nil {
 		return false, err
 	}
+	if funding != nil && funding.TopUp != nil {
+		m.track(user.ID)
+	}
 	return true, nil
 }
 
@@ -140,8 +223,51 @@ func (m *skManager) createSK(user *common.GWUser) (*common.GWSessionKey, error)
 	}, nil
 }
 
-func (m *skManager) SignTx(ctx context.Context, user *common.GWUser, tx *types.Transaction) (*types.Transaction, error) {
-	prvKey := user.SessionKey.PrivateKey.ExportECDSA()
+func (m *skManager) SendTx(ctx context.Context, user *common.GWUser, address *gethcommon.Address, tx *types.Transaction, send SendFunc) (gethcommon.Hash, error) {
+	sk, err := m.signingKey(user, address)
+	if err != nil {
+		return gethcommon.Hash{}, err
+	}
+	if sk.Funding != nil || sk.Policy != nil {
+		m.track(user.ID)
+	}
+	return m.authorizeAndSend(ctx, user, sk, tx, send)
+}
+
+// authorizeAndSend - signs and sends the transaction if the policy of the key allows it
+func (m *skManager) authorizeAndSend(ctx context.Context, user *common.GWUser, sk *common.GWSessionKey, tx *types.Transaction, send SendFunc) (gethcommon.Hash, error) {
+	if err := sk.Policy.Authorize(tx, sk.Spent, time.Now()); err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("transaction rejected by the session key policy: %w", err)
+	}
+	// the spend is reserved before signing, and the storage checks the cap again
+	// so concurrent transactions can't exceed it. The reservation is released if the transaction is not sent.
+	reserved := sk.Policy != nil && sk.Policy.SpendCap != nil && tx.Value().Sign() > 0
+	if reserved {
+		err := m.storage.AddSessionKeySpend(user.ID, *sk.Account.Address, tx.Value())
+		if err != nil {
+			return gethcommon.Hash{}, fmt.Errorf("transaction rejected by the session key policy: %w", err)
+		}
+	}
+
+	txHash, err := m.signAndSend(ctx, sk, tx, send)
+	if err != nil && reserved {
+		if releaseErr := m.storage.AddSessionKeySpend(user.ID, *sk.Account.Address, new(big.Int).Neg(tx.Value())); releaseErr != nil {
+			m.logger.Error("Could not release the spend of a transaction which was not sent", "sk", sk.Account.Address.Hex(), log.ErrKey, releaseErr)
+		}
+	}
+	return txHash, err
+}
+
+func (m *skManager) signAndSend(ctx context.Context, sk *common.GWSessionKey, tx *types.Transaction, send SendFunc) (gethcommon.Hash, error) {
+	stx, err := m.sign(sk, tx)
+	if err != nil {
+		return gethcommon.Hash{}, err
+	}
+	return send(ctx, stx)
+}
+
+func (m *skManager) sign(sk *common.GWSessionKey, tx *types.Transaction) (*types.Transaction, error) {
+	prvKey := sk.PrivateKey.ExportECDSA()
 	signer := types.NewCancunSigner(big.NewInt(int64(m.config.TenChainID)))
 
 	stx, err := types.SignTx(tx, signer, prvKey)
@@ -149,7 +275,30 @@ func (m *skManager) SignTx(ctx context.Context, user *common.GWUser, tx *types.T
 		return nil, err
 	}
 
-	m.logger.Debug("Signed transaction with session key", "stxHash", stx.Hash().Hex())
+	m.logger.Debug("Signed transaction with session key", "stxHash", stx.Hash().Hex(), "sk", sk.Account.Address.Hex())
 
 	return stx, nil
 }
+
+// signingKey - returns the requested session key, or the only active session key of the user
+func (m *skManager) signingKey(user *common.GWUser, address *gethcommon.Address) (*common.GWSessionKey, error) {
+	if address != nil {
+		sk, err := user.GetSessionKey(address)
+		if err != nil {
+			return nil, err
+		}
+		if !sk.Active {
+			return nil, fmt.Errorf("session key %s is not activated", address.Hex())
+		}
+		return sk, nil
+	}
+	active := user.ActiveSessionKeys()
+	switch len(active) {
+	case 0:
+		return nil, fmt.Errorf("please activate session key")
+	case 1:
+		return active[0], nil
+	default:
+		return nil, fmt.Errorf("the user has multiple active session keys. Please specify the session key address")
+	}
+}
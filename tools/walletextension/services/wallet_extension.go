@@ -30,6 +30,7 @@ import (
 	gethlog "github.com/ethereum/go-ethereum/log"
 	"github.com/ten-protocol/go-ten/go/common/stopcontrol"
 	"github.com/ten-protocol/go-ten/go/common/viewingkey"
+	"github.com/ten-protocol/go-ten/tools/walletextension/audit"
 	"github.com/ten-protocol/go-ten/tools/walletextension/common"
 	"github.com/ten-protocol/go-ten/tools/walletextension/ratelimiter"
 	"github.com/ten-protocol/go-ten/tools/walletextension/storage"
@@ -49,8 +50,11 @@ type Services struct {
 	SKManager           SKManager
 	Config              *common.Config
 	NewHeadsService     *subscriptioncommon.NewHeadsService
+	Filters             *FilterRegistry
+	AccountStates       *AccountStateWatcher
 	cacheInvalidationCh chan *tencommon.BatchHeader
 	MetricsTracker      metrics.Metrics
+	AuditLog            *audit.Logger // the structured audit trail of the requests. Nil when it is disabled
 }
 
 type NewHeadNotifier interface {
@@ -60,7 +64,7 @@ type NewHeadNotifier interface {
 // number of rpc responses to cache
 const rpcResponseCacheSize = 1_000_000
 
-func NewServices(hostAddrHTTP string, hostAddrWS string, storage storage.UserStorage, stopControl *stopcontrol.StopControl, version string, logger gethlog.Logger, metricsTracker metrics.Metrics, config *common.Config) *Services {
+func NewServices(hostAddrHTTP string, hostAddrWS string, userStorage storage.UserStorage, stopControl *stopcontrol.StopControl, version string, logger gethlog.Logger, metricsTracker metrics.Metrics, config *common.Config) *Services {
 	var newGatewayCache cache.Cache
 	var err error
 
@@ -75,22 +79,54 @@ func NewServices(hostAddrHTTP string, hostAddrWS string, storage storage.UserSto
 		newGatewayCache = cache.NewNoOpCache()
 	}
 
-	rateLimiter := ratelimiter.NewRateLimiter(config.RateLimitUserComputeTime, config.RateLimitWindow, uint32(config.RateLimitMaxConcurrentRequests), logger)
+	rateLimitStore := ratelimiter.NewMemoryStore()
+	if config.RateLimitSharedState {
+		rateLimitStore, err = storage.NewRateLimitStore(config.DBType, config.DBConnectionURL)
+		if err != nil {
+			logger.Error(fmt.Errorf("could not create the shared rate limit store. Cause: %w", err).Error())
+			panic(err)
+		}
+	}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimiter.Config{
+		UserComputeTime:       config.RateLimitUserComputeTime,
+		IPComputeTime:         config.RateLimitIPComputeTime,
+		Window:                config.RateLimitWindow,
+		MaxConcurrentRequests: uint32(config.RateLimitMaxConcurrentRequests),
+		MethodWeights:         config.RateLimitMethodWeights,
+	}, rateLimitStore, logger)
+
+	auditLog, err := audit.NewLogger(audit.Config{
+		Path:       config.AuditLogPath,
+		MaxSizeMB:  config.AuditLogMaxSizeMB,
+		MaxBackups: config.AuditLogMaxBackups,
+		Retention:  config.AuditLogRetention,
+		URL:        config.AuditLogURL,
+		Redaction:  audit.Redaction(config.AuditLogRedaction),
+		HashKey:    audit.HashKeyFromSecret(userStorage.GetEncryptionKey()),
+	}, logger)
+	if err != nil {
+		logger.Error(fmt.Errorf("could not create the audit log. Cause: %w", err).Error())
+		panic(err)
+	}
 
+	backendRPC := NewBackendRPC(hostAddrHTTP, hostAddrWS, logger)
 	services := Services{
 		HostAddrHTTP:        hostAddrHTTP,
 		HostAddrWS:          hostAddrWS,
-		Storage:             storage,
+		Storage:             userStorage,
 		logger:              logger,
 		stopControl:         stopControl,
 		version:             version,
 		RPCResponsesCache:   newGatewayCache,
-		BackendRPC:          NewBackendRPC(hostAddrHTTP, hostAddrWS, logger),
-		SKManager:           NewSKManager(storage, config, logger),
+		BackendRPC:          backendRPC,
+		SKManager:           NewSKManager(userStorage, backendRPC, config, logger),
 		RateLimiter:         rateLimiter,
 		Config:              config,
 		cacheInvalidationCh: make(chan *tencommon.BatchHeader),
 		MetricsTracker:      metricsTracker,
+		AuditLog:            auditLog,
+		Filters:             NewFilterRegistry(config.FilterTimeout, logger),
+		AccountStates:       NewAccountStateWatcher(backendRPC, newGatewayCache, stopControl, logger),
 	}
 
 	services.NewHeadsService = subscriptioncommon.NewNewHeadsService(
@@ -106,11 +142,14 @@ func NewServices(hostAddrHTTP string, hostAddrWS string, storage storage.UserSto
 		true,
 		logger,
 		func(newHead *tencommon.BatchHeader) error {
+			services.Filters.OnNewHead(newHead)
 			services.cacheInvalidationCh <- newHead
 			return nil
 		})
 
 	go _startCacheEviction(&services, logger)
+	go services.Filters.evictIdleFilters(stopControl)
+	go services.SKManager.ManageFunds(stopControl)
 	return &services
 }
 
@@ -124,7 +163,10 @@ func _startCacheEviction(services *Services, logger gethlog.Logger) {
 
 	for {
 		select {
-		case <-services.cacheInvalidationCh:
+		case _, ok := <-services.cacheInvalidationCh:
+			if !ok {
+				return
+			}
 			services.RPCResponsesCache.EvictShortLiving()
 
 			timer.Stop()
@@ -329,4 +371,7 @@ func (w *Services) GenerateUserMessageToSign(encryptionToken []byte, formatsSlic
 func (w *Services) Stop() {
 	w.BackendRPC.Stop()
 	close(w.cacheInvalidationCh)
+	if err := w.AuditLog.Close(); err != nil {
+		w.logger.Error("could not close the audit log", log.ErrKey, err)
+	}
 }
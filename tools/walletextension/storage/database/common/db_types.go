@@ -3,6 +3,8 @@ package common
 import (
 	"errors"
 	"fmt"
+	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 
@@ -12,14 +14,35 @@ import (
 	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
 )
 
-var ErrUserNotFound = errors.New("user not found")
+// UsersPageSize - the number of users read at once when all the users are streamed
+const UsersPageSize = 100
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrSessionKeyNotFound = errors.New("session key not found")
+)
+
+// UndecryptableUsersError - returned by ForEachUser after streaming the other users, when some users could not be decrypted.
+// These users were written with a different encryption key, or are corrupted.
+type UndecryptableUsersError struct {
+	Count int
+}
+
+func (e *UndecryptableUsersError) Error() string {
+	return fmt.Sprintf("%d users could not be decrypted with the encryption key of the storage", e.Count)
+}
 
 type GWUserDB struct {
-	UserId     []byte          `json:"userId"`
-	PrivateKey []byte          `json:"privateKey"`
-	Accounts   []GWAccountDB   `json:"accounts"`
-	SessionKey *GWSessionKeyDB `json:"sessionKey"`
-	ActiveSK   bool            `json:"activeSK"`
+	UserId      []byte           `json:"userId"`
+	PrivateKey  []byte           `json:"privateKey"`
+	Accounts    []GWAccountDB    `json:"accounts"`
+	SessionKeys []GWSessionKeyDB `json:"sessionKeys,omitempty"`
+	APIKeys     []GWAPIKeyDB     `json:"apiKeys,omitempty"`
+
+	// the single session key stored before multiple session keys were supported.
+	// It is moved to SessionKeys the next time the user is updated.
+	SessionKey *GWSessionKeyDB `json:"sessionKey,omitempty"`
+	ActiveSK   bool            `json:"activeSK,omitempty"`
 }
 
 type GWAccountDB struct {
@@ -30,16 +53,157 @@ type GWAccountDB struct {
 
 // GWSessionKeyDB - an account key-pair registered for a user
 type GWSessionKeyDB struct {
-	PrivateKey []byte      `json:"privateKey"`
-	Account    GWAccountDB `json:"account"`
+	PrivateKey []byte                            `json:"privateKey"`
+	Account    GWAccountDB                       `json:"account"`
+	Active     bool                              `json:"active"`
+	Policy     *wecommon.SessionKeyPolicy        `json:"policy,omitempty"`
+	Spent      []byte                            `json:"spent,omitempty"`
+	Funding    *wecommon.SessionKeyFunding       `json:"funding,omitempty"`
+	Sweep      *wecommon.SessionKeyFundsTransfer `json:"sweep,omitempty"`
+}
+
+// GWAPIKeyDB - an API key of the user. The revoked keys are kept, so the user can audit them
+type GWAPIKeyDB struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	Hash      []byte   `json:"hash"`
+	CreatedAt int64    `json:"createdAt"`           // unix seconds
+	RevokedAt *int64   `json:"revokedAt,omitempty"` // unix seconds
+}
+
+func NewGWAPIKeyDB(key wecommon.GWAPIKey) GWAPIKeyDB {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+	var revokedAt *int64
+	if key.RevokedAt != nil {
+		revoked := key.RevokedAt.Unix()
+		revokedAt = &revoked
+	}
+	return GWAPIKeyDB{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    scopes,
+		Hash:      key.Hash,
+		CreatedAt: key.CreatedAt.Unix(),
+		RevokedAt: revokedAt,
+	}
+}
+
+func NewGWSessionKeyDB(key wecommon.GWSessionKey) GWSessionKeyDB {
+	var spent []byte
+	if key.Spent != nil {
+		spent = key.Spent.Bytes()
+	}
+	return GWSessionKeyDB{
+		PrivateKey: crypto.FromECDSA(key.PrivateKey.ExportECDSA()),
+		Account: GWAccountDB{
+			AccountAddress: key.Account.Address.Bytes(),
+			Signature:      key.Account.Signature,
+			SignatureType:  int(key.Account.SignatureType),
+		},
+		Active:  key.Active,
+		Policy:  key.Policy,
+		Spent:   spent,
+		Funding: key.Funding,
+		Sweep:   key.Sweep,
+	}
+}
+
+// AddSessionKey - adds or replaces the session key with the same address
+func (userDB *GWUserDB) AddSessionKey(key GWSessionKeyDB) {
+	userDB.migrateLegacySessionKey()
+	for i := range userDB.SessionKeys {
+		if common.BytesToAddress(userDB.SessionKeys[i].Account.AccountAddress) == common.BytesToAddress(key.Account.AccountAddress) {
+			userDB.SessionKeys[i] = key
+			return
+		}
+	}
+	userDB.SessionKeys = append(userDB.SessionKeys, key)
+}
+
+func (userDB *GWUserDB) RemoveSessionKey(address common.Address) error {
+	userDB.migrateLegacySessionKey()
+	for i := range userDB.SessionKeys {
+		if common.BytesToAddress(userDB.SessionKeys[i].Account.AccountAddress) == address {
+			userDB.SessionKeys = append(userDB.SessionKeys[:i], userDB.SessionKeys[i+1:]...)
+			return nil
+		}
+	}
+	return ErrSessionKeyNotFound
+}
+
+// UpdateSessionKey - applies the mutation to the session key with the given address
+func (userDB *GWUserDB) UpdateSessionKey(address common.Address, mutate func(*GWSessionKeyDB) error) error {
+	userDB.migrateLegacySessionKey()
+	for i := range userDB.SessionKeys {
+		if common.BytesToAddress(userDB.SessionKeys[i].Account.AccountAddress) == address {
+			return mutate(&userDB.SessionKeys[i])
+		}
+	}
+	return ErrSessionKeyNotFound
+}
+
+// AddSpend - records the value transferred by a transaction signed with the key.
+// Returns ErrSpendCapExceeded if the total would exceed the spend cap of the policy.
+func (sk *GWSessionKeyDB) AddSpend(amount *big.Int) error {
+	spent := new(big.Int).Add(new(big.Int).SetBytes(sk.Spent), amount)
+	if spent.Sign() < 0 {
+		// a released spend can't go below zero
+		spent.SetUint64(0)
+	}
+	if sk.Policy != nil && sk.Policy.SpendCap != nil && spent.Cmp(sk.Policy.SpendCap.ToInt()) > 0 {
+		return wecommon.ErrSpendCapExceeded
+	}
+	sk.Spent = spent.Bytes()
+	return nil
+}
+
+// AddAPIKey - adds or replaces the API key with the same id
+func (userDB *GWUserDB) AddAPIKey(key GWAPIKeyDB) {
+	for i := range userDB.APIKeys {
+		if userDB.APIKeys[i].ID == key.ID {
+			userDB.APIKeys[i] = key
+			return
+		}
+	}
+	userDB.APIKeys = append(userDB.APIKeys, key)
+}
+
+// RevokeAPIKey - marks the API key as revoked and returns its hash. Revoking a key twice keeps the first revocation time
+func (userDB *GWUserDB) RevokeAPIKey(id string, revokedAt time.Time) ([]byte, error) {
+	for i := range userDB.APIKeys {
+		if userDB.APIKeys[i].ID == id {
+			if userDB.APIKeys[i].RevokedAt == nil {
+				revoked := revokedAt.Unix()
+				userDB.APIKeys[i].RevokedAt = &revoked
+			}
+			return userDB.APIKeys[i].Hash, nil
+		}
+	}
+	return nil, wecommon.ErrAPIKeyNotFound
+}
+
+func (userDB *GWUserDB) migrateLegacySessionKey() {
+	if userDB.SessionKey == nil {
+		return
+	}
+	legacy := *userDB.SessionKey
+	legacy.Active = userDB.ActiveSK
+	userDB.SessionKeys = append(userDB.SessionKeys, legacy)
+	userDB.SessionKey = nil
+	userDB.ActiveSK = false
 }
 
 func (userDB *GWUserDB) ToGWUser() (*wecommon.GWUser, error) {
 	user := &wecommon.GWUser{
-		ID:       userDB.UserId,
-		Accounts: make(map[common.Address]*wecommon.GWAccount),
-		UserKey:  userDB.PrivateKey,
-		ActiveSK: userDB.ActiveSK,
+		ID:          userDB.UserId,
+		Accounts:    make(map[common.Address]*wecommon.GWAccount),
+		UserKey:     userDB.PrivateKey,
+		SessionKeys: make(map[common.Address]*wecommon.GWSessionKey),
+		APIKeys:     make(map[string]*wecommon.GWAPIKey),
 	}
 
 	for _, accountDB := range userDB.Accounts {
@@ -53,23 +217,49 @@ func (userDB *GWUserDB) ToGWUser() (*wecommon.GWUser, error) {
 		user.Accounts[address] = &gwAccount
 	}
 
-	if userDB.SessionKey != nil {
-		ecdsaPrivateKey, err := crypto.ToECDSA(userDB.SessionKey.PrivateKey)
+	userDB.migrateLegacySessionKey()
+	for _, skDB := range userDB.SessionKeys {
+		ecdsaPrivateKey, err := crypto.ToECDSA(skDB.PrivateKey)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse ECDSA private key: %w", err)
 		}
 
 		// Convert ECDSA private key to ECIES private key
 		eciesPrivateKey := ecies.ImportECDSA(ecdsaPrivateKey)
-		acc := userDB.SessionKey.Account
-		user.SessionKey = &wecommon.GWSessionKey{
+		address := common.BytesToAddress(skDB.Account.AccountAddress)
+		user.SessionKeys[address] = &wecommon.GWSessionKey{
 			Account: &wecommon.GWAccount{
 				User:          user,
-				Address:       (*common.Address)(acc.AccountAddress),
-				Signature:     acc.Signature,
-				SignatureType: viewingkey.SignatureType(acc.SignatureType),
+				Address:       &address,
+				Signature:     skDB.Account.Signature,
+				SignatureType: viewingkey.SignatureType(skDB.Account.SignatureType),
 			},
 			PrivateKey: eciesPrivateKey,
+			Active:     skDB.Active,
+			Policy:     skDB.Policy,
+			Spent:      new(big.Int).SetBytes(skDB.Spent),
+			Funding:    skDB.Funding,
+			Sweep:      skDB.Sweep,
+		}
+	}
+
+	for _, keyDB := range userDB.APIKeys {
+		scopes := make([]wecommon.APIKeyScope, len(keyDB.Scopes))
+		for i, scope := range keyDB.Scopes {
+			scopes[i] = wecommon.APIKeyScope(scope)
+		}
+		var revokedAt *time.Time
+		if keyDB.RevokedAt != nil {
+			revoked := time.Unix(*keyDB.RevokedAt, 0)
+			revokedAt = &revoked
+		}
+		user.APIKeys[keyDB.ID] = &wecommon.GWAPIKey{
+			ID:        keyDB.ID,
+			Name:      keyDB.Name,
+			Scopes:    scopes,
+			Hash:      keyDB.Hash,
+			CreatedAt: time.Unix(keyDB.CreatedAt, 0),
+			RevokedAt: revokedAt,
 		}
 	}
 
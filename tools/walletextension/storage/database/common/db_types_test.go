@@ -0,0 +1,48 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// the users stored before multiple session keys were supported have a single "sessionKey" field
+func TestLegacySessionKeyIsMigrated(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	legacyJSON, err := json.Marshal(map[string]any{
+		"userId":     []byte{1},
+		"privateKey": []byte{2},
+		"accounts":   []GWAccountDB{},
+		"sessionKey": map[string]any{
+			"privateKey": crypto.FromECDSA(prvKey),
+			"account":    GWAccountDB{AccountAddress: address.Bytes()},
+		},
+		"activeSK": true,
+	})
+	require.NoError(t, err)
+
+	var userDB GWUserDB
+	require.NoError(t, json.Unmarshal(legacyJSON, &userDB))
+	user, err := userDB.ToGWUser()
+	require.NoError(t, err)
+	require.Len(t, user.SessionKeys, 1)
+	require.True(t, user.SessionKeys[address].Active)
+	require.Nil(t, user.SessionKeys[address].Policy)
+
+	// the legacy fields are not written back
+	require.NoError(t, userDB.UpdateSessionKey(address, func(sk *GWSessionKeyDB) error {
+		sk.Active = false
+		return nil
+	}))
+	updatedJSON, err := json.Marshal(userDB)
+	require.NoError(t, err)
+	require.NotContains(t, string(updatedJSON), `"sessionKey"`)
+	require.NotContains(t, string(updatedJSON), `"activeSK"`)
+	require.ErrorIs(t, userDB.RemoveSessionKey(common.Address{}), ErrSessionKeyNotFound)
+}
@@ -0,0 +1,180 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultDaysToRetain - the number of days of activity statistics kept by default
+const DefaultDaysToRetain = 90
+
+// GlobalMetricsDocument contains global metrics without user activity data
+type GlobalMetricsDocument struct {
+	ID                 string `json:"id"`
+	TotalUsers         uint64 `json:"totalUsers"`
+	AccountsRegistered uint64 `json:"accountsRegistered"`
+	ActiveUsersCount   int    `json:"activeUsersCount"`
+	LastUpdated        string `json:"lastUpdated"`
+	ShardCount         int    `json:"shardCount"` // Number of shards being used
+}
+
+// UserShardDocument contains activity data for a subset of users
+type UserShardDocument struct {
+	ID          string            `json:"id"`
+	ActiveUsers map[string]string `json:"activeUsers"` // double-hashed userID -> ISO timestamp
+	ShardIndex  int               `json:"shardIndex"`
+	LastUpdated string            `json:"lastUpdated"`
+}
+
+// DailyStats represents a single day's statistics
+type DailyStats struct {
+	Date          string `json:"date"`          // ISO date format YYYY-MM-DD
+	DailyActive   int    `json:"dailyActive"`   // Users active on this day
+	WeeklyActive  int    `json:"weeklyActive"`  // Users active in the past 7 days from this date
+	MonthlyActive int    `json:"monthlyActive"` // Users active in the past 30 days from this date
+}
+
+// ActivityStatsDocument contains historical user activity statistics
+type ActivityStatsDocument struct {
+	ID           string       `json:"id"`
+	LastUpdated  string       `json:"lastUpdated"`
+	DailyStats   []DailyStats `json:"dailyStats"`
+	DaysToRetain int          `json:"daysToRetain"` // How many days of history to keep
+}
+
+// UpdateDailyStats adds or replaces the entry of the current day, using countActiveUsers to count the users active since a given time
+func (stats *ActivityStatsDocument) UpdateDailyStats(now time.Time, countActiveUsers func(time.Time) (int, error)) error {
+	// Get current date in ISO format (YYYY-MM-DD)
+	today := now.Format("2006-01-02")
+
+	// Calculate time thresholds
+	dailyStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	weeklyStart := dailyStart.AddDate(0, 0, -7)
+	monthlyStart := dailyStart.AddDate(0, 0, -30)
+
+	// Count daily active users
+	dailyCount, err := countActiveUsers(dailyStart)
+	if err != nil {
+		return fmt.Errorf("failed to count daily active users: %w", err)
+	}
+
+	// Count weekly active users
+	weeklyCount, err := countActiveUsers(weeklyStart)
+	if err != nil {
+		return fmt.Errorf("failed to count weekly active users: %w", err)
+	}
+
+	// Count monthly active users
+	monthlyCount, err := countActiveUsers(monthlyStart)
+	if err != nil {
+		return fmt.Errorf("failed to count monthly active users: %w", err)
+	}
+
+	// Create today's stats
+	todayStats := DailyStats{
+		Date:          today,
+		DailyActive:   dailyCount,
+		WeeklyActive:  weeklyCount,
+		MonthlyActive: monthlyCount,
+	}
+
+	// Check if we already have an entry for today
+	found := false
+	for i, stat := range stats.DailyStats {
+		if stat.Date == today {
+			// Update existing entry
+			stats.DailyStats[i] = todayStats
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		// Add new entry
+		stats.DailyStats = append(stats.DailyStats, todayStats)
+	}
+
+	// Sort entries by date (newest first)
+	sort.Slice(stats.DailyStats, func(i, j int) bool {
+		return stats.DailyStats[i].Date > stats.DailyStats[j].Date
+	})
+
+	// Trim old entries if needed
+	if len(stats.DailyStats) > stats.DaysToRetain {
+		stats.DailyStats = stats.DailyStats[:stats.DaysToRetain]
+	}
+
+	return nil
+}
+
+// MetricsStorage interface defines the metrics storage operations
+type MetricsStorage interface {
+	LoadGlobalMetrics() (*GlobalMetricsDocument, error)
+	SaveGlobalMetrics(*GlobalMetricsDocument) error
+	LoadUserShard(string) (*UserShardDocument, error)
+	SaveUserShard(*UserShardDocument) error
+	LoadAllShards() ([]*UserShardDocument, error)
+	UpdateUserActivity(string, time.Time) error
+	GetUserActivityTimestamp(string) (time.Time, bool, error)
+	CountActiveUsers(time.Time) (int, error)
+	ResizeShards(int) error
+	LoadActivityStats() (*ActivityStatsDocument, error)
+	SaveActivityStats(*ActivityStatsDocument) error
+	UpdateDailyStats() error
+}
+
+// NoOpMetricsStorage is a no-op implementation of metrics storage
+type noOpMetricsStorage struct{}
+
+func NewNoOpMetricsStorage() MetricsStorage {
+	return &noOpMetricsStorage{}
+}
+
+func (n *noOpMetricsStorage) LoadGlobalMetrics() (*GlobalMetricsDocument, error) {
+	return &GlobalMetricsDocument{}, nil
+}
+
+func (n *noOpMetricsStorage) SaveGlobalMetrics(*GlobalMetricsDocument) error {
+	return nil
+}
+
+func (n *noOpMetricsStorage) LoadUserShard(string) (*UserShardDocument, error) {
+	return &UserShardDocument{ActiveUsers: make(map[string]string)}, nil
+}
+
+func (n *noOpMetricsStorage) SaveUserShard(*UserShardDocument) error {
+	return nil
+}
+
+func (n *noOpMetricsStorage) LoadAllShards() ([]*UserShardDocument, error) {
+	return []*UserShardDocument{}, nil
+}
+
+func (n *noOpMetricsStorage) UpdateUserActivity(string, time.Time) error {
+	return nil
+}
+
+func (n *noOpMetricsStorage) GetUserActivityTimestamp(string) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
+
+func (n *noOpMetricsStorage) CountActiveUsers(time.Time) (int, error) {
+	return 0, nil
+}
+
+func (n *noOpMetricsStorage) ResizeShards(int) error {
+	return nil
+}
+
+func (n *noOpMetricsStorage) LoadActivityStats() (*ActivityStatsDocument, error) {
+	return &ActivityStatsDocument{DailyStats: []DailyStats{}}, nil
+}
+
+func (n *noOpMetricsStorage) SaveActivityStats(*ActivityStatsDocument) error {
+	return nil
+}
+
+func (n *noOpMetricsStorage) UpdateDailyStats() error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateDailyStats(t *testing.T) {
+	now := time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC)
+	dayStart := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	counts := map[time.Time]int{
+		dayStart:                    1,
+		dayStart.AddDate(0, 0, -7):  5,
+		dayStart.AddDate(0, 0, -30): 20,
+	}
+	countActiveUsers := func(since time.Time) (int, error) {
+		return counts[since], nil
+	}
+
+	stats := &ActivityStatsDocument{
+		DailyStats:   []DailyStats{{Date: "2025-03-08"}, {Date: "2025-03-09"}, {Date: "2025-03-10"}},
+		DaysToRetain: 2,
+	}
+	require.NoError(t, stats.UpdateDailyStats(now, countActiveUsers))
+
+	// the entry of the day is replaced, the newest entries are first and the oldest are dropped
+	require.Equal(t, []DailyStats{
+		{Date: "2025-03-10", DailyActive: 1, WeeklyActive: 5, MonthlyActive: 20},
+		{Date: "2025-03-09"},
+	}, stats.DailyStats)
+}
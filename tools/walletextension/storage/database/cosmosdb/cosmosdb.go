@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
 
 	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
 
@@ -31,15 +35,17 @@ Quick summary of the CosmosDB setup:
 - use Serverless capacity mode for testnets
 - go to "Data Explorer" in the CosmosDB account and create new database named "gatewayDB"
 - inside the database create a container named "users" with partition key of "/id"
+- create a container named "apiKeys" with partition key of "/id" (the index of the valid API keys)
 - to get your connection string go to settings -> keys -> primary connection string
 
 */
 
 // CosmosDB struct represents the CosmosDB storage implementation
 type CosmosDB struct {
-	client         *azcosmos.Client
-	usersContainer *azcosmos.ContainerClient
-	encryptor      encryption.Encryptor
+	client           *azcosmos.Client
+	usersContainer   *azcosmos.ContainerClient
+	apiKeysContainer *azcosmos.ContainerClient
+	encryptor        encryption.Encryptor
 }
 
 // EncryptedDocument struct is used to store encrypted user data in CosmosDB
@@ -53,8 +59,9 @@ type EncryptedDocument struct {
 
 // Constants for the CosmosDB database and container names
 const (
-	DATABASE_NAME        = "gatewayDB"
-	USERS_CONTAINER_NAME = "users"
+	DATABASE_NAME           = "gatewayDB"
+	USERS_CONTAINER_NAME    = "users"
+	API_KEYS_CONTAINER_NAME = "apiKeys"
 )
 
 // userWithETag struct is used to store the user data along with its ETag
@@ -90,10 +97,17 @@ func NewCosmosDB(connectionString string, encryptionKey []byte) (*CosmosDB, erro
 		return nil, fmt.Errorf("failed to create users container: %w", err)
 	}
 
+	// the index of the API keys maps the HMAC of the hash of a key to the encrypted id of its user
+	apiKeysContainer, err := client.NewContainer(DATABASE_NAME, API_KEYS_CONTAINER_NAME)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API keys container: %w", err)
+	}
+
 	return &CosmosDB{
-		client:         client,
-		usersContainer: usersContainer,
-		encryptor:      *encryptor,
+		client:           client,
+		usersContainer:   usersContainer,
+		apiKeysContainer: apiKeysContainer,
+		encryptor:        *encryptor,
 	}, nil
 }
 
@@ -133,33 +147,71 @@ func (c *CosmosDB) DeleteUser(userID []byte) error {
 func (c *CosmosDB) AddSessionKey(userID []byte, key common.GWSessionKey) error {
 	ctx := context.Background()
 	return c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
-		u.SessionKey = &dbcommon.GWSessionKeyDB{
-			PrivateKey: crypto.FromECDSA(key.PrivateKey.ExportECDSA()),
-			Account: dbcommon.GWAccountDB{
-				AccountAddress: key.Account.Address.Bytes(),
-				Signature:      key.Account.Signature,
-				SignatureType:  int(key.Account.SignatureType),
-			},
-		}
+		u.AddSessionKey(dbcommon.NewGWSessionKeyDB(key))
 		return nil
 	})
 }
 
-// Sets the ActiveSK flag for the user, with retries on ETag mismatch
-func (c *CosmosDB) ActivateSessionKey(userID []byte, active bool) error {
+// Sets the Active flag of the session key, with retries on ETag mismatch
+func (c *CosmosDB) ActivateSessionKey(userID []byte, address gethcommon.Address, active bool) error {
 	ctx := context.Background()
 	return c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
-		u.ActiveSK = active
-		return nil
+		return u.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Active = active
+			return nil
+		})
 	})
 }
 
-// Removes the session key for the user, with retries on ETag mismatch
-func (c *CosmosDB) RemoveSessionKey(userID []byte) error {
+// Replaces the policy of the session key, with retries on ETag mismatch
+func (c *CosmosDB) SetSessionKeyPolicy(userID []byte, address gethcommon.Address, policy *common.SessionKeyPolicy) error {
 	ctx := context.Background()
 	return c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
-		u.SessionKey = nil
-		return nil
+		return u.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Policy = policy
+			return nil
+		})
+	})
+}
+
+// Replaces the funding configuration of the session key, with retries on ETag mismatch
+func (c *CosmosDB) SetSessionKeyFunding(userID []byte, address gethcommon.Address, funding *common.SessionKeyFunding) error {
+	ctx := context.Background()
+	return c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
+		return u.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Funding = funding
+			return nil
+		})
+	})
+}
+
+// Records the value spent by the session key, with retries on ETag mismatch.
+// The spend cap is checked against the latest version of the user, so concurrent transactions can't exceed it.
+func (c *CosmosDB) AddSessionKeySpend(userID []byte, address gethcommon.Address, amount *big.Int) error {
+	ctx := context.Background()
+	return c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
+		return u.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			return sk.AddSpend(amount)
+		})
+	})
+}
+
+// Marks the session key of the user as deleted, with retries on ETag mismatch
+func (c *CosmosDB) SetSessionKeySweep(userID []byte, address gethcommon.Address, sweep *common.SessionKeyFundsTransfer) error {
+	ctx := context.Background()
+	return c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
+		return u.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Sweep = sweep
+			return nil
+		})
+	})
+}
+
+// Removes the session key of the user, with retries on ETag mismatch
+func (c *CosmosDB) RemoveSessionKey(userID []byte, address gethcommon.Address) error {
+	ctx := context.Background()
+	return c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
+		return u.RemoveSessionKey(address)
 	})
 }
 
@@ -177,6 +229,85 @@ func (c *CosmosDB) AddAccount(userID []byte, accountAddress []byte, signature []
 	})
 }
 
+// AddAPIKey stores the key with the user, then indexes it by its hash
+func (c *CosmosDB) AddAPIKey(userID []byte, key common.GWAPIKey) error {
+	ctx := context.Background()
+	err := c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
+		u.AddAPIKey(dbcommon.NewGWAPIKeyDB(key))
+		return nil
+	})
+	if err != nil || key.IsRevoked() {
+		return err
+	}
+
+	encryptedUserID, err := c.encryptor.Encrypt(userID)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt user id: %w", err)
+	}
+	keyString, partitionKey := c.dbKey(key.Hash)
+	docJSON, err := json.Marshal(EncryptedDocument{ID: keyString, Data: encryptedUserID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+	_, err = c.apiKeysContainer.UpsertItem(ctx, partitionKey, docJSON, nil)
+	if err != nil {
+		return fmt.Errorf("failed to index API key: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey marks the key as revoked in the user, then removes it from the index.
+// The revoked keys are rejected even if they are still in the index, because the user record is checked after the lookup.
+func (c *CosmosDB) RevokeAPIKey(userID []byte, keyID string, revokedAt time.Time) error {
+	ctx := context.Background()
+	var hash []byte
+	err := c.updateUserWithRetries(ctx, userID, func(u *dbcommon.GWUserDB) error {
+		var err error
+		hash, err = u.RevokeAPIKey(keyID, revokedAt)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	keyString, partitionKey := c.dbKey(hash)
+	_, err = c.apiKeysContainer.DeleteItem(ctx, partitionKey, keyString, nil)
+	if err != nil && !strings.Contains(err.Error(), "404") {
+		return fmt.Errorf("failed to remove API key from the index: %w", err)
+	}
+	return nil
+}
+
+func (c *CosmosDB) GetUserByAPIKey(keyHash []byte) (*common.GWUser, error) {
+	keyString, partitionKey := c.dbKey(keyHash)
+	itemResponse, err := c.apiKeysContainer.ReadItem(context.Background(), partitionKey, keyString, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "404") {
+			return nil, common.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	var doc EncryptedDocument
+	if err := json.Unmarshal(itemResponse.Value, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+	userID, err := c.encryptor.Decrypt(doc.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+
+	user, err := c.getUserDB(userID)
+	if err != nil {
+		if errors.Is(err, dbcommon.ErrUserNotFound) {
+			// the user was deleted after the key was indexed
+			return nil, common.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return user.user.ToGWUser()
+}
+
 func (c *CosmosDB) GetUser(userID []byte) (*common.GWUser, error) {
 	user, err := c.getUserDB(userID)
 	if err != nil {
@@ -185,6 +316,48 @@ func (c *CosmosDB) GetUser(userID []byte) (*common.GWUser, error) {
 	return user.user.ToGWUser()
 }
 
+// ForEachUser streams all the users with a cross-partition query.
+// The documents which can't be decrypted are counted and reported with an UndecryptableUsersError once the other users were streamed.
+func (c *CosmosDB) ForEachUser(fn func(*common.GWUser) error) error {
+	ctx := context.Background()
+	undecryptable := 0
+	pager := c.usersContainer.NewQueryItemsPager("SELECT * FROM c", azcosmos.NewPartitionKey(), &azcosmos.QueryOptions{
+		PageSizeHint: dbcommon.UsersPageSize,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to query users: %w", err)
+		}
+		for _, item := range page.Items {
+			var doc EncryptedDocument
+			if err := json.Unmarshal(item, &doc); err != nil {
+				return fmt.Errorf("failed to unmarshal document: %w", err)
+			}
+			data, err := c.encryptor.Decrypt(doc.Data)
+			if err != nil {
+				undecryptable++
+				continue
+			}
+			var user dbcommon.GWUserDB
+			if err := json.Unmarshal(data, &user); err != nil {
+				return fmt.Errorf("failed to unmarshal user data: %w", err)
+			}
+			gwUser, err := user.ToGWUser()
+			if err != nil {
+				return err
+			}
+			if err := fn(gwUser); err != nil {
+				return err
+			}
+		}
+	}
+	if undecryptable > 0 {
+		return &dbcommon.UndecryptableUsersError{Count: undecryptable}
+	}
+	return nil
+}
+
 func (c *CosmosDB) getUserDB(userID []byte) (userWithETag, error) {
 	keyString, partitionKey := c.dbKey(userID)
 
@@ -5,11 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
 )
 
 const (
@@ -20,40 +20,6 @@ const (
 	DEFAULT_SHARD_COUNT    = 50 // Number of shards to distribute users across
 )
 
-// GlobalMetricsDocument contains global metrics without user activity data
-type GlobalMetricsDocument struct {
-	ID                 string `json:"id"`
-	TotalUsers         uint64 `json:"totalUsers"`
-	AccountsRegistered uint64 `json:"accountsRegistered"`
-	ActiveUsersCount   int    `json:"activeUsersCount"`
-	LastUpdated        string `json:"lastUpdated"`
-	ShardCount         int    `json:"shardCount"` // Number of shards being used
-}
-
-// UserShardDocument contains activity data for a subset of users
-type UserShardDocument struct {
-	ID          string            `json:"id"`
-	ActiveUsers map[string]string `json:"activeUsers"` // double-hashed userID -> ISO timestamp
-	ShardIndex  int               `json:"shardIndex"`
-	LastUpdated string            `json:"lastUpdated"`
-}
-
-// DailyStats represents a single day's statistics
-type DailyStats struct {
-	Date          string `json:"date"`          // ISO date format YYYY-MM-DD
-	DailyActive   int    `json:"dailyActive"`   // Users active on this day
-	WeeklyActive  int    `json:"weeklyActive"`  // Users active in the past 7 days from this date
-	MonthlyActive int    `json:"monthlyActive"` // Users active in the past 30 days from this date
-}
-
-// ActivityStatsDocument contains historical user activity statistics
-type ActivityStatsDocument struct {
-	ID           string       `json:"id"`
-	LastUpdated  string       `json:"lastUpdated"`
-	DailyStats   []DailyStats `json:"dailyStats"`
-	DaysToRetain int          `json:"daysToRetain"` // How many days of history to keep
-}
-
 // MetricsStorageCosmosDB handles metrics persistence in CosmosDB
 type MetricsStorageCosmosDB struct {
 	client           *azcosmos.Client
@@ -105,7 +71,7 @@ func (m *MetricsStorageCosmosDB) getShardIndexFromID(shardID string) int {
 }
 
 // LoadGlobalMetrics loads the global metrics document
-func (m *MetricsStorageCosmosDB) LoadGlobalMetrics() (*GlobalMetricsDocument, error) {
+func (m *MetricsStorageCosmosDB) LoadGlobalMetrics() (*dbcommon.GlobalMetricsDocument, error) {
 	ctx := context.Background()
 	partitionKey := azcosmos.NewPartitionKeyString(METRICS_DOC_ID)
 
@@ -113,7 +79,7 @@ func (m *MetricsStorageCosmosDB) LoadGlobalMetrics() (*GlobalMetricsDocument, er
 	if err != nil {
 		if strings.Contains(err.Error(), "NotFound") {
 			// Initialize with empty global metrics if not found
-			return &GlobalMetricsDocument{
+			return &dbcommon.GlobalMetricsDocument{
 				ID:         METRICS_DOC_ID,
 				ShardCount: m.shardCount,
 			}, nil
@@ -121,7 +87,7 @@ func (m *MetricsStorageCosmosDB) LoadGlobalMetrics() (*GlobalMetricsDocument, er
 		return nil, err
 	}
 
-	var doc GlobalMetricsDocument
+	var doc dbcommon.GlobalMetricsDocument
 	if err := json.Unmarshal(response.Value, &doc); err != nil {
 		return nil, err
 	}
@@ -135,7 +101,7 @@ func (m *MetricsStorageCosmosDB) LoadGlobalMetrics() (*GlobalMetricsDocument, er
 }
 
 // LoadUserShard loads a specific user shard document
-func (m *MetricsStorageCosmosDB) LoadUserShard(shardID string) (*UserShardDocument, error) {
+func (m *MetricsStorageCosmosDB) LoadUserShard(shardID string) (*dbcommon.UserShardDocument, error) {
 	ctx := context.Background()
 	partitionKey := azcosmos.NewPartitionKeyString(shardID)
 
@@ -144,7 +110,7 @@ func (m *MetricsStorageCosmosDB) LoadUserShard(shardID string) (*UserShardDocume
 		if strings.Contains(err.Error(), "NotFound") {
 			// Initialize empty shard if not found
 			shardIndex := m.getShardIndexFromID(shardID)
-			return &UserShardDocument{
+			return &dbcommon.UserShardDocument{
 				ID:          shardID,
 				ActiveUsers: make(map[string]string),
 				ShardIndex:  shardIndex,
@@ -153,7 +119,7 @@ func (m *MetricsStorageCosmosDB) LoadUserShard(shardID string) (*UserShardDocume
 		return nil, err
 	}
 
-	var doc UserShardDocument
+	var doc dbcommon.UserShardDocument
 	if err := json.Unmarshal(response.Value, &doc); err != nil {
 		return nil, err
 	}
@@ -161,8 +127,8 @@ func (m *MetricsStorageCosmosDB) LoadUserShard(shardID string) (*UserShardDocume
 }
 
 // LoadAllShards loads all user shard documents
-func (m *MetricsStorageCosmosDB) LoadAllShards() ([]*UserShardDocument, error) {
-	var shards []*UserShardDocument
+func (m *MetricsStorageCosmosDB) LoadAllShards() ([]*dbcommon.UserShardDocument, error) {
+	var shards []*dbcommon.UserShardDocument
 
 	// Load global metrics first to get current shard count
 	global, err := m.LoadGlobalMetrics()
@@ -188,7 +154,7 @@ func (m *MetricsStorageCosmosDB) LoadAllShards() ([]*UserShardDocument, error) {
 }
 
 // SaveGlobalMetrics saves the global metrics document
-func (m *MetricsStorageCosmosDB) SaveGlobalMetrics(metrics *GlobalMetricsDocument) error {
+func (m *MetricsStorageCosmosDB) SaveGlobalMetrics(metrics *dbcommon.GlobalMetricsDocument) error {
 	ctx := context.Background()
 	partitionKey := azcosmos.NewPartitionKeyString(METRICS_DOC_ID)
 
@@ -205,7 +171,7 @@ func (m *MetricsStorageCosmosDB) SaveGlobalMetrics(metrics *GlobalMetricsDocumen
 }
 
 // SaveUserShard saves a specific user shard document
-func (m *MetricsStorageCosmosDB) SaveUserShard(shard *UserShardDocument) error {
+func (m *MetricsStorageCosmosDB) SaveUserShard(shard *dbcommon.UserShardDocument) error {
 	ctx := context.Background()
 	partitionKey := azcosmos.NewPartitionKeyString(shard.ID)
 
@@ -283,7 +249,7 @@ func (m *MetricsStorageCosmosDB) CountActiveUsers(activeThreshold time.Time) (in
 }
 
 // LoadActivityStats loads the activity statistics document
-func (m *MetricsStorageCosmosDB) LoadActivityStats() (*ActivityStatsDocument, error) {
+func (m *MetricsStorageCosmosDB) LoadActivityStats() (*dbcommon.ActivityStatsDocument, error) {
 	ctx := context.Background()
 	partitionKey := azcosmos.NewPartitionKeyString(ACTIVITY_STATS_DOC_ID)
 
@@ -291,16 +257,16 @@ func (m *MetricsStorageCosmosDB) LoadActivityStats() (*ActivityStatsDocument, er
 	if err != nil {
 		if strings.Contains(err.Error(), "NotFound") {
 			// Initialize with empty stats if not found
-			return &ActivityStatsDocument{
+			return &dbcommon.ActivityStatsDocument{
 				ID:           ACTIVITY_STATS_DOC_ID,
-				DailyStats:   []DailyStats{},
-				DaysToRetain: 90, // Default to 90 days retention
+				DailyStats:   []dbcommon.DailyStats{},
+				DaysToRetain: dbcommon.DefaultDaysToRetain,
 			}, nil
 		}
 		return nil, err
 	}
 
-	var doc ActivityStatsDocument
+	var doc dbcommon.ActivityStatsDocument
 	if err := json.Unmarshal(response.Value, &doc); err != nil {
 		return nil, err
 	}
@@ -309,7 +275,7 @@ func (m *MetricsStorageCosmosDB) LoadActivityStats() (*ActivityStatsDocument, er
 }
 
 // SaveActivityStats saves the activity statistics document
-func (m *MetricsStorageCosmosDB) SaveActivityStats(stats *ActivityStatsDocument) error {
+func (m *MetricsStorageCosmosDB) SaveActivityStats(stats *dbcommon.ActivityStatsDocument) error {
 	ctx := context.Background()
 	partitionKey := azcosmos.NewPartitionKeyString(ACTIVITY_STATS_DOC_ID)
 
@@ -326,71 +292,14 @@ func (m *MetricsStorageCosmosDB) SaveActivityStats(stats *ActivityStatsDocument)
 
 // UpdateDailyStats updates the activity stats document with current day's statistics
 func (m *MetricsStorageCosmosDB) UpdateDailyStats() error {
-	// Get current date in ISO format (YYYY-MM-DD)
-	today := time.Now().UTC().Format("2006-01-02")
-
 	// Load existing stats
 	stats, err := m.LoadActivityStats()
 	if err != nil {
 		return err
 	}
 
-	// Calculate time thresholds
-	now := time.Now().UTC()
-	dailyStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	weeklyStart := dailyStart.AddDate(0, 0, -7)
-	monthlyStart := dailyStart.AddDate(0, 0, -30)
-
-	// Count daily active users
-	dailyCount, err := m.CountActiveUsers(dailyStart)
-	if err != nil {
-		return fmt.Errorf("failed to count daily active users: %w", err)
-	}
-
-	// Count weekly active users
-	weeklyCount, err := m.CountActiveUsers(weeklyStart)
-	if err != nil {
-		return fmt.Errorf("failed to count weekly active users: %w", err)
-	}
-
-	// Count monthly active users
-	monthlyCount, err := m.CountActiveUsers(monthlyStart)
-	if err != nil {
-		return fmt.Errorf("failed to count monthly active users: %w", err)
-	}
-
-	// Create today's stats
-	todayStats := DailyStats{
-		Date:          today,
-		DailyActive:   dailyCount,
-		WeeklyActive:  weeklyCount,
-		MonthlyActive: monthlyCount,
-	}
-
-	// Check if we already have an entry for today
-	found := false
-	for i, stat := range stats.DailyStats {
-		if stat.Date == today {
-			// Update existing entry
-			stats.DailyStats[i] = todayStats
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		// Add new entry
-		stats.DailyStats = append(stats.DailyStats, todayStats)
-	}
-
-	// Sort entries by date (newest first)
-	sort.Slice(stats.DailyStats, func(i, j int) bool {
-		return stats.DailyStats[i].Date > stats.DailyStats[j].Date
-	})
-
-	// Trim old entries if needed
-	if len(stats.DailyStats) > stats.DaysToRetain {
-		stats.DailyStats = stats.DailyStats[:stats.DaysToRetain]
+	if err := stats.UpdateDailyStats(time.Now().UTC(), m.CountActiveUsers); err != nil {
+		return err
 	}
 
 	// Save the updated stats
@@ -436,7 +345,7 @@ func (m *MetricsStorageCosmosDB) ResizeShards(newShardCount int) error {
 	}
 
 	// Create new shards with redistributed users
-	newShards := make(map[string]*UserShardDocument)
+	newShards := make(map[string]*dbcommon.UserShardDocument)
 
 	// Redistribute users to new shards
 	for userID, timestamp := range allActiveUsers {
@@ -446,7 +355,7 @@ func (m *MetricsStorageCosmosDB) ResizeShards(newShardCount int) error {
 		// Create shard document if it doesn't exist
 		if _, exists := newShards[shardID]; !exists {
 			shardIndex := m.getShardIndexFromID(shardID)
-			newShards[shardID] = &UserShardDocument{
+			newShards[shardID] = &dbcommon.UserShardDocument{
 				ID:          shardID,
 				ActiveUsers: make(map[string]string),
 				ShardIndex:  shardIndex,
@@ -468,74 +377,3 @@ func (m *MetricsStorageCosmosDB) ResizeShards(newShardCount int) error {
 
 	return nil
 }
-
-// MetricsStorage interface defines the metrics storage operations
-type MetricsStorage interface {
-	LoadGlobalMetrics() (*GlobalMetricsDocument, error)
-	SaveGlobalMetrics(*GlobalMetricsDocument) error
-	LoadUserShard(string) (*UserShardDocument, error)
-	SaveUserShard(*UserShardDocument) error
-	LoadAllShards() ([]*UserShardDocument, error)
-	UpdateUserActivity(string, time.Time) error
-	GetUserActivityTimestamp(string) (time.Time, bool, error)
-	CountActiveUsers(time.Time) (int, error)
-	ResizeShards(int) error
-	LoadActivityStats() (*ActivityStatsDocument, error)
-	SaveActivityStats(*ActivityStatsDocument) error
-	UpdateDailyStats() error
-}
-
-// NoOpMetricsStorage is a no-op implementation of metrics storage
-type noOpMetricsStorage struct{}
-
-func NewNoOpMetricsStorage() MetricsStorage {
-	return &noOpMetricsStorage{}
-}
-
-func (n *noOpMetricsStorage) LoadGlobalMetrics() (*GlobalMetricsDocument, error) {
-	return &GlobalMetricsDocument{}, nil
-}
-
-func (n *noOpMetricsStorage) SaveGlobalMetrics(*GlobalMetricsDocument) error {
-	return nil
-}
-
-func (n *noOpMetricsStorage) LoadUserShard(string) (*UserShardDocument, error) {
-	return &UserShardDocument{ActiveUsers: make(map[string]string)}, nil
-}
-
-func (n *noOpMetricsStorage) SaveUserShard(*UserShardDocument) error {
-	return nil
-}
-
-func (n *noOpMetricsStorage) LoadAllShards() ([]*UserShardDocument, error) {
-	return []*UserShardDocument{}, nil
-}
-
-func (n *noOpMetricsStorage) UpdateUserActivity(string, time.Time) error {
-	return nil
-}
-
-func (n *noOpMetricsStorage) GetUserActivityTimestamp(string) (time.Time, bool, error) {
-	return time.Time{}, false, nil
-}
-
-func (n *noOpMetricsStorage) CountActiveUsers(time.Time) (int, error) {
-	return 0, nil
-}
-
-func (n *noOpMetricsStorage) ResizeShards(int) error {
-	return nil
-}
-
-func (n *noOpMetricsStorage) LoadActivityStats() (*ActivityStatsDocument, error) {
-	return &ActivityStatsDocument{DailyStats: []DailyStats{}}, nil
-}
-
-func (n *noOpMetricsStorage) SaveActivityStats(*ActivityStatsDocument) error {
-	return nil
-}
-
-func (n *noOpMetricsStorage) UpdateDailyStats() error {
-	return nil
-}
@@ -0,0 +1,9 @@
+/*
+ The users are stored like in the CosmosDB container: one row per user with the entire GWUserDB JSON.
+ The id is the hex encoded HMAC of the user id and the user data is encrypted with the gateway encryption key.
+ */
+CREATE TABLE IF NOT EXISTS users
+(
+    id        TEXT PRIMARY KEY,
+    user_data BYTEA NOT NULL
+);
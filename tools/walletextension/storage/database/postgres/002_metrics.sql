@@ -0,0 +1,15 @@
+-- the global metrics and the activity statistics documents
+CREATE TABLE IF NOT EXISTS metrics
+(
+    id   TEXT PRIMARY KEY,
+    data JSONB NOT NULL
+);
+
+-- the last activity of each user, keyed by the double-hashed user id
+CREATE TABLE IF NOT EXISTS user_activity
+(
+    user_id     TEXT PRIMARY KEY,
+    last_active TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS IDX_USER_ACTIVITY_LAST_ACTIVE ON user_activity (last_active);
@@ -0,0 +1,18 @@
+-- the state of the rate limiter shared by the gateway instances
+CREATE TABLE IF NOT EXISTS rate_limit_cost
+(
+    key      TEXT   NOT NULL,
+    bucket   BIGINT NOT NULL, -- unix second
+    cost_ns  BIGINT NOT NULL,
+    PRIMARY KEY (key, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS IDX_RATE_LIMIT_COST_BUCKET ON rate_limit_cost (bucket);
+
+CREATE TABLE IF NOT EXISTS rate_limit_slot
+(
+    key        TEXT        NOT NULL,
+    id         TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key, id)
+);
@@ -0,0 +1,11 @@
+/*
+ The index of the valid API keys of the users. The id is the hex encoded HMAC of the hash of the key and
+ user_id is the id of the row of the user. The API keys themselves are stored with the encrypted user data.
+ */
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id      TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS IDX_API_KEYS_USER ON api_keys (user_id);
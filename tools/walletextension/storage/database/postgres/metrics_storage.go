@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
+)
+
+const (
+	globalMetricsDocID = "global_metrics"
+	activityStatsDocID = "activity_stats"
+	// the activity of all users is kept in the user_activity table, so there is a single logical shard
+	userShardID = "user_shard_0"
+)
+
+// MetricsStoragePostgres handles metrics persistence in PostgreSQL.
+// The global metrics and the activity statistics are stored as JSON documents, like in CosmosDB,
+// while the user activity is stored one row per user, so the active users are counted by the database.
+type MetricsStoragePostgres struct {
+	db *sql.DB
+}
+
+func NewMetricsStorage(connectionURL string) (*MetricsStoragePostgres, error) {
+	db, err := openDB(connectionURL)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsStoragePostgres{db: db}, nil
+}
+
+// LoadGlobalMetrics loads the global metrics document
+func (m *MetricsStoragePostgres) LoadGlobalMetrics() (*dbcommon.GlobalMetricsDocument, error) {
+	doc := dbcommon.GlobalMetricsDocument{ID: globalMetricsDocID, ShardCount: 1}
+	if err := m.loadDocument(globalMetricsDocID, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// SaveGlobalMetrics saves the global metrics document
+func (m *MetricsStoragePostgres) SaveGlobalMetrics(metrics *dbcommon.GlobalMetricsDocument) error {
+	metrics.LastUpdated = time.Now().UTC().Format(time.RFC3339)
+	metrics.ShardCount = 1
+	return m.saveDocument(globalMetricsDocID, metrics)
+}
+
+// LoadUserShard returns the activity of all users
+func (m *MetricsStoragePostgres) LoadUserShard(shardID string) (*dbcommon.UserShardDocument, error) {
+	rows, err := m.db.Query("SELECT user_id, last_active FROM user_activity")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user activity: %w", err)
+	}
+	defer rows.Close()
+
+	shard := &dbcommon.UserShardDocument{ID: shardID, ActiveUsers: make(map[string]string)}
+	for rows.Next() {
+		var userID string
+		var lastActive time.Time
+		if err := rows.Scan(&userID, &lastActive); err != nil {
+			return nil, fmt.Errorf("failed to scan user activity: %w", err)
+		}
+		shard.ActiveUsers[userID] = lastActive.UTC().Format(time.RFC3339)
+	}
+	return shard, rows.Err()
+}
+
+// SaveUserShard saves the activity of the users of the shard
+func (m *MetricsStoragePostgres) SaveUserShard(shard *dbcommon.UserShardDocument) error {
+	dbTx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	for userID, timestampStr := range shard.ActiveUsers {
+		timestamp, err := time.Parse(time.RFC3339, timestampStr)
+		if err != nil {
+			return fmt.Errorf("invalid activity timestamp for user %s: %w", userID, err)
+		}
+		if err := upsertUserActivity(dbTx, userID, timestamp); err != nil {
+			return err
+		}
+	}
+	return dbTx.Commit()
+}
+
+// LoadAllShards returns the single shard holding the activity of all users
+func (m *MetricsStoragePostgres) LoadAllShards() ([]*dbcommon.UserShardDocument, error) {
+	shard, err := m.LoadUserShard(userShardID)
+	if err != nil {
+		return nil, err
+	}
+	return []*dbcommon.UserShardDocument{shard}, nil
+}
+
+// UpdateUserActivity updates activity for a specific user
+func (m *MetricsStoragePostgres) UpdateUserActivity(userID string, timestamp time.Time) error {
+	return upsertUserActivity(m.db, userID, timestamp)
+}
+
+// GetUserActivityTimestamp retrieves the activity timestamp for a specific user
+func (m *MetricsStoragePostgres) GetUserActivityTimestamp(userID string) (time.Time, bool, error) {
+	var lastActive time.Time
+	err := m.db.QueryRow("SELECT last_active FROM user_activity WHERE user_id = $1", userID).Scan(&lastActive)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	return lastActive, true, nil
+}
+
+// CountActiveUsers counts the users active after the threshold
+func (m *MetricsStoragePostgres) CountActiveUsers(activeThreshold time.Time) (int, error) {
+	var count int
+	err := m.db.QueryRow("SELECT COUNT(*) FROM user_activity WHERE last_active > $1", activeThreshold).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active users: %w", err)
+	}
+	return count, nil
+}
+
+// ResizeShards is a no-op, because the user activity is not sharded in PostgreSQL
+func (m *MetricsStoragePostgres) ResizeShards(int) error {
+	return nil
+}
+
+// LoadActivityStats loads the activity statistics document
+func (m *MetricsStoragePostgres) LoadActivityStats() (*dbcommon.ActivityStatsDocument, error) {
+	doc := dbcommon.ActivityStatsDocument{
+		ID:           activityStatsDocID,
+		DailyStats:   []dbcommon.DailyStats{},
+		DaysToRetain: dbcommon.DefaultDaysToRetain,
+	}
+	if err := m.loadDocument(activityStatsDocID, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// SaveActivityStats saves the activity statistics document
+func (m *MetricsStoragePostgres) SaveActivityStats(stats *dbcommon.ActivityStatsDocument) error {
+	stats.LastUpdated = time.Now().UTC().Format(time.RFC3339)
+	return m.saveDocument(activityStatsDocID, stats)
+}
+
+// UpdateDailyStats updates the activity stats document with current day's statistics
+func (m *MetricsStoragePostgres) UpdateDailyStats() error {
+	stats, err := m.LoadActivityStats()
+	if err != nil {
+		return err
+	}
+	if err := stats.UpdateDailyStats(time.Now().UTC(), m.CountActiveUsers); err != nil {
+		return err
+	}
+	return m.SaveActivityStats(stats)
+}
+
+// loadDocument unmarshals the document into doc. When the document doesn't exist, doc is left unchanged.
+func (m *MetricsStoragePostgres) loadDocument(id string, doc any) error {
+	var data []byte
+	err := m.db.QueryRow("SELECT data FROM metrics WHERE id = $1", id).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to load metrics document %s: %w", id, err)
+	}
+	return json.Unmarshal(data, doc)
+}
+
+func (m *MetricsStoragePostgres) saveDocument(id string, doc any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.Exec(`INSERT INTO metrics(id, data) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, id, data)
+	if err != nil {
+		return fmt.Errorf("failed to save metrics document %s: %w", id, err)
+	}
+	return nil
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// upsertUserActivity never moves the activity of a user back in time
+func upsertUserActivity(db execer, userID string, timestamp time.Time) error {
+	_, err := db.Exec(`INSERT INTO user_activity(user_id, last_active) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET last_active = GREATEST(user_activity.last_active, EXCLUDED.last_active)`,
+		userID, timestamp.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update user activity: %w", err)
+	}
+	return nil
+}
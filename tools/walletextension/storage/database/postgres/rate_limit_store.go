@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ten-protocol/go-ten/tools/walletextension/ratelimiter"
+)
+
+// costFlushInterval - the costs of the finished requests are written to the database in batches at this interval
+const costFlushInterval = time.Second
+
+type costBucketKey struct {
+	key    string
+	bucket int64 // unix second
+}
+
+// RateLimitStorePostgres - a rate limiter store shared by all the gateway instances which use the same database.
+// The costs are buffered in memory and written in batches, so finishing a request doesn't wait for the database.
+// The instance sees its own costs immediately, and the costs of the other instances with a delay of up to costFlushInterval.
+// The concurrent request slots are not buffered: Acquire takes a database transaction of several round trips per
+// request and Release one statement, which is the latency cost of enforcing MaxConcurrentRequests across instances.
+type RateLimitStorePostgres struct {
+	db *sql.DB
+
+	pendingMu sync.Mutex
+	pending   map[costBucketKey]time.Duration // the costs which were not written yet
+}
+
+var _ ratelimiter.Store = (*RateLimitStorePostgres)(nil)
+
+func NewRateLimitStore(connectionURL string) (*RateLimitStorePostgres, error) {
+	db, err := openDB(connectionURL)
+	if err != nil {
+		return nil, err
+	}
+	s := newRateLimitStore(db)
+	go s.flushPeriodically()
+	return s, nil
+}
+
+func newRateLimitStore(db *sql.DB) *RateLimitStorePostgres {
+	return &RateLimitStorePostgres{db: db, pending: make(map[costBucketKey]time.Duration)}
+}
+
+// AddCost - the cost is written with the next batch
+func (s *RateLimitStorePostgres) AddCost(_ context.Context, key string, cost time.Duration, at time.Time) error {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	s.pending[costBucketKey{key: key, bucket: ratelimiter.BucketStart(at).Unix()}] += cost
+	return nil
+}
+
+// Costs - the costs written by all the instances, with the costs of this instance which were not written yet
+func (s *RateLimitStorePostgres) Costs(ctx context.Context, key string, since time.Time) ([]ratelimiter.CostBucket, error) {
+	sinceBucket := ratelimiter.BucketStart(since).Unix()
+	rows, err := s.db.QueryContext(ctx, "SELECT bucket, cost_ns FROM rate_limit_cost WHERE key = $1 AND bucket >= $2 ORDER BY bucket",
+		key, sinceBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit costs: %w", err)
+	}
+	defer rows.Close()
+
+	costs := make(map[int64]time.Duration)
+	for rows.Next() {
+		var bucket, cost int64
+		if err := rows.Scan(&bucket, &cost); err != nil {
+			return nil, fmt.Errorf("failed to scan rate limit cost: %w", err)
+		}
+		costs[bucket] += time.Duration(cost)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s.pendingMu.Lock()
+	for bucketKey, cost := range s.pending {
+		if bucketKey.key == key && bucketKey.bucket >= sinceBucket {
+			costs[bucketKey.bucket] += cost
+		}
+	}
+	s.pendingMu.Unlock()
+
+	buckets := make([]ratelimiter.CostBucket, 0, len(costs))
+	for bucket, cost := range costs {
+		buckets = append(buckets, ratelimiter.CostBucket{Start: time.Unix(bucket, 0), Cost: cost})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets, nil
+}
+
+func (s *RateLimitStorePostgres) flushPeriodically() {
+	for {
+		time.Sleep(costFlushInterval)
+		ctx, cancel := context.WithTimeout(context.Background(), costFlushInterval)
+		// the costs which could not be written are kept for the next batch
+		_ = s.flushCosts(ctx)
+		cancel()
+	}
+}
+
+// flushCosts - writes the buffered costs with a single statement
+func (s *RateLimitStorePostgres) flushCosts(ctx context.Context) error {
+	s.pendingMu.Lock()
+	batch := s.pending
+	s.pending = make(map[costBucketKey]time.Duration)
+	s.pendingMu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(batch))
+	args := make([]any, 0, 3*len(batch))
+	for bucketKey, cost := range batch {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d)", len(args)+1, len(args)+2, len(args)+3))
+		args = append(args, bucketKey.key, bucketKey.bucket, int64(cost))
+	}
+	_, err := s.db.ExecContext(ctx, "INSERT INTO rate_limit_cost(key, bucket, cost_ns) VALUES "+strings.Join(values, ", ")+
+		" ON CONFLICT (key, bucket) DO UPDATE SET cost_ns = rate_limit_cost.cost_ns + EXCLUDED.cost_ns", args...)
+	if err != nil {
+		s.pendingMu.Lock()
+		for bucketKey, cost := range batch {
+			s.pending[bucketKey] += cost
+		}
+		s.pendingMu.Unlock()
+		return fmt.Errorf("failed to add rate limit costs: %w", err)
+	}
+	return nil
+}
+
+// Acquire - the slots of the key are counted and inserted under a transaction-scoped advisory lock,
+// so concurrent requests to different instances can't exceed maxConcurrent
+func (s *RateLimitStorePostgres) Acquire(ctx context.Context, key string, maxConcurrent uint32, ttl time.Duration) (string, error) {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if _, err := dbTx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", key); err != nil {
+		return "", fmt.Errorf("failed to lock rate limit key: %w", err)
+	}
+	var open uint32
+	err = dbTx.QueryRowContext(ctx, "SELECT COUNT(*) FROM rate_limit_slot WHERE key = $1 AND expires_at > NOW()", key).Scan(&open)
+	if err != nil {
+		return "", fmt.Errorf("failed to count rate limit slots: %w", err)
+	}
+	if open >= maxConcurrent {
+		return "", nil
+	}
+	id := uuid.NewString()
+	_, err = dbTx.ExecContext(ctx, "INSERT INTO rate_limit_slot(key, id, expires_at) VALUES ($1, $2, $3)", key, id, time.Now().Add(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to insert rate limit slot: %w", err)
+	}
+	return id, dbTx.Commit()
+}
+
+func (s *RateLimitStorePostgres) Release(ctx context.Context, key string, slotID string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_slot WHERE key = $1 AND id = $2", key, slotID)
+	if err != nil {
+		return fmt.Errorf("failed to release rate limit slot: %w", err)
+	}
+	return nil
+}
+
+func (s *RateLimitStorePostgres) Prune(ctx context.Context, before time.Time) error {
+	s.pendingMu.Lock()
+	for bucketKey := range s.pending {
+		if bucketKey.bucket < ratelimiter.BucketStart(before).Unix() {
+			delete(s.pending, bucketKey)
+		}
+	}
+	s.pendingMu.Unlock()
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_cost WHERE bucket < $1", ratelimiter.BucketStart(before).Unix()); err != nil {
+		return fmt.Errorf("failed to prune rate limit costs: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_slot WHERE expires_at <= NOW()"); err != nil {
+		return fmt.Errorf("failed to prune rate limit slots: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/tools/walletextension/ratelimiter"
+)
+
+func TestRateLimitStoreCosts(t *testing.T) {
+	server := newFakeRateLimitServer()
+	store := newRateLimitStore(sql.OpenDB(server))
+	other := newRateLimitStore(sql.OpenDB(server))
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, store.AddCost(ctx, "user", 10*time.Millisecond, now))
+	require.NoError(t, store.AddCost(ctx, "user", 5*time.Millisecond, now))
+	require.NoError(t, store.AddCost(ctx, "other-user", time.Millisecond, now))
+
+	// the instance sees its costs before they are written
+	buckets, err := store.Costs(ctx, "user", now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []ratelimiter.CostBucket{{Start: ratelimiter.BucketStart(now), Cost: 15 * time.Millisecond}}, buckets)
+	buckets, err = other.Costs(ctx, "user", now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, buckets)
+
+	// the other instances see them once they are written, with a single statement
+	require.NoError(t, store.flushCosts(ctx))
+	require.Equal(t, 1, server.costWrites)
+	require.NoError(t, other.AddCost(ctx, "user", time.Millisecond, now.Add(-2*time.Second)))
+	buckets, err = other.Costs(ctx, "user", now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []ratelimiter.CostBucket{
+		{Start: ratelimiter.BucketStart(now.Add(-2 * time.Second)), Cost: time.Millisecond},
+		{Start: ratelimiter.BucketStart(now), Cost: 15 * time.Millisecond},
+	}, buckets)
+
+	// the costs are added to the costs already written
+	require.NoError(t, store.AddCost(ctx, "user", 5*time.Millisecond, now))
+	require.NoError(t, store.flushCosts(ctx))
+	buckets, err = store.Costs(ctx, "user", now)
+	require.NoError(t, err)
+	require.Equal(t, []ratelimiter.CostBucket{{Start: ratelimiter.BucketStart(now), Cost: 20 * time.Millisecond}}, buckets)
+
+	// the costs which could not be written are kept for the next batch
+	require.NoError(t, store.AddCost(ctx, "user", 5*time.Millisecond, now))
+	server.setFailing(true)
+	require.Error(t, store.flushCosts(ctx))
+	server.setFailing(false)
+	require.NoError(t, store.flushCosts(ctx))
+	buckets, err = other.Costs(ctx, "user", now)
+	require.NoError(t, err)
+	require.Equal(t, 25*time.Millisecond, buckets[0].Cost)
+
+	// the costs which were not written yet are pruned as well
+	require.NoError(t, store.Prune(ctx, now.Add(time.Minute)))
+	require.NoError(t, other.Prune(ctx, now.Add(time.Minute)))
+	buckets, err = other.Costs(ctx, "user", now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, buckets)
+}
+
+func TestRateLimitStoreAcquire(t *testing.T) {
+	server := newFakeRateLimitServer()
+	// the instances share the database, but not their connections
+	instances := []*RateLimitStorePostgres{newRateLimitStore(sql.OpenDB(server)), newRateLimitStore(sql.OpenDB(server))}
+	ctx := context.Background()
+	const maxConcurrent = 3
+
+	var wg sync.WaitGroup
+	slots := make(chan string, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(store *RateLimitStorePostgres) {
+			defer wg.Done()
+			slotID, err := store.Acquire(ctx, "user", maxConcurrent, time.Minute)
+			require.NoError(t, err)
+			if slotID != "" {
+				slots <- slotID
+			}
+		}(instances[i%2])
+	}
+	wg.Wait()
+	close(slots)
+	var acquired []string
+	for slotID := range slots {
+		acquired = append(acquired, slotID)
+	}
+	require.Len(t, acquired, maxConcurrent)
+
+	// the slots of the other keys are independent
+	slotID, err := instances[0].Acquire(ctx, "other-user", maxConcurrent, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, slotID)
+
+	// a released slot can be acquired again
+	require.NoError(t, instances[1].Release(ctx, "user", acquired[0]))
+	slotID, err = instances[0].Acquire(ctx, "user", maxConcurrent, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, slotID)
+	slotID, err = instances[0].Acquire(ctx, "user", maxConcurrent, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, slotID)
+
+	// the expired slots are not counted
+	slotID, err = instances[0].Acquire(ctx, "expiring-user", 1, -time.Second)
+	require.NoError(t, err)
+	require.NotEmpty(t, slotID)
+	slotID, err = instances[0].Acquire(ctx, "expiring-user", 1, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, slotID)
+
+	// the lock is released when the transaction fails
+	server.setFailing(true)
+	_, err = instances[0].Acquire(ctx, "user", maxConcurrent, time.Minute)
+	require.Error(t, err)
+	server.setFailing(false)
+	require.NoError(t, instances[1].Release(ctx, "user", acquired[1]))
+	slotID, err = instances[1].Acquire(ctx, "user", maxConcurrent, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, slotID)
+}
+
+// fakeRateLimitServer - an in-process stand-in for the PostgreSQL database, which executes the statements of the rate limit store.
+// The advisory locks are held until the end of the transaction of the connection which took them, like in PostgreSQL.
+type fakeRateLimitServer struct {
+	mu         sync.Mutex
+	costs      map[costBucketKey]int64
+	slots      map[string]map[string]time.Time // key -> slot id -> expiry
+	locks      map[string]*sync.Mutex
+	costWrites int
+	failing    bool // the cost writes and the slot counts fail
+}
+
+func newFakeRateLimitServer() *fakeRateLimitServer {
+	return &fakeRateLimitServer{
+		costs: make(map[costBucketKey]int64),
+		slots: make(map[string]map[string]time.Time),
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+func (f *fakeRateLimitServer) setFailing(failing bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing = failing
+}
+
+func (f *fakeRateLimitServer) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{server: f}, nil
+}
+
+func (f *fakeRateLimitServer) Driver() driver.Driver {
+	return nil
+}
+
+func (f *fakeRateLimitServer) lock(key string) *sync.Mutex {
+	f.mu.Lock()
+	l, found := f.locks[key]
+	if !found {
+		l = &sync.Mutex{}
+		f.locks[key] = l
+	}
+	f.mu.Unlock()
+	l.Lock()
+	return l
+}
+
+func (f *fakeRateLimitServer) exec(query string, args []driver.NamedValue) (*fakeRows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO rate_limit_cost"):
+		if f.failing {
+			return nil, fmt.Errorf("connection refused")
+		}
+		f.costWrites++
+		for i := 0; i < len(args); i += 3 {
+			f.costs[costBucketKey{key: args[i].Value.(string), bucket: args[i+1].Value.(int64)}] += args[i+2].Value.(int64)
+		}
+		return &fakeRows{}, nil
+
+	case strings.HasPrefix(query, "SELECT bucket, cost_ns FROM rate_limit_cost"):
+		rows := &fakeRows{columns: []string{"bucket", "cost_ns"}}
+		for bucketKey, cost := range f.costs {
+			if bucketKey.key == args[0].Value && bucketKey.bucket >= args[1].Value.(int64) {
+				rows.values = append(rows.values, []driver.Value{bucketKey.bucket, cost})
+			}
+		}
+		return rows, nil
+
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM rate_limit_slot"):
+		if f.failing {
+			return nil, fmt.Errorf("connection refused")
+		}
+		var count int64
+		for _, expiry := range f.slots[args[0].Value.(string)] {
+			if expiry.After(time.Now()) {
+				count++
+			}
+		}
+		return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{count}}}, nil
+
+	case strings.HasPrefix(query, "INSERT INTO rate_limit_slot"):
+		key := args[0].Value.(string)
+		if f.slots[key] == nil {
+			f.slots[key] = make(map[string]time.Time)
+		}
+		f.slots[key][args[1].Value.(string)] = args[2].Value.(time.Time)
+		return &fakeRows{}, nil
+
+	case strings.HasPrefix(query, "DELETE FROM rate_limit_slot WHERE key"):
+		delete(f.slots[args[0].Value.(string)], args[1].Value.(string))
+		return &fakeRows{}, nil
+
+	case strings.HasPrefix(query, "DELETE FROM rate_limit_slot WHERE expires_at"):
+		for _, slots := range f.slots {
+			for id, expiry := range slots {
+				if !expiry.After(time.Now()) {
+					delete(slots, id)
+				}
+			}
+		}
+		return &fakeRows{}, nil
+
+	case strings.HasPrefix(query, "DELETE FROM rate_limit_cost"):
+		for bucketKey := range f.costs {
+			if bucketKey.bucket < args[0].Value.(int64) {
+				delete(f.costs, bucketKey)
+			}
+		}
+		return &fakeRows{}, nil
+	}
+	return nil, fmt.Errorf("unexpected statement: %s", query)
+}
+
+type fakeConn struct {
+	server *fakeRateLimitServer
+	locks  []*sync.Mutex // the advisory locks held by the transaction
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.unlock()
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.unlock()
+	return nil
+}
+
+func (c *fakeConn) unlock() {
+	for _, l := range c.locks {
+		l.Unlock()
+	}
+	c.locks = nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.HasPrefix(query, "SELECT pg_advisory_xact_lock") {
+		c.locks = append(c.locks, c.server.lock(args[0].Value.(string)))
+		return driver.RowsAffected(0), nil
+	}
+	if _, err := c.server.exec(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.server.exec(query, args)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
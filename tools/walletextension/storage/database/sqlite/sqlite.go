@@ -12,17 +12,23 @@ package sqlite
 	}
 
 	This simplifies the schema and keeps it similar to the CosmosDB container-based storage.
+
+	The 'api_keys' table indexes the valid API keys of the users by the hash of the key.
 */
 
 import (
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"math/big"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	_ "github.com/mattn/go-sqlite3" // sqlite driver for sql.Open()
 
 	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
@@ -67,6 +73,14 @@ func NewSqliteDatabase(dbPath string) (*SqliteDB, error) {
 		return nil, err
 	}
 
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL
+	);`)
+	if err != nil {
+		return nil, err
+	}
+
 	// If there was an old 'accounts' table from a previous implementation, drop it.
 	// This ensures no leftover foreign key constraints cause issues.
 	_, _ = db.Exec("DROP TABLE IF EXISTS accounts;")
@@ -113,6 +127,11 @@ func (s *SqliteDB) DeleteUser(userID []byte) error {
 		if err != nil {
 			return fmt.Errorf("failed to delete user: %w", err)
 		}
+
+		_, err = dbTx.Exec("DELETE FROM api_keys WHERE user_id = ?", string(userID))
+		if err != nil {
+			return fmt.Errorf("failed to delete the API keys of the user: %w", err)
+		}
 		return nil
 	})
 }
@@ -136,45 +155,121 @@ func (s *SqliteDB) AddAccount(userID []byte, accountAddress []byte, signature []
 }
 
 func (s *SqliteDB) AddSessionKey(userID []byte, key common.GWSessionKey) error {
+	return s.updateUserWith(userID, func(user *dbcommon.GWUserDB) error {
+		user.AddSessionKey(dbcommon.NewGWSessionKeyDB(key))
+		return nil
+	})
+}
+
+func (s *SqliteDB) ActivateSessionKey(userID []byte, address gethcommon.Address, active bool) error {
+	return s.updateUserWith(userID, func(user *dbcommon.GWUserDB) error {
+		return user.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Active = active
+			return nil
+		})
+	})
+}
+
+func (s *SqliteDB) SetSessionKeyPolicy(userID []byte, address gethcommon.Address, policy *common.SessionKeyPolicy) error {
+	return s.updateUserWith(userID, func(user *dbcommon.GWUserDB) error {
+		return user.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Policy = policy
+			return nil
+		})
+	})
+}
+
+func (s *SqliteDB) SetSessionKeyFunding(userID []byte, address gethcommon.Address, funding *common.SessionKeyFunding) error {
+	return s.updateUserWith(userID, func(user *dbcommon.GWUserDB) error {
+		return user.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Funding = funding
+			return nil
+		})
+	})
+}
+
+func (s *SqliteDB) AddSessionKeySpend(userID []byte, address gethcommon.Address, amount *big.Int) error {
+	return s.updateUserWith(userID, func(user *dbcommon.GWUserDB) error {
+		return user.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			return sk.AddSpend(amount)
+		})
+	})
+}
+
+func (s *SqliteDB) SetSessionKeySweep(userID []byte, address gethcommon.Address, sweep *common.SessionKeyFundsTransfer) error {
+	return s.updateUserWith(userID, func(user *dbcommon.GWUserDB) error {
+		return user.UpdateSessionKey(address, func(sk *dbcommon.GWSessionKeyDB) error {
+			sk.Sweep = sweep
+			return nil
+		})
+	})
+}
+
+func (s *SqliteDB) RemoveSessionKey(userID []byte, address gethcommon.Address) error {
+	return s.updateUserWith(userID, func(user *dbcommon.GWUserDB) error {
+		return user.RemoveSessionKey(address)
+	})
+}
+
+func (s *SqliteDB) AddAPIKey(userID []byte, key common.GWAPIKey) error {
 	return s.withTx(func(dbTx *sql.Tx) error {
 		user, err := s.readUser(dbTx, userID)
 		if err != nil {
 			return err
 		}
-		user.SessionKey = &dbcommon.GWSessionKeyDB{
-			PrivateKey: crypto.FromECDSA(key.PrivateKey.ExportECDSA()),
-			Account: dbcommon.GWAccountDB{
-				AccountAddress: key.Account.Address.Bytes(),
-				Signature:      key.Account.Signature,
-				SignatureType:  int(key.Account.SignatureType),
-			},
+		user.AddAPIKey(dbcommon.NewGWAPIKeyDB(key))
+		if err := s.updateUser(dbTx, user); err != nil {
+			return err
+		}
+		if key.IsRevoked() {
+			return nil
 		}
-		return s.updateUser(dbTx, user)
+		_, err = dbTx.Exec("INSERT OR REPLACE INTO api_keys(id, user_id) VALUES (?, ?)", hex.EncodeToString(key.Hash), string(userID))
+		if err != nil {
+			return fmt.Errorf("failed to index API key: %w", err)
+		}
+		return nil
 	})
 }
 
-func (s *SqliteDB) ActivateSessionKey(userID []byte, active bool) error {
+func (s *SqliteDB) RevokeAPIKey(userID []byte, keyID string, revokedAt time.Time) error {
 	return s.withTx(func(dbTx *sql.Tx) error {
 		user, err := s.readUser(dbTx, userID)
 		if err != nil {
 			return err
 		}
-		user.ActiveSK = active
-		return s.updateUser(dbTx, user)
-	})
-}
-
-func (s *SqliteDB) RemoveSessionKey(userID []byte) error {
-	return s.withTx(func(dbTx *sql.Tx) error {
-		user, err := s.readUser(dbTx, userID)
+		hash, err := user.RevokeAPIKey(keyID, revokedAt)
 		if err != nil {
 			return err
 		}
-		user.SessionKey = nil
-		return s.updateUser(dbTx, user)
+		if err := s.updateUser(dbTx, user); err != nil {
+			return err
+		}
+		_, err = dbTx.Exec("DELETE FROM api_keys WHERE id = ?", hex.EncodeToString(hash))
+		if err != nil {
+			return fmt.Errorf("failed to remove API key from the index: %w", err)
+		}
+		return nil
 	})
 }
 
+func (s *SqliteDB) GetUserByAPIKey(keyHash []byte) (*common.GWUser, error) {
+	var userDataJSON string
+	err := s.db.QueryRow("SELECT u.user_data FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.id = ?", hex.EncodeToString(keyHash)).Scan(&userDataJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, common.ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by API key: %w", err)
+	}
+
+	var user dbcommon.GWUserDB
+	if err := json.Unmarshal([]byte(userDataJSON), &user); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user data: %w", err)
+	}
+	return user.ToGWUser()
+}
+
 func (s *SqliteDB) GetUser(userID []byte) (*common.GWUser, error) {
 	var user dbcommon.GWUserDB
 	var err error
@@ -188,6 +283,52 @@ func (s *SqliteDB) GetUser(userID []byte) (*common.GWUser, error) {
 	return user.ToGWUser()
 }
 
+// ForEachUser reads the users in pages, so fn can be slow without keeping the database locked
+func (s *SqliteDB) ForEachUser(fn func(*common.GWUser) error) error {
+	lastID := ""
+	for {
+		users, err := s.readUsersPage(lastID)
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		for _, user := range users {
+			gwUser, err := user.ToGWUser()
+			if err != nil {
+				return err
+			}
+			if err := fn(gwUser); err != nil {
+				return err
+			}
+		}
+		lastID = string(users[len(users)-1].UserId)
+	}
+}
+
+func (s *SqliteDB) readUsersPage(afterID string) ([]dbcommon.GWUserDB, error) {
+	rows, err := s.db.Query("SELECT user_data FROM users WHERE id > ? ORDER BY id LIMIT ?", afterID, dbcommon.UsersPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []dbcommon.GWUserDB
+	for rows.Next() {
+		var userDataJSON string
+		if err := rows.Scan(&userDataJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		var user dbcommon.GWUserDB
+		if err := json.Unmarshal([]byte(userDataJSON), &user); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user data: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
 func (s *SqliteDB) readUser(dbTx *sql.Tx, userID []byte) (dbcommon.GWUserDB, error) {
 	var userDataJSON string
 	err := dbTx.QueryRow("SELECT user_data FROM users WHERE id = ?", string(userID)).Scan(&userDataJSON)
@@ -206,6 +347,20 @@ func (s *SqliteDB) readUser(dbTx *sql.Tx, userID []byte) (dbcommon.GWUserDB, err
 	return user, nil
 }
 
+// updateUserWith - reads the user, applies the mutation and writes it back in the same transaction
+func (s *SqliteDB) updateUserWith(userID []byte, mutate func(*dbcommon.GWUserDB) error) error {
+	return s.withTx(func(dbTx *sql.Tx) error {
+		user, err := s.readUser(dbTx, userID)
+		if err != nil {
+			return err
+		}
+		if err := mutate(&user); err != nil {
+			return err
+		}
+		return s.updateUser(dbTx, user)
+	})
+}
+
 func (s *SqliteDB) updateUser(dbTx *sql.Tx, user dbcommon.GWUserDB) error {
 	updatedUserJSON, err := json.Marshal(user)
 	if err != nil {
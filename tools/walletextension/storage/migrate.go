@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethlog "github.com/ethereum/go-ethereum/log"
+
+	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
+)
+
+const migrationLogInterval = 1000
+
+// MigrationReport - the result of MigrateUsers
+type MigrationReport struct {
+	DryRun      bool
+	Users       int // the users read from the source
+	Accounts    int
+	SessionKeys int
+	APIKeys     int // including the revoked keys
+	Copied      int // the users written to the target. In a dry run, the users which would be written
+	Skipped     int // the users already present in the target with the same content
+	// the users of the source which could not be decrypted with the source key. The migration fails when there are any
+	Undecryptable int
+
+	// the combined checksums of the users read from the source and read back from the target.
+	// They are equal when the migration is successful
+	SourceChecksum gethcommon.Hash
+	TargetChecksum gethcommon.Hash
+}
+
+// MigrateUsers - copies all the users, with their accounts, session keys and API keys, from the source to the target storage.
+// The target re-encrypts the users with its own key, so this is also how the encryption key is rotated.
+// Every copied user is read back from the target and compared with the source, so a successful run means the
+// target holds exactly the users of the source. The migration can be re-run: the users already copied are skipped,
+// and the users which changed in the source in the meantime are copied again.
+func MigrateUsers(source, target UserStorage, dryRun bool, logger gethlog.Logger) (*MigrationReport, error) {
+	report := &MigrationReport{DryRun: dryRun}
+	var sourceChecksums, targetChecksums []gethcommon.Hash
+	err := source.ForEachUser(func(user *common.GWUser) error {
+		checksum, err := UserChecksum(user)
+		if err != nil {
+			return err
+		}
+		report.Users++
+		report.Accounts += len(user.Accounts)
+		report.SessionKeys += len(user.SessionKeys)
+		report.APIKeys += len(user.APIKeys)
+		sourceChecksums = append(sourceChecksums, checksum)
+
+		copied, targetChecksum, err := migrateUser(user, checksum, target, dryRun)
+		if err != nil {
+			return err
+		}
+		if copied {
+			report.Copied++
+		} else {
+			report.Skipped++
+		}
+		if targetChecksum != (gethcommon.Hash{}) {
+			targetChecksums = append(targetChecksums, targetChecksum)
+		}
+		if report.Users%migrationLogInterval == 0 {
+			logger.Info("Migrating users", "read", report.Users, "copied", report.Copied, "skipped", report.Skipped)
+		}
+		return nil
+	})
+	report.SourceChecksum = combinedChecksum(sourceChecksums)
+	report.TargetChecksum = combinedChecksum(targetChecksums)
+	var undecryptable *dbcommon.UndecryptableUsersError
+	if errors.As(err, &undecryptable) {
+		// the readable users were copied, but the target doesn't hold all the users of the source
+		report.Undecryptable = undecryptable.Count
+		return report, fmt.Errorf("migration incomplete: %w", err)
+	}
+	if err != nil {
+		return report, fmt.Errorf("migration stopped after %d users: %w", report.Users, err)
+	}
+	return report, nil
+}
+
+// migrateUser - returns false when the target already holds the same user, and the checksum of the user read from the target.
+// In a dry run, the checksum of a user which would be copied is empty.
+func migrateUser(user *common.GWUser, checksum gethcommon.Hash, target UserStorage, dryRun bool) (bool, gethcommon.Hash, error) {
+	existing, err := target.GetUser(user.ID)
+	switch {
+	case err == nil:
+		existingChecksum, err := UserChecksum(existing)
+		if err != nil {
+			return false, gethcommon.Hash{}, err
+		}
+		if existingChecksum == checksum {
+			return false, existingChecksum, nil
+		}
+		// the user changed since the previous run, or a previous run stopped half way
+		if !dryRun {
+			if err := target.DeleteUser(user.ID); err != nil {
+				return false, gethcommon.Hash{}, fmt.Errorf("failed to delete the outdated user: %w", err)
+			}
+		}
+	case !errors.Is(err, dbcommon.ErrUserNotFound):
+		return false, gethcommon.Hash{}, fmt.Errorf("failed to read the user from the target: %w", err)
+	}
+	if dryRun {
+		return true, gethcommon.Hash{}, nil
+	}
+
+	if err := writeUser(user, target); err != nil {
+		return false, gethcommon.Hash{}, err
+	}
+
+	written, err := target.GetUser(user.ID)
+	if err != nil {
+		return false, gethcommon.Hash{}, fmt.Errorf("failed to read back the migrated user: %w", err)
+	}
+	writtenChecksum, err := UserChecksum(written)
+	if err != nil {
+		return false, gethcommon.Hash{}, err
+	}
+	if writtenChecksum != checksum {
+		return false, gethcommon.Hash{}, fmt.Errorf("checksum mismatch for a migrated user: %s != %s", writtenChecksum, checksum)
+	}
+	return true, writtenChecksum, nil
+}
+
+func writeUser(user *common.GWUser, target UserStorage) error {
+	if err := target.AddUser(user.ID, user.UserKey); err != nil {
+		return fmt.Errorf("failed to add user: %w", err)
+	}
+	for _, address := range sortedAddresses(user.Accounts) {
+		account := user.Accounts[address]
+		if err := target.AddAccount(user.ID, address.Bytes(), account.Signature, account.SignatureType); err != nil {
+			return fmt.Errorf("failed to add account: %w", err)
+		}
+	}
+	for _, address := range sortedAddresses(user.SessionKeys) {
+		if err := target.AddSessionKey(user.ID, *user.SessionKeys[address]); err != nil {
+			return fmt.Errorf("failed to add session key: %w", err)
+		}
+	}
+	for _, id := range sortedAPIKeyIDs(user.APIKeys) {
+		if err := target.AddAPIKey(user.ID, *user.APIKeys[id]); err != nil {
+			return fmt.Errorf("failed to add API key: %w", err)
+		}
+	}
+	return nil
+}
+
+type accountChecksumData struct {
+	Address       gethcommon.Address
+	Signature     hexutil.Bytes
+	SignatureType int
+}
+
+type sessionKeyChecksumData struct {
+	Account    accountChecksumData
+	PrivateKey hexutil.Bytes
+	Active     bool
+	Policy     *common.SessionKeyPolicy
+	Spent      *hexutil.Big
+	Funding    *common.SessionKeyFunding
+	Sweep      *common.SessionKeyFundsTransfer `json:",omitempty"`
+}
+
+type apiKeyChecksumData struct {
+	ID        string
+	Name      string
+	Scopes    []common.APIKeyScope
+	Hash      hexutil.Bytes
+	CreatedAt int64
+	RevokedAt *int64
+}
+
+type userChecksumData struct {
+	ID          hexutil.Bytes
+	UserKey     hexutil.Bytes
+	Accounts    []accountChecksumData
+	SessionKeys []sessionKeyChecksumData
+	APIKeys     []apiKeyChecksumData `json:",omitempty"`
+}
+
+// UserChecksum - a hash over the entire content of the user which doesn't depend on the storage it was read from
+func UserChecksum(user *common.GWUser) (gethcommon.Hash, error) {
+	data := userChecksumData{ID: user.ID, UserKey: user.UserKey}
+	for _, address := range sortedAddresses(user.Accounts) {
+		data.Accounts = append(data.Accounts, accountChecksumData{
+			Address:       address,
+			Signature:     user.Accounts[address].Signature,
+			SignatureType: int(user.Accounts[address].SignatureType),
+		})
+	}
+	for _, address := range sortedAddresses(user.SessionKeys) {
+		sk := user.SessionKeys[address]
+		spent := new(big.Int)
+		if sk.Spent != nil {
+			spent = sk.Spent
+		}
+		data.SessionKeys = append(data.SessionKeys, sessionKeyChecksumData{
+			Account: accountChecksumData{
+				Address:       address,
+				Signature:     sk.Account.Signature,
+				SignatureType: int(sk.Account.SignatureType),
+			},
+			PrivateKey: crypto.FromECDSA(sk.PrivateKey.ExportECDSA()),
+			Active:     sk.Active,
+			Policy:     sk.Policy,
+			Spent:      (*hexutil.Big)(spent),
+			Funding:    sk.Funding,
+			Sweep:      sk.Sweep,
+		})
+	}
+	for _, id := range sortedAPIKeyIDs(user.APIKeys) {
+		key := user.APIKeys[id]
+		var revokedAt *int64
+		if key.RevokedAt != nil {
+			revoked := key.RevokedAt.Unix()
+			revokedAt = &revoked
+		}
+		data.APIKeys = append(data.APIKeys, apiKeyChecksumData{
+			ID:        key.ID,
+			Name:      key.Name,
+			Scopes:    key.Scopes,
+			Hash:      key.Hash,
+			CreatedAt: key.CreatedAt.Unix(),
+			RevokedAt: revokedAt,
+		})
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return gethcommon.Hash{}, fmt.Errorf("failed to encode the user: %w", err)
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+func sortedAddresses[V any](m map[gethcommon.Address]V) []gethcommon.Address {
+	addresses := make([]gethcommon.Address, 0, len(m))
+	for address := range m {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+	return addresses
+}
+
+func sortedAPIKeyIDs(m map[string]*common.GWAPIKey) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// combinedChecksum - the hash of the sorted checksums, so it doesn't depend on the order in which the users are streamed,
+// and a duplicated or missing user changes it
+func combinedChecksum(checksums []gethcommon.Hash) gethcommon.Hash {
+	sorted := slices.Clone(checksums)
+	slices.SortFunc(sorted, func(a, b gethcommon.Hash) int {
+		return bytes.Compare(a.Bytes(), b.Bytes())
+	})
+	data := make([]byte, 0, len(sorted)*gethcommon.HashLength)
+	for _, checksum := range sorted {
+		data = append(data, checksum.Bytes()...)
+	}
+	return crypto.Keccak256Hash(data)
+}
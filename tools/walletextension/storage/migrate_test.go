@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+	"github.com/ten-protocol/go-ten/go/common/viewingkey"
+	"github.com/ten-protocol/go-ten/integration/common/testlog"
+	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
+	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
+)
+
+func TestMigrateUsers(t *testing.T) {
+	source, err := New("sqlite", "", "", nil, testlog.Logger())
+	require.NoError(t, err)
+	target, err := New("sqlite", "", "", nil, testlog.Logger())
+	require.NoError(t, err)
+
+	var userIDs [][]byte
+	var apiKeys []*wecommon.GWAPIKey
+	for i := 0; i < 3; i++ {
+		userID := make([]byte, 20)
+		rand.Read(userID)
+		require.NoError(t, source.AddUser(userID, []byte{byte(i)}))
+		account := make([]byte, 20)
+		rand.Read(account)
+		require.NoError(t, source.AddAccount(userID, account, []byte{1, 2}, viewingkey.EIP712Signature))
+		sk := newTestSessionKey(t, &wecommon.SessionKeyPolicy{SpendCap: (*hexutil.Big)(big.NewInt(100))})
+		require.NoError(t, source.AddSessionKey(userID, sk))
+		require.NoError(t, source.AddSessionKeySpend(userID, *sk.Account.Address, big.NewInt(10)))
+		_, apiKey, err := wecommon.NewAPIKey("bot", []wecommon.APIKeyScope{wecommon.APIKeyScopeRead}, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, source.AddAPIKey(userID, *apiKey))
+		apiKeys = append(apiKeys, apiKey)
+		userIDs = append(userIDs, userID)
+	}
+	require.NoError(t, source.RevokeAPIKey(userIDs[2], apiKeys[2].ID, time.Now()))
+
+	// the dry run doesn't write anything
+	report, err := MigrateUsers(source, target, true, testlog.Logger())
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Copied)
+	_, err = target.GetUser(userIDs[0])
+	require.Error(t, err)
+
+	report, err = MigrateUsers(source, target, false, testlog.Logger())
+	require.NoError(t, err)
+	require.Equal(t, &MigrationReport{
+		Users: 3, Accounts: 3, SessionKeys: 3, APIKeys: 3, Copied: 3,
+		SourceChecksum: report.SourceChecksum, TargetChecksum: report.SourceChecksum,
+	}, report)
+	for _, userID := range userIDs {
+		sourceUser, err := source.GetUser(userID)
+		require.NoError(t, err)
+		targetUser, err := target.GetUser(userID)
+		require.NoError(t, err)
+		require.Equal(t, mustChecksum(t, sourceUser), mustChecksum(t, targetUser))
+		for _, sk := range targetUser.SessionKeys {
+			require.Equal(t, big.NewInt(10), sk.Spent)
+		}
+	}
+	// the valid API keys authenticate against the target
+	user, err := target.GetUserByAPIKey(apiKeys[0].Hash)
+	require.NoError(t, err)
+	require.Equal(t, userIDs[0], user.ID)
+	_, err = target.GetUserByAPIKey(apiKeys[2].Hash)
+	require.ErrorIs(t, err, wecommon.ErrAPIKeyNotFound)
+
+	// a second run only copies the users which changed in the meantime
+	require.NoError(t, source.AddSessionKey(userIDs[1], newTestSessionKey(t, nil)))
+	report, err = MigrateUsers(source, target, false, testlog.Logger())
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Copied)
+	require.Equal(t, 2, report.Skipped)
+	require.Equal(t, report.SourceChecksum, report.TargetChecksum)
+	targetUser, err := target.GetUser(userIDs[1])
+	require.NoError(t, err)
+	require.Len(t, targetUser.SessionKeys, 2)
+}
+
+func mustChecksum(t *testing.T, user *wecommon.GWUser) gethcommon.Hash {
+	checksum, err := UserChecksum(user)
+	require.NoError(t, err)
+	return checksum
+}
+
+// undecryptableSource - a source storage with users which could not be decrypted
+type undecryptableSource struct {
+	UserStorage
+}
+
+func (s *undecryptableSource) ForEachUser(fn func(*wecommon.GWUser) error) error {
+	if err := s.UserStorage.ForEachUser(fn); err != nil {
+		return err
+	}
+	return &dbcommon.UndecryptableUsersError{Count: 2}
+}
+
+func TestMigrateUsersFailsOnUndecryptableUsers(t *testing.T) {
+	source, err := New("sqlite", "", "", nil, testlog.Logger())
+	require.NoError(t, err)
+	target, err := New("sqlite", "", "", nil, testlog.Logger())
+	require.NoError(t, err)
+	userID := make([]byte, 20)
+	rand.Read(userID)
+	require.NoError(t, source.AddUser(userID, []byte{1}))
+
+	report, err := MigrateUsers(&undecryptableSource{UserStorage: source}, target, false, testlog.Logger())
+	var undecryptable *dbcommon.UndecryptableUsersError
+	require.ErrorAs(t, err, &undecryptable)
+	require.Equal(t, 2, report.Undecryptable)
+	// the readable users are still copied
+	require.Equal(t, 1, report.Copied)
+}
+
+func TestCombinedChecksum(t *testing.T) {
+	a := gethcommon.HexToHash("0x01")
+	b := gethcommon.HexToHash("0x02")
+	require.Equal(t, combinedChecksum([]gethcommon.Hash{a, b}), combinedChecksum([]gethcommon.Hash{b, a}))
+	// duplicated users don't cancel out
+	require.NotEqual(t, combinedChecksum(nil), combinedChecksum([]gethcommon.Hash{a, a}))
+	require.NotEqual(t, combinedChecksum([]gethcommon.Hash{b}), combinedChecksum([]gethcommon.Hash{a, a, b}))
+}
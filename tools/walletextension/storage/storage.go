@@ -2,12 +2,18 @@ package storage
 
 import (
 	"fmt"
+	"math/big"
+	"time"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	gethlog "github.com/ethereum/go-ethereum/log"
 	"github.com/ten-protocol/go-ten/go/common/viewingkey"
 
 	"github.com/ten-protocol/go-ten/tools/walletextension/common"
+	"github.com/ten-protocol/go-ten/tools/walletextension/ratelimiter"
+	dbcommon "github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
 	"github.com/ten-protocol/go-ten/tools/walletextension/storage/database/cosmosdb"
+	"github.com/ten-protocol/go-ten/tools/walletextension/storage/database/postgres"
 	"github.com/ten-protocol/go-ten/tools/walletextension/storage/database/sqlite"
 )
 
@@ -17,9 +23,23 @@ type UserStorage interface {
 	DeleteUser(userID []byte) error
 	AddAccount(userID []byte, accountAddress []byte, signature []byte, signatureType viewingkey.SignatureType) error
 	AddSessionKey(userID []byte, key common.GWSessionKey) error
-	ActivateSessionKey(userID []byte, active bool) error
-	RemoveSessionKey(userID []byte) error
+	ActivateSessionKey(userID []byte, address gethcommon.Address, active bool) error
+	SetSessionKeyPolicy(userID []byte, address gethcommon.Address, policy *common.SessionKeyPolicy) error
+	SetSessionKeyFunding(userID []byte, address gethcommon.Address, funding *common.SessionKeyFunding) error
+	AddSessionKeySpend(userID []byte, address gethcommon.Address, amount *big.Int) error
+	// SetSessionKeySweep - marks the session key as deleted, with the pending transfer of its funds
+	SetSessionKeySweep(userID []byte, address gethcommon.Address, sweep *common.SessionKeyFundsTransfer) error
+	RemoveSessionKey(userID []byte, address gethcommon.Address) error
+	// AddAPIKey - adds or replaces the API key of the user. The valid keys are indexed by their hash
+	AddAPIKey(userID []byte, key common.GWAPIKey) error
+	// RevokeAPIKey - marks the key as revoked, so it can't be used anymore. The key is still listed for the user
+	RevokeAPIKey(userID []byte, keyID string, revokedAt time.Time) error
 	GetUser(userID []byte) (*common.GWUser, error)
+	// GetUserByAPIKey - returns the user owning the valid API key with the hash, or common.ErrAPIKeyNotFound
+	GetUserByAPIKey(keyHash []byte) (*common.GWUser, error)
+	// ForEachUser - streams all the users. The iteration stops at the first error returned by fn.
+	// The users which can't be decrypted are skipped and reported with an UndecryptableUsersError at the end
+	ForEachUser(fn func(*common.GWUser) error) error
 	GetEncryptionKey() []byte
 }
 
@@ -31,6 +51,8 @@ func New(dbType, dbConnectionURL, dbPath string, randomKey []byte, logger gethlo
 		underlyingStorage, err = sqlite.NewSqliteDatabase(dbPath)
 	case "cosmosDB":
 		underlyingStorage, err = cosmosdb.NewCosmosDB(dbConnectionURL, randomKey)
+	case "postgres":
+		underlyingStorage, err = postgres.NewPostgresDB(dbConnectionURL, randomKey)
 	default:
 		panic(fmt.Sprintf("unknown db type: %s", dbType))
 	}
@@ -42,9 +64,23 @@ func New(dbType, dbConnectionURL, dbPath string, randomKey []byte, logger gethlo
 }
 
 // NewMetricsStorage is a factory function to create a MetricsStorage instance
-func NewMetricsStorage(dbType, dbConnectionURL string) (*cosmosdb.MetricsStorageCosmosDB, error) {
-	if dbType == "cosmosDB" {
+func NewMetricsStorage(dbType, dbConnectionURL string) (dbcommon.MetricsStorage, error) {
+	switch dbType {
+	case "cosmosDB":
 		return cosmosdb.NewMetricsStorage(dbConnectionURL)
+	case "postgres":
+		return postgres.NewMetricsStorage(dbConnectionURL)
+	default:
+		return nil, nil // Return nil for other database types
+	}
+}
+
+// NewRateLimitStore - the rate limiter state shared by the gateway instances which use the same database
+func NewRateLimitStore(dbType, dbConnectionURL string) (ratelimiter.Store, error) {
+	switch dbType {
+	case "postgres":
+		return postgres.NewRateLimitStore(dbConnectionURL)
+	default:
+		return nil, fmt.Errorf("the rate limit state can't be shared with db type: %s", dbType)
 	}
-	return nil, nil // Return nil for other database types
 }
@@ -4,25 +4,39 @@ import (
 	"bytes"
 	"crypto/rand"
 	"errors"
+	"math/big"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
 	"github.com/ten-protocol/go-ten/tools/walletextension/storage/database/common"
 
 	"github.com/ten-protocol/go-ten/integration/common/testlog"
 
 	"github.com/ten-protocol/go-ten/go/common/viewingkey"
 
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
 	wecommon "github.com/ten-protocol/go-ten/tools/walletextension/common"
 )
 
 var tests = map[string]func(storage UserStorage, t *testing.T){
-	"testAddAndGetUser": testAddAndGetUser,
-	"testAddAccounts":   testAddAccounts,
-	"testDeleteUser":    testDeleteUser,
-	"testGetUser":       testGetUser,
+	"testAddAndGetUser":      testAddAndGetUser,
+	"testAddAccounts":        testAddAccounts,
+	"testDeleteUser":         testDeleteUser,
+	"testGetUser":            testGetUser,
+	"testSessionKeys":        testSessionKeys,
+	"testSessionKeySpendCap": testSessionKeySpendCap,
+	"testForEachUser":        testForEachUser,
+	"testAPIKeys":            testAPIKeys,
 }
 
+// postgresURLEnv - the connection URL of a PostgreSQL database used to run the tests against the postgres storage
+const postgresURLEnv = "GATEWAY_TEST_POSTGRES_URL"
+
 func TestGatewayStorage(t *testing.T) {
 	randomKey, err := wecommon.GenerateRandomKey()
 	require.NoError(t, err)
@@ -38,6 +52,51 @@ func TestGatewayStorage(t *testing.T) {
 	}
 }
 
+func TestGatewayStoragePostgres(t *testing.T) {
+	dbURL := os.Getenv(postgresURLEnv)
+	if dbURL == "" {
+		t.Skipf("%s is not set", postgresURLEnv)
+	}
+	randomKey, err := wecommon.GenerateRandomKey()
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			storage, err := New("postgres", dbURL, "", randomKey, testlog.Logger())
+			require.NoError(t, err)
+
+			test(storage, t)
+		})
+	}
+}
+
+func TestForEachUserReportsUndecryptableUsersPostgres(t *testing.T) {
+	dbURL := os.Getenv(postgresURLEnv)
+	if dbURL == "" {
+		t.Skipf("%s is not set", postgresURLEnv)
+	}
+	key, err := wecommon.GenerateRandomKey()
+	require.NoError(t, err)
+	storage, err := New("postgres", dbURL, "", key, testlog.Logger())
+	require.NoError(t, err)
+	userID := make([]byte, 20)
+	rand.Read(userID)
+	require.NoError(t, storage.AddUser(userID, []byte{1}))
+
+	// the user is skipped by a storage with another key, but the iteration reports it
+	otherKey, err := wecommon.GenerateRandomKey()
+	require.NoError(t, err)
+	otherStorage, err := New("postgres", dbURL, "", otherKey, testlog.Logger())
+	require.NoError(t, err)
+	err = otherStorage.ForEachUser(func(user *wecommon.GWUser) error {
+		require.NotEqual(t, userID, user.ID)
+		return nil
+	})
+	var undecryptable *common.UndecryptableUsersError
+	require.ErrorAs(t, err, &undecryptable)
+	require.Positive(t, undecryptable.Count)
+}
+
 func testAddAndGetUser(storage UserStorage, t *testing.T) {
 	// Generate random user ID and private key
 	userID := make([]byte, 20)
@@ -202,3 +261,191 @@ func testGetUser(storage UserStorage, t *testing.T) {
 		t.Error("Expected error when getting non-existent user, but got none")
 	}
 }
+
+func newTestSessionKey(t *testing.T, policy *wecommon.SessionKeyPolicy) wecommon.GWSessionKey {
+	prvKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(prvKey.PublicKey)
+	return wecommon.GWSessionKey{
+		PrivateKey: ecies.ImportECDSA(prvKey),
+		Account: &wecommon.GWAccount{
+			Address:       &address,
+			Signature:     []byte{1, 2, 3},
+			SignatureType: viewingkey.EIP712Signature,
+		},
+		Policy: policy,
+	}
+}
+
+func testSessionKeys(storage UserStorage, t *testing.T) {
+	userID := make([]byte, 20)
+	rand.Read(userID)
+	require.NoError(t, storage.AddUser(userID, []byte{1}))
+
+	policy := &wecommon.SessionKeyPolicy{
+		ExpiresAt:     1_700_000_000,
+		MaxValuePerTx: (*hexutil.Big)(big.NewInt(1000)),
+		AllowedTargets: []wecommon.SessionKeyTarget{
+			{Contract: gethcommon.HexToAddress("0x01"), Selectors: []hexutil.Bytes{{1, 2, 3, 4}}},
+		},
+	}
+	sk1 := newTestSessionKey(t, policy)
+	sk2 := newTestSessionKey(t, nil)
+	require.NoError(t, storage.AddSessionKey(userID, sk1))
+	require.NoError(t, storage.AddSessionKey(userID, sk2))
+	require.NoError(t, storage.ActivateSessionKey(userID, *sk2.Account.Address, true))
+
+	user, err := storage.GetUser(userID)
+	require.NoError(t, err)
+	require.Len(t, user.SessionKeys, 2)
+	require.Len(t, user.AllAccounts(), 2)
+	require.Equal(t, policy, user.SessionKeys[*sk1.Account.Address].Policy)
+	require.False(t, user.SessionKeys[*sk1.Account.Address].Active)
+	require.True(t, user.SessionKeys[*sk2.Account.Address].Active)
+	require.Equal(t, sk2.PrivateKey.D, user.SessionKeys[*sk2.Account.Address].PrivateKey.D)
+
+	require.NoError(t, storage.SetSessionKeyPolicy(userID, *sk1.Account.Address, nil))
+	require.NoError(t, storage.RemoveSessionKey(userID, *sk2.Account.Address))
+	user, err = storage.GetUser(userID)
+	require.NoError(t, err)
+	require.Len(t, user.SessionKeys, 1)
+	require.Nil(t, user.SessionKeys[*sk1.Account.Address].Policy)
+
+	err = storage.RemoveSessionKey(userID, *sk2.Account.Address)
+	require.ErrorIs(t, err, common.ErrSessionKeyNotFound)
+}
+
+func testSessionKeySpendCap(storage UserStorage, t *testing.T) {
+	userID := make([]byte, 20)
+	rand.Read(userID)
+	require.NoError(t, storage.AddUser(userID, []byte{1}))
+
+	sk := newTestSessionKey(t, &wecommon.SessionKeyPolicy{SpendCap: (*hexutil.Big)(big.NewInt(100))})
+	require.NoError(t, storage.AddSessionKey(userID, sk))
+
+	require.NoError(t, storage.AddSessionKeySpend(userID, *sk.Account.Address, big.NewInt(60)))
+	err := storage.AddSessionKeySpend(userID, *sk.Account.Address, big.NewInt(50))
+	require.ErrorIs(t, err, wecommon.ErrSpendCapExceeded)
+	require.NoError(t, storage.AddSessionKeySpend(userID, *sk.Account.Address, big.NewInt(40)))
+
+	user, err := storage.GetUser(userID)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), user.SessionKeys[*sk.Account.Address].Spent)
+}
+
+func testForEachUser(storage UserStorage, t *testing.T) {
+	// the postgres database is shared between the tests, so only the users added here are checked
+	added := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		userID := make([]byte, 20)
+		rand.Read(userID)
+		require.NoError(t, storage.AddUser(userID, []byte{1}))
+		require.NoError(t, storage.AddSessionKey(userID, newTestSessionKey(t, nil)))
+		added[string(userID)] = true
+	}
+
+	found := 0
+	err := storage.ForEachUser(func(user *wecommon.GWUser) error {
+		if added[string(user.ID)] {
+			found++
+			require.Len(t, user.SessionKeys, 1)
+		}
+		return nil
+	})
+	// the users written by the previous runs against the shared postgres database have other keys
+	var undecryptable *common.UndecryptableUsersError
+	if !errors.As(err, &undecryptable) {
+		require.NoError(t, err)
+	}
+	require.Equal(t, len(added), found)
+
+	// the iteration stops at the first error
+	stop := errors.New("stop")
+	require.ErrorIs(t, storage.ForEachUser(func(*wecommon.GWUser) error { return stop }), stop)
+}
+
+func testAPIKeys(storage UserStorage, t *testing.T) {
+	userID := make([]byte, 20)
+	rand.Read(userID)
+	require.NoError(t, storage.AddUser(userID, []byte{1}))
+
+	readKey, readAPIKey, err := wecommon.NewAPIKey("indexer", []wecommon.APIKeyScope{wecommon.APIKeyScopeRead}, time.Now())
+	require.NoError(t, err)
+	_, sendAPIKey, err := wecommon.NewAPIKey("bot", []wecommon.APIKeyScope{wecommon.APIKeyScopeRead, wecommon.APIKeyScopeSendTx}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, storage.AddAPIKey(userID, *readAPIKey))
+	require.NoError(t, storage.AddAPIKey(userID, *sendAPIKey))
+
+	user, err := storage.GetUserByAPIKey(wecommon.HashAPIKey(readKey))
+	require.NoError(t, err)
+	require.Equal(t, userID, user.ID)
+	require.Len(t, user.APIKeys, 2)
+	apiKey, err := user.GetAPIKeyByHash(wecommon.HashAPIKey(readKey))
+	require.NoError(t, err)
+	require.Equal(t, "indexer", apiKey.Name)
+	require.True(t, apiKey.HasAnyScope(wecommon.APIKeyScopeRead))
+	require.False(t, apiKey.HasAnyScope(wecommon.APIKeyScopeSendTx, wecommon.APIKeyScopeSessionKeys))
+
+	_, err = storage.GetUserByAPIKey(wecommon.HashAPIKey("tenk_unknown"))
+	require.ErrorIs(t, err, wecommon.ErrAPIKeyNotFound)
+
+	// the revoked key can't authenticate anymore, but it is kept with the user
+	require.NoError(t, storage.RevokeAPIKey(userID, readAPIKey.ID, time.Now()))
+	_, err = storage.GetUserByAPIKey(readAPIKey.Hash)
+	require.ErrorIs(t, err, wecommon.ErrAPIKeyNotFound)
+	user, err = storage.GetUserByAPIKey(sendAPIKey.Hash)
+	require.NoError(t, err)
+	require.Len(t, user.APIKeysInfo(), 2)
+	require.True(t, user.APIKeys[readAPIKey.ID].IsRevoked())
+	_, err = user.GetAPIKeyByHash(readAPIKey.Hash)
+	require.ErrorIs(t, err, wecommon.ErrAPIKeyNotFound)
+
+	require.ErrorIs(t, storage.RevokeAPIKey(userID, "unknown", time.Now()), wecommon.ErrAPIKeyNotFound)
+
+	// deleting the user removes its keys
+	require.NoError(t, storage.DeleteUser(userID))
+	_, err = storage.GetUserByAPIKey(sendAPIKey.Hash)
+	require.ErrorIs(t, err, wecommon.ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyRevokedByAnotherInstance(t *testing.T) {
+	randomKey, err := wecommon.GenerateRandomKey()
+	require.NoError(t, err)
+	storage, err := New("sqlite", "", "", randomKey, testlog.Logger())
+	require.NoError(t, err)
+	// two gateway instances sharing the database
+	instance := storage.(*UserStorageWithCache)
+	other, err := NewUserStorageWithCache(instance.storage, testlog.Logger())
+	require.NoError(t, err)
+	instance.apiKeyTTL = 100 * time.Millisecond
+	other.apiKeyTTL = 100 * time.Millisecond
+
+	userID := make([]byte, 20)
+	rand.Read(userID)
+	require.NoError(t, instance.AddUser(userID, []byte{1}))
+	_, apiKey, err := wecommon.NewAPIKey("indexer", []wecommon.APIKeyScope{wecommon.APIKeyScopeRead}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, instance.AddAPIKey(userID, *apiKey))
+
+	// both instances cache the user of the key
+	for _, s := range []*UserStorageWithCache{instance, other} {
+		_, err := s.GetUserByAPIKey(apiKey.Hash)
+		require.NoError(t, err)
+	}
+	// ristretto applies the writes asynchronously
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, instance.RevokeAPIKey(userID, apiKey.ID, time.Now()))
+	// the instance which revoked the key sees the revocation at once
+	user, err := instance.GetUserByAPIKey(apiKey.Hash)
+	if err == nil {
+		_, err = user.GetAPIKeyByHash(apiKey.Hash)
+	}
+	require.ErrorIs(t, err, wecommon.ErrAPIKeyNotFound)
+
+	// the other instance sees it once its cached records expire
+	require.Eventually(t, func() bool {
+		_, err := other.GetUserByAPIKey(apiKey.Hash)
+		return errors.Is(err, wecommon.ErrAPIKeyNotFound)
+	}, time.Second, 10*time.Millisecond)
+}
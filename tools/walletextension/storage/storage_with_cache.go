@@ -1,6 +1,10 @@
 package storage
 
 import (
+	"math/big"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ten-protocol/go-ten/go/common/viewingkey"
 	"github.com/ten-protocol/go-ten/tools/walletextension/cache"
@@ -9,12 +13,23 @@ import (
 
 // UserStorageWithCache implements the UserStorage interface with caching
 type UserStorageWithCache struct {
-	storage UserStorage
-	cache   cache.Cache
+	storage   UserStorage
+	cache     cache.Cache
+	apiKeyTTL time.Duration
 }
 
 const UserCacheSize = 10_000
 
+const (
+	// the cache keys of the API key owners and of the users read for them are prefixed, so they can't collide with the user ids
+	apiKeyCachePrefix     = "apikey:"
+	apiKeyUserCachePrefix = "apikeyuser:"
+
+	// the users authenticated by an API key are read again after this interval, so a key revoked by another gateway
+	// instance sharing the database is rejected after at most this delay
+	apiKeyCacheTTL = 10 * time.Second
+)
+
 // NewUserStorageWithCache creates a new UserStorageWithCache instance
 func NewUserStorageWithCache(storage UserStorage, logger log.Logger) (*UserStorageWithCache, error) {
 	c, err := cache.NewCache(UserCacheSize, logger)
@@ -22,8 +37,9 @@ func NewUserStorageWithCache(storage UserStorage, logger log.Logger) (*UserStora
 		return nil, err
 	}
 	return &UserStorageWithCache{
-		storage: storage,
-		cache:   c,
+		storage:   storage,
+		cache:     c,
+		apiKeyTTL: apiKeyCacheTTL,
 	}, nil
 }
 
@@ -38,16 +54,16 @@ func (s *UserStorageWithCache) DeleteUser(userID []byte) error {
 	if err != nil {
 		return err
 	}
-	s.cache.Remove(userID)
+	s.invalidate(userID)
 	return nil
 }
 
-func (s *UserStorageWithCache) ActivateSessionKey(userID []byte, active bool) error {
-	err := s.storage.ActivateSessionKey(userID, active)
+func (s *UserStorageWithCache) ActivateSessionKey(userID []byte, address gethcommon.Address, active bool) error {
+	err := s.storage.ActivateSessionKey(userID, address, active)
 	if err != nil {
 		return err
 	}
-	s.cache.Remove(userID)
+	s.invalidate(userID)
 	return nil
 }
 
@@ -56,16 +72,52 @@ func (s *UserStorageWithCache) AddSessionKey(userID []byte, key wecommon.GWSessi
 	if err != nil {
 		return err
 	}
-	s.cache.Remove(userID)
+	s.invalidate(userID)
 	return nil
 }
 
-func (s *UserStorageWithCache) RemoveSessionKey(userID []byte) error {
-	err := s.storage.RemoveSessionKey(userID)
+func (s *UserStorageWithCache) SetSessionKeyPolicy(userID []byte, address gethcommon.Address, policy *wecommon.SessionKeyPolicy) error {
+	err := s.storage.SetSessionKeyPolicy(userID, address, policy)
 	if err != nil {
 		return err
 	}
-	s.cache.Remove(userID)
+	s.invalidate(userID)
+	return nil
+}
+
+func (s *UserStorageWithCache) SetSessionKeyFunding(userID []byte, address gethcommon.Address, funding *wecommon.SessionKeyFunding) error {
+	err := s.storage.SetSessionKeyFunding(userID, address, funding)
+	if err != nil {
+		return err
+	}
+	s.invalidate(userID)
+	return nil
+}
+
+func (s *UserStorageWithCache) AddSessionKeySpend(userID []byte, address gethcommon.Address, amount *big.Int) error {
+	err := s.storage.AddSessionKeySpend(userID, address, amount)
+	if err != nil {
+		return err
+	}
+	s.invalidate(userID)
+	return nil
+}
+
+func (s *UserStorageWithCache) SetSessionKeySweep(userID []byte, address gethcommon.Address, sweep *wecommon.SessionKeyFundsTransfer) error {
+	err := s.storage.SetSessionKeySweep(userID, address, sweep)
+	if err != nil {
+		return err
+	}
+	s.invalidate(userID)
+	return nil
+}
+
+func (s *UserStorageWithCache) RemoveSessionKey(userID []byte, address gethcommon.Address) error {
+	err := s.storage.RemoveSessionKey(userID, address)
+	if err != nil {
+		return err
+	}
+	s.invalidate(userID)
 	return nil
 }
 
@@ -75,7 +127,25 @@ func (s *UserStorageWithCache) AddAccount(userID []byte, accountAddress []byte,
 	if err != nil {
 		return err
 	}
-	s.cache.Remove(userID)
+	s.invalidate(userID)
+	return nil
+}
+
+func (s *UserStorageWithCache) AddAPIKey(userID []byte, key wecommon.GWAPIKey) error {
+	err := s.storage.AddAPIKey(userID, key)
+	if err != nil {
+		return err
+	}
+	s.invalidate(userID)
+	return nil
+}
+
+func (s *UserStorageWithCache) RevokeAPIKey(userID []byte, keyID string, revokedAt time.Time) error {
+	err := s.storage.RevokeAPIKey(userID, keyID, revokedAt)
+	if err != nil {
+		return err
+	}
+	s.invalidate(userID)
 	return nil
 }
 
@@ -86,6 +156,50 @@ func (s *UserStorageWithCache) GetUser(userID []byte) (*wecommon.GWUser, error)
 	})
 }
 
+// GetUserByAPIKey caches the owner of the key and the user only for a short time, because the revocation of a key
+// by another gateway instance doesn't invalidate the cache. The caller must check that the key of the user is still valid.
+func (s *UserStorageWithCache) GetUserByAPIKey(keyHash []byte) (*wecommon.GWUser, error) {
+	userID, err := withTTL(s.cache, append([]byte(apiKeyCachePrefix), keyHash...), s.apiKeyTTL, func() (*[]byte, error) {
+		user, err := s.storage.GetUserByAPIKey(keyHash)
+		if err != nil {
+			return nil, err
+		}
+		return &user.ID, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withTTL(s.cache, append([]byte(apiKeyUserCachePrefix), *userID...), s.apiKeyTTL, func() (*wecommon.GWUser, error) {
+		return s.storage.GetUser(*userID)
+	})
+}
+
+// invalidate removes the cached records of the user changed by this instance
+func (s *UserStorageWithCache) invalidate(userID []byte) {
+	s.cache.Remove(userID)
+	s.cache.Remove(append([]byte(apiKeyUserCachePrefix), userID...))
+}
+
+// withTTL returns the cached value of the key, or reads it and caches it for the ttl
+func withTTL[R any](c cache.Cache, key []byte, ttl time.Duration, read func() (*R, error)) (*R, error) {
+	if cached, found := c.Get(key); found {
+		if value, ok := cached.(*R); ok {
+			return value, nil
+		}
+	}
+	value, err := read()
+	if err != nil {
+		return nil, err
+	}
+	c.Set(key, value, ttl)
+	return value, nil
+}
+
+// ForEachUser streams the users from the underlying storage, bypassing the cache
+func (s *UserStorageWithCache) ForEachUser(fn func(*wecommon.GWUser) error) error {
+	return s.storage.ForEachUser(fn)
+}
+
 // GetEncryptionKey delegates to the underlying storage
 func (s *UserStorageWithCache) GetEncryptionKey() []byte {
 	return s.storage.GetEncryptionKey()
@@ -2,6 +2,7 @@ package walletextension
 
 import (
 	"crypto/tls"
+	"errors"
 	"net/http"
 	"os"
 	"time"
@@ -31,6 +32,7 @@ type Container struct {
 	stopControl     *stopcontrol.StopControl
 	logger          gethlog.Logger
 	rpcServer       node.Server
+	metricsServer   *http.Server
 	services        *services.Services
 	newHeadsService *subscription.NewHeadsService
 }
@@ -49,12 +51,12 @@ func NewContainerFromConfig(config wecommon.Config, logger gethlog.Logger) *Cont
 
 	// Create metrics tracker
 	var metricsTracker metrics.Metrics
-	if config.DBType == "cosmosDB" {
-		metricsStorage, err := storage.NewMetricsStorage(config.DBType, config.DBConnectionURL)
-		if err != nil {
-			logger.Crit("unable to create metrics storage", log.ErrKey, err)
-			os.Exit(1)
-		}
+	metricsStorage, err := storage.NewMetricsStorage(config.DBType, config.DBConnectionURL)
+	if err != nil {
+		logger.Crit("unable to create metrics storage", log.ErrKey, err)
+		os.Exit(1)
+	}
+	if metricsStorage != nil {
 		metricsTracker = metrics.NewMetricsTracker(metricsStorage, logger)
 	} else {
 		metricsTracker = metrics.NewNoOpMetricsTracker(logger)
@@ -83,6 +85,10 @@ func NewContainerFromConfig(config wecommon.Config, logger gethlog.Logger) *Cont
 		WsPath:     wecommon.APIVersion1 + "/",
 		HTTPPath:   wecommon.APIVersion1 + "/",
 		Host:       config.WalletExtensionHost,
+		// each request of a batch is authenticated, rate limited and cached individually
+		BatchItemLimit:   config.BatchMaxSize,
+		BatchConcurrency: config.BatchMaxConcurrency,
+		BatchKey:         rpcapi.BatchKey(walletExt),
 	}
 
 	// check if TLS is enabled
@@ -127,6 +133,21 @@ func NewContainerFromConfig(config wecommon.Config, logger gethlog.Logger) *Cont
 
 	rpcServer.RegisterRoutes(httpapi.NewHTTPRoutes(walletExt))
 
+	// expose the operational metrics in the Prometheus format, on their own address so they are not public
+	var metricsServer *http.Server
+	if config.MetricsAddress != "" {
+		metrics.Enable()
+		metrics.RegisterTrackerCollector(metricsTracker)
+		metrics.RegisterCollector(walletExt.BackendRPC.CollectPoolMetrics)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:              config.MetricsAddress,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
+
 	// register all RPC endpoints exposed by a typical Geth node
 	rpcServer.RegisterAPIs([]gethrpc.API{
 		{
@@ -170,6 +191,7 @@ func NewContainerFromConfig(config wecommon.Config, logger gethlog.Logger) *Cont
 	return &Container{
 		stopControl:     stopControl,
 		rpcServer:       rpcServer,
+		metricsServer:   metricsServer,
 		newHeadsService: walletExt.NewHeadsService,
 		services:        walletExt,
 		logger:          logger,
@@ -187,6 +209,14 @@ func (w *Container) Start() error {
 	if err != nil {
 		return err
 	}
+
+	if w.metricsServer != nil {
+		go func() {
+			if err := w.metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				w.logger.Error("metrics server failed", log.ErrKey, err)
+			}
+		}()
+	}
 	return nil
 }
 
@@ -203,6 +233,10 @@ func (w *Container) Stop() error {
 		}()
 	}
 
+	if w.metricsServer != nil {
+		_ = w.metricsServer.Close()
+	}
+
 	w.services.Stop()
 	return nil
 }